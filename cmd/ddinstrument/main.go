@@ -0,0 +1,114 @@
+// Command ddinstrument instruments Go programs without manual code changes,
+// by rewriting their source files to use the integrations of the contrib
+// packages:
+//
+//   - the handlers given to http.ListenAndServe, http.Serve and their TLS
+//     variants, or set in an http.Server, are wrapped with
+//     httptrace.WrapHandler, the default ServeMux standing for nil handlers;
+//   - the clients created as &http.Client{...} are wrapped with
+//     httptrace.WrapClient;
+//   - sql.Open is replaced with sqltrace.AutoOpen, which registers the
+//     driver with the integration;
+//   - the interceptors of the gRPC integration are added to the options of
+//     grpc.NewServer, grpc.Dial and grpc.DialContext.
+//
+// The files importing a contrib package are left untouched, so that the
+// programs instrumented by hand aren't instrumented twice, as well as the
+// test files. It is meant to be run before building, e.g. with
+//
+//	//go:generate ddinstrument -w -service my-service .
+//
+// or from a build script, on a copy of the sources for a build which doesn't
+// change them:
+//
+//	ddinstrument -w -service my-service ./...
+//
+// Without -w, the rewritten files are printed to the standard output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	write   = flag.Bool("w", false, "write the result to the source files instead of the standard output")
+	list    = flag.Bool("l", false, "list the files instrumented")
+	service = flag.String("service", "http.router", "service of the traced HTTP handlers")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ddinstrument [flags] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var failed bool
+	for _, path := range paths {
+		if err := walk(path); err != nil {
+			fmt.Fprintf(os.Stderr, "ddinstrument: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// walk instruments the Go files of the given directory, or of its whole
+// tree when it ends with "/...", or the given file.
+func walk(path string) error {
+	recursive := strings.HasSuffix(path, "/...")
+	if recursive {
+		path = strings.TrimSuffix(path, "/...")
+	}
+	return filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			base := filepath.Base(name)
+			if name != path && (!recursive || base == "vendor" || base == "testdata" || strings.HasPrefix(base, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name != path && (!strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go")) {
+			return nil
+		}
+		return instrument(name, info.Mode())
+	})
+}
+
+// instrument instruments the given file.
+func instrument(name string, mode os.FileMode) error {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	out, warnings, err := rewrite(name, src, *service)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "ddinstrument: %s\n", w)
+	}
+	if err != nil || out == nil {
+		return err
+	}
+	if *list {
+		fmt.Println(name)
+	}
+	if *write {
+		return ioutil.WriteFile(name, out, mode)
+	}
+	if !*list {
+		_, err = os.Stdout.Write(out)
+	}
+	return err
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	httpPath = "net/http"
+	sqlPath  = "database/sql"
+	grpcPath = "google.golang.org/grpc"
+
+	contribPath = "github.com/DataDog/dd-trace-go/contrib/"
+)
+
+// integration is a library the calls of which are rewritten, and the contrib
+// package they are rewritten to use.
+type integration struct {
+	path    string // import path of the library
+	contrib string // import path of the integration
+	name    string // name the integration is imported with
+}
+
+var integrations = []integration{
+	{path: httpPath, contrib: contribPath + "net/http", name: "httptrace"},
+	{path: sqlPath, contrib: contribPath + "database/sql", name: "sqltrace"},
+	{path: grpcPath, contrib: contribPath + "google.golang.org/grpc", name: "grpctrace"},
+}
+
+// edit replaces the bytes of a source file between start and end, which are
+// equal for insertions.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// rewriter collects the edits instrumenting a source file.
+type rewriter struct {
+	fset    *token.FileSet
+	file    *ast.File
+	src     []byte
+	service string // service of the traced HTTP handlers
+
+	names    map[string]string // local names of the imported libraries, by path
+	aliases  map[string]string // names the integrations are imported with, by path of their library
+	refs     map[string]int    // references to the imported libraries left after the edits, by path
+	edits    []edit
+	used     map[string]bool // the integrations used by the edits, by path
+	warnings []string
+}
+
+// rewrite returns the given source file instrumented, and warnings about the
+// calls which couldn't be. It returns nil when there is nothing to change:
+// the files importing an integration already are left as is.
+func rewrite(filename string, src []byte, service string) ([]byte, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &rewriter{
+		fset:    fset,
+		file:    file,
+		src:     src,
+		service: service,
+		names:   make(map[string]string),
+		aliases: make(map[string]string),
+		refs:    make(map[string]int),
+		used:    make(map[string]bool),
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if strings.HasPrefix(path, contribPath) {
+			return nil, nil, nil
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		r.names[path] = name
+	}
+	r.pickAliases()
+	ast.Inspect(file, r.visit)
+	if len(r.edits) == 0 {
+		return nil, r.warnings, nil
+	}
+	r.addImports()
+	out, err := format.Source(apply(src, r.edits))
+	return out, r.warnings, err
+}
+
+// visit records the edits of the calls and the composite literals of the
+// supported libraries.
+func (r *rewriter) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.CallExpr:
+		switch {
+		case r.isFunc(n.Fun, httpPath, "ListenAndServe", "Serve", "ServeTLS"):
+			r.wrapHandler(n, 1)
+		case r.isFunc(n.Fun, httpPath, "ListenAndServeTLS"):
+			r.wrapHandler(n, 3)
+		case r.isFunc(n.Fun, sqlPath, "Open"):
+			r.replace(n.Fun, sqlPath, "AutoOpen")
+		case r.isFunc(n.Fun, grpcPath, "NewServer"):
+			r.addOptions(n, "UnaryInterceptor", "StreamInterceptor", "UnaryServerInterceptor", "StreamServerInterceptor")
+		case r.isFunc(n.Fun, grpcPath, "Dial", "DialContext"):
+			r.addOptions(n, "WithUnaryInterceptor", "WithStreamInterceptor", "UnaryClientInterceptor", "StreamClientInterceptor")
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := n.X.(*ast.CompositeLit); ok && n.Op == token.AND && r.isFunc(lit.Type, httpPath, "Client") {
+			r.wrap(n, httpPath, "WrapClient(", ")")
+		}
+	case *ast.CompositeLit:
+		if r.isFunc(n.Type, httpPath, "Server") {
+			r.wrapServerHandler(n)
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := n.X.(*ast.Ident); ok && pkg.Obj == nil {
+			for path, name := range r.names {
+				if pkg.Name == name {
+					r.refs[path]++
+				}
+			}
+		}
+	}
+	return true
+}
+
+// pickAliases picks the names the integrations are imported with, which
+// must not clash with the imports and the identifiers of the file, such as
+// the httptrace package of the standard library.
+func (r *rewriter) pickAliases() {
+	taken := make(map[string]bool)
+	for _, name := range r.names {
+		taken[name] = true
+	}
+	ast.Inspect(r.file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			taken[id.Name] = true
+		}
+		return true
+	})
+	for _, i := range integrations {
+		alias := i.name
+		for n := 2; taken[alias]; n++ {
+			alias = i.name + strconv.Itoa(n)
+		}
+		taken[alias] = true
+		r.aliases[i.path] = alias
+	}
+}
+
+// isFunc reports whether expr is one of the given exported identifiers of
+// the library imported with the given path.
+func (r *rewriter) isFunc(expr ast.Expr, path string, names ...string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Obj != nil || r.names[path] == "" || pkg.Name != r.names[path] {
+		return false
+	}
+	for _, name := range names {
+		if sel.Sel.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapHandler wraps the handler given as the i-th argument of an HTTP server
+// function, the default ServeMux when it is nil.
+func (r *rewriter) wrapHandler(call *ast.CallExpr, i int) {
+	if len(call.Args) <= i {
+		return
+	}
+	r.wrapHandlerExpr(call.Args[i])
+}
+
+// wrapServerHandler wraps the handler of an http.Server literal, setting it
+// to the default ServeMux when it is unset.
+func (r *rewriter) wrapServerHandler(lit *ast.CompositeLit) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			// positional fields, which start with Addr and Handler
+			if len(lit.Elts) > 1 {
+				r.wrapHandlerExpr(lit.Elts[1])
+			}
+			return
+		}
+		if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Handler" {
+			r.wrapHandlerExpr(kv.Value)
+			return
+		}
+	}
+	handler := fmt.Sprintf("Handler: %s.WrapHandler(%s.DefaultServeMux, %q, \"\"),", r.contribName(httpPath), r.names[httpPath], r.service)
+	if len(lit.Elts) > 0 && r.line(lit.Lbrace) != r.line(lit.Elts[0].Pos()) {
+		handler = "\n" + handler
+	}
+	r.insert(lit.Lbrace+1, handler)
+}
+
+// wrapHandlerExpr wraps the given handler with the HTTP integration.
+func (r *rewriter) wrapHandlerExpr(h ast.Expr) {
+	suffix := fmt.Sprintf(", %q, \"\")", r.service)
+	if id, ok := h.(*ast.Ident); ok && id.Name == "nil" {
+		r.replace(h, httpPath, "WrapHandler("+r.names[httpPath]+".DefaultServeMux"+suffix)
+		return
+	}
+	r.wrap(h, httpPath, "WrapHandler(", suffix)
+}
+
+// addOptions adds the interceptors of the gRPC integration to the options of
+// a call creating a server or a client connection, unless it sets its own
+// interceptors already.
+func (r *rewriter) addOptions(call *ast.CallExpr, unary, stream, unaryInterceptor, streamInterceptor string) {
+	if call.Ellipsis.IsValid() {
+		r.warn(call, "the options are given as a slice, add the interceptors of %s by hand", r.contrib(grpcPath))
+		return
+	}
+	for _, arg := range call.Args {
+		if c, ok := arg.(*ast.CallExpr); ok && r.isFunc(c.Fun, grpcPath, unary, stream) {
+			r.warn(call, "interceptors are set already, chain those of %s by hand", r.contrib(grpcPath))
+			return
+		}
+	}
+	grpc, trace := r.names[grpcPath], r.contribName(grpcPath)
+	opts := fmt.Sprintf("%s.%s(%s.%s()), %s.%s(%s.%s())", grpc, unary, trace, unaryInterceptor, grpc, stream, trace, streamInterceptor)
+	switch {
+	case len(call.Args) == 0:
+	case strings.Contains(string(r.src[r.offset(call.Args[len(call.Args)-1].End()):r.offset(call.Rparen)]), ","):
+		// the arguments span several lines, ending with a comma
+		opts += ",\n"
+	default:
+		opts = ", " + opts
+	}
+	r.insert(call.Rparen, opts)
+}
+
+// wrap surrounds expr with a call to a function of the integration of the
+// given library, given with its opening parenthesis, and the given suffix.
+func (r *rewriter) wrap(expr ast.Expr, path, fn, suffix string) {
+	r.insert(expr.Pos(), r.contribName(path)+"."+fn)
+	r.insert(expr.End(), suffix)
+}
+
+// replace replaces expr with the given function of the integration of the
+// given library, or with any expression starting with it.
+func (r *rewriter) replace(expr ast.Expr, path, fn string) {
+	if _, ok := expr.(*ast.SelectorExpr); ok {
+		// a function of the library, which is no longer referenced
+		r.refs[path]--
+	}
+	r.edits = append(r.edits, edit{
+		start: r.offset(expr.Pos()),
+		end:   r.offset(expr.End()),
+		text:  r.contribName(path) + "." + fn,
+	})
+}
+
+func (r *rewriter) insert(pos token.Pos, text string) {
+	off := r.offset(pos)
+	r.edits = append(r.edits, edit{start: off, end: off, text: text})
+}
+
+// contribName returns the name the integration of the given library is
+// imported with, marking it as used.
+func (r *rewriter) contribName(path string) string {
+	r.used[path] = true
+	return r.aliases[path]
+}
+
+func (r *rewriter) contrib(path string) string {
+	return r.integration(path).contrib
+}
+
+func (r *rewriter) integration(path string) integration {
+	for _, i := range integrations {
+		if i.path == path {
+			return i
+		}
+	}
+	panic("ddinstrument: unknown library " + path)
+}
+
+// addImports records the edits importing the integrations used, and those
+// removing the imports of the libraries which are no longer referenced, such
+// as database/sql when it was only used to open the databases.
+func (r *rewriter) addImports() {
+	var specs string
+	for _, i := range integrations {
+		if r.used[i.path] {
+			specs += fmt.Sprintf("\t%s %q\n", r.aliases[i.path], i.contrib)
+		}
+	}
+	added := false
+	for _, decl := range r.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			for _, spec := range gen.Specs {
+				if r.unused(spec.(*ast.ImportSpec)) {
+					r.removeLine(spec)
+				}
+			}
+			if !added {
+				r.insert(gen.Rparen, "\n"+specs)
+				added = true
+			}
+			continue
+		}
+		spec := gen.Specs[0].(*ast.ImportSpec)
+		switch {
+		case !added:
+			text := "import (\n"
+			if !r.unused(spec) {
+				text += "\t" + string(r.src[r.offset(spec.Pos()):r.offset(gen.End())]) + "\n\n"
+			}
+			r.edits = append(r.edits, edit{
+				start: r.offset(gen.Pos()),
+				end:   r.offset(gen.End()),
+				text:  text + specs + ")",
+			})
+			added = true
+		case r.unused(spec):
+			r.removeLine(gen)
+		}
+	}
+}
+
+// unused reports whether the library imported by spec is no longer
+// referenced once the edits are applied.
+func (r *rewriter) unused(spec *ast.ImportSpec) bool {
+	path, _ := strconv.Unquote(spec.Path.Value)
+	return r.used[path] && r.refs[path] == 0
+}
+
+// removeLine records the edit removing the line of the given node.
+func (r *rewriter) removeLine(n ast.Node) {
+	start, end := r.offset(n.Pos()), r.offset(n.End())
+	for start > 0 && r.src[start-1] != '\n' {
+		start--
+	}
+	for end < len(r.src) && r.src[end] != '\n' {
+		end++
+	}
+	r.edits = append(r.edits, edit{start: start, end: end + 1, text: ""})
+}
+
+func (r *rewriter) warn(n ast.Node, format string, args ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf("%s: %s", r.fset.Position(n.Pos()), fmt.Sprintf(format, args...)))
+}
+
+func (r *rewriter) offset(pos token.Pos) int { return r.fset.Position(pos).Offset }
+
+func (r *rewriter) line(pos token.Pos) int { return r.fset.Position(pos).Line }
+
+// apply returns src with the given edits, which don't overlap, applied. The
+// insertions made at the same offset are applied in the order they were
+// recorded.
+func apply(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out []byte
+	var last int
+	for _, e := range edits {
+		out = append(out, src[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, src[last:]...)
+}
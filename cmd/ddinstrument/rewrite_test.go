@@ -0,0 +1,235 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	for name, tt := range map[string]struct {
+		in, out  string
+		warnings int
+	}{
+		"http": {
+			in: `package main
+
+import (
+	"net/http"
+)
+
+func main() {
+	c := &http.Client{Timeout: time.Second}
+	http.ListenAndServe(":8080", nil)
+	http.ListenAndServeTLS(":8443", "cert", "key", mux)
+}
+`,
+			out: `package main
+
+import (
+	"net/http"
+
+	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http"
+)
+
+func main() {
+	c := httptrace.WrapClient(&http.Client{Timeout: time.Second})
+	http.ListenAndServe(":8080", httptrace.WrapHandler(http.DefaultServeMux, "web", ""))
+	http.ListenAndServeTLS(":8443", "cert", "key", httptrace.WrapHandler(mux, "web", ""))
+}
+`,
+		},
+		"http.Server": {
+			in: `package main
+
+import stdhttp "net/http"
+
+var (
+	a = &stdhttp.Server{Addr: ":8080", Handler: newMux()}
+	b = stdhttp.Server{
+		Addr: ":8081",
+	}
+)
+`,
+			out: `package main
+
+import (
+	stdhttp "net/http"
+
+	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http"
+)
+
+var (
+	a = &stdhttp.Server{Addr: ":8080", Handler: httptrace.WrapHandler(newMux(), "web", "")}
+	b = stdhttp.Server{
+		Handler: httptrace.WrapHandler(stdhttp.DefaultServeMux, "web", ""),
+		Addr:    ":8081",
+	}
+)
+`,
+		},
+		"sql": {
+			in: `package main
+
+import "database/sql"
+
+func open() (*sql.DB, error) {
+	return sql.Open("postgres", dsn)
+}
+`,
+			out: `package main
+
+import (
+	"database/sql"
+
+	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql"
+)
+
+func open() (*sql.DB, error) {
+	return sqltrace.AutoOpen("postgres", dsn)
+}
+`,
+		},
+		"sql unused": {
+			in: `package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func main() {
+	db, err := sql.Open("postgres", dsn)
+	log.Print(db, err)
+}
+`,
+			out: `package main
+
+import (
+	"log"
+
+	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql"
+)
+
+func main() {
+	db, err := sqltrace.AutoOpen("postgres", dsn)
+	log.Print(db, err)
+}
+`,
+		},
+		"sql unused single": {
+			in: `package main
+
+import "database/sql"
+
+var db, err = sql.Open("postgres", dsn)
+`,
+			out: `package main
+
+import (
+	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql"
+)
+
+var db, err = sqltrace.AutoOpen("postgres", dsn)
+`,
+		},
+		"alias": {
+			in: `package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+)
+
+func main() {
+	httptrace.WithClientTrace(ctx, trace)
+	http.ListenAndServe(":8080", nil)
+}
+`,
+			out: `package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+
+	httptrace2 "github.com/DataDog/dd-trace-go/contrib/net/http"
+)
+
+func main() {
+	httptrace.WithClientTrace(ctx, trace)
+	http.ListenAndServe(":8080", httptrace2.WrapHandler(http.DefaultServeMux, "web", ""))
+}
+`,
+		},
+		"grpc": {
+			in: `package main
+
+import (
+	"google.golang.org/grpc"
+)
+
+func main() {
+	s := grpc.NewServer()
+	conn, err := grpc.Dial(
+		"localhost:50051",
+		grpc.WithInsecure(),
+	)
+	conn, err = grpc.Dial("localhost:50051", opts...)
+	s = grpc.NewServer(grpc.UnaryInterceptor(auth))
+}
+`,
+			out: `package main
+
+import (
+	"google.golang.org/grpc"
+
+	grpctrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc"
+)
+
+func main() {
+	s := grpc.NewServer(grpc.UnaryInterceptor(grpctrace.UnaryServerInterceptor()), grpc.StreamInterceptor(grpctrace.StreamServerInterceptor()))
+	conn, err := grpc.Dial(
+		"localhost:50051",
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(grpctrace.UnaryClientInterceptor()), grpc.WithStreamInterceptor(grpctrace.StreamClientInterceptor()),
+	)
+	conn, err = grpc.Dial("localhost:50051", opts...)
+	s = grpc.NewServer(grpc.UnaryInterceptor(auth))
+}
+`,
+			warnings: 2,
+		},
+		"shadowed": {
+			in: `package main
+
+import "net/http"
+
+func serve(http server) {
+	http.ListenAndServe(":8080", nil)
+}
+`,
+		},
+		"instrumented": {
+			in: `package main
+
+import (
+	"net/http"
+
+	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http"
+)
+
+func main() {
+	http.ListenAndServe(":8080", httptrace.NewServeMux())
+}
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			out, warnings, err := rewrite("main.go", []byte(tt.in), "web")
+			assert.NoError(err)
+			assert.Equal(tt.out, string(out))
+			assert.Len(warnings, tt.warnings)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package clickhouse provides functions to trace the native interface of the
+// ClickHouse/clickhouse-go package (https://github.com/ClickHouse/clickhouse-go),
+// as of its v1 API. The connections opened through database/sql can be
+// traced with the contrib/database/sql package instead.
+//
+// As the native interface doesn't take contexts, the operations are traced
+// as children of the span found in the context set with WithContext, if any.
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go"
+	"github.com/ClickHouse/clickhouse-go/lib/data"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "ClickHouse/clickhouse-go", Library: "github.com/ClickHouse/clickhouse-go"})
+}
+
+const (
+	queryKey = "sql.query"
+	rowsKey  = "clickhouse.rows"
+)
+
+// Open opens a native connection to ClickHouse with the given DSN, see
+// clickhouse.OpenDirect, and returns a traced version of it.
+func Open(dsn string, opts ...ConnOption) (*Conn, error) {
+	conn, err := clickhouse.OpenDirect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	var addr string
+	if u, err := url.Parse(dsn); err == nil {
+		addr = u.Host
+	}
+	return WrapConn(conn, addr, opts...), nil
+}
+
+// Conn is a traced native connection to ClickHouse. Its statements are
+// traced when executed, but for the inserts, which are buffered: those are
+// traced as a batch when the transaction is committed.
+type Conn struct {
+	clickhouse.Clickhouse
+	config *connConfig
+	host   string
+	port   string
+	ctx    context.Context
+
+	insert string // the insert statement prepared in the transaction, if any
+	rows   uint64 // the rows buffered for the insert
+}
+
+// WrapConn returns a traced version of the given connection to the ClickHouse
+// server at the given address. The resource of the spans is the query.
+func WrapConn(conn clickhouse.Clickhouse, addr string, opts ...ConnOption) *Conn {
+	cfg := new(connConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "clickhouse", ext.AppTypeDB)
+	tracer.RegisterIntegration(tracer.Integration{Name: "ClickHouse/clickhouse-go", Options: map[string]string{"service": cfg.serviceName}})
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return &Conn{Clickhouse: conn, config: cfg, host: host, port: port, ctx: context.Background()}
+}
+
+// WithContext sets the context the next operations are traced with: they
+// are children of the span found in ctx, if any.
+func (c *Conn) WithContext(ctx context.Context) *Conn {
+	c.ctx = ctx
+	return c
+}
+
+// newSpan returns a span for the given query.
+func (c *Conn) newSpan(name, query string) *tracer.Span {
+	span := c.config.tracer.NewChildSpanFromContext(name, c.ctx)
+	span.SetIntegration("ClickHouse/clickhouse-go")
+	span.Type = ext.SQLType
+	span.Service = c.config.serviceName
+	span.Resource = query
+	span.SetMeta(queryKey, query)
+	span.SetMeta(ext.TargetHost, c.host)
+	if c.port != "" {
+		span.SetMeta(ext.TargetPort, c.port)
+	}
+	return span
+}
+
+// Prepare wraps clickhouse.Clickhouse.Prepare. The inserts prepared are
+// traced when the transaction is committed, or now if they fail, as they are
+// sent to the server already.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	insert := isInsert(query)
+	stmt, err := c.Clickhouse.Prepare(query)
+	if err != nil {
+		if insert {
+			span := c.newSpan("clickhouse.batch", query)
+			span.FinishWithErr(err)
+		}
+		return nil, err
+	}
+	if insert {
+		c.insert, c.rows = query, 0
+	}
+	return &tracedStmt{Stmt: stmt, conn: c, query: query, insert: insert}, nil
+}
+
+// WriteBlock wraps clickhouse.Clickhouse.WriteBlock, counting the rows of
+// the block in the batch.
+func (c *Conn) WriteBlock(block *data.Block) error {
+	if block != nil {
+		c.rows += block.NumRows
+	}
+	return c.Clickhouse.WriteBlock(block)
+}
+
+// Commit wraps clickhouse.Clickhouse.Commit, which sends the rows of the
+// insert prepared in the transaction, if any: its span is a batch span.
+func (c *Conn) Commit() error {
+	if c.insert == "" {
+		return c.Clickhouse.Commit()
+	}
+	span := c.newSpan("clickhouse.batch", c.insert)
+	span.SetMetric(rowsKey, float64(c.rows))
+	c.insert, c.rows = "", 0
+	err := c.Clickhouse.Commit()
+	span.FinishWithErr(err)
+	return err
+}
+
+// Rollback wraps clickhouse.Clickhouse.Rollback, which drops the rows of
+// the insert prepared in the transaction, if any.
+func (c *Conn) Rollback() error {
+	c.insert, c.rows = "", 0
+	return c.Clickhouse.Rollback()
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	conn   *Conn
+	query  string
+	insert bool
+}
+
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.insert {
+		// the row is buffered until the commit
+		res, err := s.Stmt.Exec(args)
+		if err == nil {
+			s.conn.rows++
+		}
+		return res, err
+	}
+	span := s.conn.newSpan("clickhouse.exec", s.query)
+	res, err := s.Stmt.Exec(args)
+	span.FinishWithErr(err)
+	return res, err
+}
+
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	span := s.conn.newSpan("clickhouse.query", s.query)
+	rows, err := s.Stmt.Query(args)
+	span.FinishWithErr(err)
+	return rows, err
+}
+
+// isInsert reports whether the given query is an insert, as clickhouse-go
+// tells them apart.
+func isInsert(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) > 6 && strings.EqualFold(query[:6], "insert")
+}
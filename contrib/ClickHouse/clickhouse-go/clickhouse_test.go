@@ -0,0 +1,101 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go"
+	"github.com/ClickHouse/clickhouse-go/lib/data"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// fakeConn is a connection failing the queries on the table missing.
+type fakeConn struct {
+	clickhouse.Clickhouse
+}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: query}, nil
+}
+
+func (fakeConn) WriteBlock(block *data.Block) error { return nil }
+func (fakeConn) Commit() error                      { return nil }
+
+type fakeStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query == "DROP TABLE missing" {
+		return nil, errors.New("table missing doesn't exist")
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestExec(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	conn := WrapConn(fakeConn{}, "127.0.0.1:9000", WithServiceName("events-db"), WithTracer(testTracer))
+
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	conn.WithContext(root.Context(context.Background()))
+	for _, query := range []string{"CREATE TABLE events (id UInt64) ENGINE = Memory", "DROP TABLE missing"} {
+		stmt, err := conn.Prepare(query)
+		assert.Nil(err)
+		stmt.Exec(nil)
+	}
+	assert.Nil(conn.Commit())
+	root.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	for _, s := range traces[0] {
+		if s.Name != "clickhouse.exec" {
+			continue
+		}
+		assert.Equal(root.SpanID, s.ParentID)
+		assert.Equal("events-db", s.Service)
+		assert.Equal("127.0.0.1", s.GetMeta("out.host"))
+		assert.Equal("9000", s.GetMeta("out.port"))
+		assert.Equal(s.Resource, s.GetMeta(queryKey))
+		if s.Resource == "DROP TABLE missing" {
+			assert.Equal(int32(1), s.Error)
+		} else {
+			assert.Equal(int32(0), s.Error)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	conn := WrapConn(fakeConn{}, "localhost", WithTracer(testTracer))
+
+	stmt, err := conn.Prepare("INSERT INTO events (id) VALUES (?)")
+	assert.Nil(err)
+	for i := 0; i < 3; i++ {
+		_, err := stmt.Exec([]driver.Value{uint64(i)})
+		assert.Nil(err)
+	}
+	assert.Nil(conn.WriteBlock(&data.Block{NumRows: 2}))
+	assert.Nil(conn.Commit())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("clickhouse.batch", s.Name)
+	assert.Equal("clickhouse", s.Service)
+	assert.Equal("INSERT INTO events (id) VALUES (?)", s.Resource)
+	assert.Equal("localhost", s.GetMeta("out.host"))
+	assert.Equal(5.0, s.Metrics[rowsKey])
+}
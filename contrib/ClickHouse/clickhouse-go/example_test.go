@@ -0,0 +1,34 @@
+package clickhouse_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+
+	clickhousetrace "github.com/DataDog/dd-trace-go/contrib/ClickHouse/clickhouse-go"
+)
+
+func Example() {
+	conn, err := clickhousetrace.Open("tcp://localhost:9000", clickhousetrace.WithServiceName("events-db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the operations are children of the span found in the context
+	conn.WithContext(context.Background())
+	if _, err := conn.Begin(); err != nil {
+		log.Fatal(err)
+	}
+	stmt, err := conn.Prepare("INSERT INTO events (id, name) VALUES (?, ?)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := stmt.Exec([]driver.Value{uint64(42), "signup"}); err != nil {
+		log.Fatal(err)
+	}
+	// the rows are sent, and traced, on commit
+	if err := conn.Commit(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package clickhouse
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type connConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// ConnOption represents an option that can be passed to Open or WrapConn.
+type ConnOption func(*connConfig)
+
+func defaults(cfg *connConfig) {
+	cfg.serviceName = "clickhouse"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced connection.
+func WithServiceName(name string) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the connection.
+func WithTracer(t *tracer.Tracer) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.tracer = t
+	}
+}
@@ -0,0 +1,42 @@
+package machinery_test
+
+import (
+	"context"
+	"log"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/config"
+	"github.com/RichardKnop/machinery/v1/tasks"
+
+	machinerytrace "github.com/DataDog/dd-trace-go/contrib/RichardKnop/machinery"
+)
+
+func Example() {
+	server, err := machinery.NewServer(&config.Config{
+		Broker:        "redis://localhost:6379",
+		ResultBackend: "redis://localhost:6379",
+		DefaultQueue:  "machinery_tasks",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := machinerytrace.WrapServer(server, machinerytrace.WithServiceName("email-worker"))
+
+	// the tasks registered with the traced server are traced when processed
+	err = s.RegisterTask("email:send", func(ctx context.Context, userID int64) error {
+		// the span of the task can be found in ctx
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the tasks sent by the traced server carry the trace context
+	_, err = s.SendTaskWithContext(context.Background(), &tasks.Signature{
+		Name: "email:send",
+		Args: []tasks.Arg{{Type: "int64", Value: int64(42)}},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package machinery provides functions to trace the RichardKnop/machinery package (https://github.com/RichardKnop/machinery).
+//
+// The tasks sent by a traced Server carry the trace context of the producer
+// in the headers of their signature, so that the tasks registered with a
+// traced Server join the trace when they are processed. The sampling decision
+// of the producer is carried along, so that the tasks are kept or dropped as
+// their producer was. Groups and chains are sent untraced.
+package machinery
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/RichardKnop/machinery/v1"
+	"github.com/RichardKnop/machinery/v1/backends/result"
+	"github.com/RichardKnop/machinery/v1/tasks"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "RichardKnop/machinery", Library: "github.com/RichardKnop/machinery"})
+}
+
+const (
+	taskIDKey     = "machinery.task_id"
+	routingKeyKey = "machinery.routing_key"
+)
+
+// contextType is the type of the context.Context interface.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// Server is a machinery server which traces the tasks it sends and
+// processes.
+type Server struct {
+	*machinery.Server
+	config *config
+}
+
+// WrapServer returns a traced version of the given server.
+func WrapServer(s *machinery.Server, opts ...Option) *Server {
+	return &Server{
+		Server: s,
+		config: newConfig(opts...),
+	}
+}
+
+// SendTask sends the given task, see SendTaskWithContext.
+func (s *Server) SendTask(signature *tasks.Signature) (*result.AsyncResult, error) {
+	return s.SendTaskWithContext(context.Background(), signature)
+}
+
+// SendTaskWithContext sends the given task in a producer span, which is a
+// child of the one found in the given context, if any. The trace context is
+// added to the headers of the signature.
+func (s *Server) SendTaskWithContext(ctx context.Context, signature *tasks.Signature) (*result.AsyncResult, error) {
+	t := s.config.tracer
+	if !t.Enabled() {
+		return s.Server.SendTaskWithContext(ctx, signature)
+	}
+	span := t.NewChildSpanFromContext("machinery.send", ctx)
+	span.SetIntegration("RichardKnop/machinery")
+	span.Type = ext.AppTypeWorker
+	span.Service = s.config.serviceName
+	span.Resource = signature.Name
+
+	if signature.Headers == nil {
+		signature.Headers = make(tasks.Headers)
+	}
+	internal.InjectHeaders(span, func(k, v string) { signature.Headers[k] = v })
+	res, err := s.Server.SendTaskWithContext(ctx, signature)
+	span.SetMeta(taskIDKey, signature.UUID)
+	if signature.RoutingKey != "" {
+		span.SetMeta(routingKeyKey, signature.RoutingKey)
+	}
+	span.FinishWithErr(err)
+	return res, err
+}
+
+// RegisterTask registers the given task function under the given name, so
+// that its processing is traced.
+func (s *Server) RegisterTask(name string, taskFunc interface{}) error {
+	return s.Server.RegisterTask(name, wrapTask(s.config, name, taskFunc))
+}
+
+// RegisterTasks registers the given task functions by name, so that their
+// processing is traced.
+func (s *Server) RegisterTasks(namedTaskFuncs map[string]interface{}) error {
+	wrapped := make(map[string]interface{}, len(namedTaskFuncs))
+	for name, fn := range namedTaskFuncs {
+		wrapped[name] = wrapTask(s.config, name, fn)
+	}
+	return s.Server.RegisterTasks(wrapped)
+}
+
+// wrapTask returns a task function processing the tasks with the given one
+// in a span, which continues the trace found in the headers of their
+// signature, if any. It takes a context as its first argument, so that
+// machinery gives it the signature, and passes it along when the given
+// function takes it. Values other than functions are returned as is, for
+// machinery to reject them.
+func wrapTask(cfg *config, name string, taskFunc interface{}) interface{} {
+	fn := reflect.ValueOf(taskFunc)
+	typ := fn.Type()
+	if typ.Kind() != reflect.Func {
+		return taskFunc
+	}
+	takesContext := typ.NumIn() > 0 && typ.In(0) == contextType
+	in := []reflect.Type{contextType}
+	for i := 0; i < typ.NumIn(); i++ {
+		if i > 0 || !takesContext {
+			in = append(in, typ.In(i))
+		}
+	}
+	out := make([]reflect.Type, typ.NumOut())
+	for i := range out {
+		out[i] = typ.Out(i)
+	}
+	wrapped := reflect.FuncOf(in, out, typ.IsVariadic())
+	return reflect.MakeFunc(wrapped, func(args []reflect.Value) []reflect.Value {
+		ctx, _ := args[0].Interface().(context.Context)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		t := cfg.tracer
+		if !t.Enabled() {
+			return call(fn, ctx, args, takesContext)
+		}
+		span := t.NewRootSpan("machinery.process", cfg.serviceName, name)
+		span.SetIntegration("RichardKnop/machinery")
+		span.Type = ext.AppTypeWorker
+		if signature := tasks.SignatureFromContext(ctx); signature != nil {
+			internal.ExtractHeaders(func(k string) string {
+				v, _ := signature.Headers[k].(string)
+				return v
+			}).Continue(t, span)
+			span.SetMeta(taskIDKey, signature.UUID)
+			if signature.RoutingKey != "" {
+				span.SetMeta(routingKeyKey, signature.RoutingKey)
+			}
+		}
+		defer internal.FinishOnPanic(span, nil)
+		results := call(fn, tracer.ContextWithSpan(ctx, span), args, takesContext)
+		var err error
+		if n := len(results); n > 0 {
+			err, _ = results[n-1].Interface().(error)
+		}
+		span.FinishWithErr(err)
+		return results
+	}).Interface()
+}
+
+// call calls the task function with the arguments given to its wrapper,
+// replacing their context with the given one, or removing it when the task
+// function doesn't take one.
+func call(fn reflect.Value, ctx context.Context, args []reflect.Value, takesContext bool) []reflect.Value {
+	if takesContext {
+		args[0] = reflect.ValueOf(ctx)
+	} else {
+		args = args[1:]
+	}
+	if fn.Type().IsVariadic() {
+		return fn.CallSlice(args)
+	}
+	return fn.Call(args)
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "machinery", ext.AppTypeWorker)
+	tracer.RegisterIntegration(tracer.Integration{Name: "RichardKnop/machinery", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
@@ -0,0 +1,77 @@
+package machinery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+func TestWrapTask(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	cfg := &config{serviceName: "consumer", tracer: testTracer}
+	producer := testTracer.NewRootSpan("machinery.send", "producer", "add")
+	signature := &tasks.Signature{
+		UUID:    "task_1",
+		Name:    "add",
+		Args:    []tasks.Arg{{Type: "int64", Value: int64(1)}, {Type: "int64", Value: int64(2)}},
+		Headers: make(tasks.Headers),
+	}
+	internal.InjectHeaders(producer, func(k, v string) { signature.Headers[k] = v })
+
+	// machinery gives the signature in the context of the wrapper
+	task, err := tasks.NewWithSignature(wrapTask(cfg, "add", func(a, b int64) (int64, error) {
+		return a + b, nil
+	}), signature)
+	assert.NoError(err)
+	results, err := task.Call()
+	assert.NoError(err)
+	assert.Len(results, 1)
+	assert.Equal(int64(3), results[0].Value)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("machinery.process", s.Name)
+	assert.Equal("consumer", s.Service)
+	assert.Equal("add", s.Resource)
+	assert.Equal("task_1", s.GetMeta("machinery.task_id"))
+	assert.Equal(producer.TraceID, s.TraceID)
+	assert.Equal(producer.SpanID, s.ParentID)
+}
+
+func TestWrapTaskContext(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	cfg := &config{serviceName: "consumer", tracer: testTracer}
+	signature := &tasks.Signature{UUID: "task_2", Name: "fail"}
+
+	// the task functions taking a context get the span of the task
+	task, err := tasks.NewWithSignature(wrapTask(cfg, "fail", func(ctx context.Context) error {
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		assert.Equal(signature, tasks.SignatureFromContext(ctx))
+		return errors.New("boom")
+	}), signature)
+	assert.NoError(err)
+	_, err = task.Call()
+	assert.Error(err)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("fail", s.Resource)
+	assert.Equal(uint64(0), s.ParentID)
+	assert.Equal(int32(1), s.Error)
+}
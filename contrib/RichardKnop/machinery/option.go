@@ -0,0 +1,30 @@
+package machinery
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type config struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// Option represents an option that can be passed to WrapServer.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "machinery"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced tasks.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the tasks.
+func WithTracer(t *tracer.Tracer) Option {
+	return func(cfg *config) {
+		cfg.tracer = t
+	}
+}
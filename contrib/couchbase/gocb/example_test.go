@@ -0,0 +1,38 @@
+package gocb_test
+
+import (
+	"context"
+	"log"
+
+	"gopkg.in/couchbase/gocb.v1"
+
+	gocbtrace "github.com/DataDog/dd-trace-go/contrib/couchbase/gocb"
+)
+
+func Example() {
+	cluster, err := gocb.Connect("couchbase://localhost")
+	if err != nil {
+		log.Fatal(err)
+	}
+	cluster.Authenticate(gocb.PasswordAuthenticator{Username: "Administrator", Password: "password"})
+	bucket, err := cluster.OpenBucket("users", "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	users := gocbtrace.WrapBucket(bucket, gocbtrace.WithServiceName("users-db"))
+
+	// the operations are children of the span found in the context
+	ctx := context.Background()
+	if _, err := users.WithContext(ctx).Upsert("user::42", map[string]string{"name": "bob"}, 0); err != nil {
+		log.Fatal(err)
+	}
+
+	traced := gocbtrace.WrapCluster(cluster).WithContext(ctx)
+	res, err := traced.Query("SELECT name FROM users WHERE age > $1", []interface{}{42}, func(q *gocb.N1qlQuery) {
+		q.Consistency(gocb.RequestPlus)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Close()
+}
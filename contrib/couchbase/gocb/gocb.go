@@ -0,0 +1,154 @@
+// Package gocb provides functions to trace the couchbase/gocb package (https://github.com/couchbase/gocb),
+// as of its v1 API, imported from gopkg.in/couchbase/gocb.v1.
+//
+// As gocb v1 doesn't take contexts, the operations are traced as children of
+// the span found in the context set with WithContext, if any.
+package gocb
+
+import (
+	"context"
+
+	"gopkg.in/couchbase/gocb.v1"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "couchbase/gocb", Library: "gopkg.in/couchbase/gocb.v1"})
+}
+
+const (
+	bucketKey    = "couchbase.bucket"
+	statementKey = "couchbase.statement"
+	documentKey  = "couchbase.document_id"
+)
+
+func newConfig(opts ...WrapOption) *wrapConfig {
+	cfg := new(wrapConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "couchbase", ext.AppTypeDB)
+	tracer.RegisterIntegration(tracer.Integration{Name: "couchbase/gocb", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
+
+// newSpan returns a span for an operation, which is a child of the one found
+// in the given context, if any.
+func newSpan(ctx context.Context, cfg *wrapConfig, name, resource string) *tracer.Span {
+	span := cfg.tracer.NewChildSpanFromContext(name, ctx)
+	span.SetIntegration("couchbase/gocb")
+	span.Type = "couchbase"
+	span.Service = cfg.serviceName
+	span.Resource = resource
+	return span
+}
+
+// Cluster is a Couchbase cluster which traces the N1QL queries made with
+// Query. Those executed with ExecuteN1qlQuery aren't traced, as the
+// statements of their queries can't be read.
+type Cluster struct {
+	*gocb.Cluster
+	config *wrapConfig
+	ctx    context.Context
+}
+
+// WrapCluster returns a traced version of the given cluster.
+func WrapCluster(c *gocb.Cluster, opts ...WrapOption) *Cluster {
+	return &Cluster{Cluster: c, config: newConfig(opts...), ctx: context.Background()}
+}
+
+// WithContext returns a copy of the cluster whose queries are children of
+// the span found in ctx, if any.
+func (c *Cluster) WithContext(ctx context.Context) *Cluster {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+// Query executes the given N1QL statement with the given parameters, see
+// gocb.Cluster.ExecuteN1qlQuery. The query can be configured with setup, for
+// example to set its consistency, which may be nil. The resource of its span
+// is the sanitized statement, in which the literals are replaced with '?'.
+func (c *Cluster) Query(statement string, params interface{}, setup func(*gocb.N1qlQuery)) (gocb.QueryResults, error) {
+	q := gocb.NewN1qlQuery(statement)
+	if setup != nil {
+		setup(q)
+	}
+	resource := Sanitize(statement)
+	span := newSpan(c.ctx, c.config, "couchbase.query", resource)
+	span.SetMeta(statementKey, resource)
+	res, err := c.Cluster.ExecuteN1qlQuery(q, params)
+	span.FinishWithErr(err)
+	return res, err
+}
+
+// Bucket is a Couchbase bucket which traces its key-value operations.
+type Bucket struct {
+	*gocb.Bucket
+	config *wrapConfig
+	ctx    context.Context
+}
+
+// WrapBucket returns a traced version of the given bucket.
+func WrapBucket(b *gocb.Bucket, opts ...WrapOption) *Bucket {
+	return &Bucket{Bucket: b, config: newConfig(opts...), ctx: context.Background()}
+}
+
+// WithContext returns a copy of the bucket whose operations are children of
+// the span found in ctx, if any.
+func (b *Bucket) WithContext(ctx context.Context) *Bucket {
+	b2 := *b
+	b2.ctx = ctx
+	return &b2
+}
+
+// newSpan returns a span for a key-value operation on the given document.
+func (b *Bucket) newSpan(op, key string) *tracer.Span {
+	span := newSpan(b.ctx, b.config, "couchbase.kv", op)
+	span.SetMeta(bucketKey, b.Bucket.Name())
+	span.SetMeta(documentKey, key)
+	return span
+}
+
+// Get wraps gocb.Bucket.Get.
+func (b *Bucket) Get(key string, valuePtr interface{}) (gocb.Cas, error) {
+	span := b.newSpan("Get", key)
+	cas, err := b.Bucket.Get(key, valuePtr)
+	span.FinishWithErr(err)
+	return cas, err
+}
+
+// Insert wraps gocb.Bucket.Insert.
+func (b *Bucket) Insert(key string, value interface{}, expiry uint32) (gocb.Cas, error) {
+	span := b.newSpan("Insert", key)
+	cas, err := b.Bucket.Insert(key, value, expiry)
+	span.FinishWithErr(err)
+	return cas, err
+}
+
+// Upsert wraps gocb.Bucket.Upsert.
+func (b *Bucket) Upsert(key string, value interface{}, expiry uint32) (gocb.Cas, error) {
+	span := b.newSpan("Upsert", key)
+	cas, err := b.Bucket.Upsert(key, value, expiry)
+	span.FinishWithErr(err)
+	return cas, err
+}
+
+// Replace wraps gocb.Bucket.Replace.
+func (b *Bucket) Replace(key string, value interface{}, cas gocb.Cas, expiry uint32) (gocb.Cas, error) {
+	span := b.newSpan("Replace", key)
+	cas, err := b.Bucket.Replace(key, value, cas, expiry)
+	span.FinishWithErr(err)
+	return cas, err
+}
+
+// Remove wraps gocb.Bucket.Remove.
+func (b *Bucket) Remove(key string, cas gocb.Cas) (gocb.Cas, error) {
+	span := b.newSpan("Remove", key)
+	cas, err := b.Bucket.Remove(key, cas)
+	span.FinishWithErr(err)
+	return cas, err
+}
@@ -0,0 +1,98 @@
+package gocb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/couchbase/gocb.v1"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+const (
+	couchbaseHost = "couchbase://127.0.0.1"
+	bucketName    = "trace"
+)
+
+func newCluster(t *testing.T) *gocb.Cluster {
+	cluster, err := gocb.Connect(couchbaseHost)
+	if err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+	if err := cluster.Authenticate(gocb.PasswordAuthenticator{Username: "Administrator", Password: "password"}); err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+	return cluster
+}
+
+func TestBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	cluster := newCluster(t)
+	defer cluster.Close()
+	bucket, err := cluster.OpenBucket(bucketName, "")
+	if err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+	b := WrapBucket(bucket, WithServiceName("users-db"), WithTracer(testTracer))
+
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	traced := b.WithContext(root.Context(context.Background()))
+	_, err = traced.Upsert("user::1", map[string]string{"name": "bob"}, 0)
+	assert.Nil(err)
+	var user map[string]string
+	_, err = traced.Get("user::2", &user)
+	assert.NotNil(err)
+	root.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	for _, s := range traces[0] {
+		if s.Name != "couchbase.kv" {
+			continue
+		}
+		assert.Equal(root.SpanID, s.ParentID)
+		assert.Equal("users-db", s.Service)
+		assert.Equal(bucketName, s.GetMeta(bucketKey))
+		switch s.Resource {
+		case "Upsert":
+			assert.Equal("user::1", s.GetMeta(documentKey))
+			assert.Equal(int32(0), s.Error)
+		case "Get":
+			assert.Equal("user::2", s.GetMeta(documentKey))
+			assert.Equal(int32(1), s.Error)
+		default:
+			t.Errorf("unexpected resource %q", s.Resource)
+		}
+	}
+}
+
+func TestClusterQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	cluster := WrapCluster(newCluster(t), WithTracer(testTracer))
+	defer cluster.Close()
+	if _, err := cluster.OpenBucket(bucketName, ""); err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+
+	res, err := cluster.Query("SELECT 'bob' AS name, 42 AS age", nil, nil)
+	assert.Nil(err)
+	if res != nil {
+		res.Close()
+	}
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("couchbase.query", s.Name)
+	assert.Equal("couchbase", s.Service)
+	assert.Equal("SELECT ? AS name, ? AS age", s.Resource)
+	assert.Equal("SELECT ? AS name, ? AS age", s.GetMeta(statementKey))
+}
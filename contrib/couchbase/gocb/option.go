@@ -0,0 +1,30 @@
+package gocb
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type wrapConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// WrapOption represents an option that can be passed to WrapCluster or WrapBucket.
+type WrapOption func(*wrapConfig)
+
+func defaults(cfg *wrapConfig) {
+	cfg.serviceName = "couchbase"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced operations.
+func WithServiceName(name string) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the operations.
+func WithTracer(t *tracer.Tracer) WrapOption {
+	return func(cfg *wrapConfig) {
+		cfg.tracer = t
+	}
+}
@@ -0,0 +1,65 @@
+package gocb
+
+import "bytes"
+
+// Sanitize returns the given N1QL statement with its string and numeric
+// literals replaced with '?', so that it doesn't hold any data and the
+// statements only differing by their values are grouped together.
+func Sanitize(statement string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(statement); {
+		c := statement[i]
+		switch {
+		case c == '\'' || c == '"':
+			// string literal, in which quotes are escaped by doubling them
+			// or with a backslash
+			j := i + 1
+			for j < len(statement) {
+				if statement[j] == '\\' {
+					j += 2
+					continue
+				}
+				if statement[j] == c {
+					if j+1 < len(statement) && statement[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			buf.WriteByte('?')
+			i = j + 1
+		case c == '`':
+			// escaped identifier, kept as is
+			j := i + 1
+			for j < len(statement) && statement[j] != '`' {
+				j++
+			}
+			if j < len(statement) {
+				j++
+			}
+			buf.WriteString(statement[i:j])
+			i = j
+		case isDigit(c) && (i == 0 || !isIdentChar(statement[i-1])):
+			j := i
+			for j < len(statement) && (isDigit(statement[j]) || statement[j] == '.' || statement[j] == 'e' || statement[j] == 'E') {
+				j++
+			}
+			buf.WriteByte('?')
+			i = j
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,24 @@
+package gocb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	assert := assert.New(t)
+
+	for in, out := range map[string]string{
+		"SELECT * FROM `travel-sample` WHERE type = 'airline' LIMIT 10": "SELECT * FROM `travel-sample` WHERE type = ? LIMIT ?",
+		`SELECT name FROM users WHERE email = "bob@example.com"`:        `SELECT name FROM users WHERE email = ?`,
+		"SELECT * FROM b WHERE name = 'O''Brien' AND age > 42.5":        "SELECT * FROM b WHERE name = ? AND age > ?",
+		`SELECT * FROM b WHERE note = 'it\'s' AND v1 = $1`:              `SELECT * FROM b WHERE note = ? AND v1 = $1`,
+		"SELECT * FROM b USE KEYS ['user::1', 'user::2']":               "SELECT * FROM b USE KEYS [?, ?]",
+		"UPSERT INTO b (KEY, VALUE) VALUES ('k', {'n': 1e3})":           "UPSERT INTO b (KEY, VALUE) VALUES (?, {?: ?})",
+		"SELECT a1, `col 2` FROM b":                                     "SELECT a1, `col 2` FROM b",
+		"SELECT 'unterminated":                                          "SELECT ?",
+	} {
+		assert.Equal(out, Sanitize(in), in)
+	}
+}
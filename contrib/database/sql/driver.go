@@ -11,6 +11,10 @@ import (
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "database/sql", Library: "database/sql"})
+}
+
 var _ driver.Driver = (*tracedDriver)(nil)
 
 // tracedDriver wraps an inner sql driver with tracing. It implements the (database/sql).driver.Driver interface.
@@ -55,6 +59,7 @@ type traceParams struct {
 func (tp *traceParams) newChildSpanFromContext(ctx context.Context, resource string, query string) *tracer.Span {
 	name := fmt.Sprintf("%s.query", tp.driverName)
 	span := tp.config.tracer.NewChildSpanFromContext(name, ctx)
+	span.SetIntegration("database/sql")
 	span.Type = ext.SQLType
 	span.Service = tp.config.serviceName
 	span.Resource = resource
@@ -15,8 +15,15 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"sync"
+
+	"github.com/DataDog/dd-trace-go/tracer"
 )
 
+// registerMu serializes the registrations of the drivers, as sql.Register
+// panics when a driver is registered twice.
+var registerMu sync.Mutex
+
 // Register tells the sql integration package about the driver that we will be tracing. It must
 // be called before Open, if that connection is to be traced. It uses the driverName suffixed
 // with ".db" as the default service name.
@@ -25,6 +32,8 @@ func Register(driverName string, driver driver.Driver, opts ...RegisterOption) {
 		panic("sqltrace: Register driver is nil")
 	}
 	name := tracedDriverName(driverName)
+	registerMu.Lock()
+	defer registerMu.Unlock()
 	if driverExists(name) {
 		// no problem, carry on
 		return
@@ -37,6 +46,7 @@ func Register(driverName string, driver driver.Driver, opts ...RegisterOption) {
 	if cfg.serviceName == "" {
 		cfg.serviceName = driverName + ".db"
 	}
+	tracer.RegisterIntegration(tracer.Integration{Name: "database/sql", Options: map[string]string{"service": cfg.serviceName, "driver": driverName}})
 	sql.Register(name, &tracedDriver{
 		Driver:     driver,
 		driverName: driverName,
@@ -58,3 +68,20 @@ func Open(driverName, dataSourceName string) (*sql.DB, error) {
 	}
 	return sql.Open(name, dataSourceName)
 }
+
+// AutoOpen is like Open, but it first registers the driver registered under
+// driverName in the database/sql package when that wasn't done with
+// Register, using the default options. It is the function the calls to
+// sql.Open are replaced with by the ddinstrument command.
+func AutoOpen(driverName, dataSourceName string) (*sql.DB, error) {
+	if !driverExists(tracedDriverName(driverName)) {
+		// sql.Open doesn't connect, it only looks the driver up
+		db, err := sql.Open(driverName, dataSourceName)
+		if err != nil {
+			return nil, err
+		}
+		Register(driverName, db.Driver())
+		db.Close()
+	}
+	return Open(driverName, dataSourceName)
+}
@@ -1,8 +1,12 @@
 package sql
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"log"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/DataDog/dd-trace-go/contrib/internal/sqltest"
@@ -11,6 +15,7 @@ import (
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
 )
 
 // tableName holds the SQL table that these tests will be run against. It must be unique cross-repo.
@@ -92,3 +97,29 @@ func TestPostgres(t *testing.T) {
 	}
 	sqltest.RunAll(t, testConfig)
 }
+
+// nopDriver is a driver which can't connect, enough to be registered.
+type nopDriver struct{}
+
+func (nopDriver) Open(name string) (driver.Conn, error) { return nil, errors.New("nop") }
+
+func TestAutoOpenConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	if !driverExists("nop-concurrent") {
+		sql.Register("nop-concurrent", nopDriver{})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := AutoOpen("nop-concurrent", "")
+			if assert.NoError(err) {
+				db.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(driverExists(tracedDriverName("nop-concurrent")))
+}
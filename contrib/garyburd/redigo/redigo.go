@@ -15,6 +15,10 @@ import (
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "garyburd/redigo", Library: "github.com/garyburd/redigo/redis"})
+}
+
 // Conn is an implementation of the redis.Conn interface that supports tracing
 type Conn struct {
 	redis.Conn
@@ -92,6 +96,7 @@ func DialURL(rawurl string, options ...interface{}) (redis.Conn, error) {
 func (tc Conn) newChildSpan(ctx context.Context) *tracer.Span {
 	p := tc.params
 	span := p.config.tracer.NewChildSpanFromContext("redis.command", ctx)
+	span.SetIntegration("garyburd/redigo")
 	span.Service = p.config.serviceName
 	span.SetMeta("out.network", p.network)
 	span.SetMeta("out.port", p.port)
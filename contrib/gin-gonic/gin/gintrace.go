@@ -2,22 +2,35 @@
 package gin
 
 import (
+	"bufio"
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 
+	"github.com/DataDog/dd-trace-go/contrib/internal"
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 	"github.com/gin-gonic/gin"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "gin-gonic/gin", Library: "github.com/gin-gonic/gin"})
+}
+
 const spanKey = "dd-trace-span"
 
 // Trace returns middleware that will trace incoming requests.
 // The last parameter is optional and can be used to pass a custom tracer.
-func Middleware(service string) gin.HandlerFunc {
+func Middleware(service string, opts ...Option) gin.HandlerFunc {
+	cfg := new(config)
+	for _, fn := range opts {
+		fn(cfg)
+	}
 	// TODO(gbbr): Handle this when we switch to OpenTracing.
 	t := tracer.DefaultTracer
 	t.SetServiceInfo(service, "gin-gonic/gin", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "gin-gonic/gin", Options: map[string]string{"service": service}})
 	return func(c *gin.Context) {
 		// bail out if tracing isn't enabled
 		if !t.Enabled() {
@@ -26,30 +39,101 @@ func Middleware(service string) gin.HandlerFunc {
 		}
 
 		resource := c.HandlerName()
-		span, ctx := t.NewChildSpanWithContext("http.request", c.Request.Context())
-		defer span.Finish()
+		if cfg.resourceNamer != nil {
+			resource = cfg.resourceNamer(c.Request)
+		}
+		name := "http.request"
+		if cfg.methodInName {
+			name += "." + strings.ToLower(c.Request.Method)
+		}
+		span, ctx := t.NewChildSpanWithContext(name, c.Request.Context())
+		span.SetIntegration("gin-gonic/gin")
 
 		span.Service = service
 		span.Resource = resource
 		span.Type = ext.HTTPType
 		span.SetMeta(ext.HTTPMethod, c.Request.Method)
 		span.SetMeta(ext.HTTPURL, c.Request.URL.Path)
+		if route := c.FullPath(); route != "" {
+			span.SetMeta(ext.HTTPRoute, route)
+		}
+		if cfg.forceKeep {
+			internal.ForceKeep(t, span, c.Request)
+		}
 
 		// pass the span through the request context
 		c.Request = c.Request.WithContext(ctx)
+		body := internal.CountBody(c.Request)
+		streaming := cfg.isStreaming != nil && cfg.isStreaming(c.Request)
+
+		var finished bool
+		finish := func() {
+			if finished {
+				return
+			}
+			finished = true
+			span.SetMeta(ext.HTTPCode, strconv.Itoa(c.Writer.Status()))
+			span.SetMetric(ext.HTTPRequestSize, float64(body.Count()))
+			if size := c.Writer.Size(); !streaming && size >= 0 {
+				span.SetMetric(ext.HTTPResponseSize, float64(size))
+			}
+			if len(c.Errors) > 0 {
+				span.SetMeta("gin.errors", c.Errors.String())
+				span.SetError(c.Errors[0])
+			}
+			span.Finish()
+		}
+		if streaming {
+			span.SetMeta(ext.HTTPStreaming, "true")
+			c.Writer = &streamingWriter{ResponseWriter: c.Writer, finish: finish}
+		}
+
+		defer internal.FinishOnPanic(span, finish)
 
 		// serve the request to the next middleware
 		c.Next()
 
-		span.SetMeta(ext.HTTPCode, strconv.Itoa(c.Writer.Status()))
-
-		if len(c.Errors) > 0 {
-			span.SetMeta("gin.errors", c.Errors.String())
-			span.SetError(c.Errors[0])
-		}
+		finish()
 	}
 }
 
+// streamingWriter finishes the request's span as soon as the response
+// header is written.
+type streamingWriter struct {
+	gin.ResponseWriter
+	finish func()
+}
+
+// WriteHeaderNow implements gin.ResponseWriter.
+func (w *streamingWriter) WriteHeaderNow() {
+	w.ResponseWriter.WriteHeaderNow()
+	w.finish()
+}
+
+// Write implements gin.ResponseWriter.
+func (w *streamingWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString implements gin.ResponseWriter.
+func (w *streamingWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush implements gin.ResponseWriter.
+func (w *streamingWriter) Flush() {
+	w.WriteHeaderNow()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack implements gin.ResponseWriter.
+func (w *streamingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	defer w.finish()
+	return w.ResponseWriter.Hijack()
+}
+
 // HTML will trace the rendering of the template as a child of the span in the given context.
 func HTML(c *gin.Context, code int, name string, obj interface{}) {
 	t := tracer.DefaultTracer
@@ -59,6 +143,7 @@ func HTML(c *gin.Context, code int, name string, obj interface{}) {
 	}
 
 	span := t.NewChildSpanFromContext("gin.render.html", c.Request.Context())
+	span.SetIntegration("gin-gonic/gin")
 	span.SetMeta("go.template", name)
 	defer func() {
 		if r := recover(); r != nil {
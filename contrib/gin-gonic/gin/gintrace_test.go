@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -80,6 +81,77 @@ func TestTrace200(t *testing.T) {
 	assert.Equal(s.GetMeta("http.status_code"), "200")
 	assert.Equal(s.GetMeta("http.method"), "GET")
 	assert.Equal(s.GetMeta("http.url"), "/user/123")
+	assert.Equal(s.GetMeta("http.route"), "/user/:id")
+	assert.Equal(s.Metrics["http.request.size"], float64(0))
+	assert.Equal(s.Metrics["http.response.size"], float64(3))
+}
+
+func TestForceKeep(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+	testTracer.SetSampleRate(0)
+	tracer.DefaultTracer = testTracer
+
+	for _, keep := range []bool{false, true} {
+		var opts []Option
+		if keep {
+			opts = append(opts, WithForceKeep())
+		}
+		router := gin.New()
+		router.Use(Middleware("foobar", opts...))
+		router.GET("/user/:id", func(c *gin.Context) {})
+
+		r := httptest.NewRequest("GET", "/user/123", nil)
+		r.Header.Set(ext.HTTPHeaderForceKeep, "true")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		// the header is ignored by default
+		testTracer.ForceFlush()
+		traces := testTransport.Traces()
+		if !keep {
+			assert.Len(traces, 0)
+			continue
+		}
+		assert.Len(traces, 1)
+		assert.Equal(ext.PriorityUserKeep, traces[0][0].GetSamplingPriority())
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+	tracer.DefaultTracer = testTracer
+
+	router := gin.New()
+	router.Use(Middleware("foobar", WithStreaming(func(r *http.Request) bool {
+		return r.URL.Path == "/events"
+	})))
+	router.GET("/events", func(c *gin.Context) {
+		span, ok := tracer.SpanFromContext(c.Request.Context())
+		assert.True(ok)
+		c.Writer.WriteString("data: hello\n\n")
+		c.Writer.Flush()
+		// the span is done once the header is sent
+		assert.NotEqual(int64(0), span.Duration)
+	})
+
+	r := httptest.NewRequest("GET", "/events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(200, w.Code)
+	assert.Equal("data: hello\n\n", w.Body.String())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 1)
+	if len(spans) < 1 {
+		t.Fatalf("no spans")
+	}
+	s := spans[0]
+	assert.Equal(s.GetMeta("http.status_code"), "200")
+	assert.Equal(s.GetMeta("http.streaming"), "true")
 }
 
 func TestDisabled(t *testing.T) {
@@ -0,0 +1,51 @@
+package gin
+
+import "net/http"
+
+type config struct {
+	isStreaming   func(*http.Request) bool
+	resourceNamer func(*http.Request) string
+	methodInName  bool
+	forceKeep     bool
+}
+
+// Option represents an option that can be passed to Middleware.
+type Option func(*config)
+
+// WithStreaming marks the requests for which match returns true as streaming or
+// long-polling requests (e.g. server-sent events or websockets). Their spans are
+// finished as soon as the response header is written, so that hours-long connections
+// don't skew latency. Errors added to the gin context afterwards are not reported.
+func WithStreaming(match func(*http.Request) bool) Option {
+	return func(cfg *config) {
+		cfg.isStreaming = match
+	}
+}
+
+// WithResourceNamer sets the function used to name the resource of each request,
+// e.g. to group endpoints differently than the router does. It replaces the
+// default naming, which uses the name of the handler.
+func WithResourceNamer(namer func(*http.Request) string) Option {
+	return func(cfg *config) {
+		cfg.resourceNamer = namer
+	}
+}
+
+// WithMethodInOperationName appends the lowercase method of the requests to
+// the name of their spans, e.g. "http.request.get" instead of "http.request".
+func WithMethodInOperationName() Option {
+	return func(cfg *config) {
+		cfg.methodInName = true
+	}
+}
+
+// WithForceKeep keeps the traces of the requests sent with the
+// "x-datadog-force-keep: true" header or coming from Synthetic tests, whatever
+// the sampler decides, so that a specific request can be traced on demand.
+// Only enable it when the clients are trusted, as they could otherwise have
+// all their requests traced.
+func WithForceKeep() Option {
+	return func(cfg *config) {
+		cfg.forceKeep = true
+	}
+}
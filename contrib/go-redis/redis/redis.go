@@ -10,9 +10,14 @@ import (
 
 	"github.com/go-redis/redis"
 
+	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "go-redis/redis", Library: "github.com/go-redis/redis"})
+}
+
 // Client is used to trace requests to a redis server.
 type Client struct {
 	*redis.Client
@@ -59,6 +64,7 @@ func WrapClient(c *redis.Client, opts ...ClientOption) *Client {
 		config: cfg,
 	}
 	cfg.tracer.SetServiceInfo(cfg.serviceName, "redis", ext.AppTypeDB)
+	tracer.RegisterIntegration(tracer.Integration{Name: "go-redis/redis", Options: map[string]string{"service": cfg.serviceName}})
 	tc := &Client{c, params}
 	tc.Client.WrapProcess(createWrapperFromClient(tc))
 	return tc
@@ -73,6 +79,7 @@ func (c *Client) Pipeline() *Pipeliner {
 // are traced, and that emitted spans are children of the given Context.
 func (c *Pipeliner) ExecWithContext(ctx context.Context) ([]redis.Cmder, error) {
 	span := c.params.config.tracer.NewChildSpanFromContext("redis.command", ctx)
+	span.SetIntegration("go-redis/redis")
 
 	span.Service = c.params.config.serviceName
 	span.SetMeta("out.host", c.params.host)
@@ -94,6 +101,7 @@ func (c *Pipeliner) ExecWithContext(ctx context.Context) ([]redis.Cmder, error)
 // Exec calls Pipeline.Exec() ensuring that the resulting Redis calls are traced.
 func (c *Pipeliner) Exec() ([]redis.Cmder, error) {
 	span := c.params.config.tracer.NewRootSpan("redis.command", c.params.config.serviceName, "redis")
+	span.SetIntegration("go-redis/redis")
 
 	span.SetMeta("out.host", c.params.host)
 	span.SetMeta("out.port", c.params.port)
@@ -140,6 +148,7 @@ func createWrapperFromClient(tc *Client) func(oldProcess func(cmd redis.Cmder) e
 			p := tc.params
 
 			span := p.config.tracer.NewChildSpanFromContext("redis.command", ctx)
+			span.SetIntegration("go-redis/redis")
 			span.Service = p.config.serviceName
 			span.Resource = parts[0]
 			span.SetMeta("redis.raw_command", raw)
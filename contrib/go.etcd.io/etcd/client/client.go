@@ -0,0 +1,160 @@
+// Package client provides functions to trace the etcd client (https://github.com/etcd-io/etcd/tree/main/client/v3).
+//
+// The KV, lease and watch operations are traced with spans whose resource
+// holds the sanitized key, see SanitizeKey, and DialOptions traces the gRPC
+// calls made by the client underneath.
+package client
+
+import (
+	"context"
+	"strconv"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+
+	grpctrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "go.etcd.io/etcd", Library: "go.etcd.io/etcd/client/v3"})
+}
+
+const leaseIDKey = "etcd.lease_id"
+
+func newConfig(opts ...ClientOption) *clientConfig {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "etcd", ext.AppTypeDB)
+	tracer.RegisterIntegration(tracer.Integration{Name: "go.etcd.io/etcd", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
+
+// newSpan returns a span for an operation, which is a child of the one found
+// in the given context, if any.
+func (cfg *clientConfig) newSpan(ctx context.Context, name, resource string) *tracer.Span {
+	span := cfg.tracer.NewChildSpanFromContext(name, ctx)
+	span.SetIntegration("go.etcd.io/etcd")
+	span.Type = "etcd"
+	span.Service = cfg.serviceName
+	span.Resource = resource
+	return span
+}
+
+// DialOptions returns the gRPC dial options tracing the calls made by a
+// client, to be appended to clientv3.Config.DialOptions.
+func DialOptions(opts ...ClientOption) []grpc.DialOption {
+	cfg := newConfig(opts...)
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(grpctrace.UnaryClientInterceptor(
+			grpctrace.WithServiceName(cfg.serviceName),
+			grpctrace.WithTracer(cfg.tracer),
+		)),
+	}
+}
+
+// WrapClient traces the KV, lease and watch operations of the given client,
+// and returns it.
+func WrapClient(c *clientv3.Client, opts ...ClientOption) *clientv3.Client {
+	c.KV = WrapKV(c.KV, opts...)
+	c.Lease = WrapLease(c.Lease, opts...)
+	c.Watcher = WrapWatcher(c.Watcher, opts...)
+	return c
+}
+
+// WrapKV returns a traced version of the given KV.
+func WrapKV(kv clientv3.KV, opts ...ClientOption) clientv3.KV {
+	return &tracedKV{KV: kv, config: newConfig(opts...)}
+}
+
+type tracedKV struct {
+	clientv3.KV
+	config *clientConfig
+}
+
+func (kv *tracedKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	span := kv.config.newSpan(ctx, "etcd.kv", "Get "+SanitizeKey(key))
+	resp, err := kv.KV.Get(ctx, key, opts...)
+	if resp != nil {
+		span.SetMetric("etcd.count", float64(resp.Count))
+	}
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+func (kv *tracedKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	span := kv.config.newSpan(ctx, "etcd.kv", "Put "+SanitizeKey(key))
+	resp, err := kv.KV.Put(ctx, key, val, opts...)
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+func (kv *tracedKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	span := kv.config.newSpan(ctx, "etcd.kv", "Delete "+SanitizeKey(key))
+	resp, err := kv.KV.Delete(ctx, key, opts...)
+	if resp != nil {
+		span.SetMetric("etcd.deleted", float64(resp.Deleted))
+	}
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+// WrapLease returns a traced version of the given lease client.
+func WrapLease(l clientv3.Lease, opts ...ClientOption) clientv3.Lease {
+	return &tracedLease{Lease: l, config: newConfig(opts...)}
+}
+
+type tracedLease struct {
+	clientv3.Lease
+	config *clientConfig
+}
+
+func (l *tracedLease) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	span := l.config.newSpan(ctx, "etcd.lease", "Grant")
+	span.SetMetric("etcd.ttl", float64(ttl))
+	resp, err := l.Lease.Grant(ctx, ttl)
+	if resp != nil {
+		span.SetMeta(leaseIDKey, strconv.FormatInt(int64(resp.ID), 16))
+	}
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+func (l *tracedLease) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	span := l.config.newSpan(ctx, "etcd.lease", "Revoke")
+	span.SetMeta(leaseIDKey, strconv.FormatInt(int64(id), 16))
+	resp, err := l.Lease.Revoke(ctx, id)
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+func (l *tracedLease) KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	span := l.config.newSpan(ctx, "etcd.lease", "KeepAliveOnce")
+	span.SetMeta(leaseIDKey, strconv.FormatInt(int64(id), 16))
+	resp, err := l.Lease.KeepAliveOnce(ctx, id)
+	span.FinishWithErr(err)
+	return resp, err
+}
+
+// WrapWatcher returns a traced version of the given watcher.
+func WrapWatcher(w clientv3.Watcher, opts ...ClientOption) clientv3.Watcher {
+	return &tracedWatcher{Watcher: w, config: newConfig(opts...)}
+}
+
+type tracedWatcher struct {
+	clientv3.Watcher
+	config *clientConfig
+}
+
+// Watch starts watching the given key. As watches last until their context
+// is done, only their creation is traced.
+func (w *tracedWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	span := w.config.newSpan(ctx, "etcd.watch", "Watch "+SanitizeKey(key))
+	ch := w.Watcher.Watch(ctx, key, opts...)
+	span.Finish()
+	return ch
+}
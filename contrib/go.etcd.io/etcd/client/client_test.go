@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// fakeKV holds a single key.
+type fakeKV struct {
+	clientv3.KV
+}
+
+func (fakeKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	if key != "/services/api/42" {
+		return nil, errors.New("unavailable")
+	}
+	return &clientv3.GetResponse{Count: 1}, nil
+}
+
+func TestKV(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	kv := WrapKV(fakeKV{}, WithServiceName("registry"), WithTracer(testTracer))
+
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	ctx := root.Context(context.Background())
+	_, err := kv.Get(ctx, "/services/api/42")
+	assert.Nil(err)
+	_, err = kv.Get(ctx, "/services/web/7")
+	assert.NotNil(err)
+	root.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	var errors int32
+	for _, s := range traces[0] {
+		if s.Name != "etcd.kv" {
+			continue
+		}
+		assert.Equal(root.SpanID, s.ParentID)
+		assert.Equal("registry", s.Service)
+		assert.Contains([]string{"Get /services/api/?", "Get /services/web/?"}, s.Resource)
+		errors += s.Error
+	}
+	assert.Equal(int32(1), errors)
+}
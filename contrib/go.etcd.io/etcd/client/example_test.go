@@ -0,0 +1,28 @@
+package client_test
+
+import (
+	"context"
+	"log"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	etcdtrace "github.com/DataDog/dd-trace-go/contrib/go.etcd.io/etcd/client"
+)
+
+func Example() {
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{"localhost:2379"},
+		DialTimeout: 5 * time.Second,
+		DialOptions: etcdtrace.DialOptions(etcdtrace.WithServiceName("registry")),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	c = etcdtrace.WrapClient(c, etcdtrace.WithServiceName("registry"))
+	defer c.Close()
+
+	if _, err := c.Put(context.Background(), "/services/api/42", "10.0.0.42:8080"); err != nil {
+		log.Fatal(err)
+	}
+}
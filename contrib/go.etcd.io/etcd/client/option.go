@@ -0,0 +1,31 @@
+package client
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type clientConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// ClientOption represents an option that can be passed to WrapClient,
+// WrapKV, WrapLease, WrapWatcher or DialOptions.
+type ClientOption func(*clientConfig)
+
+func defaults(cfg *clientConfig) {
+	cfg.serviceName = "etcd"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced operations.
+func WithServiceName(name string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the operations.
+func WithTracer(t *tracer.Tracer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.tracer = t
+	}
+}
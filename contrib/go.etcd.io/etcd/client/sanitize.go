@@ -0,0 +1,42 @@
+package client
+
+import "strings"
+
+// maxKeyLength is the maximum length of the sanitized keys.
+const maxKeyLength = 128
+
+// SanitizeKey returns the given key with the path segments which look like
+// identifiers, such as numbers, UUIDs or hashes, replaced with '?', so that
+// the operations on similar keys are grouped together, e.g.
+// "/services/api/4f2a9c1e" becomes "/services/api/?".
+func SanitizeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		if isIdentifier(s) {
+			segments[i] = "?"
+		}
+	}
+	key = strings.Join(segments, "/")
+	if len(key) > maxKeyLength {
+		key = key[:maxKeyLength] + "..."
+	}
+	return key
+}
+
+// isIdentifier reports whether the given path segment looks like an
+// identifier: it is made of at least one digit and only of hexadecimal
+// digits and dashes, or of digits only.
+func isIdentifier(s string) bool {
+	var digits int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F', c == '-':
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for in, out := range map[string]string{
+		"":                       "",
+		"/":                      "/",
+		"/config/feature-flags":  "/config/feature-flags",
+		"/services/api/42":       "/services/api/?",
+		"/services/api/4f2a9c1e": "/services/api/?",
+		"/locks/de305d54-75b4-431b-adb2-eb6b9e546014/owner": "/locks/?/owner",
+		"/nodes/node-1":   "/nodes/node-1",
+		"/cache/deadbeef": "/cache/deadbeef",
+		"registry/v2":     "registry/v2",
+	} {
+		assert.Equal(out, SanitizeKey(in), in)
+	}
+	assert.Len(SanitizeKey("/"+strings.Repeat("a", 200)), maxKeyLength+3)
+}
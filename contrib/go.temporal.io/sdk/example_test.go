@@ -0,0 +1,31 @@
+package temporal_test
+
+import (
+	"log"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/worker"
+
+	temporaltrace "github.com/DataDog/dd-trace-go/contrib/go.temporal.io/sdk"
+)
+
+func Example() {
+	// the same interceptor traces clients and workers
+	i := temporaltrace.NewInterceptor(temporaltrace.WithServiceName("orders"))
+
+	c, err := client.Dial(client.Options{
+		Interceptors: []interceptor.ClientInterceptor{i},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	w := worker.New(c, "orders", worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{i},
+	})
+	if err := w.Run(worker.InterruptCh()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package temporal
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type interceptorConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// InterceptorOption represents an option that can be passed to NewInterceptor.
+type InterceptorOption func(*interceptorConfig)
+
+func defaults(cfg *interceptorConfig) {
+	cfg.serviceName = "temporal"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced workflows and activities.
+func WithServiceName(name string) InterceptorOption {
+	return func(cfg *interceptorConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the workflows and activities.
+func WithTracer(t *tracer.Tracer) InterceptorOption {
+	return func(cfg *interceptorConfig) {
+		cfg.tracer = t
+	}
+}
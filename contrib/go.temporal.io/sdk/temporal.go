@@ -0,0 +1,236 @@
+// Package temporal provides functions to trace the Temporal Go SDK (https://github.com/temporalio/sdk-go).
+//
+// The interceptor returned by NewInterceptor traces the workflows started by
+// clients, and the workflow and activity executions of workers. The trace
+// context is propagated from clients to workflows, and from workflows to
+// their activities and child workflows, through Temporal headers.
+//
+// Workflow code is replayed from the event history each time a worker needs
+// to rebuild the state of a workflow, which would record its spans again.
+// Workflow executions which start by a replay, and the calls they make while
+// replaying, are not traced.
+package temporal
+
+import (
+	"context"
+	"strconv"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "go.temporal.io/sdk", Library: "go.temporal.io/sdk"})
+}
+
+const (
+	// headerKey is the Temporal header holding the trace context.
+	headerKey = "_dd-trace"
+
+	// pass trace ids with these keys
+	traceIDKey  = "x-datadog-trace-id"
+	parentIDKey = "x-datadog-parent-id"
+)
+
+// spanKey is the key of the workflow span in workflow contexts.
+type spanKey struct{}
+
+// NewInterceptor returns an interceptor tracing Temporal clients and workers.
+// It is registered with client.Options.Interceptors for clients, and
+// worker.Options.Interceptors for workers.
+func NewInterceptor(opts ...InterceptorOption) interceptor.Interceptor {
+	cfg := new(interceptorConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "temporal", ext.AppTypeRPC)
+	tracer.RegisterIntegration(tracer.Integration{Name: "go.temporal.io/sdk", Options: map[string]string{"service": cfg.serviceName}})
+	return &tracingInterceptor{config: cfg}
+}
+
+type tracingInterceptor struct {
+	interceptor.InterceptorBase
+	config *interceptorConfig
+}
+
+func (i *tracingInterceptor) InterceptClient(next interceptor.ClientOutboundInterceptor) interceptor.ClientOutboundInterceptor {
+	o := &clientOutbound{config: i.config}
+	o.Next = next
+	return o
+}
+
+func (i *tracingInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	in := &activityInbound{config: i.config}
+	in.Next = next
+	return in
+}
+
+func (i *tracingInterceptor) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	in := &workflowInbound{config: i.config}
+	in.Next = next
+	return in
+}
+
+// clientOutbound traces the workflows started by a client.
+type clientOutbound struct {
+	interceptor.ClientOutboundInterceptorBase
+	config *interceptorConfig
+}
+
+func (o *clientOutbound) ExecuteWorkflow(ctx context.Context, in *interceptor.ClientExecuteWorkflowInput) (client.WorkflowRun, error) {
+	t := o.config.tracer
+	if !t.Enabled() {
+		return o.Next.ExecuteWorkflow(ctx, in)
+	}
+	span := t.NewChildSpanFromContext("temporal.start_workflow", ctx)
+	span.SetIntegration("go.temporal.io/sdk")
+	span.Type = "temporal"
+	span.Service = o.config.serviceName
+	span.Resource = in.WorkflowType
+	if in.Options != nil {
+		span.SetMeta("temporal.task_queue", in.Options.TaskQueue)
+	}
+	injectSpan(interceptor.Header(ctx), span)
+
+	run, err := o.Next.ExecuteWorkflow(tracer.ContextWithSpan(ctx, span), in)
+	if run != nil {
+		span.SetMeta("temporal.workflow_id", run.GetID())
+	}
+	span.FinishWithErr(err)
+	return run, err
+}
+
+// activityInbound traces the activity executions of a worker.
+type activityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+	config *interceptorConfig
+}
+
+func (in *activityInbound) ExecuteActivity(ctx context.Context, input *interceptor.ExecuteActivityInput) (interface{}, error) {
+	t := in.config.tracer
+	if !t.Enabled() {
+		return in.Next.ExecuteActivity(ctx, input)
+	}
+	info := activity.GetInfo(ctx)
+	span := newSpan(t, "temporal.activity", in.config.serviceName, info.ActivityType.Name, interceptor.Header(ctx))
+	span.SetMeta("temporal.workflow_id", info.WorkflowExecution.ID)
+	span.SetMeta("temporal.task_queue", info.TaskQueue)
+	span.SetMeta("temporal.attempt", strconv.Itoa(int(info.Attempt)))
+
+	ret, err := in.Next.ExecuteActivity(tracer.ContextWithSpan(ctx, span), input)
+	span.FinishWithErr(err)
+	return ret, err
+}
+
+// workflowInbound traces the workflow executions of a worker.
+type workflowInbound struct {
+	interceptor.WorkflowInboundInterceptorBase
+	config *interceptorConfig
+}
+
+func (in *workflowInbound) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	o := &workflowOutbound{config: in.config}
+	o.Next = outbound
+	return in.Next.Init(o)
+}
+
+func (in *workflowInbound) ExecuteWorkflow(ctx workflow.Context, input *interceptor.ExecuteWorkflowInput) (interface{}, error) {
+	t := in.config.tracer
+	if !t.Enabled() || workflow.IsReplaying(ctx) {
+		return in.Next.ExecuteWorkflow(ctx, input)
+	}
+	info := workflow.GetInfo(ctx)
+	span := newSpan(t, "temporal.workflow", in.config.serviceName, info.WorkflowType.Name, interceptor.WorkflowHeader(ctx))
+	span.SetMeta("temporal.workflow_id", info.WorkflowExecution.ID)
+	span.SetMeta("temporal.run_id", info.WorkflowExecution.RunID)
+	span.SetMeta("temporal.task_queue", info.TaskQueueName)
+
+	ret, err := in.Next.ExecuteWorkflow(workflow.WithValue(ctx, spanKey{}, span), input)
+	span.FinishWithErr(err)
+	return ret, err
+}
+
+// workflowOutbound propagates the trace context of a workflow to the
+// activities and child workflows it starts.
+type workflowOutbound struct {
+	interceptor.WorkflowOutboundInterceptorBase
+	config *interceptorConfig
+}
+
+func (o *workflowOutbound) ExecuteActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	o.inject(ctx)
+	return o.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+func (o *workflowOutbound) ExecuteLocalActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	o.inject(ctx)
+	return o.Next.ExecuteLocalActivity(ctx, activityType, args...)
+}
+
+func (o *workflowOutbound) ExecuteChildWorkflow(ctx workflow.Context, childWorkflowType string, args ...interface{}) workflow.ChildWorkflowFuture {
+	o.inject(ctx)
+	return o.Next.ExecuteChildWorkflow(ctx, childWorkflowType, args...)
+}
+
+// inject writes the context of the span of the workflow, if traced, to the
+// headers of the outgoing call.
+func (o *workflowOutbound) inject(ctx workflow.Context) {
+	if workflow.IsReplaying(ctx) {
+		return
+	}
+	if span, ok := ctx.Value(spanKey{}).(*tracer.Span); ok {
+		injectSpan(interceptor.WorkflowHeader(ctx), span)
+	}
+}
+
+// newSpan returns a span for an execution, which is a child of the span
+// whose context is found in the given headers, if any.
+func newSpan(t *tracer.Tracer, name, service, resource string, header map[string]*commonpb.Payload) *tracer.Span {
+	span := t.NewRootSpan(name, service, resource)
+	span.SetIntegration("go.temporal.io/sdk")
+	span.Type = "temporal"
+	if traceID, parentID := extractIDs(header); traceID != 0 && parentID != 0 {
+		span.TraceID = traceID
+		span.ParentID = parentID
+		t.Sample(span)
+	}
+	return span
+}
+
+// injectSpan writes the context of the given span to the given headers.
+func injectSpan(header map[string]*commonpb.Payload, span *tracer.Span) {
+	if header == nil || span.TraceID == 0 {
+		return
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(map[string]string{
+		traceIDKey:  strconv.FormatUint(span.TraceID, 10),
+		parentIDKey: strconv.FormatUint(span.SpanID, 10),
+	})
+	if err != nil {
+		return
+	}
+	header[headerKey] = payload
+}
+
+// extractIDs returns the trace context found in the given headers, or zeros.
+func extractIDs(header map[string]*commonpb.Payload) (traceID, parentID uint64) {
+	payload, ok := header[headerKey]
+	if !ok {
+		return 0, 0
+	}
+	var ids map[string]string
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &ids); err != nil {
+		return 0, 0
+	}
+	traceID, _ = strconv.ParseUint(ids[traceIDKey], 10, 64)
+	parentID, _ = strconv.ParseUint(ids[parentIDKey], 10, 64)
+	return traceID, parentID
+}
@@ -0,0 +1,84 @@
+package temporal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+func greet(ctx workflow.Context, name string) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+	var greeting string
+	err := workflow.ExecuteActivity(ctx, hello, name).Get(ctx, &greeting)
+	return greeting, err
+}
+
+func hello(ctx context.Context, name string) (string, error) {
+	if _, ok := tracer.SpanFromContext(ctx); !ok {
+		return "", nil
+	}
+	return "Hello " + name, nil
+}
+
+func TestInjectExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, _ := tracertest.GetTestTracer()
+	span := testTracer.NewRootSpan("temporal.start_workflow", "temporal", "greet")
+	header := make(map[string]*commonpb.Payload)
+	injectSpan(header, span)
+
+	traceID, parentID := extractIDs(header)
+	assert.Equal(span.TraceID, traceID)
+	assert.Equal(span.SpanID, parentID)
+
+	traceID, parentID = extractIDs(nil)
+	assert.Equal(uint64(0), traceID)
+	assert.Equal(uint64(0), parentID)
+}
+
+func TestWorker(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	var s testsuite.WorkflowTestSuite
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{NewInterceptor(WithServiceName("greeter"), WithTracer(testTracer))},
+	})
+	env.RegisterWorkflow(greet)
+	env.RegisterActivity(hello)
+	env.ExecuteWorkflow(greet, "bob")
+	assert.True(env.IsWorkflowCompleted())
+	assert.Nil(env.GetWorkflowError())
+	var greeting string
+	assert.Nil(env.GetWorkflowResult(&greeting))
+	assert.Equal("Hello bob", greeting)
+
+	// the activity is traced as a child of the workflow
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := make(map[string]*tracer.Span)
+	for _, s := range traces[0] {
+		spans[s.Name] = s
+	}
+	wf, act := spans["temporal.workflow"], spans["temporal.activity"]
+	assert.NotNil(wf)
+	assert.NotNil(act)
+	assert.Equal("greeter", wf.Service)
+	assert.Equal("greet", wf.Resource)
+	assert.Equal("hello", act.Resource)
+	assert.Equal(wf.TraceID, act.TraceID)
+	assert.Equal(wf.SpanID, act.ParentID)
+}
@@ -13,6 +13,10 @@ import (
 	"github.com/gocql/gocql"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "gocql/gocql", Library: "github.com/gocql/gocql"})
+}
+
 // Query inherits from gocql.Query, it keeps the tracer and the context.
 type Query struct {
 	*gocql.Query
@@ -74,6 +78,7 @@ func (tq *Query) PageState(state []byte) *Query {
 func (tq *Query) newChildSpan(ctx context.Context) *tracer.Span {
 	p := tq.params
 	span := p.config.tracer.NewChildSpanFromContext(ext.CassandraQuery, ctx)
+	span.SetIntegration("gocql/gocql")
 	span.Type = ext.CassandraType
 	span.Service = p.config.serviceName
 	span.Resource = p.query
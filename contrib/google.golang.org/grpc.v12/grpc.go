@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/DataDog/dd-trace-go/contrib/internal"
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 
@@ -13,12 +14,21 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+func init() {
+	// tested against the version of gRPC vendored by the CI
+	tracer.RegisterIntegration(tracer.Integration{Name: "google.golang.org/grpc.v12", Library: "google.golang.org/grpc", Version: "v1.5.2"})
+}
+
 // pass trace ids with these headers
 const (
 	traceIDKey  = "x-datadog-trace-id"
 	parentIDKey = "x-datadog-parent-id"
 )
 
+// messageDirectionKey tells whether the message of a "grpc.message" span was
+// sent or received.
+const messageDirectionKey = "grpc.message.direction"
+
 // UnaryServerInterceptor will trace requests to the given grpc server.
 func UnaryServerInterceptor(opts ...InterceptorOption) grpc.UnaryServerInterceptor {
 	cfg := new(interceptorConfig)
@@ -31,11 +41,13 @@ func UnaryServerInterceptor(opts ...InterceptorOption) grpc.UnaryServerIntercept
 	}
 	t := cfg.tracer
 	t.SetServiceInfo(cfg.serviceName, "grpc-server", ext.AppTypeRPC)
+	tracer.RegisterIntegration(tracer.Integration{Name: "google.golang.org/grpc.v12", Options: map[string]string{"service": cfg.serviceName}})
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if !t.Enabled() {
 			return handler(ctx, req)
 		}
 		span := serverSpan(t, ctx, info.FullMethod, cfg.serviceName)
+		defer internal.FinishOnPanic(span, nil)
 		resp, err := handler(tracer.ContextWithSpan(ctx, span), req)
 		span.FinishWithErr(err)
 		return resp, err
@@ -53,6 +65,7 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 		cfg.serviceName = "grpc.client"
 	}
 	cfg.tracer.SetServiceInfo(cfg.serviceName, "grpc-client", ext.AppTypeRPC)
+	tracer.RegisterIntegration(tracer.Integration{Name: "google.golang.org/grpc.v12", Options: map[string]string{"service": cfg.serviceName}})
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 		var child *tracer.Span
 		span, ok := tracer.SpanFromContext(ctx)
@@ -61,6 +74,7 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 		if ok && span.Tracer() != nil {
 			t := span.Tracer()
 			child = t.NewChildSpan("grpc.client", span)
+			child.SetIntegration("google.golang.org/grpc.v12")
 			child.SetMeta("grpc.method", method)
 			ctx = setIDs(child, ctx)
 			ctx = tracer.ContextWithSpan(ctx, child)
@@ -80,6 +94,7 @@ func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientIntercept
 
 func serverSpan(t *tracer.Tracer, ctx context.Context, method, service string) *tracer.Span {
 	span := t.NewRootSpan("grpc.server", service, method)
+	span.SetIntegration("google.golang.org/grpc.v12")
 	span.SetMeta("gprc.method", method)
 	span.Type = "go"
 
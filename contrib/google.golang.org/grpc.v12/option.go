@@ -3,8 +3,10 @@ package grpc
 import "github.com/DataDog/dd-trace-go/tracer"
 
 type interceptorConfig struct {
-	serviceName string
-	tracer      *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	serviceName    string
+	tracer         *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	streamCalls    bool
+	streamMessages bool
 }
 
 // InterceptorOption represents an option that can be passed to the grpc unary
@@ -14,6 +16,8 @@ type InterceptorOption func(*interceptorConfig)
 func defaults(cfg *interceptorConfig) {
 	cfg.serviceName = "grpc.client"
 	cfg.tracer = tracer.DefaultTracer
+	cfg.streamCalls = true
+	cfg.streamMessages = true
 }
 
 // WithServiceName sets the given service name for the intercepted client.
@@ -28,3 +32,20 @@ func WithTracer(t *tracer.Tracer) InterceptorOption {
 		cfg.tracer = t
 	}
 }
+
+// WithStreamCalls sets whether a stream is traced by a span lasting as long as
+// the stream. Long-lived streams may be better traced by their messages only.
+// It defaults to true.
+func WithStreamCalls(enabled bool) InterceptorOption {
+	return func(cfg *interceptorConfig) {
+		cfg.streamCalls = enabled
+	}
+}
+
+// WithStreamMessages sets whether each message sent or received on a stream
+// is traced by a "grpc.message" span. It defaults to true.
+func WithStreamMessages(enabled bool) InterceptorOption {
+	return func(cfg *interceptorConfig) {
+		cfg.streamMessages = enabled
+	}
+}
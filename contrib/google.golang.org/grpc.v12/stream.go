@@ -0,0 +1,231 @@
+package grpc
+
+import (
+	"io"
+	"sync"
+
+	context "golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// StreamServerInterceptor will trace streams handled by the given grpc server.
+// Depending on the WithStreamCalls and WithStreamMessages options, a stream is
+// traced by a span lasting as long as the stream, by a span for each message
+// it sends or receives, or by both, the default.
+func StreamServerInterceptor(opts ...InterceptorOption) grpc.StreamServerInterceptor {
+	cfg := new(interceptorConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	if cfg.serviceName == "" {
+		cfg.serviceName = "grpc.server"
+	}
+	t := cfg.tracer
+	t.SetServiceInfo(cfg.serviceName, "grpc-server", ext.AppTypeRPC)
+	tracer.RegisterIntegration(tracer.Integration{Name: "google.golang.org/grpc.v12", Options: map[string]string{"service": cfg.serviceName}})
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !t.Enabled() || (!cfg.streamCalls && !cfg.streamMessages) {
+			return handler(srv, ss)
+		}
+		ctx := ss.Context()
+		var span *tracer.Span
+		if cfg.streamCalls {
+			span = serverSpan(t, ctx, info.FullMethod, cfg.serviceName)
+			defer internal.FinishOnPanic(span, nil)
+			ctx = tracer.ContextWithSpan(ctx, span)
+		}
+		stream := &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			call:         span,
+			method:       info.FullMethod,
+			config:       cfg,
+		}
+		err := handler(srv, stream)
+		if span != nil {
+			span.SetMeta("grpc.code", grpc.Code(err).String())
+			span.FinishWithErr(err)
+		}
+		return err
+	}
+}
+
+// serverStream is a server stream passing the span of the call to the handler
+// and tracing the messages.
+type serverStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	call   *tracer.Span // span of the whole stream, if traced
+	method string
+	config *interceptorConfig
+}
+
+// Context implements grpc.ServerStream.
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// SendMsg implements grpc.ServerStream.
+func (ss *serverStream) SendMsg(m interface{}) error {
+	span := ss.messageSpan("send")
+	err := ss.ServerStream.SendMsg(m)
+	span.FinishWithErr(err)
+	return err
+}
+
+// RecvMsg implements grpc.ServerStream. The span of the message starts once
+// it is received, so that the time spent waiting for the client isn't
+// counted.
+func (ss *serverStream) RecvMsg(m interface{}) error {
+	err := ss.ServerStream.RecvMsg(m)
+	if err == io.EOF {
+		// the client closed its side of the stream, there's no message
+		return err
+	}
+	ss.messageSpan("receive").FinishWithErr(err)
+	return err
+}
+
+// messageSpan returns the span of a message, which is a child of the call span
+// when there is one, or continues the trace of the client otherwise. It
+// returns nil when messages aren't traced.
+func (ss *serverStream) messageSpan(direction string) *tracer.Span {
+	if !ss.config.streamMessages {
+		return nil
+	}
+	var span *tracer.Span
+	if ss.call != nil {
+		span = ss.call.Tracer().NewChildSpan("grpc.message", ss.call)
+	} else {
+		span = serverSpan(ss.config.tracer, ss.ServerStream.Context(), ss.method, ss.config.serviceName)
+		span.Name = "grpc.message"
+	}
+	span.SetMeta(messageDirectionKey, direction)
+	return span
+}
+
+// StreamClientInterceptor will add tracing to the streams of a grpc client.
+// As with UnaryClientInterceptor, only the streams which are part of a trace
+// are traced, as configured by the WithStreamCalls and WithStreamMessages
+// options.
+func StreamClientInterceptor(opts ...InterceptorOption) grpc.StreamClientInterceptor {
+	cfg := new(interceptorConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	if cfg.serviceName == "" {
+		cfg.serviceName = "grpc.client"
+	}
+	t := cfg.tracer
+	t.SetServiceInfo(cfg.serviceName, "grpc-client", ext.AppTypeRPC)
+	tracer.RegisterIntegration(tracer.Integration{Name: "google.golang.org/grpc.v12", Options: map[string]string{"service": cfg.serviceName}})
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		parent, ok := tracer.SpanFromContext(ctx)
+		if !ok || parent.Tracer() == nil || (!cfg.streamCalls && !cfg.streamMessages) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		var call *tracer.Span
+		if cfg.streamCalls {
+			call = parent.Tracer().NewChildSpan("grpc.client", parent)
+			call.SetIntegration("google.golang.org/grpc.v12")
+			call.SetMeta("grpc.method", method)
+			ctx = setIDs(call, ctx)
+			ctx = tracer.ContextWithSpan(ctx, call)
+		} else {
+			// without a call span, the messages of the server are attached
+			// to the caller, as are the ones of the client.
+			ctx = setIDs(&tracer.Span{TraceID: parent.TraceID, ParentID: parent.SpanID}, ctx)
+		}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			if call != nil {
+				call.SetMeta("grpc.code", grpc.Code(err).String())
+				call.FinishWithErr(err)
+			}
+			return cs, err
+		}
+		stream := &clientStream{
+			ClientStream: cs,
+			parent:       parent,
+			call:         call,
+			config:       cfg,
+		}
+		if call != nil {
+			// the stream may be abandoned before it is read until its end
+			go func() {
+				<-cs.Context().Done()
+				stream.finish(nil)
+			}()
+		}
+		return stream, nil
+	}
+}
+
+// clientStream is a client stream tracing its messages and finishing the
+// span of the call when the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	parent *tracer.Span // span of the caller
+	call   *tracer.Span // span of the whole stream, if traced
+	config *interceptorConfig
+	once   sync.Once
+}
+
+// SendMsg implements grpc.ClientStream.
+func (cs *clientStream) SendMsg(m interface{}) error {
+	span := cs.messageSpan("send")
+	err := cs.ClientStream.SendMsg(m)
+	span.FinishWithErr(err)
+	if err != nil {
+		cs.finish(err)
+	}
+	return err
+}
+
+// RecvMsg implements grpc.ClientStream. The span of the message starts once
+// it is received, so that the time spent waiting for the server isn't
+// counted.
+func (cs *clientStream) RecvMsg(m interface{}) error {
+	err := cs.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		// the stream ended successfully, there's no message
+		cs.finish(nil)
+		return err
+	}
+	cs.messageSpan("receive").FinishWithErr(err)
+	if err != nil {
+		cs.finish(err)
+	}
+	return err
+}
+
+// messageSpan returns the span of a message, or nil when messages aren't traced.
+func (cs *clientStream) messageSpan(direction string) *tracer.Span {
+	if !cs.config.streamMessages {
+		return nil
+	}
+	parent := cs.parent
+	if cs.call != nil {
+		parent = cs.call
+	}
+	span := parent.Tracer().NewChildSpan("grpc.message", parent)
+	span.SetMeta(messageDirectionKey, direction)
+	return span
+}
+
+// finish finishes the span of the call, once.
+func (cs *clientStream) finish(err error) {
+	if cs.call == nil {
+		return
+	}
+	cs.once.Do(func() {
+		cs.call.SetMeta("grpc.code", grpc.Code(err).String())
+		cs.call.FinishWithErr(err)
+	})
+}
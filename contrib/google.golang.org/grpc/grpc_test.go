@@ -219,13 +219,15 @@ func (r *rig) Close() {
 	r.listener.Close()
 }
 
-func newRig(t *tracer.Tracer, traceClient bool) (*rig, error) {
+func newRig(t *tracer.Tracer, traceClient bool, opts ...InterceptorOption) (*rig, error) {
+	opts = append(opts, WithServiceName("grpc"), WithTracer(t))
 	server := grpc.NewServer(
-		grpc.UnaryInterceptor(
-			UnaryServerInterceptor(WithServiceName("grpc"), WithTracer(t)),
-		))
+		grpc.UnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(opts...)),
+	)
 
 	RegisterFixtureServer(server, new(fixtureServer))
+	server.RegisterService(&streamServiceDesc, new(fixtureServer))
 
 	li, err := net.Listen("tcp4", "127.0.0.1:0")
 	if err != nil {
@@ -234,13 +236,14 @@ func newRig(t *tracer.Tracer, traceClient bool) (*rig, error) {
 	// start our test fixtureServer.
 	go server.Serve(li)
 
-	opts := []grpc.DialOption{grpc.WithInsecure()}
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
 	if traceClient {
-		opts = append(opts, grpc.WithUnaryInterceptor(
-			UnaryClientInterceptor(WithServiceName("grpc"), WithTracer(t)),
-		))
+		dialOpts = append(dialOpts,
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor(opts...)),
+			grpc.WithStreamInterceptor(StreamClientInterceptor(opts...)),
+		)
 	}
-	conn, err := grpc.Dial(li.Addr().String(), opts...)
+	conn, err := grpc.Dial(li.Addr().String(), dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("error dialing: %s", err)
 	}
@@ -0,0 +1,38 @@
+package grpctest_test
+
+import (
+	"testing"
+
+	context "golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc/grpctest"
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+func Example() {
+	var t *testing.T // given to the test function
+
+	rig, err := grpctest.NewRig(func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rig.Close()
+
+	root := rig.Tracer.NewRootSpan("test", "my-service", "TestHealth")
+	ctx := tracer.ContextWithSpan(context.Background(), root)
+	if _, err := healthpb.NewHealthClient(rig.Conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	root.Finish()
+
+	for _, s := range rig.Spans() {
+		if s.TraceID != root.TraceID {
+			t.Errorf("span %q isn't part of the trace", s.Name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package grpctest provides an in-process gRPC client and server, both traced
+// with a test tracer, to assert in unit tests how traces propagate across
+// them without running an agent.
+package grpctest
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	grpctrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// bufSize is the size of the in-memory buffer of the connections.
+const bufSize = 1024 * 1024
+
+// Rig is a traced gRPC server and a traced client connection to it, which
+// communicate in memory.
+type Rig struct {
+	Server    *grpc.Server
+	Conn      *grpc.ClientConn
+	Tracer    *tracer.Tracer
+	Transport *tracertest.DummyTransport
+
+	listener *bufconn.Listener
+	wg       sync.WaitGroup
+}
+
+// NewRig returns a Rig whose server has the services registered by the given
+// function. The unary and stream interceptors of the server and the client
+// are set up with the given options, with the test tracer of the rig. As with
+// real clients, the calls are only traced when their context holds a span.
+func NewRig(register func(*grpc.Server), opts ...grpctrace.InterceptorOption) (*Rig, error) {
+	t, transport := tracertest.GetTestTracer()
+	opts = append(opts, grpctrace.WithTracer(t))
+	r := &Rig{
+		Server: grpc.NewServer(
+			grpc.UnaryInterceptor(grpctrace.UnaryServerInterceptor(opts...)),
+			grpc.StreamInterceptor(grpctrace.StreamServerInterceptor(opts...)),
+		),
+		Tracer:    t,
+		Transport: transport,
+		listener:  bufconn.Listen(bufSize),
+	}
+	register(r.Server)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.Server.Serve(r.listener)
+	}()
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return r.listener.Dial()
+		}),
+		grpc.WithUnaryInterceptor(grpctrace.UnaryClientInterceptor(opts...)),
+		grpc.WithStreamInterceptor(grpctrace.StreamClientInterceptor(opts...)),
+	)
+	if err != nil {
+		r.Server.Stop()
+		r.wg.Wait()
+		return nil, err
+	}
+	r.Conn = conn
+	return r, nil
+}
+
+// Spans flushes the tracer of the rig and returns all the spans it recorded
+// so far, from all the traces.
+func (r *Rig) Spans() []*tracer.Span {
+	r.Tracer.ForceFlush()
+	var spans []*tracer.Span
+	for _, trace := range r.Transport.Traces() {
+		spans = append(spans, trace...)
+	}
+	return spans
+}
+
+// Close closes the client connection, and stops the server and the tracer.
+func (r *Rig) Close() {
+	r.Conn.Close()
+	r.Server.Stop()
+	r.wg.Wait()
+	r.Tracer.Stop()
+}
@@ -0,0 +1,105 @@
+package grpctest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	context "golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	grpctrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc"
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+func TestRig(t *testing.T) {
+	assert := assert.New(t)
+
+	rig, err := NewRig(func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	}, grpctrace.WithServiceName("health"))
+	assert.Nil(err)
+	defer rig.Close()
+
+	root := rig.Tracer.NewRootSpan("test", "test", "test")
+	ctx := tracer.ContextWithSpan(context.Background(), root)
+	_, err = healthpb.NewHealthClient(rig.Conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(err)
+	root.Finish()
+
+	spans := rig.Spans()
+	assert.Len(spans, 3)
+	var client, server *tracer.Span
+	for _, s := range spans {
+		switch s.Name {
+		case "grpc.client":
+			client = s
+		case "grpc.server":
+			server = s
+		}
+	}
+	assert.NotNil(client)
+	assert.NotNil(server)
+	assert.Equal(root.TraceID, server.TraceID)
+	assert.Equal("health", server.Service)
+}
+
+// echoDesc describes a service with a single bidirectional stream, echoing
+// the health checks it receives.
+var echoDesc = grpc.ServiceDesc{
+	ServiceName: "grpctest.Echo",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Echo",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				for {
+					in := new(healthpb.HealthCheckRequest)
+					if err := stream.RecvMsg(in); err == io.EOF {
+						return nil
+					} else if err != nil {
+						return err
+					}
+					if err := stream.SendMsg(&healthpb.HealthCheckResponse{}); err != nil {
+						return err
+					}
+				}
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+func TestRigStream(t *testing.T) {
+	assert := assert.New(t)
+
+	rig, err := NewRig(func(s *grpc.Server) {
+		s.RegisterService(&echoDesc, new(struct{}))
+	}, grpctrace.WithServiceName("echo"), grpctrace.WithStreamMessages(false))
+	assert.Nil(err)
+	defer rig.Close()
+
+	root := rig.Tracer.NewRootSpan("test", "test", "test")
+	ctx := tracer.ContextWithSpan(context.Background(), root)
+	stream, err := grpc.NewClientStream(ctx, &echoDesc.Streams[0], rig.Conn, "/grpctest.Echo/Echo")
+	assert.Nil(err)
+	assert.Nil(stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	assert.Nil(stream.RecvMsg(new(healthpb.HealthCheckResponse)))
+	assert.Nil(stream.CloseSend())
+	assert.Equal(io.EOF, stream.RecvMsg(new(healthpb.HealthCheckResponse)))
+	root.Finish()
+
+	// the stream is traced on both sides
+	spans := rig.Spans()
+	assert.Len(spans, 3)
+	for _, s := range spans {
+		assert.Equal(root.TraceID, s.TraceID)
+		if s.Name == "grpc.server" {
+			assert.Equal("/grpctest.Echo/Echo", s.Resource)
+			assert.Equal("echo", s.Service)
+		}
+	}
+}
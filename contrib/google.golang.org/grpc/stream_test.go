@@ -0,0 +1,161 @@
+package grpc
+
+import (
+	"io"
+	"testing"
+
+	context "golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamServiceDesc describes a service with a single bidirectional stream,
+// echoing the requests it receives.
+var streamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.StreamFixture",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Echo",
+			Handler:       echoHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "fixtures.proto",
+}
+
+func echoHandler(srv interface{}, stream grpc.ServerStream) error {
+	for {
+		in := new(FixtureRequest)
+		if err := stream.RecvMsg(in); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(&FixtureReply{Message: in.Name}); err != nil {
+			return err
+		}
+	}
+}
+
+// echo sends the given names on a new Echo stream and reads the replies until
+// the end of the stream.
+func echo(ctx context.Context, conn *grpc.ClientConn, names ...string) error {
+	stream, err := grpc.NewClientStream(ctx, &streamServiceDesc.Streams[0], conn, "/grpc.StreamFixture/Echo")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := stream.SendMsg(&FixtureRequest{Name: name}); err != nil {
+			return err
+		}
+		if err := stream.RecvMsg(new(FixtureReply)); err != nil {
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	if err := stream.RecvMsg(new(FixtureReply)); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// streamSpans runs an Echo stream of two messages within a root span, with
+// the given options, and returns all the spans which were flushed.
+func streamSpans(t *testing.T, opts ...InterceptorOption) (root *tracer.Span, spans []*tracer.Span) {
+	testTracer, testTransport := tracertest.GetTestTracer()
+	testTracer.SetDebugLogging(debug)
+
+	rig, err := newRig(testTracer, true, opts...)
+	if err != nil {
+		t.Fatalf("error setting up rig: %s", err)
+	}
+	defer rig.Close()
+
+	root = testTracer.NewRootSpan("a", "b", "c")
+	ctx := tracer.ContextWithSpan(context.Background(), root)
+	if err := echo(ctx, rig.conn, "one", "two"); err != nil {
+		t.Fatal(err)
+	}
+	root.Finish()
+
+	testTracer.ForceFlush()
+	for _, trace := range testTransport.Traces() {
+		spans = append(spans, trace...)
+	}
+	return root, spans
+}
+
+// countSpans returns the number of spans with the given name and message
+// direction, if any.
+func countSpans(spans []*tracer.Span, name, direction string) int {
+	var n int
+	for _, s := range spans {
+		if s.Name == name && (direction == "" || s.GetMeta(messageDirectionKey) == direction) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+	root, spans := streamSpans(t)
+
+	var sspan, cspan *tracer.Span
+	for _, s := range spans {
+		assert.Equal(root.TraceID, s.TraceID)
+		assert.Equal(int32(0), s.Error)
+		switch s.Name {
+		case "grpc.server":
+			sspan = s
+		case "grpc.client":
+			cspan = s
+		}
+	}
+	if assert.NotNil(sspan) && assert.NotNil(cspan) {
+		assert.Equal("/grpc.StreamFixture/Echo", sspan.Resource)
+		assert.Equal("OK", sspan.GetMeta("grpc.code"))
+		assert.Equal("OK", cspan.GetMeta("grpc.code"))
+		for _, s := range spans {
+			if s.Name == "grpc.message" {
+				assert.Contains([]uint64{sspan.SpanID, cspan.SpanID}, s.ParentID)
+			}
+		}
+	}
+	// both sides receive two messages, the end of the stream has no span
+	assert.Equal(4, countSpans(spans, "grpc.message", "receive"))
+	assert.Equal(4, countSpans(spans, "grpc.message", "send"))
+}
+
+func TestStreamCallsOnly(t *testing.T) {
+	assert := assert.New(t)
+	_, spans := streamSpans(t, WithStreamMessages(false))
+
+	assert.Len(spans, 3)
+	assert.Equal(1, countSpans(spans, "grpc.server", ""))
+	assert.Equal(1, countSpans(spans, "grpc.client", ""))
+	assert.Equal(0, countSpans(spans, "grpc.message", ""))
+}
+
+func TestStreamMessagesOnly(t *testing.T) {
+	assert := assert.New(t)
+	root, spans := streamSpans(t, WithStreamCalls(false))
+
+	assert.Equal(0, countSpans(spans, "grpc.server", ""))
+	assert.Equal(0, countSpans(spans, "grpc.client", ""))
+	assert.Equal(8, countSpans(spans, "grpc.message", ""))
+	for _, s := range spans {
+		assert.Equal(root.TraceID, s.TraceID)
+		if s.Name == "grpc.message" {
+			// messages are attached to the trace of the caller
+			assert.Equal(root.SpanID, s.ParentID)
+		}
+	}
+}
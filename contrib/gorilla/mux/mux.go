@@ -7,9 +7,14 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "gorilla/mux", Library: "github.com/gorilla/mux"})
+}
+
 // Router registers routes to be matched and dispatches a handler.
 type Router struct {
 	*mux.Router
@@ -24,6 +29,7 @@ func NewRouter(opts ...RouterOption) *Router {
 		fn(cfg)
 	}
 	cfg.tracer.SetServiceInfo(cfg.serviceName, "gorilla/mux", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "gorilla/mux", Options: map[string]string{"service": cfg.serviceName}})
 	return &Router{
 		Router: mux.NewRouter(),
 		config: cfg,
@@ -36,19 +42,39 @@ func NewRouter(opts ...RouterOption) *Router {
 // all the incoming requests to the underlying multiplexer
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	var (
-		match mux.RouteMatch
-		route string
-		err   error
+		match     mux.RouteMatch
+		route     string
+		resource  string
+		unmatched bool
 	)
 	// get the resource associated to this request
 	if r.Match(req, &match) {
+		var err error
 		route, err = match.Route.GetPathTemplate()
 		if err != nil {
-			route = "unknown"
+			route = ""
+			resource = req.Method + " unknown"
+		} else {
+			resource = req.Method + " " + route
 		}
 	} else {
-		route = "unknown"
+		// answered with a 404 or a 405
+		resource = internal.NotFoundResource
+		unmatched = true
+	}
+	if r.config.resourceNamer != nil {
+		resource = r.config.resourceNamer(req)
+		unmatched = false
 	}
-	resource := req.Method + " " + route
-	internal.TraceAndServe(r.Router, w, req, r.config.serviceName, resource, r.config.tracer)
+	internal.TraceAndServeWithConfig(r.Router, w, req, &internal.ServeConfig{
+		Service:      r.config.serviceName,
+		Resource:     resource,
+		Integration:  "gorilla/mux",
+		Tracer:       r.config.tracer,
+		Route:        route,
+		Streaming:    r.config.isStreaming != nil && r.config.isStreaming(req),
+		MethodInName: r.config.methodInName,
+		Unmatched:    unmatched,
+		ForceKeep:    r.config.forceKeep,
+	})
 }
@@ -3,9 +3,11 @@ package mux
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	"github.com/DataDog/dd-trace-go/tracer/tracertest"
 	"github.com/stretchr/testify/assert"
 )
@@ -63,6 +65,7 @@ func TestHttpTracer200(t *testing.T) {
 	assert.Equal("http.request", s.Name)
 	assert.Equal("my-service", s.Service)
 	assert.Equal("GET "+url, s.Resource)
+	assert.Equal(url, s.GetMeta("http.route"))
 	assert.Equal("200", s.GetMeta("http.status_code"))
 	assert.Equal("GET", s.GetMeta("http.method"))
 	assert.Equal(url, s.GetMeta("http.url"))
@@ -98,6 +101,84 @@ func TestHttpTracer500(t *testing.T) {
 	assert.Equal(int32(1), s.Error)
 }
 
+func TestNotFound(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+
+	r := httptest.NewRequest("GET", "/wp-admin.php", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(404, w.Code)
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("404 not found", s.Resource)
+	assert.Equal("", s.GetMeta("http.route"))
+	assert.Equal("/wp-admin.php", s.GetMeta("http.url"))
+	assert.Equal("404", s.GetMeta("http.status_code"))
+}
+
+func TestResourceNamer(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	router := NewRouter(WithServiceName("my-service"), WithTracer(testTracer), WithResourceNamer(func(r *http.Request) string {
+		return "api " + strings.TrimPrefix(r.URL.Path, "/v2")
+	}))
+	router.HandleFunc("/v2/users", handler200(t))
+
+	r := httptest.NewRequest("GET", "/v2/users", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("api /users", traces[0][0].Resource)
+}
+
+func TestMethodInOperationName(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	router := NewRouter(WithServiceName("my-service"), WithTracer(testTracer), WithMethodInOperationName())
+	router.HandleFunc("/users/{id}", handler200(t))
+
+	r := httptest.NewRequest("POST", "/users/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("http.request.post", s.Name)
+	assert.Equal("POST /users/{id}", s.Resource)
+	assert.Equal("/users/{id}", s.GetMeta("http.route"))
+}
+
+func TestForceKeep(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+	tracer.SetSampleRate(0)
+
+	// the header is ignored by default
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set(ext.HTTPHeaderForceKeep, "true")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	keeping := NewRouter(WithServiceName("my-service"), WithTracer(tracer), WithForceKeep())
+	keeping.HandleFunc("/200", handler200(t))
+	router = keeping
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(ext.PriorityUserKeep, traces[0][0].GetSamplingPriority())
+}
+
 func setup(t *testing.T) (*tracer.Tracer, *tracertest.DummyTransport, http.Handler) {
 	h200 := handler200(t)
 	h500 := handler500(t)
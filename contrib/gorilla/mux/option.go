@@ -1,10 +1,18 @@
 package mux
 
-import "github.com/DataDog/dd-trace-go/tracer"
+import (
+	"net/http"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
 
 type routerConfig struct {
-	serviceName string
-	tracer      *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	serviceName   string
+	tracer        *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	isStreaming   func(*http.Request) bool
+	resourceNamer func(*http.Request) string
+	methodInName  bool
+	forceKeep     bool
 }
 
 // RouterOption represents an option that can be passed to NewRouter.
@@ -27,3 +35,41 @@ func WithTracer(t *tracer.Tracer) RouterOption {
 		cfg.tracer = t
 	}
 }
+
+// WithStreaming marks the requests for which match returns true as streaming or
+// long-polling requests (e.g. server-sent events or websockets). Their spans are
+// finished as soon as the response header is written instead of when the handler
+// returns, so that hours-long connections don't skew latency.
+func WithStreaming(match func(*http.Request) bool) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.isStreaming = match
+	}
+}
+
+// WithResourceNamer sets the function used to name the resource of each request,
+// e.g. to group endpoints differently than the router does. It replaces the
+// default naming, which uses the method and the matched route.
+func WithResourceNamer(namer func(*http.Request) string) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.resourceNamer = namer
+	}
+}
+
+// WithMethodInOperationName appends the lowercase method of the requests to
+// the name of their spans, e.g. "http.request.get" instead of "http.request".
+func WithMethodInOperationName() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.methodInName = true
+	}
+}
+
+// WithForceKeep keeps the traces of the requests sent with the
+// "x-datadog-force-keep: true" header or coming from Synthetic tests, whatever
+// the sampler decides, so that a specific request can be traced on demand.
+// Only enable it when the clients are trusted, as they could otherwise have
+// all their requests traced.
+func WithForceKeep() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.forceKeep = true
+	}
+}
@@ -0,0 +1,33 @@
+package websocket_test
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+
+	websockettrace "github.com/DataDog/dd-trace-go/contrib/gorilla/websocket"
+)
+
+var upgrader websocket.Upgrader
+
+func echo(w http.ResponseWriter, r *http.Request) {
+	c, err := websockettrace.Upgrade(&upgrader, w, r, nil, websockettrace.WithMessageTracing())
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		typ, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := c.WriteMessage(typ, msg); err != nil {
+			return
+		}
+	}
+}
+
+func Example() {
+	http.HandleFunc("/echo", echo)
+	http.ListenAndServe(":8080", nil)
+}
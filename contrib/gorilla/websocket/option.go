@@ -0,0 +1,39 @@
+package websocket
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type upgradeConfig struct {
+	serviceName   string
+	traceMessages bool
+	tracer        *tracer.Tracer
+}
+
+// UpgradeOption represents an option that can be passed to Upgrade.
+type UpgradeOption func(*upgradeConfig)
+
+func defaults(cfg *upgradeConfig) {
+	cfg.serviceName = "websocket"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the upgraded connection.
+func WithServiceName(name string) UpgradeOption {
+	return func(cfg *upgradeConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithMessageTracing enables the tracing of the messages read and written
+// with ReadMessage and WriteMessage on the upgraded connection.
+func WithMessageTracing() UpgradeOption {
+	return func(cfg *upgradeConfig) {
+		cfg.traceMessages = true
+	}
+}
+
+// WithTracer sets the tracer used to trace the connection.
+func WithTracer(t *tracer.Tracer) UpgradeOption {
+	return func(cfg *upgradeConfig) {
+		cfg.tracer = t
+	}
+}
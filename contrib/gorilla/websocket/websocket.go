@@ -0,0 +1,117 @@
+// Package websocket provides functions to trace the gorilla/websocket package (https://github.com/gorilla/websocket).
+package websocket
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "gorilla/websocket", Library: "github.com/gorilla/websocket"})
+}
+
+const (
+	// messageTypeKey is the meta key holding the type of a message, "text",
+	// "binary", "close", "ping" or "pong".
+	messageTypeKey = "websocket.message.type"
+	// messageSizeKey is the metric key holding the size of a message in bytes.
+	messageSizeKey = "websocket.message.size"
+)
+
+// Conn is a websocket connection which traces the messages it reads and
+// writes, when enabled with WithMessageTracing.
+type Conn struct {
+	*websocket.Conn
+	config   *upgradeConfig
+	resource string
+}
+
+// Upgrade upgrades the HTTP server connection to the websocket protocol using
+// the given upgrader, tracing the handshake with a span which is a child of
+// the one found in the request context, if any.
+//
+// The messages of a connection can live much longer than the request which
+// opened it, so each message is traced with a span starting its own trace,
+// whose resource is the path of the request.
+func Upgrade(u *websocket.Upgrader, w http.ResponseWriter, r *http.Request, responseHeader http.Header, opts ...UpgradeOption) (*Conn, error) {
+	cfg := new(upgradeConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "gorilla/websocket", ext.AppTypeWeb)
+
+	span := cfg.tracer.NewChildSpanFromContext("websocket.upgrade", r.Context())
+	span.SetIntegration("gorilla/websocket")
+	span.Type = ext.HTTPType
+	span.Service = cfg.serviceName
+	span.Resource = r.URL.Path
+	span.SetMeta(ext.HTTPMethod, r.Method)
+	span.SetMeta(ext.HTTPURL, r.URL.Path)
+
+	c, err := u.Upgrade(w, r, responseHeader)
+	span.FinishWithErr(err)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: c, config: cfg, resource: r.URL.Path}, nil
+}
+
+// newMessageSpan returns a span starting a new trace, for a message of the
+// given type.
+func (c *Conn) newMessageSpan(name string, messageType int) *tracer.Span {
+	span := c.config.tracer.NewRootSpan(name, c.config.serviceName, c.resource)
+	span.SetIntegration("gorilla/websocket")
+	span.Type = ext.HTTPType
+	span.SetMeta(messageTypeKey, messageTypeName(messageType))
+	return span
+}
+
+// ReadMessage wraps websocket.Conn.ReadMessage, tracing the read if message
+// tracing is enabled. The span is started once the message has been read, so
+// that the time spent waiting for the peer isn't reported as latency.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if !c.config.traceMessages {
+		return c.Conn.ReadMessage()
+	}
+	messageType, p, err = c.Conn.ReadMessage()
+	span := c.newMessageSpan("websocket.read", messageType)
+	span.SetMetric(messageSizeKey, float64(len(p)))
+	span.FinishWithErr(err)
+	return messageType, p, err
+}
+
+// WriteMessage wraps websocket.Conn.WriteMessage, tracing the write if
+// message tracing is enabled.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if !c.config.traceMessages {
+		return c.Conn.WriteMessage(messageType, data)
+	}
+	span := c.newMessageSpan("websocket.write", messageType)
+	span.SetMetric(messageSizeKey, float64(len(data)))
+	err := c.Conn.WriteMessage(messageType, data)
+	span.FinishWithErr(err)
+	return err
+}
+
+// messageTypeName returns the name of a websocket message type.
+func messageTypeName(messageType int) string {
+	switch messageType {
+	case websocket.TextMessage:
+		return "text"
+	case websocket.BinaryMessage:
+		return "binary"
+	case websocket.CloseMessage:
+		return "close"
+	case websocket.PingMessage:
+		return "ping"
+	case websocket.PongMessage:
+		return "pong"
+	}
+	return strconv.Itoa(messageType)
+}
@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// echoServer starts a traced server echoing the messages received on the
+// websocket connections it upgrades with the given options. The returned
+// channel receives a value each time a request has been served.
+func echoServer(trc *tracer.Tracer, opts ...UpgradeOption) (*httptest.Server, chan struct{}) {
+	done := make(chan struct{}, 1)
+	var upgrader websocket.Upgrader
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(&upgrader, w, r, nil, opts...)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			typ, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(typ, msg); err != nil {
+				return
+			}
+		}
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internal.TraceAndServe(echo, w, r, "web", "GET /echo", "net/http", trc)
+		done <- struct{}{}
+	})), done
+}
+
+func TestUpgrade(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	srv, done := echoServer(testTracer, WithServiceName("ws"), WithTracer(testTracer))
+	defer srv.Close()
+
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+	assert.Nil(err)
+	assert.Nil(c.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, msg, err := c.ReadMessage()
+	assert.Nil(err)
+	assert.Equal("hello", string(msg))
+	c.Close()
+	<-done
+
+	// only the handshake is traced, as a child of the request
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 2)
+	var upgrade, request *tracer.Span
+	for _, s := range spans {
+		if s.Name == "websocket.upgrade" {
+			upgrade = s
+		} else {
+			request = s
+		}
+	}
+	assert.NotNil(upgrade)
+	assert.NotNil(request)
+	assert.Equal(request.SpanID, upgrade.ParentID)
+	assert.Equal("ws", upgrade.Service)
+	assert.Equal("/echo", upgrade.Resource)
+	assert.Equal("GET", upgrade.GetMeta("http.method"))
+	assert.Equal(int32(0), upgrade.Error)
+}
+
+func TestUpgradeError(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	srv, done := echoServer(testTracer, WithTracer(testTracer))
+	defer srv.Close()
+
+	// a plain HTTP request can't be upgraded
+	resp, err := http.Get(srv.URL + "/echo")
+	assert.Nil(err)
+	resp.Body.Close()
+	<-done
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	for _, s := range traces[0] {
+		if s.Name == "websocket.upgrade" {
+			assert.Equal("websocket", s.Service)
+			assert.Equal(int32(1), s.Error)
+		}
+	}
+}
+
+func TestMessageTracing(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	srv, done := echoServer(testTracer, WithTracer(testTracer), WithMessageTracing())
+	defer srv.Close()
+
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+	assert.Nil(err)
+	assert.Nil(c.WriteMessage(websocket.BinaryMessage, []byte("hello")))
+	_, _, err = c.ReadMessage()
+	assert.Nil(err)
+	c.Close()
+	<-done
+
+	// messages are traced in their own traces
+	testTracer.ForceFlush()
+	var reads, writes int
+	for _, trace := range testTransport.Traces() {
+		for _, s := range trace {
+			switch s.Name {
+			case "websocket.read":
+				reads++
+				if s.Error == 0 {
+					assert.Equal("binary", s.GetMeta(messageTypeKey))
+					assert.Equal(5.0, s.Metrics[messageSizeKey])
+				}
+			case "websocket.write":
+				writes++
+				assert.Equal(uint64(0), s.ParentID)
+				assert.Equal("/echo", s.Resource)
+				assert.Equal("binary", s.GetMeta(messageTypeKey))
+				assert.Equal(5.0, s.Metrics[messageSizeKey])
+			}
+		}
+	}
+	// the last read fails when the client goes away
+	assert.Equal(2, reads)
+	assert.Equal(1, writes)
+}
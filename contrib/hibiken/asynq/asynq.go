@@ -0,0 +1,133 @@
+// Package asynq provides functions to trace the hibiken/asynq package (https://github.com/hibiken/asynq).
+//
+// Tasks enqueued with a traced Client carry the trace context of the producer
+// in their payload, which the Middleware of the server removes before the
+// task reaches its handler. Both sides have to be traced: a handler which
+// isn't wrapped with the Middleware receives the payload with the trace
+// context. The sampling decision of the producer is carried along, so that the
+// tasks are kept or dropped as their producer was.
+package asynq
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "hibiken/asynq", Library: "github.com/hibiken/asynq"})
+}
+
+// Client is an asynq client which traces the tasks it enqueues.
+type Client struct {
+	*asynq.Client
+	config *config
+}
+
+// WrapClient returns a traced version of the given client.
+func WrapClient(c *asynq.Client, opts ...Option) *Client {
+	return &Client{
+		Client: c,
+		config: newConfig(opts...),
+	}
+}
+
+// Enqueue enqueues the given task, see EnqueueContext.
+func (c *Client) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.EnqueueContext(context.Background(), task, opts...)
+}
+
+// EnqueueContext enqueues the given task in a producer span, which is a child
+// of the one found in the given context, if any. The trace context is added
+// to the payload of the task so that its processing joins the trace, which
+// creates a new task: the options of the task have to be given here rather
+// than to asynq.NewTask.
+func (c *Client) EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	t := c.config.tracer
+	if !t.Enabled() {
+		return c.Client.EnqueueContext(ctx, task, opts...)
+	}
+	span := t.NewChildSpanFromContext("asynq.enqueue", ctx)
+	span.SetIntegration("hibiken/asynq")
+	span.Type = ext.AppTypeWorker
+	span.Service = c.config.serviceName
+	span.Resource = task.Type()
+
+	task = asynq.NewTask(task.Type(), internal.WrapPayload(span, task.Payload()))
+	info, err := c.Client.EnqueueContext(ctx, task, opts...)
+	if info != nil {
+		span.SetMeta("asynq.task_id", info.ID)
+		span.SetMeta("asynq.queue", info.Queue)
+	}
+	span.FinishWithErr(err)
+	return info, err
+}
+
+// Middleware returns a server middleware tracing the processing of tasks,
+// and removing the trace context added to their payload by traced clients.
+// The handlers receive a copy of such tasks with the original payload, which
+// has no result writer, see ResultWriter. The other tasks are given as is.
+func Middleware(opts ...Option) asynq.MiddlewareFunc {
+	cfg := newConfig(opts...)
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			remote, payload := internal.UnwrapPayload(task.Payload())
+			if len(payload) != len(task.Payload()) {
+				// the copy has no result writer, see ResultWriter
+				ctx = context.WithValue(ctx, resultWriterKey{}, task.ResultWriter())
+				task = asynq.NewTask(task.Type(), payload)
+			}
+			t := cfg.tracer
+			if !t.Enabled() {
+				return next.ProcessTask(ctx, task)
+			}
+			span := t.NewRootSpan("asynq.process", cfg.serviceName, task.Type())
+			remote.Continue(t, span)
+			span.SetIntegration("hibiken/asynq")
+			span.Type = ext.AppTypeWorker
+			if id, ok := asynq.GetTaskID(ctx); ok {
+				span.SetMeta("asynq.task_id", id)
+			}
+			if queue, ok := asynq.GetQueueName(ctx); ok {
+				span.SetMeta("asynq.queue", queue)
+			}
+			if n, ok := asynq.GetRetryCount(ctx); ok {
+				span.SetMetric("asynq.retry_count", float64(n))
+			}
+			defer internal.FinishOnPanic(span, nil)
+			err := next.ProcessTask(tracer.ContextWithSpan(ctx, span), task)
+			span.FinishWithErr(err)
+			return err
+		})
+	}
+}
+
+// resultWriterKey is the context key of the result writer of the processed
+// task.
+type resultWriterKey struct{}
+
+// ResultWriter returns the result writer of the given task, processed in the
+// given context. The handlers wrapped by the Middleware have to use it rather
+// than the ResultWriter method of the task, as the tasks enqueued by a traced
+// Client are given to them as copies without one.
+func ResultWriter(ctx context.Context, task *asynq.Task) *asynq.ResultWriter {
+	if w, ok := ctx.Value(resultWriterKey{}).(*asynq.ResultWriter); ok {
+		return w
+	}
+	return task.ResultWriter()
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "asynq", ext.AppTypeWorker)
+	tracer.RegisterIntegration(tracer.Integration{Name: "hibiken/asynq", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
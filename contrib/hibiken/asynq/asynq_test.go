@@ -0,0 +1,65 @@
+package asynq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+func TestMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	producer := testTracer.NewRootSpan("asynq.enqueue", "producer", "email:send")
+	task := asynq.NewTask("email:send", internal.WrapPayload(producer, []byte("payload")))
+
+	var received *asynq.Task
+	h := Middleware(WithServiceName("consumer"), WithTracer(testTracer))(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		received = task
+		return errors.New("boom")
+	}))
+	assert.NotNil(h.ProcessTask(context.Background(), task))
+	assert.Equal("payload", string(received.Payload()))
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("asynq.process", s.Name)
+	assert.Equal("consumer", s.Service)
+	assert.Equal("email:send", s.Resource)
+	assert.Equal(producer.TraceID, s.TraceID)
+	assert.Equal(producer.SpanID, s.ParentID)
+	assert.Equal(int32(1), s.Error)
+}
+
+func TestMiddlewareUntracedTask(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	task := asynq.NewTask("email:send", []byte("payload"))
+
+	// the tasks without a trace context aren't copied
+	var received *asynq.Task
+	h := Middleware(WithTracer(testTracer))(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		assert.Equal(task.ResultWriter(), ResultWriter(ctx, task))
+		received = task
+		return nil
+	}))
+	assert.Nil(h.ProcessTask(context.Background(), task))
+	assert.True(received == task)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(uint64(0), traces[0][0].ParentID)
+}
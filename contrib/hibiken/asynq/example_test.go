@@ -0,0 +1,33 @@
+package asynq_test
+
+import (
+	"context"
+	"log"
+
+	"github.com/hibiken/asynq"
+
+	asynqtrace "github.com/DataDog/dd-trace-go/contrib/hibiken/asynq"
+)
+
+func Example_client() {
+	c := asynqtrace.WrapClient(asynq.NewClient(asynq.RedisClientOpt{Addr: "localhost:6379"}))
+	defer c.Close()
+
+	_, err := c.EnqueueContext(context.Background(), asynq.NewTask("email:send", []byte(`{"user_id":42}`)), asynq.MaxRetry(5))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Example_server() {
+	srv := asynq.NewServer(asynq.RedisClientOpt{Addr: "localhost:6379"}, asynq.Config{Concurrency: 10})
+	mux := asynq.NewServeMux()
+	mux.Use(asynqtrace.Middleware(asynqtrace.WithServiceName("email-worker")))
+	mux.HandleFunc("email:send", func(ctx context.Context, t *asynq.Task) error {
+		// the span of the task can be found in ctx
+		return nil
+	})
+	if err := srv.Run(mux); err != nil {
+		log.Fatal(err)
+	}
+}
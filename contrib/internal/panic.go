@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// FinishOnPanic is meant to be deferred by server integrations right after
+// starting the span of a request or message. If the handler panics, it
+// records the panic value and the stack trace on the span, finishes it by
+// calling finish, or span.Finish when finish is nil, and panics again with
+// the same value, so that the panic is handled as if the handler wasn't
+// traced. It does nothing when the handler returns normally.
+//
+//	span := t.NewRootSpan("grpc.server", service, method)
+//	defer internal.FinishOnPanic(span, nil)
+func FinishOnPanic(span *tracer.Span, finish func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	span.SetError(fmt.Errorf("panic: %v", r))
+	span.SetMeta(ext.ErrorType, "panic")
+	if finish != nil {
+		finish()
+	} else {
+		span.Finish()
+	}
+	panic(r)
+}
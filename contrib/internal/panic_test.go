@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinishOnPanic(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	// no panic
+	func() {
+		span := testTracer.NewRootSpan("web.request", "web", "/")
+		defer span.Finish()
+		defer FinishOnPanic(span, nil)
+	}()
+
+	// panic, finished with the given func
+	var finished bool
+	assert.PanicsWithValue("boom", func() {
+		span := testTracer.NewRootSpan("web.request", "web", "/")
+		defer FinishOnPanic(span, func() {
+			finished = true
+			span.Finish()
+		})
+		panic("boom")
+	})
+	assert.True(finished)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 2)
+	assert.Equal(int32(0), traces[0][0].Error)
+	s := traces[1][0]
+	assert.Equal(int32(1), s.Error)
+	assert.Equal("panic: boom", s.GetMeta("error.msg"))
+	assert.Equal("panic", s.GetMeta("error.type"))
+	assert.Contains(s.GetMeta("error.stack"), "TestFinishOnPanic")
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// payloadPrefix starts the payloads holding a trace context, which is
+// followed by the trace and parent IDs, the sampling priority and, for 128-bit
+// trace IDs, their upper bits in hexadecimal, separated by colons, and a
+// newline. Payloads wrapped by earlier versions have no priority.
+var payloadPrefix = []byte("\x00dd-trace:")
+
+// PayloadContext is the trace context of the producer of a message, as found
+// in its payload or its headers.
+type PayloadContext struct {
+	TraceID  uint64
+	ParentID uint64
+	// TraceIDHigh holds the upper 64 bits of 128-bit trace IDs, it is 0 for
+	// 64-bit ones.
+	TraceIDHigh uint64
+	// Priority is the sampling priority of the trace of the producer, which
+	// is only known when HasPriority is true.
+	Priority    int
+	HasPriority bool
+}
+
+// Continue makes the given root span, which consumes the message, continue
+// the trace of the producer, when there is one. The sampling decision of the
+// producer is honored when it is known, so that a trace dropped upstream isn't
+// kept on its own downstream.
+func (c PayloadContext) Continue(t *tracer.Tracer, span *tracer.Span) {
+	if c.TraceID == 0 || c.ParentID == 0 {
+		return
+	}
+	span.TraceID = c.TraceID
+	span.ParentID = c.ParentID
+	span.SetTraceIDHigh(c.TraceIDHigh)
+	if c.HasPriority {
+		t.SampleWithPriority(span, c.Priority)
+	} else {
+		t.Sample(span)
+	}
+}
+
+// samplingPriority returns the sampling priority of the trace of the span,
+// which is derived from the sampling decision when none was set.
+func samplingPriority(span *tracer.Span) int {
+	if span.HasSamplingPriority() {
+		return span.GetSamplingPriority()
+	}
+	if span.Sampled {
+		return ext.PriorityAutoKeep
+	}
+	return ext.PriorityAutoReject
+}
+
+// WrapPayload returns the given message payload prefixed with the context of
+// the span, for the messaging systems which have no headers to carry it.
+func WrapPayload(span *tracer.Span, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(payloadPrefix)
+	buf.WriteString(strconv.FormatUint(span.TraceID, 10))
+	buf.WriteByte(':')
+	buf.WriteString(strconv.FormatUint(span.SpanID, 10))
+	buf.WriteByte(':')
+	buf.WriteString(strconv.Itoa(samplingPriority(span)))
+	if high := span.TraceIDHigh(); high != 0 {
+		buf.WriteByte(':')
+		buf.WriteString(formatTraceIDHigh(high))
+	}
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// UnwrapPayload returns the trace context found in a payload returned by
+// WrapPayload, and the original payload. Other payloads are returned as is,
+// with an empty context.
+func UnwrapPayload(payload []byte) (PayloadContext, []byte) {
+	var c PayloadContext
+	if !bytes.HasPrefix(payload, payloadPrefix) {
+		return c, payload
+	}
+	rest := payload[len(payloadPrefix):]
+	nl := bytes.IndexByte(rest, '\n')
+	if nl < 0 {
+		return c, payload
+	}
+	fields := bytes.SplitN(rest[:nl], []byte{':'}, 4)
+	if len(fields) < 2 {
+		return c, payload
+	}
+	traceID, err := strconv.ParseUint(string(fields[0]), 10, 64)
+	if err != nil {
+		return c, payload
+	}
+	parentID, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return c, payload
+	}
+	if len(fields) == 4 {
+		high, ok := tracer.ParseTraceIDHigh(string(fields[3]))
+		if !ok {
+			return c, payload
+		}
+		c.TraceIDHigh = high
+	}
+	if len(fields) >= 3 {
+		priority, err := strconv.Atoi(string(fields[2]))
+		if err != nil {
+			return PayloadContext{}, payload
+		}
+		c.Priority = priority
+		c.HasPriority = true
+	}
+	c.TraceID = traceID
+	c.ParentID = parentID
+	return c, rest[nl+1:]
+}
+
+// the headers holding the trace context, for the messaging systems which
+// carry string headers along with their messages
+const (
+	traceIDHeader  = "x-datadog-trace-id"
+	parentIDHeader = "x-datadog-parent-id"
+	priorityHeader = "x-datadog-sampling-priority"
+	// tagsHeader holds the upper bits of 128-bit trace IDs, in the
+	// "_dd.p.tid" trace-level tag.
+	tagsHeader     = "x-datadog-tags"
+	traceIDHighTag = "_dd.p.tid"
+)
+
+// formatTraceIDHigh formats the upper bits of a 128-bit trace ID as the
+// "_dd.p.tid" tag does.
+func formatTraceIDHigh(high uint64) string {
+	return fmt.Sprintf("%016x", high)
+}
+
+// InjectHeaders writes the context of the span with the given function, as
+// string headers.
+func InjectHeaders(span *tracer.Span, set func(key, value string)) {
+	set(traceIDHeader, strconv.FormatUint(span.TraceID, 10))
+	set(parentIDHeader, strconv.FormatUint(span.SpanID, 10))
+	set(priorityHeader, strconv.Itoa(samplingPriority(span)))
+	if high := span.TraceIDHigh(); high != 0 {
+		set(tagsHeader, traceIDHighTag+"="+formatTraceIDHigh(high))
+	}
+}
+
+// ExtractHeaders returns the trace context written by InjectHeaders, read
+// with the given function, which returns the empty string for the missing
+// headers. The context is empty when the headers are missing or invalid.
+func ExtractHeaders(get func(key string) string) PayloadContext {
+	var c PayloadContext
+	traceID, err := strconv.ParseUint(get(traceIDHeader), 10, 64)
+	if err != nil {
+		return c
+	}
+	parentID, err := strconv.ParseUint(get(parentIDHeader), 10, 64)
+	if err != nil {
+		return c
+	}
+	if priority, err := strconv.Atoi(get(priorityHeader)); err == nil {
+		c.Priority = priority
+		c.HasPriority = true
+	}
+	for _, tag := range strings.Split(get(tagsHeader), ",") {
+		if v := strings.TrimPrefix(tag, traceIDHighTag+"="); v != tag {
+			c.TraceIDHigh, _ = tracer.ParseTraceIDHigh(v)
+		}
+	}
+	c.TraceID = traceID
+	c.ParentID = parentID
+	return c
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+func TestPayload(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, _ := tracertest.GetTestTracer()
+	span := testTracer.NewRootSpan("asynq.enqueue", "asynq", "email:send")
+	payload := WrapPayload(span, []byte(`{"to":42}`))
+
+	c, original := UnwrapPayload(payload)
+	assert.Equal(span.TraceID, c.TraceID)
+	assert.Equal(span.SpanID, c.ParentID)
+	assert.True(c.HasPriority)
+	assert.Equal(ext.PriorityAutoKeep, c.Priority)
+	assert.Equal(`{"to":42}`, string(original))
+
+	span.SetSamplingPriority(ext.PriorityUserReject)
+	c, _ = UnwrapPayload(WrapPayload(span, nil))
+	assert.Equal(ext.PriorityUserReject, c.Priority)
+
+	// the upper bits of 128-bit trace IDs are kept
+	testTracer.SetTraceID128Generation(true)
+	span = testTracer.NewRootSpan("asynq.enqueue", "asynq", "email:send")
+	assert.NotEqual(uint64(0), span.TraceIDHigh())
+	c, _ = UnwrapPayload(WrapPayload(span, nil))
+	assert.Equal(span.TraceIDHigh(), c.TraceIDHigh)
+	consumer := testTracer.NewRootSpan("asynq.process", "asynq", "email:send")
+	c.Continue(testTracer, consumer)
+	assert.Equal(span.TraceIDHex(), consumer.TraceIDHex())
+
+	// payloads wrapped by earlier versions carry no priority
+	c, original = UnwrapPayload([]byte("\x00dd-trace:1:2\n{}"))
+	assert.Equal(uint64(1), c.TraceID)
+	assert.Equal(uint64(2), c.ParentID)
+	assert.False(c.HasPriority)
+	assert.Equal("{}", string(original))
+
+	// payloads without trace context are left untouched
+	for _, p := range []string{`{"to":42}`, "", "\x00dd-trace:1:2", "\x00dd-trace:x:2\n{}", "\x00dd-trace:1:2:x\n{}", "\x00dd-trace:1:2:1:x\n{}"} {
+		c, original = UnwrapPayload([]byte(p))
+		assert.Equal(PayloadContext{}, c)
+		assert.Equal(p, string(original))
+	}
+}
+
+func TestPayloadContinue(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	producer := testTracer.NewRootSpan("nsq.publish", "producer", "Publish events")
+	producer.SetSamplingPriority(ext.PriorityUserReject)
+	c, _ := UnwrapPayload(WrapPayload(producer, nil))
+
+	// the consumer doesn't keep the trace dropped by the producer
+	span := testTracer.NewRootSpan("nsq.consume", "consumer", "Consume events")
+	c.Continue(testTracer, span)
+	assert.Equal(producer.TraceID, span.TraceID)
+	assert.Equal(producer.SpanID, span.ParentID)
+	assert.False(span.Sampled)
+	span.Finish()
+
+	// it is sent with its priority for the agent to compute its statistics
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(ext.PriorityUserReject, traces[0][0].GetSamplingPriority())
+}
+
+func TestHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, _ := tracertest.GetTestTracer()
+	span := testTracer.NewRootSpan("machinery.send", "machinery", "email:send")
+	span.SetSamplingPriority(ext.PriorityUserKeep)
+	headers := make(map[string]string)
+	InjectHeaders(span, func(k, v string) { headers[k] = v })
+
+	get := func(k string) string { return headers[k] }
+	assert.Equal(PayloadContext{TraceID: span.TraceID, ParentID: span.SpanID, Priority: ext.PriorityUserKeep, HasPriority: true}, ExtractHeaders(get))
+
+	// so are the upper bits of 128-bit trace IDs
+	testTracer.SetTraceID128Generation(true)
+	span128 := testTracer.NewRootSpan("machinery.send", "machinery", "email:send")
+	headers128 := make(map[string]string)
+	InjectHeaders(span128, func(k, v string) { headers128[k] = v })
+	c := ExtractHeaders(func(k string) string { return headers128[k] })
+	assert.NotEqual(uint64(0), c.TraceIDHigh)
+	assert.Equal(span128.TraceIDHigh(), c.TraceIDHigh)
+
+	// the priority is optional
+	delete(headers, "x-datadog-sampling-priority")
+	assert.Equal(PayloadContext{TraceID: span.TraceID, ParentID: span.SpanID}, ExtractHeaders(get))
+
+	// invalid headers give an empty context
+	headers["x-datadog-parent-id"] = "x"
+	assert.Equal(PayloadContext{}, ExtractHeaders(get))
+	assert.Equal(PayloadContext{}, ExtractHeaders(func(string) string { return "" }))
+}
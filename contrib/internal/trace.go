@@ -1,36 +1,143 @@
 package internal
 
 import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+// NotFoundResource is the resource of the requests which didn't match any route. Using
+// a constant rather than their URL prevents scanners from creating countless resources.
+// The requests answered with another status, such as redirects, are named after it
+// instead, see ServeConfig.Unmatched.
+const NotFoundResource = "404 not found"
+
+// statusResource returns the resource of the requests which didn't match any route and
+// were answered with the given status, e.g. "405 method not allowed".
+func statusResource(status int) string {
+	return strconv.Itoa(status) + " " + strings.ToLower(http.StatusText(status))
+}
+
 // TraceAndServe will apply tracing to the given http.Handler using the passed tracer under the given service and resource.
-func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, service, resource string, t *tracer.Tracer) {
+// The integration is the name of the calling integration, see tracer.Span.SetIntegration.
+func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, service, resource, integration string, t *tracer.Tracer) {
+	TraceAndServeWithConfig(h, w, r, &ServeConfig{
+		Service:     service,
+		Resource:    resource,
+		Integration: integration,
+		Tracer:      t,
+	})
+}
+
+// TraceAndServeStreaming is like TraceAndServe but is meant for streaming and long-polling
+// endpoints (e.g. server-sent events or websockets): the span is finished as soon as the
+// response header is written, so that the time spent streaming the body, which can last
+// for hours, doesn't skew the latency of the service.
+func TraceAndServeStreaming(h http.Handler, w http.ResponseWriter, r *http.Request, service, resource, integration string, t *tracer.Tracer) {
+	TraceAndServeWithConfig(h, w, r, &ServeConfig{
+		Service:     service,
+		Resource:    resource,
+		Integration: integration,
+		Tracer:      t,
+		Streaming:   true,
+	})
+}
+
+// ServeConfig specifies how TraceAndServeWithConfig traces a request.
+type ServeConfig struct {
+	Service     string
+	Resource    string
+	Integration string // see tracer.Span.SetIntegration
+	Tracer      *tracer.Tracer
+
+	// Route is the route template matched by the request, e.g. "/users/:id",
+	// which is set as the http.route tag when not empty.
+	Route string
+	// Streaming makes the span finish as soon as the response header is
+	// written, see TraceAndServeStreaming.
+	Streaming bool
+	// MethodInName appends the lowercase method of the request to the
+	// operation name, e.g. "http.request.get".
+	MethodInName bool
+	// ForceKeep keeps the traces of the requests asking for it, see
+	// ForceKeep. It is off by default, as any client could otherwise have
+	// its requests traced.
+	ForceKeep bool
+	// Unmatched tells that the request matched no route: the resource is
+	// then named after the status of the response, such as "404 not found"
+	// or "405 method not allowed", once it is written.
+	Unmatched bool
+}
+
+// TraceAndServeWithConfig applies tracing to the given http.Handler as specified by cfg.
+func TraceAndServeWithConfig(h http.Handler, w http.ResponseWriter, r *http.Request, cfg *ServeConfig) {
+	t := cfg.Tracer
 	// bail out if tracing isn't enabled
 	if !t.Enabled() {
 		h.ServeHTTP(w, r)
 		return
 	}
 
-	span, ctx := t.NewChildSpanWithContext("http.request", r.Context())
-	defer span.Finish()
+	name := "http.request"
+	if cfg.MethodInName {
+		name += "." + strings.ToLower(r.Method)
+	}
+	span, ctx := t.NewChildSpanWithContext(name, r.Context())
+	span.SetIntegration(cfg.Integration)
 
 	span.Type = ext.HTTPType
-	span.Service = service
-	span.Resource = resource
+	span.Service = cfg.Service
+	span.Resource = cfg.Resource
 	span.SetMeta(ext.HTTPMethod, r.Method)
 	span.SetMeta(ext.HTTPURL, r.URL.Path)
+	if cfg.Route != "" {
+		span.SetMeta(ext.HTTPRoute, cfg.Route)
+	}
+	if cfg.ForceKeep {
+		ForceKeep(t, span, r)
+	}
 
 	traceRequest := r.WithContext(ctx)
 	traceWriter := NewResponseWriter(w, span)
+	traceWriter.body = CountBody(traceRequest)
+	traceWriter.unmatched = cfg.Unmatched
+	if cfg.Streaming {
+		span.SetMeta(ext.HTTPStreaming, "true")
+		traceWriter.streaming = true
+	}
+	defer traceWriter.finish()
+	defer FinishOnPanic(span, traceWriter.finish)
 
 	h.ServeHTTP(traceWriter, traceRequest)
 }
 
+// ForceKeep keeps the trace of the span of the given request, whatever the
+// sampler decided, when the request asks for it with the ext.HTTPHeaderForceKeep
+// header, or when it comes from a Synthetic test, so that a specific request
+// can be traced on demand in production. It has to be called before the span
+// has any children. As any client can send the header, it should only be
+// called when the integration was configured to, see ServeConfig.ForceKeep.
+func ForceKeep(t *tracer.Tracer, span *tracer.Span, r *http.Request) {
+	origin := r.Header.Get(ext.HTTPHeaderOrigin)
+	synthetics := strings.HasPrefix(origin, ext.OriginSynthetics)
+	if keep, _ := strconv.ParseBool(r.Header.Get(ext.HTTPHeaderForceKeep)); !keep && !synthetics {
+		return
+	}
+	t.SampleWithPriority(span, ext.PriorityUserKeep)
+	span.SetSamplingPriority(ext.PriorityUserKeep)
+	if origin != "" {
+		span.SetMeta(ext.Origin, origin)
+	}
+}
+
 // ResponseWriter is a small wrapper around an http response writer that will
 // intercept and store the status of a request.
 // It implements the ResponseWriter interface.
@@ -38,11 +145,44 @@ type ResponseWriter struct {
 	http.ResponseWriter
 	span   *tracer.Span
 	status int
+	size   int64        // bytes written in the response body
+	body   *BodyCounter // the body of the request, if counted
+
+	// streaming reports whether the span should be finished as soon as
+	// the response header is written.
+	streaming bool
+	// unmatched reports whether the resource is named after the status,
+	// see ServeConfig.Unmatched.
+	unmatched bool
+	finished  bool
 }
 
 // New ResponseWriter allocateds and returns a new ResponseWriter.
 func NewResponseWriter(w http.ResponseWriter, span *tracer.Span) *ResponseWriter {
-	return &ResponseWriter{w, span, 0}
+	return &ResponseWriter{ResponseWriter: w, span: span}
+}
+
+// finish finishes the span, unless it was already finished.
+func (w *ResponseWriter) finish() {
+	if w.finished {
+		return
+	}
+	w.finished = true
+	if w.unmatched {
+		status := w.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.span.Resource = statusResource(status)
+	}
+	if w.body != nil {
+		w.span.SetMetric(ext.HTTPRequestSize, float64(w.body.Count()))
+	}
+	if !w.streaming {
+		// the body of streamed responses is mostly written after the span is finished
+		w.span.SetMetric(ext.HTTPResponseSize, float64(w.size))
+	}
+	w.span.Finish()
 }
 
 // Write writes the data to the connection as part of an HTTP reply.
@@ -52,7 +192,9 @@ func (w *ResponseWriter) Write(b []byte) (int, error) {
 	if w.status == 0 {
 		w.WriteHeader(http.StatusOK)
 	}
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
 }
 
 // WriteHeader sends an HTTP response header with status code.
@@ -64,4 +206,61 @@ func (w *ResponseWriter) WriteHeader(status int) {
 	if status >= 500 && status < 600 {
 		w.span.Error = 1
 	}
+	if w.streaming {
+		w.finish()
+	}
+}
+
+// Flush sends any buffered data to the client, if the wrapped writer
+// supports it. It is needed by handlers streaming their response.
+func (w *ResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			// flushing implicitly sends the header
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, e.g. to upgrade it to the
+// websocket protocol. It fails if the wrapped writer doesn't support it.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.ResponseWriter does not implement http.Hijacker")
+	}
+	if w.streaming {
+		// the connection now belongs to the caller and can stay open indefinitely
+		defer w.finish()
+	}
+	return h.Hijack()
+}
+
+// BodyCounter wraps the body of a request to count the bytes read from it.
+type BodyCounter struct {
+	io.ReadCloser
+	n int64
+}
+
+// CountBody replaces the body of the given request with a BodyCounter, and returns it.
+func CountBody(r *http.Request) *BodyCounter {
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	b := &BodyCounter{ReadCloser: r.Body}
+	r.Body = b
+	return b
+}
+
+// Read implements io.Reader.
+func (b *BodyCounter) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes read so far.
+func (b *BodyCounter) Count() int64 {
+	return atomic.LoadInt64(&b.n)
 }
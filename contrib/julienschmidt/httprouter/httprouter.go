@@ -7,11 +7,16 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 
+	"github.com/DataDog/dd-trace-go/tracer"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 
 	"github.com/DataDog/dd-trace-go/contrib/internal"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "julienschmidt/httprouter", Library: "github.com/julienschmidt/httprouter"})
+}
+
 // Router is a traced version of httprouter.Router.
 type Router struct {
 	*httprouter.Router
@@ -26,6 +31,7 @@ func New(opts ...RouterOption) *Router {
 		fn(cfg)
 	}
 	cfg.tracer.SetServiceInfo(cfg.serviceName, "julienschmidt/httprouter", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "julienschmidt/httprouter", Options: map[string]string{"service": cfg.serviceName}})
 	return &Router{httprouter.New(), cfg}
 }
 
@@ -33,10 +39,30 @@ func New(opts ...RouterOption) *Router {
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// get the resource associated to this request
 	route := req.URL.Path
-	_, ps, _ := r.Router.Lookup(req.Method, route)
+	h, ps, _ := r.Router.Lookup(req.Method, route)
 	for _, param := range ps {
 		route = strings.Replace(route, param.Value, ":"+param.Key, 1)
 	}
 	resource := req.Method + " " + route
-	internal.TraceAndServe(r.Router, w, req, r.config.serviceName, resource, r.config.tracer)
+	unmatched := h == nil
+	if unmatched {
+		// answered with a 404, a 405 or a redirect
+		route = ""
+		resource = internal.NotFoundResource
+	}
+	if r.config.resourceNamer != nil {
+		resource = r.config.resourceNamer(req)
+		unmatched = false
+	}
+	internal.TraceAndServeWithConfig(r.Router, w, req, &internal.ServeConfig{
+		Service:      r.config.serviceName,
+		Resource:     resource,
+		Integration:  "julienschmidt/httprouter",
+		Tracer:       r.config.tracer,
+		Route:        route,
+		Streaming:    r.config.isStreaming != nil && r.config.isStreaming(req),
+		MethodInName: r.config.methodInName,
+		Unmatched:    unmatched,
+		ForceKeep:    r.config.forceKeep,
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	"github.com/DataDog/dd-trace-go/tracer/tracertest"
 )
 
@@ -100,6 +101,56 @@ func TestHttpTracer500(t *testing.T) {
 	assert.Equal(int32(1), s.Error)
 }
 
+func TestHttpTracerUnmatched(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+
+	// the requests matching no route are named after their status
+	for _, tt := range []struct {
+		method, url string
+		code        int
+		resource    string
+	}{
+		{"GET", "/404", 404, "404 not found"},
+		{"POST", "/200", 405, "405 method not allowed"},
+		{"GET", "/200/", 301, "301 moved permanently"},
+	} {
+		r := httptest.NewRequest(tt.method, tt.url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(tt.code, w.Code)
+
+		tracer.ForceFlush()
+		traces := transport.Traces()
+		assert.Len(traces, 1)
+		s := traces[0][0]
+		assert.Equal(tt.resource, s.Resource)
+		assert.Equal("", s.GetMeta("http.route"))
+	}
+}
+
+func TestForceKeep(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+	tracer.SetSampleRate(0)
+
+	// the header is ignored by default
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set(ext.HTTPHeaderForceKeep, "true")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	keeping := New(WithServiceName("my-service"), WithTracer(tracer), WithForceKeep())
+	keeping.GET("/200", handler200(t))
+	router = keeping
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(ext.PriorityUserKeep, traces[0][0].GetSamplingPriority())
+}
+
 func setup(t *testing.T) (*tracer.Tracer, *tracertest.DummyTransport, http.Handler) {
 	h200 := handler200(t)
 	h500 := handler500(t)
@@ -0,0 +1,21 @@
+package micro_test
+
+import (
+	"log"
+
+	micro "github.com/micro/go-micro"
+
+	microtrace "github.com/DataDog/dd-trace-go/contrib/micro/go-micro"
+)
+
+func Example() {
+	service := micro.NewService(
+		micro.Name("greeter"),
+		micro.WrapClient(microtrace.NewClientWrapper()),
+		micro.WrapHandler(microtrace.NewHandlerWrapper()),
+	)
+	service.Init()
+	if err := service.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
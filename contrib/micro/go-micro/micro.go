@@ -0,0 +1,143 @@
+// Package micro provides functions to trace the micro/go-micro package (https://github.com/micro/go-micro),
+// as of its v1 API.
+package micro
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/client"
+	"github.com/micro/go-micro/metadata"
+	"github.com/micro/go-micro/server"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "micro/go-micro", Library: "github.com/micro/go-micro"})
+}
+
+// pass trace ids with these metadata keys
+const (
+	traceIDKey  = "X-Datadog-Trace-Id"
+	parentIDKey = "X-Datadog-Parent-Id"
+)
+
+const endpointKey = "micro.endpoint"
+
+func newConfig(opts ...WrapperOption) *wrapperConfig {
+	cfg := new(wrapperConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// service returns the service of the spans of calls to the given
+// go-micro service.
+func (cfg *wrapperConfig) service(name string) string {
+	if cfg.serviceName != "" {
+		return cfg.serviceName
+	}
+	return name
+}
+
+// NewClientWrapper returns a client wrapper tracing the calls made by the
+// client, and passing their trace context to the called services.
+func NewClientWrapper(opts ...WrapperOption) client.Wrapper {
+	cfg := newConfig(opts...)
+	return func(c client.Client) client.Client {
+		return &tracedClient{Client: c, config: cfg}
+	}
+}
+
+type tracedClient struct {
+	client.Client
+	config *wrapperConfig
+}
+
+func (c *tracedClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	t := c.config.tracer
+	if !t.Enabled() {
+		return c.Client.Call(ctx, req, rsp, opts...)
+	}
+	span := t.NewChildSpanFromContext("micro.client", ctx)
+	span.SetIntegration("micro/go-micro")
+	span.Type = ext.AppTypeRPC
+	span.Service = c.config.service(req.Service())
+	span.Resource = req.Endpoint()
+	span.SetMeta(endpointKey, req.Endpoint())
+
+	md, _ := metadata.FromContext(ctx)
+	out := make(metadata.Metadata, len(md)+2)
+	for k, v := range md {
+		out[k] = v
+	}
+	out[traceIDKey] = strconv.FormatUint(span.TraceID, 10)
+	out[parentIDKey] = strconv.FormatUint(span.SpanID, 10)
+	ctx = metadata.NewContext(tracer.ContextWithSpan(ctx, span), out)
+
+	err := c.Client.Call(ctx, req, rsp, opts...)
+	span.FinishWithErr(err)
+	return err
+}
+
+// NewHandlerWrapper returns a server handler wrapper tracing the calls
+// handled by the server, as children of the calling spans when the callers
+// are traced.
+func NewHandlerWrapper(opts ...WrapperOption) server.HandlerWrapper {
+	cfg := newConfig(opts...)
+	if cfg.serviceName != "" {
+		cfg.tracer.SetServiceInfo(cfg.serviceName, "go-micro", ext.AppTypeRPC)
+		tracer.RegisterIntegration(tracer.Integration{Name: "micro/go-micro", Options: map[string]string{"service": cfg.serviceName}})
+	}
+	// the services named after the requests, described once
+	var described sync.Map
+	return func(h server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			t := cfg.tracer
+			if !t.Enabled() {
+				return h(ctx, req, rsp)
+			}
+			service := cfg.service(req.Service())
+			if _, ok := described.LoadOrStore(service, struct{}{}); !ok && cfg.serviceName == "" {
+				t.SetServiceInfo(service, "go-micro", ext.AppTypeRPC)
+			}
+			span := t.NewRootSpan("micro.server", service, req.Endpoint())
+			span.SetIntegration("micro/go-micro")
+			span.Type = ext.AppTypeRPC
+			span.SetMeta(endpointKey, req.Endpoint())
+			if md, ok := metadata.FromContext(ctx); ok {
+				traceID, parentID := getID(md, traceIDKey), getID(md, parentIDKey)
+				if traceID != 0 && parentID != 0 {
+					span.TraceID = traceID
+					span.ParentID = parentID
+					t.Sample(span)
+				}
+			}
+			defer internal.FinishOnPanic(span, nil)
+			err := h(tracer.ContextWithSpan(ctx, span), req, rsp)
+			span.FinishWithErr(err)
+			return err
+		}
+	}
+}
+
+// getID parses an id from the metadata, whose keys may have been
+// canonicalized by the transport.
+func getID(md metadata.Metadata, name string) uint64 {
+	for k, v := range md {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return id
+		}
+	}
+	return 0
+}
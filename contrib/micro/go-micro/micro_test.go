@@ -0,0 +1,82 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/micro/go-micro/client"
+	"github.com/micro/go-micro/metadata"
+	"github.com/micro/go-micro/server"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+type clientRequest struct {
+	client.Request
+}
+
+func (clientRequest) Service() string  { return "greeter" }
+func (clientRequest) Endpoint() string { return "Greeter.Hello" }
+
+type serverRequest struct {
+	server.Request
+}
+
+func (serverRequest) Service() string  { return "greeter" }
+func (serverRequest) Endpoint() string { return "Greeter.Hello" }
+
+// loopbackClient hands the calls to a server handler, as a transport would.
+type loopbackClient struct {
+	client.Client
+	handler server.HandlerFunc
+}
+
+func (c *loopbackClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	md, _ := metadata.FromContext(ctx)
+	return c.handler(metadata.NewContext(context.Background(), md), serverRequest{}, rsp)
+}
+
+func TestPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	handler := NewHandlerWrapper(WithTracer(testTracer))(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		return errors.New("unavailable")
+	})
+	c := NewClientWrapper(WithServiceName("greeter-client"), WithTracer(testTracer))(&loopbackClient{handler: handler})
+
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	err := c.Call(root.Context(context.Background()), clientRequest{}, nil)
+	assert.NotNil(err)
+	root.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := make(map[string]*tracer.Span)
+	for _, s := range traces[0] {
+		spans[s.Name] = s
+	}
+	cli, srv := spans["micro.client"], spans["micro.server"]
+	assert.Equal("greeter-client", cli.Service)
+	assert.Equal("Greeter.Hello", cli.Resource)
+	assert.Equal(root.SpanID, cli.ParentID)
+	assert.Equal("greeter", srv.Service)
+	assert.Equal("Greeter.Hello", srv.Resource)
+	assert.Equal(cli.TraceID, srv.TraceID)
+	assert.Equal(cli.SpanID, srv.ParentID)
+	assert.Equal(int32(1), srv.Error)
+}
+
+func TestGetID(t *testing.T) {
+	assert := assert.New(t)
+
+	md := metadata.Metadata{"x-datadog-trace-id": "42", "X-Datadog-Parent-Id": "nope"}
+	assert.Equal(uint64(42), getID(md, traceIDKey))
+	assert.Equal(uint64(0), getID(md, parentIDKey))
+}
@@ -0,0 +1,31 @@
+package micro
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type wrapperConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// WrapperOption represents an option that can be passed to NewClientWrapper
+// or NewHandlerWrapper.
+type WrapperOption func(*wrapperConfig)
+
+func defaults(cfg *wrapperConfig) {
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced calls. It
+// defaults to the name of the go-micro service handling them.
+func WithServiceName(name string) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the calls.
+func WithTracer(t *tracer.Tracer) WrapperOption {
+	return func(cfg *wrapperConfig) {
+		cfg.tracer = t
+	}
+}
@@ -0,0 +1,34 @@
+package minio_test
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/minio/minio-go"
+
+	miniotrace "github.com/DataDog/dd-trace-go/contrib/minio/minio-go"
+)
+
+func Example() {
+	c, err := minio.New("play.min.io", "access-key", "secret-key", true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c = miniotrace.WrapClient(c, miniotrace.WithServiceName("uploads"), miniotrace.WithKeyHashing())
+
+	f, err := os.Open("avatar.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the request is a child of the span found in the context, if any
+	_, err = c.PutObjectWithContext(context.Background(), "avatars", "users/42.png", f, st.Size(), minio.PutObjectOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
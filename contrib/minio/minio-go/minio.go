@@ -0,0 +1,167 @@
+// Package minio provides functions to trace the minio/minio-go package (https://github.com/minio/minio-go),
+// and other clients of S3-compatible storages through their HTTP transport.
+//
+// The requests are traced as children of the span found in their context,
+// which is the one given to the methods of the minio client taking one, such
+// as GetObjectWithContext.
+package minio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "minio/minio-go", Library: "github.com/minio/minio-go"})
+}
+
+const (
+	bucketKey        = "s3.bucket"
+	objectKey        = "s3.key"
+	requestBytesKey  = "s3.request_bytes"
+	responseBytesKey = "s3.response_bytes"
+)
+
+// WrapClient traces the requests of the given client, and returns it. It
+// replaces the transport of the client with a traced http.DefaultTransport.
+func WrapClient(c *minio.Client, opts ...ClientOption) *minio.Client {
+	c.SetCustomTransport(WrapRoundTripper(http.DefaultTransport, opts...))
+	return c
+}
+
+// WrapRoundTripper returns a round tripper tracing the S3 requests it sends
+// with the given one. The bucket is found in the path of the requests, or in
+// their host when it starts with the bucket, as in "bucket.s3.amazonaws.com".
+func WrapRoundTripper(rt http.RoundTripper, opts ...ClientOption) http.RoundTripper {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "minio", ext.AppTypeDB)
+	tracer.RegisterIntegration(tracer.Integration{Name: "minio/minio-go", Options: map[string]string{"service": cfg.serviceName}})
+	return &roundTripper{base: rt, config: cfg}
+}
+
+type roundTripper struct {
+	base   http.RoundTripper
+	config *clientConfig
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := rt.config.tracer
+	if !t.Enabled() {
+		return rt.base.RoundTrip(req)
+	}
+	bucket, key := parseRequest(req)
+	span := t.NewChildSpanFromContext("s3.request", req.Context())
+	span.SetIntegration("minio/minio-go")
+	span.Type = ext.HTTPType
+	span.Service = rt.config.serviceName
+	span.Resource = operation(req, bucket, key)
+	span.SetMeta(ext.HTTPMethod, req.Method)
+	span.SetMeta(ext.TargetHost, req.URL.Host)
+	if bucket != "" {
+		span.SetMeta(bucketKey, bucket)
+	}
+	if key != "" {
+		if rt.config.hashKeys {
+			sum := sha256.Sum256([]byte(key))
+			key = hex.EncodeToString(sum[:])
+		}
+		span.SetMeta(objectKey, key)
+	}
+	if n, err := strconv.ParseInt(req.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64); err == nil {
+		// the body is sent in signed chunks
+		span.SetMetric(requestBytesKey, float64(n))
+	} else if req.ContentLength > 0 {
+		span.SetMetric(requestBytesKey, float64(req.ContentLength))
+	}
+
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil {
+		span.FinishWithErr(err)
+		return resp, err
+	}
+	span.SetMeta(ext.HTTPCode, strconv.Itoa(resp.StatusCode))
+	if resp.ContentLength >= 0 {
+		span.SetMetric(responseBytesKey, float64(resp.ContentLength))
+	}
+	if resp.StatusCode >= 500 || (resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+		span.Error = 1
+	}
+	span.Finish()
+	return resp, err
+}
+
+// parseRequest returns the bucket and object key of the given S3 request.
+func parseRequest(req *http.Request) (bucket, key string) {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	host := req.URL.Host
+	if i := strings.Index(host, ".s3."); i > 0 {
+		// virtual-hosted-style request
+		return host[:i], path
+	}
+	if i := strings.Index(host, ".s3-"); i > 0 {
+		return host[:i], path
+	}
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// operation returns the name of the S3 operation made by the given request.
+func operation(req *http.Request, bucket, key string) string {
+	q := req.URL.Query()
+	switch {
+	case bucket == "":
+		return "ListBuckets"
+	case key == "":
+		switch req.Method {
+		case http.MethodGet:
+			if _, ok := q["location"]; ok {
+				return "GetBucketLocation"
+			}
+			return "ListObjects"
+		case http.MethodPut:
+			return "MakeBucket"
+		case http.MethodDelete:
+			return "RemoveBucket"
+		case http.MethodHead:
+			return "BucketExists"
+		case http.MethodPost:
+			if _, ok := q["delete"]; ok {
+				return "RemoveObjects"
+			}
+		}
+	default:
+		switch req.Method {
+		case http.MethodGet:
+			return "GetObject"
+		case http.MethodPut:
+			if _, ok := q["partNumber"]; ok {
+				return "PutObjectPart"
+			}
+			if req.Header.Get("X-Amz-Copy-Source") != "" {
+				return "CopyObject"
+			}
+			return "PutObject"
+		case http.MethodHead:
+			return "StatObject"
+		case http.MethodDelete:
+			return "RemoveObject"
+		case http.MethodPost:
+			return "MultipartUpload"
+		}
+	}
+	return req.Method
+}
@@ -0,0 +1,151 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// fakeS3 is a minimal S3 server storing the objects in memory.
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := r.URL.Query()["location"]; ok {
+		w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := ioutil.ReadAll(r.Body)
+		s.objects[r.URL.Path] = b
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		b, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(&fakeS3{objects: make(map[string][]byte)})
+	defer srv.Close()
+	c, err := minio.NewV2(strings.TrimPrefix(srv.URL, "http://"), "key", "secret", false)
+	assert.Nil(err)
+	testTracer, testTransport := tracertest.GetTestTracer()
+	c = WrapClient(c, WithServiceName("uploads"), WithTracer(testTracer))
+
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	ctx := root.Context(context.Background())
+	data := []byte("hello world")
+	_, err = c.PutObjectWithContext(ctx, "avatars", "users/42.png", bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
+	assert.Nil(err)
+	obj, err := c.GetObjectWithContext(ctx, "avatars", "users/42.png", minio.GetObjectOptions{})
+	assert.Nil(err)
+	got, err := ioutil.ReadAll(obj)
+	assert.Nil(err)
+	assert.Equal(data, got)
+	obj.Close()
+	root.Finish()
+
+	testTracer.ForceFlush()
+	// the bucket location is looked up in its own trace, out of context
+	spans := make(map[string]*tracer.Span)
+	for _, trace := range testTransport.Traces() {
+		for _, s := range trace {
+			if s.Name == "s3.request" {
+				spans[s.Resource] = s
+			}
+		}
+	}
+	put, get := spans["PutObject"], spans["GetObject"]
+	assert.NotNil(put)
+	assert.NotNil(get)
+	assert.Equal(root.SpanID, put.ParentID)
+	assert.Equal(root.SpanID, get.ParentID)
+	assert.Equal("uploads", put.Service)
+	assert.Equal("avatars", put.GetMeta(bucketKey))
+	assert.Equal("users/42.png", put.GetMeta(objectKey))
+	assert.Equal(float64(len(data)), put.Metrics[requestBytesKey])
+	assert.Equal("200", get.GetMeta("http.status_code"))
+	assert.Equal(float64(len(data)), get.Metrics[responseBytesKey])
+}
+
+func TestRoundTripper(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(&fakeS3{objects: map[string][]byte{"/logs/2018/01.gz": []byte("gzipped")}})
+	defer srv.Close()
+	testTracer, testTransport := tracertest.GetTestTracer()
+	client := &http.Client{Transport: WrapRoundTripper(http.DefaultTransport, WithKeyHashing(), WithTracer(testTracer))}
+
+	resp, err := client.Get(srv.URL + "/logs/2018/01.gz")
+	assert.Nil(err)
+	resp.Body.Close()
+	resp, err = client.Get(srv.URL + "/logs/missing")
+	assert.Nil(err)
+	resp.Body.Close()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 2)
+	get := traces[0][0]
+	assert.Equal("GetObject", get.Resource)
+	assert.Equal("logs", get.GetMeta(bucketKey))
+	assert.Len(get.GetMeta(objectKey), 64)
+	assert.NotEqual("2018/01.gz", get.GetMeta(objectKey))
+	assert.Equal(7.0, get.Metrics[responseBytesKey])
+	assert.Equal(int32(0), get.Error)
+
+	// missing objects aren't errors
+	missing := traces[1][0]
+	assert.Equal("404", missing.GetMeta("http.status_code"))
+	assert.Equal(int32(0), missing.Error)
+}
+
+func TestParseRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	for url, want := range map[string][3]string{
+		"http://localhost:9000/":                           {"", "", "ListBuckets"},
+		"http://localhost:9000/avatars/":                   {"avatars", "", "ListObjects"},
+		"http://localhost:9000/avatars?location=":          {"avatars", "", "GetBucketLocation"},
+		"http://localhost:9000/avatars/users/42.png":       {"avatars", "users/42.png", "GetObject"},
+		"https://avatars.s3.amazonaws.com/users/42.png":    {"avatars", "users/42.png", "GetObject"},
+		"https://avatars.s3-eu-west-1.amazonaws.com/a.png": {"avatars", "a.png", "GetObject"},
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		bucket, key := parseRequest(req)
+		assert.Equal(want[0], bucket, url)
+		assert.Equal(want[1], key, url)
+		assert.Equal(want[2], operation(req, bucket, key), url)
+	}
+}
@@ -0,0 +1,39 @@
+package minio
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type clientConfig struct {
+	serviceName string
+	hashKeys    bool
+	tracer      *tracer.Tracer
+}
+
+// ClientOption represents an option that can be passed to WrapClient or WrapRoundTripper.
+type ClientOption func(*clientConfig)
+
+func defaults(cfg *clientConfig) {
+	cfg.serviceName = "minio"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced requests.
+func WithServiceName(name string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithKeyHashing replaces the object keys reported on the spans with their
+// SHA-256 hash, for the buckets whose keys hold sensitive data.
+func WithKeyHashing() ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.hashKeys = true
+	}
+}
+
+// WithTracer sets the tracer used to trace the requests.
+func WithTracer(t *tracer.Tracer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.tracer = t
+	}
+}
@@ -21,3 +21,12 @@ func Example_withServiceName() {
 	})
 	http.ListenAndServe(":8080", mux)
 }
+
+func ExampleWrapClient() {
+	client := httptrace.WrapClient(&http.Client{}, httptrace.WithConnectionTimings(false))
+	resp, err := client.Get("https://www.datadoghq.com")
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
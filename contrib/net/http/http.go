@@ -1,4 +1,5 @@
-// Package http provides functions to trace the net/http package (https://golang.org/pkg/net/http).
+// Package http provides functions to trace the net/http package (https://golang.org/pkg/net/http),
+// both for incoming requests and for requests sent by clients.
 package http
 
 import (
@@ -9,6 +10,10 @@ import (
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "net/http", Library: "net/http"})
+}
+
 // ServeMux is an HTTP request multiplexer that traces all the incoming requests.
 type ServeMux struct {
 	*http.ServeMux
@@ -24,6 +29,7 @@ func NewServeMux(opts ...MuxOption) *ServeMux {
 		fn(cfg)
 	}
 	cfg.tracer.SetServiceInfo(cfg.serviceName, "net/http", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "net/http", Options: map[string]string{"service": cfg.serviceName}})
 	return &ServeMux{
 		ServeMux: http.NewServeMux(),
 		config:   cfg,
@@ -38,21 +44,46 @@ func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// get the resource associated to this request
 	_, route := mux.Handler(r)
 	resource := r.Method + " " + route
-	internal.TraceAndServe(mux.ServeMux, w, r, mux.config.serviceName, resource, mux.config.tracer)
+	unmatched := route == ""
+	if unmatched {
+		// answered with a 404 or a 405
+		resource = internal.NotFoundResource
+	}
+	if mux.config.resourceNamer != nil {
+		resource = mux.config.resourceNamer(r)
+		unmatched = false
+	}
+	internal.TraceAndServeWithConfig(mux.ServeMux, w, r, &internal.ServeConfig{
+		Service:      mux.config.serviceName,
+		Resource:     resource,
+		Integration:  "net/http",
+		Tracer:       mux.config.tracer,
+		Route:        route,
+		Streaming:    mux.config.isStreaming != nil && mux.config.isStreaming(r),
+		MethodInName: mux.config.methodInName,
+		ForceKeep:    mux.config.forceKeep,
+		Unmatched:    unmatched,
+	})
 }
 
 // WrapHandlerWithTracer wraps an http.Handler with the default tracer using the
-// specified service and resource.
+// specified service and resource. The resource defaults to the method of the
+// request when empty.
 func WrapHandler(h http.Handler, service, resource string) http.Handler {
 	return WrapHandlerWithTracer(h, service, resource, tracer.DefaultTracer)
 }
 
 // WrapHandlerWithTracer wraps an http.Handler with the given tracer using the
-// specified service and resource.
+// specified service and resource. The resource defaults to the method of the
+// request when empty.
 //
 // TODO(gbbr): Remove this once we switch to OpenTracing fully.
 func WrapHandlerWithTracer(h http.Handler, service, resource string, t *tracer.Tracer) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		internal.TraceAndServe(h, w, req, service, resource, t)
+		resource := resource
+		if resource == "" {
+			resource = req.Method
+		}
+		internal.TraceAndServe(h, w, req, service, resource, "net/http", t)
 	})
 }
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	"github.com/DataDog/dd-trace-go/tracer/tracertest"
 	"github.com/stretchr/testify/assert"
 )
@@ -63,10 +66,13 @@ func TestHttpTracer200(t *testing.T) {
 	assert.Equal("http.request", s.Name)
 	assert.Equal("my-service", s.Service)
 	assert.Equal("GET "+url, s.Resource)
+	assert.Equal(url, s.GetMeta("http.route"))
 	assert.Equal("200", s.GetMeta("http.status_code"))
 	assert.Equal("GET", s.GetMeta("http.method"))
+	assert.Equal("net/http", s.GetMeta("component"))
 	assert.Equal(url, s.GetMeta("http.url"))
 	assert.Equal(int32(0), s.Error)
+	assert.Equal(uint64(1), tracer.Stats().Integrations["net/http"].Spans)
 }
 
 func TestHttpTracer500(t *testing.T) {
@@ -128,6 +134,219 @@ func TestWrapHandler200(t *testing.T) {
 	assert.Equal(int32(0), s.Error)
 }
 
+func TestWrapHandlerDefaultResource(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := tracertest.GetTestTracer()
+	handler := WrapHandlerWithTracer(handler200(t), "my-service", "", tracer)
+
+	r := httptest.NewRequest("POST", "/users", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal("POST", traces[0][0].Resource)
+}
+
+func TestHttpTracerBodySizes(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	mux := NewServeMux(WithTracer(testTracer))
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+		w.Write([]byte("!"))
+	})
+
+	r := httptest.NewRequest("POST", "/echo", strings.NewReader("hello"))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal("hello!", w.Body.String())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal(float64(5), s.Metrics["http.request.size"])
+	assert.Equal(float64(6), s.Metrics["http.response.size"])
+}
+
+func TestHttpTracerStreaming(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	mux := NewServeMux(
+		WithServiceName("my-service"),
+		WithTracer(testTracer),
+		WithStreaming(func(r *http.Request) bool { return r.URL.Path == "/events" }),
+	)
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		span := tracer.SpanFromContextDefault(r.Context())
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		// the span is done once the header is sent
+		assert.NotEqual(int64(0), span.Duration)
+		duration := span.Duration
+		_, err := w.Write([]byte("data: hello\n\n"))
+		assert.Nil(err)
+		assert.Equal(duration, span.Duration)
+	})
+
+	r := httptest.NewRequest("GET", "/events", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	assert.Equal(200, w.Code)
+	assert.True(w.Flushed)
+	assert.Equal("data: hello\n\n", w.Body.String())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 1)
+	s := spans[0]
+	assert.Equal("GET /events", s.Resource)
+	assert.Equal("200", s.GetMeta("http.status_code"))
+	assert.Equal("true", s.GetMeta("http.streaming"))
+
+	// other routes are not affected
+	r = httptest.NewRequest("GET", "/", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	testTracer.ForceFlush()
+	traces = testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("", traces[0][0].GetMeta("http.streaming"))
+}
+
+func TestHttpTracerPanic(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	mux := NewServeMux(WithServiceName("my-service"), WithTracer(testTracer))
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	})
+
+	r := httptest.NewRequest("GET", "/panic", nil)
+	assert.PanicsWithValue("oops", func() {
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	})
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal(int32(1), s.Error)
+	assert.Equal("panic: oops", s.GetMeta("error.msg"))
+	assert.NotEqual("", s.GetMeta("error.stack"))
+	assert.Equal(float64(0), s.Metrics["http.response.size"])
+}
+
+func TestNotFound(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+
+	r := httptest.NewRequest("GET", "/wp-admin.php", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(404, w.Code)
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("404 not found", s.Resource)
+	assert.Equal("", s.GetMeta("http.route"))
+	assert.Equal("/wp-admin.php", s.GetMeta("http.url"))
+	assert.Equal("404", s.GetMeta("http.status_code"))
+}
+
+func TestResourceNamer(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	router := NewServeMux(WithServiceName("my-service"), WithTracer(testTracer), WithResourceNamer(func(r *http.Request) string {
+		return "api " + strings.TrimPrefix(r.URL.Path, "/v2")
+	}))
+	router.HandleFunc("/v2/users", handler200(t))
+
+	r := httptest.NewRequest("GET", "/v2/users", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal("api /users", traces[0][0].Resource)
+}
+
+func TestMethodInOperationName(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	router := NewServeMux(WithServiceName("my-service"), WithTracer(testTracer), WithMethodInOperationName())
+	router.HandleFunc("/users/", handler200(t))
+
+	r := httptest.NewRequest("POST", "/users/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("http.request.post", s.Name)
+	assert.Equal("POST /users/", s.Resource)
+	assert.Equal("/users/", s.GetMeta("http.route"))
+}
+
+func TestForceKeep(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport, router := setup(t)
+	tracer.SetSampleRate(0)
+
+	// the header is ignored by default
+	r := httptest.NewRequest("GET", "/200", nil)
+	r.Header.Set(ext.HTTPHeaderForceKeep, "true")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	mux := NewServeMux(WithServiceName("my-service"), WithTracer(tracer), WithForceKeep())
+	mux.HandleFunc("/200", handler200(t))
+	router = mux
+	for header, value := range map[string]string{
+		"":                      "",
+		ext.HTTPHeaderForceKeep: "0",
+	} {
+		r := httptest.NewRequest("GET", "/200", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	for header, value := range map[string]string{
+		ext.HTTPHeaderForceKeep: "true",
+		ext.HTTPHeaderOrigin:    "synthetics-browser",
+	} {
+		r := httptest.NewRequest("GET", "/200", nil)
+		r.Header.Set(header, value)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		tracer.ForceFlush()
+		traces := transport.Traces()
+		assert.Len(traces, 1, header)
+		s := traces[0][0]
+		assert.Equal(ext.PriorityUserKeep, s.GetSamplingPriority())
+		assert.Equal("200", s.GetMeta(ext.HTTPCode))
+		if header == ext.HTTPHeaderOrigin {
+			assert.Equal(value, s.GetMeta(ext.Origin))
+		}
+	}
+}
+
 func setup(t *testing.T) (*tracer.Tracer, *tracertest.DummyTransport, http.Handler) {
 	h200 := handler200(t)
 	h500 := handler500(t)
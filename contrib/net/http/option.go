@@ -1,10 +1,18 @@
 package http
 
-import "github.com/DataDog/dd-trace-go/tracer"
+import (
+	"net/http"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
 
 type muxConfig struct {
-	serviceName string
-	tracer      *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	serviceName   string
+	tracer        *tracer.Tracer // TODO(gbbr): Remove this when we switch.
+	isStreaming   func(*http.Request) bool
+	resourceNamer func(*http.Request) string
+	methodInName  bool
+	forceKeep     bool
 }
 
 // MuxOption represents an option that can be passed to NewServeMux.
@@ -27,3 +35,95 @@ func WithTracer(t *tracer.Tracer) MuxOption {
 		cfg.tracer = t
 	}
 }
+
+// WithStreaming marks the requests for which match returns true as streaming or
+// long-polling requests (e.g. server-sent events or websockets). Their spans are
+// finished as soon as the response header is written instead of when the handler
+// returns, so that hours-long connections don't skew latency.
+func WithStreaming(match func(*http.Request) bool) MuxOption {
+	return func(cfg *muxConfig) {
+		cfg.isStreaming = match
+	}
+}
+
+// WithResourceNamer sets the function used to name the resource of each request,
+// e.g. to group endpoints differently than the router does. It replaces the
+// default naming, which uses the method and the matched route.
+func WithResourceNamer(namer func(*http.Request) string) MuxOption {
+	return func(cfg *muxConfig) {
+		cfg.resourceNamer = namer
+	}
+}
+
+type roundTripperConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+	timings     timingsMode
+}
+
+// timingsMode specifies how the timings of the connection phases of
+// outbound requests are reported.
+type timingsMode int
+
+const (
+	timingsOff     timingsMode = iota // not reported
+	timingsMetrics                    // as metrics of the request span
+	timingsSpans                      // as metrics and as child spans
+)
+
+// RoundTripperOption represents an option that can be passed to
+// WrapRoundTripper and WrapClient.
+type RoundTripperOption func(*roundTripperConfig)
+
+func rtDefaults(cfg *roundTripperConfig) {
+	cfg.serviceName = "http.client"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// RTWithServiceName sets the service name of the outbound requests.
+func RTWithServiceName(name string) RoundTripperOption {
+	return func(cfg *roundTripperConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// RTWithTracer sets the tracer used to trace the outbound requests.
+func RTWithTracer(t *tracer.Tracer) RoundTripperOption {
+	return func(cfg *roundTripperConfig) {
+		cfg.tracer = t
+	}
+}
+
+// WithConnectionTimings records how long the DNS lookup, the connection, the
+// TLS handshake and the wait for the first byte of the response took, using
+// net/http/httptrace. They are reported as metrics of the request span, in
+// nanoseconds, and, when asSpans is true, the first three also as child spans
+// named "http.dns", "http.connect" and "http.tls". Phases skipped because a
+// connection was reused aren't reported.
+func WithConnectionTimings(asSpans bool) RoundTripperOption {
+	return func(cfg *roundTripperConfig) {
+		cfg.timings = timingsMetrics
+		if asSpans {
+			cfg.timings = timingsSpans
+		}
+	}
+}
+
+// WithMethodInOperationName appends the lowercase method of the requests to
+// the name of their spans, e.g. "http.request.get" instead of "http.request".
+func WithMethodInOperationName() MuxOption {
+	return func(cfg *muxConfig) {
+		cfg.methodInName = true
+	}
+}
+
+// WithForceKeep keeps the traces of the requests sent with the
+// "x-datadog-force-keep: true" header or coming from Synthetic tests, whatever
+// the sampler decides, so that a specific request can be traced on demand.
+// Only enable it when the clients are trusted, as they could otherwise have
+// all their requests traced.
+func WithForceKeep() MuxOption {
+	return func(cfg *muxConfig) {
+		cfg.forceKeep = true
+	}
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// Metrics reporting the time, in nanoseconds, spent in each phase of an
+// outbound request when connection timings are enabled. The time to first
+// byte is counted from the start of the request.
+const (
+	dnsMetric     = "http.timing.dns"
+	connectMetric = "http.timing.connect"
+	tlsMetric     = "http.timing.tls"
+	ttfbMetric    = "http.timing.ttfb"
+)
+
+// WrapClient traces the requests sent by the given client, and returns it.
+// If the client has no transport, http.DefaultTransport is traced.
+func WrapClient(c *http.Client, opts ...RoundTripperOption) *http.Client {
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	c.Transport = WrapRoundTripper(rt, opts...)
+	return c
+}
+
+// WrapRoundTripper returns a round tripper tracing the requests it sends with
+// the given one, as children of the span found in their context.
+func WrapRoundTripper(rt http.RoundTripper, opts ...RoundTripperOption) http.RoundTripper {
+	cfg := new(roundTripperConfig)
+	rtDefaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "net/http", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "net/http", Options: map[string]string{"service": cfg.serviceName}})
+	return &roundTripper{base: rt, config: cfg}
+}
+
+type roundTripper struct {
+	base   http.RoundTripper
+	config *roundTripperConfig
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := rt.config.tracer
+	if !t.Enabled() {
+		return rt.base.RoundTrip(req)
+	}
+	span := t.NewChildSpanFromContext("http.request", req.Context())
+	span.SetIntegration("net/http")
+	span.Type = ext.HTTPType
+	span.Service = rt.config.serviceName
+	span.Resource = req.Method
+	span.SetMeta(ext.HTTPMethod, req.Method)
+	span.SetMeta(ext.HTTPURL, req.URL.Path)
+	span.SetMeta(ext.TargetHost, req.URL.Host)
+
+	var ct *clientTrace
+	if rt.config.timings != timingsOff {
+		ct = &clientTrace{span: span, spans: rt.config.timings == timingsSpans, start: time.Now()}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), ct.hooks()))
+	}
+
+	resp, err := rt.base.RoundTrip(req)
+	if ct != nil {
+		ct.finish()
+	}
+	if err != nil {
+		span.FinishWithErr(err)
+		return resp, err
+	}
+	span.SetMeta(ext.HTTPCode, strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= 500 && resp.StatusCode < 600 {
+		span.Error = 1
+	}
+	span.Finish()
+	return resp, err
+}
+
+// clientTrace records the timings of the phases of a request in its span.
+type clientTrace struct {
+	mu    sync.Mutex
+	span  *tracer.Span
+	spans bool // whether phases are also reported as child spans
+	start time.Time
+
+	dns, connect, tls phase
+	closed            bool // whether the request is over
+}
+
+// phase is a step of the connection to the server.
+type phase struct {
+	start time.Time
+	span  *tracer.Span
+	done  bool
+}
+
+func (ct *clientTrace) hooks() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { ct.begin(&ct.dns, "http.dns") },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			ct.end(&ct.dns, dnsMetric, info.Err)
+		},
+		ConnectStart: func(_, _ string) { ct.begin(&ct.connect, "http.connect") },
+		ConnectDone: func(_, _ string, err error) {
+			ct.end(&ct.connect, connectMetric, err)
+		},
+		TLSHandshakeStart: func() { ct.begin(&ct.tls, "http.tls") },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			ct.end(&ct.tls, tlsMetric, err)
+		},
+		GotFirstResponseByte: func() {
+			ct.span.SetMetric(ttfbMetric, float64(time.Since(ct.start).Nanoseconds()))
+		},
+	}
+}
+
+// begin starts the given phase. Only its first start is taken into account,
+// as a dialer may try several addresses concurrently.
+func (ct *clientTrace) begin(p *phase, name string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.closed || !p.start.IsZero() {
+		return
+	}
+	p.start = time.Now()
+	if ct.spans {
+		p.span = ct.span.Tracer().NewChildSpan(name, ct.span)
+		p.span.Type = ext.HTTPType
+	}
+}
+
+// end ends the given phase and records its duration.
+func (ct *clientTrace) end(p *phase, metric string, err error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.closed || p.start.IsZero() || p.done {
+		return
+	}
+	p.done = true
+	ct.span.SetMetric(metric, float64(time.Since(p.start).Nanoseconds()))
+	if p.span != nil {
+		p.span.FinishWithErr(err)
+	}
+}
+
+// finish finishes the child spans of the phases which never ended, e.g.
+// because the request was canceled, and ignores any later event.
+func (ct *clientTrace) finish() {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.closed = true
+	for _, p := range []*phase{&ct.dns, &ct.connect, &ct.tls} {
+		if p.span != nil && !p.done {
+			p.done = true
+			p.span.Finish()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTripper(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := WrapClient(&http.Client{}, RTWithServiceName("my-client"), RTWithTracer(testTracer))
+	resp, err := client.Get(srv.URL + "/users")
+	assert.Nil(err)
+	resp.Body.Close()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 1)
+
+	s := spans[0]
+	assert.Equal("http.request", s.Name)
+	assert.Equal("my-client", s.Service)
+	assert.Equal("GET", s.Resource)
+	assert.Equal("/users", s.GetMeta("http.url"))
+	assert.Equal("500", s.GetMeta("http.status_code"))
+	assert.Equal(int32(1), s.Error)
+	_, ok := s.Metrics[connectMetric]
+	assert.False(ok)
+}
+
+func TestRoundTripperConnectionTimings(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	for name, asSpans := range map[string]bool{"metrics": false, "spans": true} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			testTracer, testTransport := tracertest.GetTestTracer()
+
+			// use a new transport so that the connection isn't reused
+			tr := &http.Transport{TLSClientConfig: srv.Client().Transport.(*http.Transport).TLSClientConfig}
+			defer tr.CloseIdleConnections()
+			client := WrapClient(&http.Client{Transport: tr}, RTWithTracer(testTracer), WithConnectionTimings(asSpans))
+			resp, err := client.Get(srv.URL)
+			assert.Nil(err)
+			resp.Body.Close()
+
+			testTracer.ForceFlush()
+			traces := testTransport.Traces()
+			assert.Len(traces, 1)
+			spans := traces[0]
+
+			byName := make(map[string]int)
+			for _, s := range spans {
+				byName[s.Name]++
+			}
+			root := spans[0]
+			for _, s := range spans {
+				if s.Name == "http.request" {
+					root = s
+				}
+			}
+			for _, m := range []string{connectMetric, tlsMetric, ttfbMetric} {
+				assert.True(root.Metrics[m] > 0, m)
+			}
+			assert.True(root.Metrics[ttfbMetric] >= root.Metrics[connectMetric])
+			if asSpans {
+				assert.Len(spans, 3)
+				assert.Equal(1, byName["http.connect"])
+				assert.Equal(1, byName["http.tls"])
+				for _, s := range spans {
+					if s != root {
+						assert.Equal(root.SpanID, s.ParentID)
+					}
+				}
+			} else {
+				assert.Len(spans, 1)
+			}
+		})
+	}
+}
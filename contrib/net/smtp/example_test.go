@@ -0,0 +1,33 @@
+package smtp_test
+
+import (
+	"context"
+	"log"
+	"net/smtp"
+
+	smtptrace "github.com/DataDog/dd-trace-go/contrib/net/smtp"
+)
+
+func Example() {
+	auth := smtp.PlainAuth("", "user@example.com", "password", "mail.example.com")
+	msg := []byte("To: recipient@example.net\r\n" +
+		"Subject: Hello\r\n" +
+		"\r\n" +
+		"This is the email body.\r\n")
+	err := smtptrace.SendMail(context.Background(), "mail.example.com:25", auth, "sender@example.org", []string{"recipient@example.net"}, msg)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_SendMail() {
+	c, err := smtptrace.Dial("mail.example.com:25", smtptrace.WithServiceName("notifications"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Quit()
+	err = c.SendMail(context.Background(), "sender@example.org", []string{"recipient@example.net"}, []byte("Subject: Hello\r\n\r\nHi!\r\n"))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package smtp
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type clientConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// ClientOption represents an option that can be passed to Dial or SendMail.
+type ClientOption func(*clientConfig)
+
+func defaults(cfg *clientConfig) {
+	cfg.serviceName = "smtp"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced mail sends.
+func WithServiceName(name string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the mail sends.
+func WithTracer(t *tracer.Tracer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.tracer = t
+	}
+}
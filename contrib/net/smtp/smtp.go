@@ -0,0 +1,130 @@
+// Package smtp provides functions to trace the net/smtp package (https://golang.org/pkg/net/smtp).
+package smtp
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "net/smtp", Library: "net/smtp"})
+}
+
+const (
+	// recipientsKey is the metric key holding the number of recipients of a mail.
+	recipientsKey = "smtp.recipients"
+	// fromDomainKey is the meta key holding the domain of the sender. The
+	// addresses are never recorded as they are personal data.
+	fromDomainKey = "smtp.from_domain"
+)
+
+// Client is an SMTP client which traces the mails it sends with SendMail.
+type Client struct {
+	*smtp.Client
+	config *clientConfig
+	host   string
+	port   string
+}
+
+// Dial returns a new traced Client connected to an SMTP server at addr,
+// which must include a port, as in "mail.example.com:smtp".
+func Dial(addr string, opts ...ClientOption) (*Client, error) {
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(c, addr, opts...), nil
+}
+
+// NewClient returns a new traced Client using an existing connection to the
+// SMTP server at addr, see smtp.NewClient.
+func NewClient(conn net.Conn, addr string, opts ...ClientOption) (*Client, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(c, addr, opts...), nil
+}
+
+func newClient(c *smtp.Client, addr string, opts ...ClientOption) *Client {
+	cfg := parseOptions(opts...)
+	host, port, _ := net.SplitHostPort(addr)
+	return &Client{
+		Client: c,
+		config: cfg,
+		host:   host,
+		port:   port,
+	}
+}
+
+// SendMail sends a mail from the given address to the given recipients, in a
+// span which is a child of the one found in the given context, if any. The
+// message is sent as is, see smtp.SendMail for its expected format.
+func (c *Client) SendMail(ctx context.Context, from string, to []string, msg []byte) (err error) {
+	span := newSendSpan(ctx, c.config, c.host, c.port, from, to)
+	defer func() {
+		span.FinishWithErr(err)
+	}()
+
+	if err = c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// SendMail wraps smtp.SendMail, tracing the send in a span which is a child
+// of the one found in the given context, if any.
+func SendMail(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte, opts ...ClientOption) error {
+	cfg := parseOptions(opts...)
+	host, port, _ := net.SplitHostPort(addr)
+	span := newSendSpan(ctx, cfg, host, port, from, to)
+	err := smtp.SendMail(addr, a, from, to, msg)
+	span.FinishWithErr(err)
+	return err
+}
+
+func parseOptions(opts ...ClientOption) *clientConfig {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "net/smtp", ext.AppTypeWeb)
+	tracer.RegisterIntegration(tracer.Integration{Name: "net/smtp", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
+
+// newSendSpan returns a span for sending a mail to the given server.
+func newSendSpan(ctx context.Context, cfg *clientConfig, host, port, from string, to []string) *tracer.Span {
+	span := cfg.tracer.NewChildSpanFromContext("smtp.send", ctx)
+	span.SetIntegration("net/smtp")
+	span.Type = "smtp"
+	span.Service = cfg.serviceName
+	span.Resource = "SendMail"
+	span.SetMeta(ext.TargetHost, host)
+	span.SetMeta(ext.TargetPort, port)
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		span.SetMeta(fromDomainKey, strings.TrimSuffix(from[i+1:], ">"))
+	}
+	span.SetMetric(recipientsKey, float64(len(to)))
+	return span
+}
@@ -0,0 +1,140 @@
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+// fakeServer is a minimal SMTP server accepting the mails sent to it, but
+// rejecting the recipients of the domain reject.test.
+type fakeServer struct {
+	net.Listener
+	mails chan string // receives the data of each accepted mail
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{Listener: l, mails: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "RCPT") && strings.Contains(cmd, "@REJECT.TEST"):
+			reply("550 no such user")
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"):
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data []string
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data = append(data, line)
+			}
+			s.mails <- strings.Join(data, "")
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestClientSendMail(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newFakeServer(t)
+	defer srv.Close()
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	c, err := Dial(srv.Addr().String(), WithServiceName("mailer"), WithTracer(testTracer))
+	assert.Nil(err)
+	defer c.Close()
+	root := testTracer.NewRootSpan("notify", "notifier", "notify")
+	err = c.SendMail(root.Context(context.Background()), "bob@example.com", []string{"alice@example.org", "carol@example.org"}, []byte("Subject: hi\r\n\r\nhello\r\n"))
+	assert.Nil(err)
+	root.Finish()
+	assert.Contains(<-srv.mails, "hello")
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	for _, s := range traces[0] {
+		if s.Name != "smtp.send" {
+			continue
+		}
+		assert.Equal(root.SpanID, s.ParentID)
+		assert.Equal("mailer", s.Service)
+		assert.Equal("SendMail", s.Resource)
+		assert.Equal("127.0.0.1", s.GetMeta("out.host"))
+		assert.Equal("example.com", s.GetMeta(fromDomainKey))
+		assert.Equal(2.0, s.Metrics[recipientsKey])
+		assert.Equal(int32(0), s.Error)
+		for _, v := range s.Meta {
+			assert.NotContains(v, "bob@")
+			assert.NotContains(v, "alice@")
+		}
+	}
+}
+
+func TestSendMailError(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newFakeServer(t)
+	defer srv.Close()
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	err := SendMail(context.Background(), srv.Addr().String(), nil, "bob@example.com", []string{"alice@reject.test"}, []byte("hello\r\n"), WithTracer(testTracer))
+	assert.NotNil(err)
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("smtp.send", s.Name)
+	assert.Equal("smtp", s.Service)
+	assert.Equal(1.0, s.Metrics[recipientsKey])
+	assert.Equal(int32(1), s.Error)
+}
@@ -0,0 +1,38 @@
+package nsq_test
+
+import (
+	"context"
+	"log"
+
+	"github.com/nsqio/go-nsq"
+
+	nsqtrace "github.com/DataDog/dd-trace-go/contrib/nsqio/go-nsq"
+)
+
+func Example_producer() {
+	p, err := nsq.NewProducer("127.0.0.1:4150", nsq.NewConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+	producer := nsqtrace.WrapProducer(p)
+	defer producer.Stop()
+
+	if err := producer.PublishContext(context.Background(), "events", []byte("signup")); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Example_consumer() {
+	c, err := nsq.NewConsumer("events", "mailer", nsq.NewConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.AddHandler(nsqtrace.WrapHandler("events", "mailer", func(ctx context.Context, m *nsq.Message) error {
+		// the span of the message can be found in ctx
+		return nil
+	}, nsqtrace.WithServiceName("mailer")))
+	if err := c.ConnectToNSQD("127.0.0.1:4150"); err != nil {
+		log.Fatal(err)
+	}
+	<-c.StopChan
+}
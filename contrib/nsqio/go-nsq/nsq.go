@@ -0,0 +1,145 @@
+// Package nsq provides functions to trace the nsqio/go-nsq package (https://github.com/nsqio/go-nsq).
+//
+// NSQ messages have no headers, so the messages published by a traced
+// Producer carry the trace context of the producer in their body, which the
+// handlers wrapped with WrapHandler remove before processing them. Both sides
+// have to be traced: a handler which isn't wrapped receives the bodies with
+// the trace context. The sampling decision of the producer is carried along,
+// so that the handlers keep or drop the traces as the producer did.
+package nsq
+
+import (
+	"context"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "nsqio/go-nsq", Library: "github.com/nsqio/go-nsq"})
+}
+
+const (
+	topicKey    = "nsq.topic"
+	channelKey  = "nsq.channel"
+	messagesKey = "nsq.messages"
+	attemptsKey = "nsq.attempts"
+)
+
+func newConfig(opts ...Option) *config {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "nsq", ext.AppTypeWorker)
+	tracer.RegisterIntegration(tracer.Integration{Name: "nsqio/go-nsq", Options: map[string]string{"service": cfg.serviceName}})
+	return cfg
+}
+
+// Producer is an NSQ producer which traces the messages it publishes.
+type Producer struct {
+	*nsq.Producer
+	config *config
+}
+
+// WrapProducer returns a traced version of the given producer.
+func WrapProducer(p *nsq.Producer, opts ...Option) *Producer {
+	return &Producer{Producer: p, config: newConfig(opts...)}
+}
+
+// newSpan returns a span for publishing to the given topic, which is a child
+// of the one found in the given context, if any.
+func (p *Producer) newSpan(ctx context.Context, topic string) *tracer.Span {
+	span := p.config.tracer.NewChildSpanFromContext("nsq.publish", ctx)
+	span.SetIntegration("nsqio/go-nsq")
+	span.Type = ext.AppTypeWorker
+	span.Service = p.config.serviceName
+	span.Resource = "Publish " + topic
+	span.SetMeta(topicKey, topic)
+	span.SetMeta(ext.TargetHost, p.Producer.String())
+	return span
+}
+
+// Publish publishes a message, see PublishContext.
+func (p *Producer) Publish(topic string, body []byte) error {
+	return p.PublishContext(context.Background(), topic, body)
+}
+
+// PublishContext publishes a message to the given topic, in a span which is a
+// child of the one found in the given context, if any. The trace context is
+// added to the body of the message.
+func (p *Producer) PublishContext(ctx context.Context, topic string, body []byte) error {
+	span := p.newSpan(ctx, topic)
+	err := p.Producer.Publish(topic, internal.WrapPayload(span, body))
+	span.FinishWithErr(err)
+	return err
+}
+
+// MultiPublish publishes messages, see MultiPublishContext.
+func (p *Producer) MultiPublish(topic string, bodies [][]byte) error {
+	return p.MultiPublishContext(context.Background(), topic, bodies)
+}
+
+// MultiPublishContext publishes messages to the given topic at once, in a
+// span which is a child of the one found in the given context, if any. The
+// trace context is added to the body of each message.
+func (p *Producer) MultiPublishContext(ctx context.Context, topic string, bodies [][]byte) error {
+	span := p.newSpan(ctx, topic)
+	span.SetMetric(messagesKey, float64(len(bodies)))
+	wrapped := make([][]byte, len(bodies))
+	for i, body := range bodies {
+		wrapped[i] = internal.WrapPayload(span, body)
+	}
+	err := p.Producer.MultiPublish(topic, wrapped)
+	span.FinishWithErr(err)
+	return err
+}
+
+// DeferredPublish publishes a delayed message, see DeferredPublishContext.
+func (p *Producer) DeferredPublish(topic string, delay time.Duration, body []byte) error {
+	return p.DeferredPublishContext(context.Background(), topic, delay, body)
+}
+
+// DeferredPublishContext publishes a message to the given topic, which is
+// delivered after the given delay, in a span which is a child of the one found
+// in the given context, if any. The trace context is added to the body of the
+// message.
+func (p *Producer) DeferredPublishContext(ctx context.Context, topic string, delay time.Duration, body []byte) error {
+	span := p.newSpan(ctx, topic)
+	err := p.Producer.DeferredPublish(topic, delay, internal.WrapPayload(span, body))
+	span.FinishWithErr(err)
+	return err
+}
+
+// WrapHandler returns a handler of the messages of the given topic and
+// channel, which traces the calls of the given function as children of the
+// producer spans, when they were traced. The trace context is removed from
+// the body of the messages before they are given to the function, along with
+// a context holding the span of the message.
+func WrapHandler(topic, channel string, fn func(ctx context.Context, m *nsq.Message) error, opts ...Option) nsq.Handler {
+	cfg := newConfig(opts...)
+	return nsq.HandlerFunc(func(m *nsq.Message) error {
+		remote, body := internal.UnwrapPayload(m.Body)
+		m.Body = body
+		t := cfg.tracer
+		if !t.Enabled() {
+			return fn(context.Background(), m)
+		}
+		span := t.NewRootSpan("nsq.consume", cfg.serviceName, "Consume "+topic)
+		remote.Continue(t, span)
+		span.SetIntegration("nsqio/go-nsq")
+		span.Type = ext.AppTypeWorker
+		span.SetMeta(topicKey, topic)
+		span.SetMeta(channelKey, channel)
+		span.SetMetric(attemptsKey, float64(m.Attempts))
+		defer internal.FinishOnPanic(span, nil)
+		err := fn(tracer.ContextWithSpan(context.Background(), span), m)
+		span.FinishWithErr(err)
+		return err
+	})
+}
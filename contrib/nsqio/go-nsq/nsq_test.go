@@ -0,0 +1,103 @@
+package nsq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nsqio/go-nsq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/contrib/internal"
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+const nsqdAddr = "127.0.0.1:4150"
+
+func TestWrapHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	producer := testTracer.NewRootSpan("nsq.publish", "producer", "Publish events")
+	m := nsq.NewMessage(nsq.MessageID{}, internal.WrapPayload(producer, []byte("signup")))
+	m.Attempts = 2
+
+	h := WrapHandler("events", "mailer", func(ctx context.Context, m *nsq.Message) error {
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		assert.Equal("signup", string(m.Body))
+		return errors.New("smtp down")
+	}, WithServiceName("mailer"), WithTracer(testTracer))
+	assert.NotNil(h.HandleMessage(m))
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("nsq.consume", s.Name)
+	assert.Equal("mailer", s.Service)
+	assert.Equal("Consume events", s.Resource)
+	assert.Equal("events", s.GetMeta(topicKey))
+	assert.Equal("mailer", s.GetMeta(channelKey))
+	assert.Equal(2.0, s.Metrics[attemptsKey])
+	assert.Equal(producer.TraceID, s.TraceID)
+	assert.Equal(producer.SpanID, s.ParentID)
+	assert.Equal(int32(1), s.Error)
+}
+
+func TestWrapHandlerDroppedProducer(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	producer := testTracer.NewRootSpan("nsq.publish", "producer", "Publish events")
+	producer.SetSamplingPriority(ext.PriorityUserReject)
+	m := nsq.NewMessage(nsq.MessageID{}, internal.WrapPayload(producer, []byte("signup")))
+
+	h := WrapHandler("events", "mailer", func(ctx context.Context, m *nsq.Message) error {
+		span, _ := tracer.SpanFromContext(ctx)
+		assert.False(span.Sampled)
+		return nil
+	}, WithTracer(testTracer))
+	assert.Nil(h.HandleMessage(m))
+
+	// the trace dropped by the producer isn't kept by the consumer, it is
+	// only sent for the agent to compute its statistics
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(ext.PriorityUserReject, traces[0][0].GetSamplingPriority())
+}
+
+func TestProducer(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := nsq.NewProducer(nsqdAddr, nsq.NewConfig())
+	assert.Nil(err)
+	defer p.Stop()
+	p.SetLogger(nil, nsq.LogLevelError)
+	if err := p.Ping(); err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	tp := WrapProducer(p, WithTracer(testTracer))
+	root := testTracer.NewRootSpan("web.request", "web", "/")
+	assert.Nil(tp.MultiPublishContext(root.Context(context.Background()), "events", [][]byte{[]byte("a"), []byte("b")}))
+	root.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	for _, s := range traces[0] {
+		if s.Name == "nsq.publish" {
+			assert.Equal(root.SpanID, s.ParentID)
+			assert.Equal("nsq", s.Service)
+			assert.Equal("Publish events", s.Resource)
+			assert.Equal(nsqdAddr, s.GetMeta("out.host"))
+			assert.Equal(2.0, s.Metrics[messagesKey])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package nsq
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type config struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// Option represents an option that can be passed to WrapProducer or WrapHandler.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "nsq"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced messages.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the messages.
+func WithTracer(t *tracer.Tracer) Option {
+	return func(cfg *config) {
+		cfg.tracer = t
+	}
+}
@@ -14,6 +14,11 @@ import (
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
+func init() {
+	// the transport traces the requests of any version of the library
+	tracer.RegisterIntegration(tracer.Integration{Name: "olivere/elastic"})
+}
+
 // NewHTTPClient returns a new http.Client which traces requests under the given service name.
 func NewHTTPClient(opts ...ClientOption) *http.Client {
 	cfg := new(clientConfig)
@@ -37,6 +42,7 @@ const maxContentLength = 500 * 1024
 func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	span := t.config.tracer.NewChildSpanFromContext("elasticsearch.query", req.Context())
 	defer span.Finish()
+	span.SetIntegration("olivere/elastic")
 
 	span.Service = t.config.serviceName
 	span.Type = ext.AppTypeDB
@@ -0,0 +1,115 @@
+// Package cron provides functions to trace the robfig/cron package (https://github.com/robfig/cron).
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/robfig/cron"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func init() {
+	tracer.RegisterIntegration(tracer.Integration{Name: "robfig/cron", Library: "github.com/robfig/cron"})
+}
+
+const (
+	jobNameKey  = "cron.job"
+	scheduleKey = "cron.schedule"
+)
+
+// Job is a cron.Job tracing each of its runs with a span starting a new
+// trace, and counting the runs which succeeded and failed.
+type Job struct {
+	// successes and failures are accessed atomically
+	successes uint64
+	failures  uint64
+
+	name     string
+	schedule string
+	run      func(ctx context.Context) error
+	config   *jobConfig
+}
+
+// WrapFunc returns a traced job running the given function. The name
+// identifies the job, and the schedule is the spec it is added with, both are
+// reported on the spans. A run fails when the function returns an error or
+// panics.
+func WrapFunc(name, schedule string, fn func(ctx context.Context) error, opts ...JobOption) *Job {
+	cfg := new(jobConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	cfg.tracer.SetServiceInfo(cfg.serviceName, "cron", ext.AppTypeWorker)
+	tracer.RegisterIntegration(tracer.Integration{Name: "robfig/cron", Options: map[string]string{"service": cfg.serviceName}})
+	return &Job{
+		name:     name,
+		schedule: schedule,
+		run:      fn,
+		config:   cfg,
+	}
+}
+
+// WrapJob returns a traced version of the given job, see WrapFunc. A run of
+// the job only fails when it panics.
+func WrapJob(name, schedule string, job cron.Job, opts ...JobOption) *Job {
+	return WrapFunc(name, schedule, func(context.Context) error {
+		job.Run()
+		return nil
+	}, opts...)
+}
+
+// AddFunc adds a traced job running the given function to c, on the given
+// schedule, see WrapFunc.
+func AddFunc(c *cron.Cron, spec, name string, fn func(ctx context.Context) error, opts ...JobOption) (*Job, error) {
+	job := WrapFunc(name, spec, fn, opts...)
+	if err := c.AddJob(spec, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Run runs the job in a new span, which can be found in the context given to
+// the function of the job.
+func (j *Job) Run() {
+	t := j.config.tracer
+	span := t.NewRootSpan("cron.run", j.config.serviceName, j.name)
+	span.SetIntegration("robfig/cron")
+	span.Type = ext.AppTypeWorker
+	span.SetMeta(jobNameKey, j.name)
+	span.SetMeta(scheduleKey, j.schedule)
+
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			j.finish(span, fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+		j.finish(span, err)
+	}()
+	err = j.run(tracer.ContextWithSpan(context.Background(), span))
+}
+
+// finish records the result of a run.
+func (j *Job) finish(span *tracer.Span, err error) {
+	if err != nil {
+		atomic.AddUint64(&j.failures, 1)
+	} else {
+		atomic.AddUint64(&j.successes, 1)
+	}
+	span.FinishWithErr(err)
+}
+
+// Successes returns the number of runs of the job which succeeded.
+func (j *Job) Successes() uint64 {
+	return atomic.LoadUint64(&j.successes)
+}
+
+// Failures returns the number of runs of the job which failed.
+func (j *Job) Failures() uint64 {
+	return atomic.LoadUint64(&j.failures)
+}
@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/robfig/cron"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+)
+
+func TestJobRun(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	fail := false
+	job := WrapFunc("cleanup", "@hourly", func(ctx context.Context) error {
+		_, ok := tracer.SpanFromContext(ctx)
+		assert.True(ok)
+		if fail {
+			return errors.New("disk full")
+		}
+		return nil
+	}, WithServiceName("janitor"), WithTracer(testTracer))
+
+	job.Run()
+	fail = true
+	job.Run()
+	assert.Equal(uint64(1), job.Successes())
+	assert.Equal(uint64(1), job.Failures())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 2)
+	for i, trace := range traces {
+		s := trace[0]
+		assert.Equal("cron.run", s.Name)
+		assert.Equal("janitor", s.Service)
+		assert.Equal("cleanup", s.Resource)
+		assert.Equal("cleanup", s.GetMeta(jobNameKey))
+		assert.Equal("@hourly", s.GetMeta(scheduleKey))
+		assert.Equal(uint64(0), s.ParentID)
+		assert.Equal(int32(i), s.Error)
+	}
+}
+
+type panickyJob struct{}
+
+func (panickyJob) Run() { panic("oops") }
+
+func TestJobPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	testTracer, testTransport := tracertest.GetTestTracer()
+	job := WrapJob("panicky", "@every 1s", panickyJob{}, WithTracer(testTracer))
+	assert.Panics(job.Run)
+	assert.Equal(uint64(0), job.Successes())
+	assert.Equal(uint64(1), job.Failures())
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal("cron", s.Service)
+	assert.Equal(int32(1), s.Error)
+	assert.Equal("panic: oops", s.GetMeta("error.msg"))
+}
+
+func TestAddFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	c := cron.New()
+	_, err := AddFunc(c, "not a spec", "broken", func(context.Context) error { return nil })
+	assert.NotNil(err)
+
+	job, err := AddFunc(c, "@daily", "report", func(context.Context) error { return nil })
+	assert.Nil(err)
+	entries := c.Entries()
+	assert.Len(entries, 1)
+	assert.Equal(job, entries[0].Job)
+}
@@ -0,0 +1,22 @@
+package cron_test
+
+import (
+	"context"
+	"log"
+
+	"github.com/robfig/cron"
+
+	crontrace "github.com/DataDog/dd-trace-go/contrib/robfig/cron"
+)
+
+func Example() {
+	c := cron.New()
+	_, err := crontrace.AddFunc(c, "@every 5m", "sync-users", func(ctx context.Context) error {
+		// the span of the run can be found in ctx
+		return nil
+	}, crontrace.WithServiceName("scheduler"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.Start()
+}
@@ -0,0 +1,30 @@
+package cron
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type jobConfig struct {
+	serviceName string
+	tracer      *tracer.Tracer
+}
+
+// JobOption represents an option that can be passed to WrapFunc, WrapJob or AddFunc.
+type JobOption func(*jobConfig)
+
+func defaults(cfg *jobConfig) {
+	cfg.serviceName = "cron"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the given service name for the traced job runs.
+func WithServiceName(name string) JobOption {
+	return func(cfg *jobConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithTracer sets the tracer used to trace the job runs.
+func WithTracer(t *tracer.Tracer) JobOption {
+	return func(cfg *jobConfig) {
+		cfg.tracer = t
+	}
+}
@@ -0,0 +1,191 @@
+package opentracing
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	ot "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// the B3 multi-header propagation headers
+	b3TraceIDHeader      = "x-b3-traceid"
+	b3SpanIDHeader       = "x-b3-spanid"
+	b3ParentSpanIDHeader = "x-b3-parentspanid"
+	b3SampledHeader      = "x-b3-sampled"
+	b3FlagsHeader        = "x-b3-flags"
+
+	// b3SingleHeader is the B3 single-header propagation header.
+	b3SingleHeader = "b3"
+)
+
+// B3Propagator propagates the SpanContext in the B3 headers of Zipkin, so
+// that traces continue across the services instrumented with Zipkin or
+// proxied by Envoy. The sampling decision travels as the sampled state of
+// B3, the debug flag standing for a trace kept by the user. Trace IDs are
+// sent as 16 or 32 hexadecimal characters, whether they have 64 or 128 bits.
+type B3Propagator struct {
+	single bool
+}
+
+// NewB3Propagator returns a new propagator using the B3 multi-header
+// format, with a header per field, such as X-B3-TraceId.
+func NewB3Propagator() *B3Propagator {
+	return &B3Propagator{}
+}
+
+// NewB3SinglePropagator returns a new propagator using the B3 single-header
+// format, the fields being held by the b3 header. Both formats are
+// extracted.
+func NewB3SinglePropagator() *B3Propagator {
+	return &B3Propagator{single: true}
+}
+
+// String describes the headers used by the propagator.
+func (p *B3Propagator) String() string {
+	if p.single {
+		return "b3 single header"
+	}
+	return "b3multi (x-b3-traceid, x-b3-spanid, x-b3-sampled)"
+}
+
+// Inject implements Propagator.
+func (p *B3Propagator) Inject(context ot.SpanContext, carrier interface{}) error {
+	ctx, ok := context.(SpanContext)
+	if !ok || ctx.traceID == 0 || ctx.spanID == 0 {
+		return ot.ErrInvalidSpanContext
+	}
+	writer, ok := carrier.(ot.TextMapWriter)
+	if !ok {
+		return ot.ErrInvalidCarrier
+	}
+	traceID, spanID := formatB3ID(ctx.traceID), formatB3ID(ctx.spanID)
+	if ctx.traceIDHigh != 0 {
+		traceID = formatB3ID(ctx.traceIDHigh) + traceID
+	}
+	var sampled string
+	if priority, ok := ctx.samplingPriority(); ok {
+		switch {
+		case priority >= ext.PriorityUserKeep:
+			sampled = "d"
+		case priority > 0:
+			sampled = "1"
+		default:
+			sampled = "0"
+		}
+	}
+	if p.single {
+		header := traceID + "-" + spanID
+		if sampled != "" {
+			header += "-" + sampled
+		}
+		writer.Set(b3SingleHeader, header)
+		return nil
+	}
+	writer.Set(b3TraceIDHeader, traceID)
+	writer.Set(b3SpanIDHeader, spanID)
+	switch sampled {
+	case "d":
+		writer.Set(b3FlagsHeader, "1")
+	case "1", "0":
+		writer.Set(b3SampledHeader, sampled)
+	}
+	return nil
+}
+
+// Extract implements Propagator. The single header takes precedence over
+// the multiple headers when both are received.
+func (p *B3Propagator) Extract(carrier interface{}) (ot.SpanContext, error) {
+	reader, ok := carrier.(ot.TextMapReader)
+	if !ok {
+		return nil, ot.ErrInvalidCarrier
+	}
+	var single, traceID, spanID, sampled, flags string
+	err := reader.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case b3SingleHeader:
+			single = v
+		case b3TraceIDHeader:
+			traceID = v
+		case b3SpanIDHeader:
+			spanID = v
+		case b3SampledHeader:
+			sampled = v
+		case b3FlagsHeader:
+			flags = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if single != "" {
+		return parseB3Single(single)
+	}
+	if traceID == "" || spanID == "" {
+		return nil, ot.ErrSpanContextNotFound
+	}
+	if flags == "1" {
+		sampled = "d"
+	}
+	return newB3Context(traceID, spanID, sampled)
+}
+
+// parseB3Single returns the context held by the given b3 header value, of
+// the form "{TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}", the last two
+// fields being optional. A header holding the sampling state only carries
+// no context.
+func parseB3Single(header string) (ot.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	switch len(parts) {
+	case 1:
+		return nil, ot.ErrSpanContextNotFound
+	case 2:
+		return newB3Context(parts[0], parts[1], "")
+	case 3, 4:
+		return newB3Context(parts[0], parts[1], parts[2])
+	}
+	return nil, ot.ErrSpanContextCorrupted
+}
+
+// newB3Context returns the context of the given B3 fields, the sampling
+// state being "1" or "true" for sampled traces, "0" or "false" for dropped
+// traces, "d" for debug traces, or empty when it is left to this process.
+func newB3Context(traceID, spanID, sampled string) (ot.SpanContext, error) {
+	if len(traceID) != 16 && len(traceID) != 32 || len(spanID) != 16 {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	tid, err := strconv.ParseUint(traceID[len(traceID)-16:], 16, 64)
+	if err != nil || tid == 0 {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	sid, err := strconv.ParseUint(spanID, 16, 64)
+	if err != nil || sid == 0 {
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	ctx := SpanContext{traceID: tid, spanID: sid}
+	if len(traceID) == 32 {
+		if ctx.traceIDHigh, err = strconv.ParseUint(traceID[:16], 16, 64); err != nil {
+			return nil, ot.ErrSpanContextCorrupted
+		}
+	}
+	switch sampled {
+	case "":
+	case "1", "true":
+		ctx.priority, ctx.hasPriority, ctx.sampled = ext.PriorityAutoKeep, true, true
+	case "0", "false":
+		ctx.priority, ctx.hasPriority = ext.PriorityAutoReject, true
+	case "d":
+		ctx.priority, ctx.hasPriority, ctx.sampled = ext.PriorityUserKeep, true, true
+	default:
+		return nil, ot.ErrSpanContextCorrupted
+	}
+	return ctx, nil
+}
+
+// formatB3ID returns the given ID as 16 lower-case hexadecimal digits.
+func formatB3ID(id uint64) string {
+	s := strconv.FormatUint(id, 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
@@ -0,0 +1,123 @@
+package opentracing
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestB3PropagatorInjectExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, propagator := range []*B3Propagator{NewB3Propagator(), NewB3SinglePropagator()} {
+		config := NewConfiguration()
+		config.TextMapPropagator = propagator
+		tracer, _, _ := NewTracer(config)
+
+		root := tracer.StartSpan("web.request").(*Span)
+		root.Span.SetSamplingPriority(1)
+		headers := http.Header{}
+		carrier := opentracing.HTTPHeadersCarrier(headers)
+		assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+		if propagator.single {
+			assert.Equal(formatB3ID(root.Span.TraceID)+"-"+formatB3ID(root.Span.SpanID)+"-1", headers.Get("b3"))
+		} else {
+			assert.Equal(formatB3ID(root.Span.TraceID), headers.Get("X-B3-TraceId"))
+			assert.Equal(formatB3ID(root.Span.SpanID), headers.Get("X-B3-SpanId"))
+			assert.Equal("1", headers.Get("X-B3-Sampled"))
+		}
+
+		propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+		assert.Nil(err)
+		child := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+		assert.Equal(root.Span.TraceID, child.Span.TraceID)
+		assert.Equal(root.Span.SpanID, child.Span.ParentID)
+		assert.Equal(1, child.Span.GetSamplingPriority())
+	}
+}
+
+func TestB3PropagatorExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tt := range []struct {
+		headers  map[string]string
+		traceID  uint64
+		high     uint64
+		spanID   uint64
+		priority int
+		has      bool
+		err      error
+	}{
+		{
+			headers: map[string]string{"X-B3-TraceId": "463ac35c9f6413ad48485a3953bb6124", "X-B3-SpanId": "a2fb4a1d1a96d312", "X-B3-Sampled": "1"},
+			traceID: 0x48485a3953bb6124, high: 0x463ac35c9f6413ad, spanID: 0xa2fb4a1d1a96d312, priority: 1, has: true,
+		},
+		{
+			headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "a2fb4a1d1a96d312", "X-B3-ParentSpanId": "0020000000000001"},
+			traceID: 0x48485a3953bb6124, spanID: 0xa2fb4a1d1a96d312,
+		},
+		{
+			headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "a2fb4a1d1a96d312", "X-B3-Sampled": "false"},
+			traceID: 0x48485a3953bb6124, spanID: 0xa2fb4a1d1a96d312, priority: 0, has: true,
+		},
+		{
+			headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "a2fb4a1d1a96d312", "X-B3-Flags": "1"},
+			traceID: 0x48485a3953bb6124, spanID: 0xa2fb4a1d1a96d312, priority: 2, has: true,
+		},
+		{
+			headers: map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d-05e3ac9a4f6e3b90"},
+			traceID: 0x64fe8b2a57d3eff7, high: 0x80f198ee56343ba8, spanID: 0xe457b5a2e4d86bd1, priority: 2, has: true,
+		},
+		{
+			headers: map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-0", "X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "a2fb4a1d1a96d312"},
+			traceID: 0x64fe8b2a57d3eff7, spanID: 0xe457b5a2e4d86bd1, priority: 0, has: true,
+		},
+		{headers: map[string]string{"b3": "0"}, err: opentracing.ErrSpanContextNotFound},
+		{headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124"}, err: opentracing.ErrSpanContextNotFound},
+		{headers: map[string]string{"b3": "64fe8b2a57d3eff7-e457b5a2e4d86bd1-maybe"}, err: opentracing.ErrSpanContextCorrupted},
+		{headers: map[string]string{"X-B3-TraceId": "0000000000000000", "X-B3-SpanId": "a2fb4a1d1a96d312"}, err: opentracing.ErrSpanContextCorrupted},
+		{headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "a2fb"}, err: opentracing.ErrSpanContextCorrupted},
+	} {
+		headers := http.Header{}
+		for k, v := range tt.headers {
+			headers.Set(k, v)
+		}
+		ctx, err := NewB3Propagator().Extract(opentracing.HTTPHeadersCarrier(headers))
+		assert.Equal(tt.err, err, "%v", tt.headers)
+		if err != nil {
+			continue
+		}
+		c := ctx.(SpanContext)
+		assert.Equal(tt.traceID, c.traceID)
+		assert.Equal(tt.high, c.traceIDHigh)
+		assert.Equal(tt.spanID, c.spanID)
+		assert.Equal(tt.priority, c.priority)
+		assert.Equal(tt.has, c.hasPriority)
+	}
+}
+
+func TestB3PropagatorInjectDebug(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := SpanContext{traceID: 1, spanID: 2, priority: 2, hasPriority: true}
+	headers := http.Header{}
+	assert.Nil(NewB3Propagator().Inject(ctx, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal("1", headers.Get("X-B3-Flags"))
+	assert.Equal("", headers.Get("X-B3-Sampled"))
+
+	assert.Nil(NewB3SinglePropagator().Inject(ctx, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal("0000000000000001-0000000000000002-d", headers.Get("b3"))
+
+	assert.Equal(opentracing.ErrInvalidSpanContext, NewB3Propagator().Inject(SpanContext{}, opentracing.HTTPHeadersCarrier(headers)))
+}
+
+func TestB3PropagatorInject128(t *testing.T) {
+	assert := assert.New(t)
+
+	headers := http.Header{}
+	ctx := SpanContext{traceID: 0x48485a3953bb6124, traceIDHigh: 0x463ac35c9f6413ad, spanID: 0xa2fb4a1d1a96d312}
+	assert.Nil(NewB3Propagator().Inject(ctx, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal("463ac35c9f6413ad48485a3953bb6124", headers.Get("X-B3-TraceId"))
+}
@@ -3,6 +3,8 @@ package opentracing
 import (
 	"os"
 	"path/filepath"
+
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
 )
 
 // Configuration struct configures the Datadog tracer. Please use the NewConfiguration
@@ -31,8 +33,26 @@ type Configuration struct {
 	// all spans.
 	GlobalTags map[string]interface{}
 
-	// TextMapPropagator is an injector used for Context propagation.
+	// TextMapPropagator is an injector used for Context propagation, such
+	// as NewTextMapPropagator for the Datadog headers, or NewW3CPropagator,
+	// NewB3Propagator and NewB3SinglePropagator for the headers of other
+	// tracers. NewConfiguration sets it to the propagator of the
+	// propagation styles, which are used when it is left as is or nil.
 	TextMapPropagator Propagator
+
+	// PropagationStyleInject and PropagationStyleExtract are the
+	// comma-separated propagation styles used when TextMapPropagator isn't
+	// set otherwise, see NewPropagator. They are read from the
+	// DD_TRACE_PROPAGATION_STYLE_INJECT and
+	// DD_TRACE_PROPAGATION_STYLE_EXTRACT environment variables, or from
+	// DD_TRACE_PROPAGATION_STYLE for both, and are "datadog" by default.
+	// Invalid styles are reported in the logs, and the Datadog headers are
+	// used instead.
+	PropagationStyleInject  string
+	PropagationStyleExtract string
+
+	// defaultPropagator is the TextMapPropagator set by NewConfiguration.
+	defaultPropagator Propagator
 }
 
 // NewConfiguration creates a `Configuration` object with default values.
@@ -40,19 +60,42 @@ func NewConfiguration() *Configuration {
 	// default service name is the Go binary name
 	binaryName := filepath.Base(os.Args[0])
 
+	inject, extract := propagationStylesFromEnv()
+
+	propagator := stylesPropagator(inject, extract)
+
 	// Configuration struct with default values
 	return &Configuration{
-		Enabled:           true,
-		Debug:             false,
-		ServiceName:       binaryName,
-		SampleRate:        1,
-		AgentHostname:     "localhost",
-		AgentPort:         "8126",
-		GlobalTags:        make(map[string]interface{}),
-		TextMapPropagator: NewTextMapPropagator("", "", ""),
+		Enabled:                 true,
+		Debug:                   false,
+		ServiceName:             binaryName,
+		SampleRate:              1,
+		AgentHostname:           "localhost",
+		AgentPort:               "8126",
+		GlobalTags:              make(map[string]interface{}),
+		TextMapPropagator:       propagator,
+		PropagationStyleInject:  inject,
+		PropagationStyleExtract: extract,
+		defaultPropagator:       propagator,
 	}
 }
 
+// EffectiveConfiguration describes the configuration a running Tracer
+// actually uses, as returned by Tracer.Config.
+type EffectiveConfiguration struct {
+	// Config is the configuration of the underlying Datadog tracer.
+	ddtrace.Config
+
+	// ServiceName is the name of this application.
+	ServiceName string
+
+	// GlobalTags holds the tags applied to all spans.
+	GlobalTags map[string]interface{}
+
+	// Propagator describes how the SpanContext is injected and extracted.
+	Propagator string
+}
+
 type noopCloser struct{}
 
 func (c *noopCloser) Close() error { return nil }
@@ -56,3 +56,24 @@ func TestDisabledTracer(t *testing.T) {
 	assert.IsType(&noopCloser{}, closer)
 	assert.Nil(err)
 }
+
+func TestTracerConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	config.SampleRate = 0.25
+	config.ServiceName = "api-intake"
+	config.AgentHostname = "ddagent.consul.local"
+	config.AgentPort = "58126"
+	config.GlobalTags["env"] = "staging"
+	tracer, _, err := NewTracer(config)
+	assert.Nil(err)
+
+	cfg := tracer.(*Tracer).Config()
+	assert.Equal("api-intake", cfg.ServiceName)
+	assert.Equal("http://ddagent.consul.local:58126", cfg.AgentURL)
+	assert.Equal("rate", cfg.Sampler)
+	assert.Equal(0.25, cfg.SampleRate)
+	assert.Equal(map[string]interface{}{"env": "staging"}, cfg.GlobalTags)
+	assert.Equal("text_map (trace: x-datadog-trace-id, parent: x-datadog-parent-id, baggage prefix: ot-baggage-)", cfg.Propagator)
+}
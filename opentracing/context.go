@@ -1,5 +1,14 @@
 package opentracing
 
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	ot "github.com/opentracing/opentracing-go"
+)
+
 // SpanContext represents Span state that must propagate to descendant Spans
 // and across process boundaries.
 type SpanContext struct {
@@ -9,6 +18,23 @@ type SpanContext struct {
 	sampled  bool
 	span     *Span
 	baggage  map[string]string
+
+	// traceIDHigh holds the 64 upper bits of 128-bit trace IDs, 0 for
+	// 64-bit trace IDs.
+	traceIDHigh uint64
+
+	// tracestate holds the W3C tracestate members of other vendors, which
+	// are propagated untouched.
+	tracestate []string
+
+	// tags holds the trace-level tags, shared by the spans of the trace.
+	tags *traceTags
+
+	// priority is the sampling priority received upstream, if hasPriority,
+	// and origin the origin of the trace, such as "synthetics".
+	priority    int
+	hasPriority bool
+	origin      string
 }
 
 // ForeachBaggageItem grants access to all baggage items stored in the
@@ -36,11 +62,152 @@ func (c SpanContext) WithBaggageItem(key, val string) SpanContext {
 	}
 	// Use positional parameters so the compiler will help catch new fields.
 	return SpanContext{
-		traceID:  c.traceID,
-		spanID:   c.spanID,
-		parentID: c.parentID,
-		sampled:  c.sampled,
-		span:     c.span,
-		baggage:  newBaggage,
+		traceID:     c.traceID,
+		spanID:      c.spanID,
+		traceIDHigh: c.traceIDHigh,
+		parentID:    c.parentID,
+		sampled:     c.sampled,
+		span:        c.span,
+		baggage:     newBaggage,
+		tracestate:  c.tracestate,
+		tags:        c.tags,
+		priority:    c.priority,
+		hasPriority: c.hasPriority,
+		origin:      c.origin,
+	}
+}
+
+// samplingPriority returns the sampling priority of the trace, as set on its
+// span in this process or as received upstream, if any.
+func (c SpanContext) samplingPriority() (int, bool) {
+	if c.span != nil && c.span.Span.HasSamplingPriority() {
+		return c.span.Span.GetSamplingPriority(), true
+	}
+	return c.priority, c.hasPriority
+}
+
+// Equal reports whether other refers to the same span as c. Baggage is not
+// compared.
+func (c SpanContext) Equal(other ot.SpanContext) bool {
+	o, ok := asSpanContext(other)
+	return ok && c.traceID != 0 && c.traceID == o.traceID && c.spanID == o.spanID
+}
+
+// SameTrace reports whether other belongs to the same trace as c.
+func (c SpanContext) SameTrace(other ot.SpanContext) bool {
+	o, ok := asSpanContext(other)
+	return ok && c.traceID != 0 && c.traceID == o.traceID
+}
+
+// IsParentOf reports whether c is the direct parent of child. It is useful
+// to assert that parentage was kept across process boundaries.
+func (c SpanContext) IsParentOf(child ot.SpanContext) bool {
+	o, ok := asSpanContext(child)
+	return ok && c.SameTrace(o) && c.spanID != 0 && o.parentID == c.spanID
+}
+
+// asSpanContext returns the SpanContext held by ctx, if any.
+func asSpanContext(ctx ot.SpanContext) (SpanContext, bool) {
+	switch c := ctx.(type) {
+	case SpanContext:
+		return c, true
+	case *SpanContext:
+		if c != nil {
+			return *c, true
+		}
+	}
+	return SpanContext{}, false
+}
+
+// MarshalText implements encoding.TextMarshaler. It encodes the IDs of the
+// context, its sampling priority and its baggage in a compact string, such as
+// "123:456:1?user=bob", which can be stored, e.g. in a job record or a log
+// line, and turned back into a SpanContext with UnmarshalText to start
+// children of the span later. The sampling priority is left empty when there
+// is none, and the 64 upper bits of 128-bit trace IDs follow it in hex, e.g.
+// "123:456::5868468000000000".
+func (c SpanContext) MarshalText() ([]byte, error) {
+	if c.traceID == 0 || c.spanID == 0 {
+		return nil, ot.ErrInvalidSpanContext
+	}
+	high := c.traceIDHigh
+	if c.span != nil {
+		high = c.span.Span.TraceIDHigh()
+	}
+	priority, hasPriority := c.samplingPriority()
+
+	text := strconv.AppendUint(nil, c.traceID, 10)
+	text = append(text, ':')
+	text = strconv.AppendUint(text, c.spanID, 10)
+	if hasPriority || high != 0 {
+		text = append(text, ':')
+		if hasPriority {
+			text = strconv.AppendInt(text, int64(priority), 10)
+		}
+	}
+	if high != 0 {
+		text = append(text, ':')
+		text = append(text, formatB3ID(high)...)
+	}
+	if len(c.baggage) > 0 {
+		baggage := make(url.Values, len(c.baggage))
+		for k, v := range c.baggage {
+			baggage.Set(k, v)
+		}
+		text = append(text, '?')
+		text = append(text, baggage.Encode()...)
+	}
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It decodes a context
+// encoded by MarshalText. The span it refers to is considered remote, as if
+// it had been extracted from a carrier.
+func (c *SpanContext) UnmarshalText(text []byte) error {
+	s := string(text)
+	var baggage map[string]string
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		values, err := url.ParseQuery(s[i+1:])
+		if err != nil {
+			return ot.ErrSpanContextCorrupted
+		}
+		baggage = make(map[string]string, len(values))
+		for k := range values {
+			baggage[k] = values.Get(k)
+		}
+		s = s[:i]
+	}
+	fields := strings.Split(s, ":")
+	if len(fields) < 2 || len(fields) > 4 {
+		return ot.ErrSpanContextCorrupted
+	}
+	traceID, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil || traceID == 0 {
+		return ot.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || spanID == 0 {
+		return ot.ErrSpanContextCorrupted
+	}
+	ctx := SpanContext{
+		traceID: traceID,
+		spanID:  spanID,
+		baggage: baggage,
+	}
+	if len(fields) > 2 && fields[2] != "" {
+		priority, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return ot.ErrSpanContextCorrupted
+		}
+		ctx.priority, ctx.hasPriority = priority, true
+	}
+	if len(fields) > 3 {
+		high, ok := ddtrace.ParseTraceIDHigh(fields[3])
+		if !ok {
+			return ot.ErrSpanContextCorrupted
+		}
+		ctx.traceIDHigh = high
 	}
+	*c = ctx
+	return nil
 }
@@ -3,6 +3,8 @@ package opentracing
 import (
 	"testing"
 
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +29,107 @@ func TestSpanContextIterator(t *testing.T) {
 	assert.Len(baggageIterator, 1)
 	assert.Equal("value", baggageIterator["key"])
 }
+
+func TestSpanContextMarshalText(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := SpanContext{traceID: 123, spanID: 456, baggage: map[string]string{"user": "bob smith", "tier": "1"}}
+	text, err := ctx.MarshalText()
+	assert.Nil(err)
+	assert.Equal("123:456?tier=1&user=bob+smith", string(text))
+
+	var decoded SpanContext
+	assert.Nil(decoded.UnmarshalText(text))
+	assert.Equal(uint64(123), decoded.traceID)
+	assert.Equal(uint64(456), decoded.spanID)
+	assert.Equal(ctx.baggage, decoded.baggage)
+
+	text, err = SpanContext{traceID: 1, spanID: 2}.MarshalText()
+	assert.Nil(err)
+	assert.Equal("1:2", string(text))
+	assert.Nil(decoded.UnmarshalText(text))
+	assert.Equal(SpanContext{traceID: 1, spanID: 2}, decoded)
+
+	// the sampling priority and the 128-bit trace IDs round-trip
+	ctx = SpanContext{traceID: 1, spanID: 2, priority: -1, hasPriority: true}
+	text, err = ctx.MarshalText()
+	assert.Nil(err)
+	assert.Equal("1:2:-1", string(text))
+	assert.Nil(decoded.UnmarshalText(text))
+	assert.Equal(ctx, decoded)
+	ctx = SpanContext{traceID: 1, spanID: 2, traceIDHigh: 0x5868468000000000}
+	text, err = ctx.MarshalText()
+	assert.Nil(err)
+	assert.Equal("1:2::5868468000000000", string(text))
+	assert.Nil(decoded.UnmarshalText(text))
+	assert.Equal(ctx, decoded)
+	ctx.priority, ctx.hasPriority = 2, true
+	ctx.baggage = map[string]string{"user": "bob"}
+	text, err = ctx.MarshalText()
+	assert.Nil(err)
+	assert.Equal("1:2:2:5868468000000000?user=bob", string(text))
+	assert.Nil(decoded.UnmarshalText(text))
+	assert.Equal(ctx, decoded)
+
+	_, err = SpanContext{}.MarshalText()
+	assert.Equal(opentracing.ErrInvalidSpanContext, err)
+	for _, text := range []string{"", "12", "a:2", "1:", "0:2", "1:2?%zz", "1:2:x", "1:2::12", "1:2:1:5868468000000000:3"} {
+		assert.Equal(opentracing.ErrSpanContextCorrupted, decoded.UnmarshalText([]byte(text)), text)
+	}
+}
+
+func TestSpanContextResume(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _, _ := NewTracer(NewConfiguration())
+	root := tracer.StartSpan("job.enqueue")
+	text, err := root.Context().(SpanContext).MarshalText()
+	assert.Nil(err)
+
+	var ctx SpanContext
+	assert.Nil(ctx.UnmarshalText(text))
+	child := tracer.StartSpan("job.run", opentracing.ChildOf(ctx)).(*Span)
+	assert.Equal(root.(*Span).Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.(*Span).Span.SpanID, child.Span.ParentID)
+
+	// the sampling decision and the 128-bit trace ID are resumed as well
+	root.(*Span).Span.SetTraceIDHigh(42)
+	root.(*Span).Span.SetSamplingPriority(ext.PriorityUserKeep)
+	text, err = root.Context().(SpanContext).MarshalText()
+	assert.Nil(err)
+	assert.Nil(ctx.UnmarshalText(text))
+	child = tracer.StartSpan("job.run", opentracing.ChildOf(ctx)).(*Span)
+	assert.Equal(uint64(42), child.Span.TraceIDHigh())
+	assert.Equal(ext.PriorityUserKeep, child.Span.GetSamplingPriority())
+}
+
+func TestSpanContextCompare(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _, _ := NewTracer(NewConfiguration())
+	root := tracer.StartSpan("web.request")
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context()))
+	other := tracer.StartSpan("web.request")
+
+	rootCtx := root.Context().(SpanContext)
+	childCtx := child.Context().(SpanContext)
+	assert.True(rootCtx.Equal(root.Context()))
+	assert.True(rootCtx.Equal(&rootCtx))
+	assert.False(rootCtx.Equal(child.Context()))
+	assert.True(rootCtx.SameTrace(child.Context()))
+	assert.False(rootCtx.SameTrace(other.Context()))
+	assert.True(rootCtx.IsParentOf(child.Context()))
+	assert.False(childCtx.IsParentOf(root.Context()))
+	assert.False(rootCtx.IsParentOf(other.Context()))
+	assert.False(rootCtx.Equal(nil))
+	assert.False(SpanContext{}.Equal(SpanContext{}))
+
+	// parentage is kept across process boundaries
+	carrier := opentracing.TextMapCarrier{}
+	assert.Nil(tracer.Inject(root.Context(), opentracing.TextMap, carrier))
+	remote, err := tracer.Extract(opentracing.TextMap, carrier)
+	assert.Nil(err)
+	assert.True(rootCtx.Equal(remote))
+	remoteChild := tracer.StartSpan("worker.run", opentracing.ChildOf(remote))
+	assert.True(rootCtx.IsParentOf(remoteChild.Context()))
+}
@@ -0,0 +1,171 @@
+package opentracing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+
+	ot "github.com/opentracing/opentracing-go"
+)
+
+// The propagation styles, naming the header formats the SpanContext is
+// injected in and extracted from, see NewPropagator.
+const (
+	// PropagationStyleDatadog uses the Datadog headers, such as
+	// x-datadog-trace-id, see NewTextMapPropagator.
+	PropagationStyleDatadog = "datadog"
+	// PropagationStyleTraceContext uses the W3C Trace Context headers, see
+	// NewW3CPropagator.
+	PropagationStyleTraceContext = "tracecontext"
+	// PropagationStyleB3 uses the B3 multiple headers, see NewB3Propagator.
+	// "b3" is accepted as well.
+	PropagationStyleB3 = "b3multi"
+	// PropagationStyleB3Single uses the B3 single header, see
+	// NewB3SinglePropagator.
+	PropagationStyleB3Single = "b3 single header"
+	// PropagationStyleNone disables the propagation.
+	PropagationStyleNone = "none"
+)
+
+const (
+	// the environment variables holding the propagation styles used to
+	// inject and to extract the SpanContext, the last one for both
+	envPropagationStyleInject  = "DD_TRACE_PROPAGATION_STYLE_INJECT"
+	envPropagationStyleExtract = "DD_TRACE_PROPAGATION_STYLE_EXTRACT"
+	envPropagationStyle        = "DD_TRACE_PROPAGATION_STYLE"
+)
+
+// NewPropagator returns a propagator injecting the SpanContext in the
+// headers of all the given inject styles, and extracting it from the headers
+// of the first of the given extract styles found in the carrier. Styles are
+// given as comma-separated lists, such as "tracecontext,datadog", of the
+// PropagationStyle constants, case-insensitive. It is the propagator of the
+// Tracer unless the TextMapPropagator of its Configuration is set, so that
+// services can interoperate with the ones instrumented by other tracers,
+// without a hand-rolled Propagator.
+func NewPropagator(inject, extract string) (Propagator, error) {
+	injectors, err := parsePropagationStyles(inject)
+	if err != nil {
+		return nil, err
+	}
+	extractors, err := parsePropagationStyles(extract)
+	if err != nil {
+		return nil, err
+	}
+	inject, extract = normalizeStyles(inject), normalizeStyles(extract)
+	if inject == extract && len(injectors) == 1 {
+		// a single style, the propagator of which is used as is
+		return injectors[0], nil
+	}
+	return &chainedPropagator{
+		inject:     inject,
+		extract:    extract,
+		injectors:  injectors,
+		extractors: extractors,
+	}, nil
+}
+
+// stylesPropagator returns the propagator of the given propagation styles,
+// or the Datadog one if they are invalid, logging why.
+func stylesPropagator(inject, extract string) Propagator {
+	p, err := NewPropagator(inject, extract)
+	if err != nil {
+		ddtrace.Warnf("opentracing", "ignoring the propagation styles %q and %q: %v; using the Datadog headers", inject, extract, err)
+		return NewTextMapPropagator("", "", "")
+	}
+	return p
+}
+
+// propagationStylesFromEnv returns the propagation styles used to inject and
+// to extract the SpanContext set in the environment, the Datadog one by
+// default.
+func propagationStylesFromEnv() (inject, extract string) {
+	inject, extract = PropagationStyleDatadog, PropagationStyleDatadog
+	if v := os.Getenv(envPropagationStyle); v != "" {
+		inject, extract = v, v
+	}
+	if v := os.Getenv(envPropagationStyleInject); v != "" {
+		inject = v
+	}
+	if v := os.Getenv(envPropagationStyleExtract); v != "" {
+		extract = v
+	}
+	return inject, extract
+}
+
+// parsePropagationStyles returns the propagators of the given
+// comma-separated propagation styles.
+func parsePropagationStyles(styles string) ([]Propagator, error) {
+	var propagators []Propagator
+	for _, style := range strings.Split(styles, ",") {
+		switch strings.ToLower(strings.TrimSpace(style)) {
+		case "", PropagationStyleNone:
+		case PropagationStyleDatadog:
+			propagators = append(propagators, NewTextMapPropagator("", "", ""))
+		case PropagationStyleTraceContext:
+			propagators = append(propagators, NewW3CPropagator())
+		case PropagationStyleB3, "b3":
+			propagators = append(propagators, NewB3Propagator())
+		case PropagationStyleB3Single:
+			propagators = append(propagators, NewB3SinglePropagator())
+		default:
+			return nil, fmt.Errorf("unknown propagation style %q", strings.TrimSpace(style))
+		}
+	}
+	return propagators, nil
+}
+
+// normalizeStyles returns the given comma-separated propagation styles, in
+// lower case and without spaces around the commas.
+func normalizeStyles(styles string) string {
+	var normalized []string
+	for _, style := range strings.Split(styles, ",") {
+		if style = strings.ToLower(strings.TrimSpace(style)); style != "" {
+			normalized = append(normalized, style)
+		}
+	}
+	if len(normalized) == 0 {
+		return PropagationStyleNone
+	}
+	return strings.Join(normalized, ",")
+}
+
+// chainedPropagator injects the SpanContext with several propagators, and
+// extracts it with the first one which finds it.
+type chainedPropagator struct {
+	inject, extract       string
+	injectors, extractors []Propagator
+}
+
+// String describes the propagation styles used.
+func (p *chainedPropagator) String() string {
+	return "inject: " + p.inject + ", extract: " + p.extract
+}
+
+// Inject implements Propagator.
+func (p *chainedPropagator) Inject(context ot.SpanContext, carrier interface{}) error {
+	for _, i := range p.injectors {
+		if err := i.Inject(context, carrier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract implements Propagator. When no propagator finds the SpanContext,
+// the first error other than ot.ErrSpanContextNotFound is returned, if any.
+func (p *chainedPropagator) Extract(carrier interface{}) (ot.SpanContext, error) {
+	err := ot.ErrSpanContextNotFound
+	for _, e := range p.extractors {
+		ctx, extractErr := e.Extract(carrier)
+		if extractErr == nil {
+			return ctx, nil
+		}
+		if err == ot.ErrSpanContextNotFound {
+			err = extractErr
+		}
+	}
+	return nil, err
+}
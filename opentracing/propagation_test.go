@@ -0,0 +1,105 @@
+package opentracing
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPropagator(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := NewPropagator("datadog", "Datadog")
+	assert.Nil(err)
+	assert.IsType(&TextMapPropagator{}, p)
+
+	p, err = NewPropagator("tracecontext, b3", "b3 single header,datadog")
+	assert.Nil(err)
+	assert.Equal("inject: tracecontext,b3, extract: b3 single header,datadog", p.(*chainedPropagator).String())
+
+	_, err = NewPropagator("datadog,jaeger", "datadog")
+	assert.EqualError(err, `unknown propagation style "jaeger"`)
+
+	// nothing is propagated with the none style
+	p, err = NewPropagator("none", "")
+	assert.Nil(err)
+	headers := http.Header{}
+	assert.Nil(p.Inject(SpanContext{traceID: 1, spanID: 2}, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Len(headers, 0)
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(http.Header{"X-Datadog-Trace-Id": {"1"}, "X-Datadog-Parent-Id": {"2"}}))
+	assert.Equal(opentracing.ErrSpanContextNotFound, err)
+}
+
+func TestTracerPropagationStyles(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	config.PropagationStyleInject = "datadog,tracecontext,b3"
+	config.PropagationStyleExtract = "tracecontext,datadog"
+	tracer, _, err := NewTracer(config)
+	assert.Nil(err)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	headers := http.Header{}
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal(strconv.FormatUint(root.Span.TraceID, 10), headers.Get("x-datadog-trace-id"))
+	assert.NotEqual("", headers.Get("traceparent"))
+	assert.Equal(formatB3ID(root.Span.TraceID), headers.Get("x-b3-traceid"))
+
+	// the first style found wins
+	headers.Set("traceparent", "00-00000000000000000000000000000001-0000000000000002-01")
+	ctx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal(uint64(1), ctx.(SpanContext).traceID)
+
+	headers.Del("traceparent")
+	ctx, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal(root.Span.TraceID, ctx.(SpanContext).traceID)
+
+	// a corrupted context is reported when no other style is found
+	headers = http.Header{"Traceparent": {"00-garbage"}}
+	_, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Equal(opentracing.ErrSpanContextCorrupted, err)
+
+	// unknown styles are reported, and the Datadog headers used instead
+	config = NewConfiguration()
+	config.PropagationStyleExtract = "jaeger"
+	_, _, err = NewTracer(config)
+	assert.Nil(err)
+	assert.IsType(&TextMapPropagator{}, config.TextMapPropagator)
+}
+
+func TestPropagationStylesFromEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	assert.Equal("datadog", config.PropagationStyleInject)
+	assert.Equal("datadog", config.PropagationStyleExtract)
+
+	os.Setenv(envPropagationStyle, "tracecontext")
+	os.Setenv(envPropagationStyleExtract, "b3,tracecontext")
+	defer os.Unsetenv(envPropagationStyle)
+	defer os.Unsetenv(envPropagationStyleExtract)
+	config = NewConfiguration()
+	assert.Equal("tracecontext", config.PropagationStyleInject)
+	assert.Equal("b3,tracecontext", config.PropagationStyleExtract)
+	assert.Equal("inject: tracecontext, extract: b3,tracecontext", config.TextMapPropagator.(*chainedPropagator).String())
+
+	// invalid styles fall back to the Datadog headers, logging a warning
+	// through the tracer's logger
+	var buf bytes.Buffer
+	ddtrace.SetJSONLogging(&buf)
+	defer ddtrace.SetJSONLogging(nil)
+	os.Setenv(envPropagationStyle, "jaeger")
+	config = NewConfiguration()
+	assert.IsType(&TextMapPropagator{}, config.TextMapPropagator)
+	assert.Contains(buf.String(), `"level":"warn","component":"opentracing"`)
+	assert.Contains(buf.String(), "ignoring the propagation styles")
+}
@@ -1,9 +1,12 @@
 package opentracing
 
 import (
+	"net/http"
 	"strconv"
 	"strings"
 
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+
 	ot "github.com/opentracing/opentracing-go"
 )
 
@@ -19,10 +22,23 @@ type Propagator interface {
 	Extract(carrier interface{}) (ot.SpanContext, error)
 }
 
+// InjectHTTP injects the context of the given span in the headers of the
+// given outgoing request, using the tracer of the span. It spares the
+// conversion of the headers to an opentracing.HTTPHeadersCarrier.
+func InjectHTTP(span ot.Span, req *http.Request) error {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	return span.Tracer().Inject(span.Context(), ot.HTTPHeaders, ot.HTTPHeadersCarrier(req.Header))
+}
+
 const (
 	defaultBaggageHeaderPrefix = "ot-baggage-"
 	defaultTraceIDHeader       = "x-datadog-trace-id"
 	defaultParentIDHeader      = "x-datadog-parent-id"
+	// samplingPriorityHeader propagates the sampling priority of the trace,
+	// so that the downstream services follow the decision made upstream.
+	samplingPriorityHeader = "x-datadog-sampling-priority"
 )
 
 // NewTextMapPropagator returns a new propagator which uses opentracing.TextMap
@@ -52,9 +68,16 @@ type TextMapPropagator struct {
 	parentHeader  string
 }
 
+// String describes the headers used by the propagator.
+func (p *TextMapPropagator) String() string {
+	return "text_map (trace: " + p.traceHeader + ", parent: " + p.parentHeader + ", baggage prefix: " + p.baggagePrefix + ")"
+}
+
 // Inject defines the TextMapPropagator to propagate SpanContext data
 // out of the current process. The implementation propagates the
-// TraceID and the current active SpanID, as well as the Span baggage.
+// TraceID and the current active SpanID, as well as the sampling priority of
+// the trace, the Span baggage, the trace-level tags and the W3C tracestate
+// members of other vendors received upstream.
 func (p *TextMapPropagator) Inject(context ot.SpanContext, carrier interface{}) error {
 	ctx, ok := context.(SpanContext)
 	if !ok {
@@ -68,11 +91,40 @@ func (p *TextMapPropagator) Inject(context ot.SpanContext, carrier interface{})
 	// propagate the TraceID and the current active SpanID
 	writer.Set(p.traceHeader, strconv.FormatUint(ctx.traceID, 10))
 	writer.Set(p.parentHeader, strconv.FormatUint(ctx.spanID, 10))
+	if priority, ok := ctx.samplingPriority(); ok {
+		writer.Set(samplingPriorityHeader, strconv.Itoa(priority))
+	}
 
 	// propagate OpenTracing baggage
 	for k, v := range ctx.baggage {
 		writer.Set(p.baggagePrefix+k, v)
 	}
+
+	// propagate the trace-level tags, including those the tracer set on the
+	// local root, such as the sampling decision maker
+	tags := ctx.tags
+	if ctx.span != nil {
+		if tags == nil {
+			tags = &traceTags{}
+		}
+		for k, v := range ctx.span.Span.PropagatedTags() {
+			tags.set(k, v)
+		}
+	}
+	if tags != nil {
+		if header, ok := tags.encode(); !ok {
+			if ctx.span != nil {
+				ctx.span.Span.SetMeta(propagationErrorTag, "inject_max_size")
+			}
+		} else if header != "" {
+			writer.Set(defaultTraceTagsHeader, header)
+		}
+	}
+
+	// pass the W3C trace state of other vendors through, if any
+	if len(ctx.tracestate) > 0 {
+		writer.Set(tracestateHeader, formatTracestate(ctx))
+	}
 	return nil
 }
 
@@ -84,6 +136,10 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 	}
 	var err error
 	var traceID, parentID uint64
+	var priority int
+	var hasPriority bool
+	var tracestate []string
+	var tags *traceTags
 	decodedBaggage := make(map[string]string)
 
 	// extract SpanContext fields
@@ -99,6 +155,15 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 			if err != nil {
 				return ot.ErrSpanContextCorrupted
 			}
+		case samplingPriorityHeader:
+			// an invalid priority leaves the decision to this service
+			if n, err := strconv.Atoi(v); err == nil {
+				priority, hasPriority = n, true
+			}
+		case defaultTraceTagsHeader:
+			tags = decodeTraceTags(v)
+		case tracestateHeader:
+			tracestate = append(tracestate, parseTracestate(v)...)
 		default:
 			lowercaseK := strings.ToLower(k)
 			if strings.HasPrefix(lowercaseK, p.baggagePrefix) {
@@ -117,9 +182,18 @@ func (p *TextMapPropagator) Extract(carrier interface{}) (ot.SpanContext, error)
 		return nil, ot.ErrSpanContextNotFound
 	}
 
-	return SpanContext{
-		traceID: traceID,
-		spanID:  parentID,
-		baggage: decodedBaggage,
-	}, nil
+	ctx := SpanContext{
+		traceID:     traceID,
+		spanID:      parentID,
+		baggage:     decodedBaggage,
+		tracestate:  tracestate,
+		tags:        tags,
+		priority:    priority,
+		hasPriority: hasPriority,
+	}
+	if tags != nil {
+		// the upper bits of 128-bit trace IDs are sent as a trace-level tag
+		ctx.traceIDHigh, _ = ddtrace.ParseTraceIDHigh(tags.all()[traceIDHighTag])
+	}
+	return ctx, nil
 }
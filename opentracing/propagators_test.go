@@ -1,10 +1,14 @@
 package opentracing
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
 
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +83,228 @@ func TestTracerTextMapPropagationHeader(t *testing.T) {
 	assert.Equal(headers.Get("pid"), pid)
 	assert.Equal(headers.Get("bg-item"), "x")
 }
+
+func TestTracerSamplingPriorityPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _, _ := NewTracer(NewConfiguration())
+	root := tracer.StartSpan("web.request").(*Span)
+	ddtrace.KeepTrace(root.Span)
+	headers := http.Header{}
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+	assert.Equal(strconv.Itoa(ext.PriorityUserKeep), headers.Get("x-datadog-sampling-priority"))
+
+	// the downstream service follows the decision
+	downstream, _, _ := NewTracer(NewConfiguration())
+	propagated, err := downstream.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	span := downstream.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(ext.PriorityUserKeep, span.Span.GetSamplingPriority())
+	assert.True(span.Span.Sampled)
+
+	// an invalid priority is ignored
+	headers.Set("x-datadog-sampling-priority", "keep")
+	propagated, err = downstream.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	_, ok := propagated.(SpanContext).samplingPriority()
+	assert.False(ok)
+}
+
+func TestTracerTracestatePassThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	headers := http.Header{}
+	headers.Set("x-datadog-trace-id", "42")
+	headers.Set("x-datadog-parent-id", "24")
+	headers.Set("tracestate", "rojo=00f067aa0ba902b7, dd=s:1;p:0000000000000018,congo=t61rcWkgMzE")
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	assert.Equal([]string{"rojo=00f067aa0ba902b7", "congo=t61rcWkgMzE"}, propagated.(SpanContext).tracestate)
+
+	// the trace state is passed to the children and re-emitted downstream,
+	// only the Datadog member being updated
+	root := tracer.StartSpan("web.request", opentracing.ChildOf(propagated))
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context())).(*Span)
+	child.Span.SetSamplingPriority(2)
+
+	out := http.Header{}
+	err = tracer.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out))
+	assert.Nil(err)
+	assert.Equal("dd=s:2;p:"+fmt.Sprintf("%016x", child.Span.SpanID)+",rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", out.Get("tracestate"))
+}
+
+func TestTracerTracestateAbsent(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request")
+	headers := http.Header{}
+	err := tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal("", headers.Get("tracestate"))
+}
+
+func TestParseTracestate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(parseTracestate(""))
+	assert.Nil(parseTracestate("dd=s:1"))
+	assert.Equal([]string{"a=1", "b=2"}, parseTracestate("a=1,,=x,c=, dd=s:0 ,b=2"))
+}
+
+func TestFormatTracestateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	var members []string
+	for i := 0; i < 40; i++ {
+		members = append(members, "v"+strconv.Itoa(i)+"=x")
+	}
+	header := formatTracestate(SpanContext{spanID: 255, tracestate: members})
+	assert.Equal(maxTracestateMembers, len(strings.Split(header, ",")))
+	assert.True(strings.HasPrefix(header, "dd=p:00000000000000ff,v0=x,"))
+}
+
+func TestTracerDecisionMakerPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _, _ := NewTracer(NewConfiguration())
+	root := tracer.StartSpan("web.request").(*Span)
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context()))
+	dm := root.Span.GetMeta("_dd.p.dm")
+	assert.NotEqual("", dm)
+
+	// the decision maker set by the tracer on the local root is propagated
+	headers := http.Header{}
+	err := tracer.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Contains(strings.Split(headers.Get("x-datadog-tags"), ","), "_dd.p.dm="+dm)
+}
+
+func TestTracerTraceTagsPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	headers := http.Header{}
+	headers.Set("x-datadog-trace-id", "42")
+	headers.Set("x-datadog-parent-id", "24")
+	headers.Set("x-datadog-tags", "_dd.p.upstream_services=api|1|1,other=dropped,_dd.p.dm=-1")
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+
+	// the tags are set on the local root span
+	root := tracer.StartSpan("web.request", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal("api|1|1", root.Span.GetMeta("_dd.p.upstream_services"))
+	assert.Equal("-1", root.Span.GetMeta("_dd.p.dm"))
+	assert.Equal("", root.Span.GetMeta("other"))
+
+	// trace-level tags set on any span of the trace are propagated downstream
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context())).(*Span)
+	child.SetTag("_dd.p.team", "intake")
+	child.SetTag("local", "not propagated")
+
+	out := http.Header{}
+	err = tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out))
+	assert.Nil(err)
+	tags := decodeTraceTags(out.Get("x-datadog-tags")).all()
+	assert.Equal(map[string]string{
+		"_dd.p.upstream_services": "api|1|1",
+		"_dd.p.dm":                "-1",
+		"_dd.p.team":              "intake",
+	}, tags)
+}
+
+func TestTracerTraceID128Propagation(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+	tracer.(*Tracer).impl.SetTraceID128Generation(true)
+
+	// the upper bits of the trace ID are sent with the trace-level tags
+	root := tracer.StartSpan("web.request").(*Span)
+	high := root.Span.TraceIDHigh()
+	assert.NotEqual(uint64(0), high)
+	headers := http.Header{}
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+	assert.Equal(fmt.Sprintf("%016x", high), decodeTraceTags(headers.Get("x-datadog-tags")).all()[traceIDHighTag])
+
+	// and the trace continues downstream with the same 128-bit ID
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	assert.Equal(high, propagated.(SpanContext).traceIDHigh)
+	remote := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(high, remote.Span.TraceIDHigh())
+	assert.Equal(root.Span.TraceIDHex(), remote.Span.TraceIDHex())
+
+	// a trace with a 64-bit ID keeps it downstream
+	headers = http.Header{}
+	headers.Set("x-datadog-trace-id", "42")
+	headers.Set("x-datadog-parent-id", "24")
+	headers.Set("x-datadog-tags", "_dd.p.tid=malformed")
+	propagated, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	remote = tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(uint64(0), remote.Span.TraceIDHigh())
+	assert.Equal("", remote.Span.GetMeta("_dd.p.tid"))
+}
+
+func TestTracerTraceTagsMaxSize(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	headers := http.Header{}
+	err := tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal("_dd.p.dm="+root.Span.GetMeta("_dd.p.dm"), headers.Get("x-datadog-tags"))
+
+	root.SetTag("_dd.p.big", strings.Repeat("x", maxTraceTagsHeaderSize))
+	headers = http.Header{}
+	err = tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	_, found := headers["X-Datadog-Tags"]
+	assert.False(found)
+	assert.Equal("inject_max_size", root.Span.GetMeta("_dd.propagation_error"))
+
+	// oversized headers are ignored on extraction
+	assert.Len(decodeTraceTags("_dd.p.big="+strings.Repeat("x", maxTraceTagsHeaderSize)).all(), 0)
+}
+
+func TestInjectHTTP(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	req := &http.Request{}
+	assert.Nil(root.InjectHTTP(req))
+	assert.Equal(strconv.FormatUint(root.Span.TraceID, 10), req.Header.Get("x-datadog-trace-id"))
+	assert.Equal(strconv.FormatUint(root.Span.SpanID, 10), req.Header.Get("x-datadog-parent-id"))
+
+	// the propagated context can be extracted on the other side
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+	assert.Nil(err)
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(root.Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+
+	// any opentracing span can be used with the package-level helper
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	var span opentracing.Span = child
+	assert.Nil(InjectHTTP(span, req))
+	assert.Equal(strconv.FormatUint(child.Span.SpanID, 10), req.Header.Get("x-datadog-parent-id"))
+}
@@ -2,7 +2,7 @@ package opentracing
 
 import (
 	"fmt"
-	"time"
+	"net/http"
 
 	ddtrace "github.com/DataDog/dd-trace-go/tracer"
 	ot "github.com/opentracing/opentracing-go"
@@ -29,9 +29,17 @@ func (s *Span) Context() ot.SpanContext {
 	return s.context
 }
 
+// InjectHTTP injects the context of this Span in the headers of the given
+// outgoing request, using the propagator of its tracer.
+func (s *Span) InjectHTTP(req *http.Request) error {
+	return InjectHTTP(s, req)
+}
+
 // SetBaggageItem sets a key:value pair on this Span and its SpanContext
 // that also propagates to descendants of this Span.
 func (s *Span) SetBaggageItem(key, val string) ot.Span {
+	s.Span.SetBaggageItem(key, val)
+
 	s.Span.Lock()
 	defer s.Span.Unlock()
 
@@ -77,6 +85,10 @@ func (s *Span) SetTag(key string, value interface{}) ot.Span {
 		// NOTE: locking is not required because the `SetMeta` is
 		// already thread-safe
 		s.Span.SetMeta(key, fmt.Sprint(value))
+		if isTraceTag(key) && s.context.tags != nil {
+			// trace-level tags are propagated to downstream processes
+			s.context.tags.set(key, fmt.Sprint(value))
+		}
 	}
 	return s
 }
@@ -85,9 +97,9 @@ func (s *Span) SetTag(key string, value interface{}) ot.Span {
 // timestamps and log data.
 func (s *Span) FinishWithOptions(options ot.FinishOptions) {
 	if options.FinishTime.IsZero() {
-		options.FinishTime = time.Now().UTC()
+		s.Span.Finish()
+		return
 	}
-
 	s.Span.FinishWithTime(options.FinishTime.UnixNano())
 }
 
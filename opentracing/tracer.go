@@ -2,10 +2,11 @@ package opentracing
 
 import (
 	"errors"
+	"fmt"
 	"io"
-	"time"
 
 	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 	ot "github.com/opentracing/opentracing-go"
 )
 
@@ -33,12 +34,8 @@ func (t *Tracer) StartSpan(operationName string, options ...ot.StartSpanOption)
 }
 
 func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpanOptions) ot.Span {
-	if options.StartTime.IsZero() {
-		options.StartTime = time.Now().UTC()
-	}
-
 	var context SpanContext
-	var hasParent bool
+	var hasParent, followsFrom bool
 	var parent *Span
 	var span *ddtrace.Span
 
@@ -50,14 +47,27 @@ func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpan
 		}
 
 		// if we have parenting define it
-		if ref.Type == ot.ChildOfRef {
+		switch ref.Type {
+		case ot.ChildOfRef:
 			hasParent = true
+			followsFrom = false
 			context = ctx
 			parent = ctx.span
+		case ot.FollowsFromRef:
+			if !hasParent {
+				// a ChildOf reference takes precedence
+				hasParent = true
+				followsFrom = true
+				context = ctx
+				parent = ctx.span
+			}
 		}
 	}
 
-	if parent == nil {
+	if parent != nil && followsFrom {
+		// the span is caused by its parent, which doesn't wait for it
+		span = t.impl.NewFollowsFromSpan(operationName, parent.Span)
+	} else if parent == nil {
 		// create a root Span with the default service name and resource
 		span = t.impl.NewRootSpan(operationName, t.config.ServiceName, operationName)
 
@@ -67,7 +77,30 @@ func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpan
 			// values manually
 			span.TraceID = context.traceID
 			span.ParentID = context.spanID
-			t.impl.Sample(span)
+			span.SetTraceIDHigh(context.traceIDHigh)
+		}
+		if context.hasPriority {
+			// the sampling decision was made upstream
+			t.impl.SampleWithPriority(span, context.priority)
+		} else if hasParent || len(options.Tags) > 0 {
+			// sample again, knowing where the trace comes from and how
+			// the span is started
+			t.impl.SampleWithParams(span, ddtrace.SamplingParams{
+				Remote:  hasParent,
+				Baggage: context.baggage,
+				Tags:    options.Tags,
+			})
+		}
+		// the baggage is set once sampled, as the ext.DebugBaggage item
+		// keeps the trace whatever was decided upstream
+		for k, v := range context.baggage {
+			span.SetBaggageItem(k, v)
+		}
+		if context.origin != "" {
+			span.SetMeta(ext.Origin, context.origin)
+		}
+		if followsFrom {
+			span.SetMeta(ext.SpanRelationship, ext.RelationshipFollowsFrom)
 		}
 	} else {
 		// create a child Span that inherits from a parent
@@ -79,25 +112,54 @@ func (t *Tracer) startSpanWithOptions(operationName string, options ot.StartSpan
 	otSpan := &Span{
 		Span: span,
 		context: SpanContext{
-			traceID:  span.TraceID,
-			spanID:   span.SpanID,
-			parentID: span.ParentID,
-			sampled:  span.Sampled,
+			traceID:     span.TraceID,
+			traceIDHigh: span.TraceIDHigh(),
+			spanID:      span.SpanID,
+			parentID:    span.ParentID,
+			sampled:     span.Sampled,
 		},
 		tracer: t,
 	}
 	otSpan.context.span = otSpan
+	if hasParent {
+		// the trace state of other vendors and the origin belong to the
+		// whole trace
+		otSpan.context.tracestate = context.tracestate
+		otSpan.context.origin = context.origin
+	}
+	if hasParent && context.tags != nil {
+		otSpan.context.tags = context.tags
+	} else {
+		otSpan.context.tags = &traceTags{}
+	}
+	if parent == nil && hasParent {
+		// the trace-level tags received from upstream go on the local root
+		for k, v := range otSpan.context.tags.all() {
+			if k != traceIDHighTag {
+				span.SetMeta(k, v)
+			}
+		}
+	}
+	if high := otSpan.context.traceIDHigh; parent == nil && high != 0 {
+		// downstream Datadog processes get the upper bits of the trace ID
+		// with the trace-level tags
+		otSpan.context.tags.set(traceIDHighTag, formatB3ID(high))
+	}
 
-	// set start time
-	otSpan.Span.Start = options.StartTime.UnixNano()
+	// set start time, the span started now otherwise
+	if !options.StartTime.IsZero() {
+		otSpan.Span.Start = options.StartTime.UnixNano()
+	}
 
+	// propagate baggage items, received upstream for remote parents
+	baggage := context.baggage
 	if parent != nil {
-		// propagate baggage items
-		if l := len(parent.context.baggage); l > 0 {
-			otSpan.context.baggage = make(map[string]string, len(parent.context.baggage))
-			for k, v := range parent.context.baggage {
-				otSpan.context.baggage[k] = v
-			}
+		baggage = parent.context.baggage
+	}
+	if hasParent && len(baggage) > 0 {
+		otSpan.context.baggage = make(map[string]string, len(baggage))
+		for k, v := range baggage {
+			otSpan.context.baggage[k] = v
 		}
 	}
 
@@ -136,6 +198,25 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (ot.SpanContex
 	return nil, ot.ErrUnsupportedFormat
 }
 
+// Config returns the configuration the tracer actually uses, so that it can
+// be displayed by debug endpoints or ops tooling.
+func (t *Tracer) Config() EffectiveConfiguration {
+	cfg := EffectiveConfiguration{
+		Config:      t.impl.Config(),
+		ServiceName: t.config.ServiceName,
+		GlobalTags:  make(map[string]interface{}, len(t.config.GlobalTags)),
+	}
+	for k, v := range t.config.GlobalTags {
+		cfg.GlobalTags[k] = v
+	}
+	if s, ok := t.config.TextMapPropagator.(fmt.Stringer); ok {
+		cfg.Propagator = s.String()
+	} else {
+		cfg.Propagator = fmt.Sprintf("%T", t.config.TextMapPropagator)
+	}
+	return cfg
+}
+
 // Close method implements `io.Closer` interface to graceful shutdown the Datadog
 // Tracer. Note that this is a blocking operation that waits for the flushing Go
 // routine.
@@ -159,6 +240,12 @@ func NewTracer(config *Configuration) (ot.Tracer, io.Closer, error) {
 		return &ot.NoopTracer{}, &noopCloser{}, nil
 	}
 
+	if config.TextMapPropagator == nil || config.TextMapPropagator == config.defaultPropagator {
+		// the propagation styles may have been changed since
+		config.TextMapPropagator = stylesPropagator(config.PropagationStyleInject, config.PropagationStyleExtract)
+		config.defaultPropagator = config.TextMapPropagator
+	}
+
 	// configure a Datadog Tracer
 	transport := ddtrace.NewTransport(config.AgentHostname, config.AgentPort)
 	tracer := &Tracer{
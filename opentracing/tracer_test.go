@@ -1,6 +1,7 @@
 package opentracing
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -56,6 +57,32 @@ func TestTracerStartChildSpan(t *testing.T) {
 	assert.Equal(tRoot.Span.TraceID, tChild.Span.ParentID)
 }
 
+func TestTracerStartFollowsFromSpan(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	span := tracer.StartSpan("cache.warmup", opentracing.FollowsFrom(root.Context())).(*Span)
+	assert.Equal(root.Span.TraceID, span.Span.TraceID)
+	assert.Equal(root.Span.SpanID, span.Span.ParentID)
+	assert.Equal("follows_from", span.Span.GetMeta("span.relationship"))
+
+	// a ChildOf reference takes precedence
+	child := tracer.StartSpan("db.query", opentracing.FollowsFrom(span.Context()), opentracing.ChildOf(root.Context())).(*Span)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+	assert.Equal("", child.Span.GetMeta("span.relationship"))
+
+	// remote contexts can be followed too
+	var remote SpanContext
+	assert.Nil(remote.UnmarshalText([]byte("123:456")))
+	span = tracer.StartSpan("job.run", opentracing.FollowsFrom(remote)).(*Span)
+	assert.Equal(uint64(123), span.Span.TraceID)
+	assert.Equal(uint64(456), span.Span.ParentID)
+	assert.Equal("follows_from", span.Span.GetMeta("span.relationship"))
+}
+
 func TestTracerBaggagePropagation(t *testing.T) {
 	assert := assert.New(t)
 
@@ -69,6 +96,28 @@ func TestTracerBaggagePropagation(t *testing.T) {
 	assert.True(ok)
 
 	assert.Equal("value", context.baggage["key"])
+	assert.Equal("value", child.(*Span).Span.BaggageItem("key"))
+}
+
+func TestTracerBaggageRemotePropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request")
+	root.SetBaggageItem("user", "bob")
+	headers := http.Header{}
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+	assert.Equal("bob", headers.Get("ot-baggage-user"))
+
+	// the baggage received upstream is set on the local root
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	remote := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal("bob", remote.BaggageItem("user"))
+	assert.Equal("bob", remote.Span.BaggageItem("user"))
 }
 
 func TestTracerBaggageImmutability(t *testing.T) {
@@ -129,3 +178,73 @@ func TestTracerSpanStartTime(t *testing.T) {
 
 	assert.Equal(startTime.UnixNano(), span.Span.Start)
 }
+
+// routeSampler keeps the traces coming from upstream with the given baggage
+// item, or started with the given HTTP route.
+type routeSampler struct {
+	baggage, route string
+}
+
+func (s routeSampler) Sample(params ddtrace.SamplingParams) bool {
+	if params.Remote {
+		_, ok := params.Baggage[s.baggage]
+		return ok
+	}
+	return params.Tags["http.route"] == s.route
+}
+
+func TestTracerCustomSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+	ddtrace.DefaultTracer.SetSampler(routeSampler{baggage: "debug", route: "/users/:id"})
+	defer ddtrace.DefaultTracer.SetSampler(nil)
+
+	span := tracer.StartSpan("web.request", opentracing.Tag{Key: "http.route", Value: "/users/:id"})
+	assert.True(span.(*Span).Sampled)
+	span = tracer.StartSpan("web.request", opentracing.Tag{Key: "http.route", Value: "/health"})
+	assert.False(span.(*Span).Sampled)
+	span = tracer.StartSpan("web.request")
+	assert.False(span.(*Span).Sampled)
+
+	for baggage, sampled := range map[string]bool{"debug": true, "other": false} {
+		root := tracer.StartSpan("web.request").SetBaggageItem(baggage, "1")
+		carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+		assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+		ctx, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+		assert.Nil(err)
+		span = tracer.StartSpan("web.request", opentracing.ChildOf(ctx))
+		assert.Equal(sampled, span.(*Span).Sampled, baggage)
+	}
+}
+
+func TestTracerBaggageDebug(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	tracer, _, _ := NewTracer(config)
+	tracer.(*Tracer).impl.SetDebugBaggage(true)
+
+	// the debug item received upstream keeps a trace dropped there
+	upstream := SpanContext{
+		traceID:     42,
+		spanID:      24,
+		priority:    0,
+		hasPriority: true,
+		baggage:     map[string]string{"dd.debug": "1"},
+	}
+	root := tracer.StartSpan("web.request", opentracing.ChildOf(upstream)).(*Span)
+	assert.True(root.Span.Sampled)
+	assert.Equal(2, root.Span.GetSamplingPriority())
+
+	// and goes downstream
+	headers := http.Header{}
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal("1", headers.Get("ot-baggage-dd.debug"))
+
+	// unless the tracer doesn't honor it
+	tracer.(*Tracer).impl.SetDebugBaggage(false)
+	root = tracer.StartSpan("web.request", opentracing.ChildOf(upstream)).(*Span)
+	assert.False(root.Span.Sampled)
+}
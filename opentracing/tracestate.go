@@ -0,0 +1,107 @@
+package opentracing
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// tracestateHeader is the W3C Trace Context header holding the
+	// vendor-specific trace state.
+	tracestateHeader = "tracestate"
+
+	// tracestateDatadogKey is the key of the Datadog tracestate member.
+	tracestateDatadogKey = "dd"
+
+	// maxTracestateMembers is the maximum number of members of a tracestate,
+	// as defined by the W3C Trace Context specification.
+	maxTracestateMembers = 32
+)
+
+// parseTracestate returns the members of the given tracestate header value
+// which belong to other vendors, in order. The Datadog member, empty and
+// malformed members are dropped.
+func parseTracestate(header string) []string {
+	var members []string
+	for _, m := range strings.Split(header, ",") {
+		m = strings.TrimSpace(m)
+		i := strings.IndexByte(m, '=')
+		if i <= 0 || i == len(m)-1 {
+			continue
+		}
+		if m[:i] == tracestateDatadogKey {
+			continue
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+// parseDatadogTracestate returns the sampling priority, if any, and the
+// origin held by the Datadog member of the given tracestate header value.
+func parseDatadogTracestate(header string) (priority int, hasPriority bool, origin string) {
+	for _, m := range strings.Split(header, ",") {
+		m = strings.TrimSpace(m)
+		if !strings.HasPrefix(m, tracestateDatadogKey+"=") {
+			continue
+		}
+		for _, field := range strings.Split(m[len(tracestateDatadogKey)+1:], ";") {
+			i := strings.IndexByte(field, ':')
+			if i < 0 {
+				continue
+			}
+			switch field[:i] {
+			case "s":
+				if p, err := strconv.Atoi(field[i+1:]); err == nil {
+					priority, hasPriority = p, true
+				}
+			case "o":
+				origin = field[i+1:]
+			}
+		}
+		break
+	}
+	return priority, hasPriority, origin
+}
+
+// datadogTracestate returns the value of the Datadog tracestate member for
+// the given context: its span ID as the last Datadog parent and, if known,
+// the sampling priority and the origin of the trace.
+func datadogTracestate(ctx SpanContext) string {
+	var fields []string
+	if priority, ok := ctx.samplingPriority(); ok {
+		fields = append(fields, "s:"+strconv.Itoa(priority))
+	}
+	if ctx.origin != "" {
+		fields = append(fields, "o:"+sanitizeTracestateValue(ctx.origin))
+	}
+	p := strconv.FormatUint(ctx.spanID, 16)
+	fields = append(fields, "p:"+strings.Repeat("0", 16-len(p))+p)
+	return strings.Join(fields, ";")
+}
+
+// sanitizeTracestateValue replaces the characters which can't appear in the
+// value of a field of the Datadog tracestate member with underscores.
+func sanitizeTracestateValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == ',' || r == ';' || r == '=' || r == '~' {
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+// formatTracestate returns the tracestate header value for the given context.
+// The Datadog member comes first, as it was updated last, followed by the
+// members of other vendors, untouched.
+func formatTracestate(ctx SpanContext) string {
+	members := ctx.tracestate
+	if len(members) > maxTracestateMembers-1 {
+		members = members[:maxTracestateMembers-1]
+	}
+	dd := tracestateDatadogKey + "=" + datadogTracestate(ctx)
+	if len(members) == 0 {
+		return dd
+	}
+	return dd + "," + strings.Join(members, ",")
+}
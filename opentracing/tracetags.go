@@ -0,0 +1,109 @@
+package opentracing
+
+import (
+	"strings"
+	"sync"
+
+	ddtrace "github.com/DataDog/dd-trace-go/tracer"
+)
+
+const (
+	// defaultTraceTagsHeader is the header propagating the trace-level tags.
+	defaultTraceTagsHeader = "x-datadog-tags"
+
+	// traceTagPrefix is the prefix of the tags which are propagated to
+	// downstream processes, such as the sampling decision maker or the
+	// upstream services.
+	traceTagPrefix = "_dd.p."
+
+	// maxTraceTagsHeaderSize is the maximum size of the trace tags header,
+	// above which the tags are not propagated.
+	maxTraceTagsHeaderSize = 512
+
+	// propagationErrorTag is set on a span whose trace-level tags could not
+	// be propagated.
+	propagationErrorTag = "_dd.propagation_error"
+
+	// traceIDHighTag holds the 64 upper bits of 128-bit trace IDs, in 16
+	// lowercase hexadecimal characters.
+	traceIDHighTag = "_dd.p.tid"
+)
+
+// traceTags holds the trace-level tags propagated to downstream processes. It
+// is shared by all the spans of a trace in this process.
+type traceTags struct {
+	sync.RWMutex
+	tags map[string]string
+}
+
+// set sets a trace-level tag.
+func (t *traceTags) set(key, value string) {
+	t.Lock()
+	if t.tags == nil {
+		t.tags = make(map[string]string)
+	}
+	t.tags[key] = value
+	t.Unlock()
+}
+
+// all returns a copy of the trace-level tags.
+func (t *traceTags) all() map[string]string {
+	t.RLock()
+	defer t.RUnlock()
+	tags := make(map[string]string, len(t.tags))
+	for k, v := range t.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// encode returns the header value holding the tags, in the form
+// "key1=value1,key2=value2". It returns false if they can't be propagated
+// because the header would be too large.
+func (t *traceTags) encode() (string, bool) {
+	t.RLock()
+	defer t.RUnlock()
+	pairs := make([]string, 0, len(t.tags))
+	for k, v := range t.tags {
+		if strings.ContainsAny(k, "=,") || strings.ContainsRune(v, ',') {
+			continue
+		}
+		pairs = append(pairs, k+"="+v)
+	}
+	header := strings.Join(pairs, ",")
+	if len(header) > maxTraceTagsHeaderSize {
+		return "", false
+	}
+	return header, true
+}
+
+// decodeTraceTags returns the trace-level tags held by the given header
+// value. Tags which are not meant to be propagated or are malformed are
+// dropped, as well as all of them if the header is too large.
+func decodeTraceTags(header string) *traceTags {
+	t := &traceTags{}
+	if len(header) > maxTraceTagsHeaderSize {
+		return t
+	}
+	for _, pair := range strings.Split(header, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if !strings.HasPrefix(key, traceTagPrefix) || value == "" {
+			continue
+		}
+		if _, ok := ddtrace.ParseTraceIDHigh(value); key == traceIDHighTag && !ok {
+			continue
+		}
+		t.set(key, value)
+	}
+	return t
+}
+
+// isTraceTag reports whether the given tag is a trace-level tag, propagated
+// to downstream processes.
+func isTraceTag(key string) bool {
+	return strings.HasPrefix(key, traceTagPrefix)
+}
@@ -0,0 +1,135 @@
+package opentracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ot "github.com/opentracing/opentracing-go"
+)
+
+// traceparentHeader is the W3C Trace Context header holding the IDs of the
+// trace and of the parent span, and whether the trace is sampled.
+const traceparentHeader = "traceparent"
+
+// W3CPropagator propagates the SpanContext in the W3C Trace Context headers,
+// traceparent and tracestate, so that traces continue across the services
+// instrumented with OpenTelemetry or any other tracer following the W3C
+// specification. The sampling priority and the origin of the trace travel in
+// the "dd" member of tracestate, and the members of other vendors are passed
+// through. The 128-bit trace IDs received upstream are kept whole, see
+// tracer.SetTraceID128Generation.
+type W3CPropagator struct{}
+
+// NewW3CPropagator returns a new propagator using the W3C Trace Context
+// headers.
+func NewW3CPropagator() *W3CPropagator {
+	return &W3CPropagator{}
+}
+
+// String describes the headers used by the propagator.
+func (p *W3CPropagator) String() string {
+	return "tracecontext (traceparent, tracestate)"
+}
+
+// Inject implements Propagator.
+func (p *W3CPropagator) Inject(context ot.SpanContext, carrier interface{}) error {
+	ctx, ok := context.(SpanContext)
+	if !ok || ctx.traceID == 0 || ctx.spanID == 0 {
+		return ot.ErrInvalidSpanContext
+	}
+	writer, ok := carrier.(ot.TextMapWriter)
+	if !ok {
+		return ot.ErrInvalidCarrier
+	}
+	var flags int
+	if priority, ok := ctx.samplingPriority(); ok && priority > 0 || !ok && ctx.sampled {
+		flags = 1
+	}
+	writer.Set(traceparentHeader, fmt.Sprintf("00-%016x%016x-%016x-%02x", ctx.traceIDHigh, ctx.traceID, ctx.spanID, flags))
+	writer.Set(tracestateHeader, formatTracestate(ctx))
+	return nil
+}
+
+// Extract implements Propagator.
+func (p *W3CPropagator) Extract(carrier interface{}) (ot.SpanContext, error) {
+	reader, ok := carrier.(ot.TextMapReader)
+	if !ok {
+		return nil, ot.ErrInvalidCarrier
+	}
+	var traceparent, tracestate string
+	err := reader.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case traceparentHeader:
+			traceparent = v
+		case tracestateHeader:
+			// the header may be split over several fields
+			if tracestate != "" {
+				tracestate += ","
+			}
+			tracestate += v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if traceparent == "" {
+		return nil, ot.ErrSpanContextNotFound
+	}
+	ctx, sampled, err := parseTraceparent(traceparent)
+	if err != nil {
+		return nil, err
+	}
+	ctx.tracestate = parseTracestate(tracestate)
+	ctx.sampled = sampled
+	ctx.priority, ctx.hasPriority = 0, true
+	if sampled {
+		ctx.priority = 1
+	}
+	priority, hasPriority, origin := parseDatadogTracestate(tracestate)
+	ctx.origin = origin
+	// the sampled flag wins when they disagree, as it may have been updated
+	// by the tracer of another vendor since
+	if hasPriority && sampled == (priority > 0) {
+		ctx.priority = priority
+	}
+	return ctx, nil
+}
+
+// parseTraceparent returns the context held by the given traceparent header
+// value, and whether the trace is sampled.
+func parseTraceparent(header string) (SpanContext, bool, error) {
+	header = strings.TrimSpace(header)
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	if parts[0] == "ff" || parts[0] == "00" && len(parts) != 4 || header != strings.ToLower(header) {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	if _, err := strconv.ParseUint(parts[0], 16, 8); err != nil {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	high, err := strconv.ParseUint(parts[1][:16], 16, 64)
+	if err != nil {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	traceID, err := strconv.ParseUint(parts[1][16:], 16, 64)
+	if err != nil || traceID == 0 && high == 0 {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil || spanID == 0 {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return SpanContext{}, false, ot.ErrSpanContextCorrupted
+	}
+	if traceID == 0 {
+		// the lower 64 bits are the trace ID of the spans
+		return SpanContext{}, false, ot.ErrSpanContextNotFound
+	}
+	return SpanContext{traceID: traceID, traceIDHigh: high, spanID: spanID}, flags&1 == 1, nil
+}
@@ -0,0 +1,102 @@
+package opentracing
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestW3CPropagatorInjectExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	config.TextMapPropagator = NewW3CPropagator()
+	tracer, _, _ := NewTracer(config)
+
+	root := tracer.StartSpan("web.request").(*Span)
+	root.Span.SetSamplingPriority(2)
+	headers := http.Header{}
+	carrier := opentracing.HTTPHeadersCarrier(headers)
+	assert.Nil(tracer.Inject(root.Context(), opentracing.HTTPHeaders, carrier))
+	assert.Equal(fmt.Sprintf("00-%032x-%016x-01", root.Span.TraceID, root.Span.SpanID), headers.Get("traceparent"))
+	assert.Equal(fmt.Sprintf("dd=s:2;p:%016x", root.Span.SpanID), headers.Get("tracestate"))
+
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, carrier)
+	assert.Nil(err)
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(propagated)).(*Span)
+	assert.Equal(root.Span.TraceID, child.Span.TraceID)
+	assert.Equal(root.Span.SpanID, child.Span.ParentID)
+	assert.Equal(2, child.Span.GetSamplingPriority())
+	assert.True(child.Span.Sampled)
+}
+
+func TestW3CPropagatorExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfiguration()
+	config.TextMapPropagator = NewW3CPropagator()
+	tracer, _, _ := NewTracer(config)
+
+	headers := http.Header{}
+	headers.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	headers.Set("tracestate", "rojo=00f067aa0ba902b7,dd=s:-1;o:synthetics;p:0000000000000018")
+	propagated, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	ctx := propagated.(SpanContext)
+	assert.Equal(uint64(0xa3ce929d0e0e4736), ctx.traceID)
+	assert.Equal(uint64(0x4bf92f3577b34da6), ctx.traceIDHigh)
+	assert.Equal(uint64(0x00f067aa0ba902b7), ctx.spanID)
+	assert.Equal(-1, ctx.priority)
+	assert.Equal("synthetics", ctx.origin)
+	assert.Equal([]string{"rojo=00f067aa0ba902b7"}, ctx.tracestate)
+
+	// the decision made upstream is kept, and the origin set on the root
+	root := tracer.StartSpan("web.request", opentracing.ChildOf(propagated)).(*Span)
+	assert.False(root.Span.Sampled)
+	assert.Equal(-1, root.Span.GetSamplingPriority())
+	assert.Equal("synthetics", root.Span.GetMeta("_dd.origin"))
+	assert.Equal("4bf92f3577b34da6", root.Span.GetMeta("_dd.p.tid"))
+
+	// and propagated downstream
+	child := tracer.StartSpan("db.query", opentracing.ChildOf(root.Context())).(*Span)
+	out := http.Header{}
+	assert.Nil(tracer.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out)))
+	assert.Equal(fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%016x-00", child.Span.SpanID), out.Get("traceparent"))
+	assert.Equal(fmt.Sprintf("dd=s:-1;o:synthetics;p:%016x,rojo=00f067aa0ba902b7", child.Span.SpanID), out.Get("tracestate"))
+
+	// the sampled flag wins over a Datadog priority which disagrees
+	headers.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	propagated, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	assert.Nil(err)
+	assert.Equal(1, propagated.(SpanContext).priority)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	assert := assert.New(t)
+
+	for header, err := range map[string]error{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       nil,
+		" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ":     nil,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-later": nil,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-later": opentracing.ErrSpanContextCorrupted,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       opentracing.ErrSpanContextCorrupted,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01":       opentracing.ErrSpanContextCorrupted,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":       opentracing.ErrSpanContextCorrupted,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":       opentracing.ErrSpanContextCorrupted,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":          opentracing.ErrSpanContextCorrupted,
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01":                       opentracing.ErrSpanContextCorrupted,
+		"00-4bf92f3577b34da60000000000000000-00f067aa0ba902b7-01":       opentracing.ErrSpanContextNotFound,
+	} {
+		_, _, e := parseTraceparent(header)
+		assert.Equal(err, e, header)
+	}
+}
+
+func TestSanitizeTracestateValue(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("a_b_c_d_e", sanitizeTracestateValue("a,b;c=d~e"))
+	assert.Equal("rum", sanitizeTracestateValue("rum"))
+}
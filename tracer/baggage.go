@@ -0,0 +1,82 @@
+package tracer
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// SetBaggageItem sets a baggage item of the span: a key/value pair which is
+// inherited by the children of the span created afterwards, unlike meta. It
+// is propagated to the downstream services by the Datadog propagator of the
+// opentracing package only, as "ot-baggage-" prefixed headers: the W3C and
+// B3 propagators and the integrations of the contrib packages propagate the
+// IDs of the trace alone. Propagated baggage is sent with every request, so
+// it should be kept small. Setting
+// the ext.DebugBaggage item keeps the trace, see KeepTrace, when it is
+// enabled with SetDebugBaggage.
+func (s *Span) SetBaggageItem(key, value string) {
+	if s == nil {
+		return
+	}
+	if key == ext.DebugBaggage && s.tracer != nil && s.tracer.DebugBaggageEnabled() {
+		if debug, _ := strconv.ParseBool(value); debug {
+			defer KeepTrace(s)
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	// the map may be shared with the parent and the children of the span
+	baggage := make(map[string]string, len(s.baggage)+1)
+	for k, v := range s.baggage {
+		baggage[k] = v
+	}
+	baggage[key] = value
+	s.baggage = baggage
+}
+
+// BaggageItem returns the value of the baggage item of the span with the
+// given key, or the empty string if it isn't set.
+func (s *Span) BaggageItem(key string) string {
+	if s == nil {
+		return ""
+	}
+	s.RLock()
+	defer s.RUnlock()
+	return s.baggage[key]
+}
+
+// ForeachBaggageItem calls handler with each baggage item of the span, until
+// it returns false. The span must not be changed by handler.
+func (s *Span) ForeachBaggageItem(handler func(k, v string) bool) {
+	if s == nil {
+		return
+	}
+	s.RLock()
+	baggage := s.baggage
+	s.RUnlock()
+	for k, v := range baggage {
+		if !handler(k, v) {
+			break
+		}
+	}
+}
+
+// SetDebugBaggage makes the ext.DebugBaggage baggage item keep the traces it
+// is set on, whatever the sampler decides, including the ones continued from
+// upstream services. It is disabled by default, as any client sending the
+// item could otherwise have all its requests traced.
+func (t *Tracer) SetDebugBaggage(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&t.debugBaggage, 1)
+	} else {
+		atomic.StoreUint32(&t.debugBaggage, 0)
+	}
+}
+
+// DebugBaggageEnabled returns true if the ext.DebugBaggage baggage item keeps
+// the traces it is set on.
+func (t *Tracer) DebugBaggageEnabled() bool {
+	return atomic.LoadUint32(&t.debugBaggage) == 1
+}
@@ -0,0 +1,106 @@
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func TestSpanBaggage(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetBaggageItem("user", "bob")
+	assert.Equal("bob", root.BaggageItem("user"))
+	assert.Equal("", root.BaggageItem("tier"))
+	_, found := root.Meta["user"]
+	assert.False(found)
+
+	// children inherit the baggage, without changing their parent's
+	child := tracer.NewChildSpan("redis.command", root)
+	child.SetBaggageItem("tier", "1")
+	assert.Equal("bob", child.BaggageItem("user"))
+	assert.Equal("1", child.BaggageItem("tier"))
+	assert.Equal("", root.BaggageItem("tier"))
+	assert.Equal("bob", tracer.NewFollowsFromSpan("async.job", child).BaggageItem("user"))
+
+	// items set afterwards aren't
+	root.SetBaggageItem("user", "alice")
+	assert.Equal("bob", child.BaggageItem("user"))
+
+	items := make(map[string]string)
+	child.ForeachBaggageItem(func(k, v string) bool {
+		items[k] = v
+		return true
+	})
+	assert.Equal(map[string]string{"user": "bob", "tier": "1"}, items)
+
+	var n int
+	child.ForeachBaggageItem(func(k, v string) bool {
+		n++
+		return false
+	})
+	assert.Equal(1, n)
+
+	var nilSpan *Span
+	nilSpan.SetBaggageItem("user", "bob")
+	assert.Equal("", nilSpan.BaggageItem("user"))
+}
+
+func TestSpanBaggageDropped(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	// the baggage goes downstream even if the trace is dropped
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetBaggageItem("user", "bob")
+	assert.Equal("bob", tracer.NewChildSpan("redis.command", root).BaggageItem("user"))
+}
+
+func TestSpanBaggageDebug(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	// the debug item is ignored by default
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetBaggageItem(ext.DebugBaggage, "1")
+	assert.False(root.Sampled)
+
+	// it keeps a dropped trace once enabled
+	tracer.SetDebugBaggage(true)
+	assert.True(tracer.Config().DebugBaggage)
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(root.Sampled)
+	root.SetBaggageItem(ext.DebugBaggage, "1")
+	assert.True(root.Sampled)
+	assert.Equal(ext.PriorityUserKeep, root.GetSamplingPriority())
+	assert.True(tracer.NewChildSpan("redis.command", root).Sampled)
+
+	// unless it is false
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetBaggageItem(ext.DebugBaggage, "false")
+	assert.False(root.Sampled)
+
+	// set on a child, it keeps the child and the spans it creates as well
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	child.SetBaggageItem(ext.DebugBaggage, "1")
+	assert.True(child.Sampled)
+	grandchild := tracer.NewChildSpan("redis.command", child)
+	assert.True(grandchild.Sampled)
+	grandchild.Finish()
+	child.Finish()
+	root.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+}
@@ -0,0 +1,145 @@
+// Package batch helps tracing consumers which process messages in batches,
+// such as Kafka or SQS consumers.
+//
+// A batch is traced by a single span, which records the trace context of the
+// producer of each message, since a span can only have one parent. The
+// processing of each message can additionally be traced by its own child span.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+const (
+	// linksKey holds the producer contexts of the messages of a batch, as a
+	// comma separated list of "<trace id>:<span id>".
+	linksKey = "batch.links"
+	// droppedLinksKey counts the producer contexts which weren't recorded
+	// because the batch had more than maxLinks of them.
+	droppedLinksKey = "batch.links_dropped"
+	sizeKey         = "batch.size"
+
+	// messageTraceIDKey and messageParentIDKey hold the producer context of a message on its span.
+	messageTraceIDKey  = "message.trace_id"
+	messageParentIDKey = "message.parent_id"
+	messageIndexKey    = "message.index"
+
+	maxLinks = 128
+)
+
+// Message is the trace context of the producer of a message, as extracted from its
+// headers or its payload. Messages which carry no context have zero IDs.
+type Message struct {
+	TraceID  uint64
+	ParentID uint64
+}
+
+func (m Message) valid() bool { return m.TraceID != 0 && m.ParentID != 0 }
+
+// Span traces the processing of a batch of messages.
+type Span struct {
+	*tracer.Span
+	msgs   []Message
+	config *config
+}
+
+// Start starts the span of the batch made of the given messages, as a child of the
+// span found in ctx. When ctx holds no span and all the messages were produced by the
+// same span, the batch span continues its trace. The returned context holds the batch span.
+func Start(ctx context.Context, name string, msgs []Message, opts ...Option) (*Span, context.Context) {
+	cfg := new(config)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	t := cfg.tracer
+	b := &Span{msgs: msgs, config: cfg}
+
+	var span *tracer.Span
+	if _, ok := tracer.SpanFromContext(ctx); ok {
+		span = t.NewChildSpanFromContext(name, ctx)
+		span.Service = cfg.serviceName
+	} else {
+		span = t.NewRootSpan(name, cfg.serviceName, name)
+		if m, ok := commonProducer(msgs); ok {
+			span.TraceID = m.TraceID
+			span.ParentID = m.ParentID
+			t.Sample(span)
+		}
+	}
+	span.Type = ext.AppTypeWorker
+	if cfg.resource != "" {
+		span.Resource = cfg.resource
+	}
+	span.SetMetric(sizeKey, float64(len(msgs)))
+	if links, dropped := encodeLinks(msgs); links != "" {
+		span.SetMeta(linksKey, links)
+		if dropped > 0 {
+			span.SetMetric(droppedLinksKey, float64(dropped))
+		}
+	}
+	b.Span = span
+	return b, tracer.ContextWithSpan(ctx, span)
+}
+
+// StartMessage starts the span of the processing of the i-th message of the batch as a
+// child of the batch span, and returns it with a context holding it. The producer context
+// of the message is recorded on the span. Unless the WithMessageSpans option was given,
+// no span is created and the returned span is nil, which is safe to use.
+func (b *Span) StartMessage(ctx context.Context, i int) (*tracer.Span, context.Context) {
+	if !b.config.messageSpans || i < 0 || i >= len(b.msgs) {
+		return nil, ctx
+	}
+	span := b.config.tracer.NewChildSpan(b.config.messageName, b.Span)
+	span.Type = ext.AppTypeWorker
+	span.SetMetric(messageIndexKey, float64(i))
+	if m := b.msgs[i]; m.valid() {
+		span.SetMeta(messageTraceIDKey, strconv.FormatUint(m.TraceID, 10))
+		span.SetMeta(messageParentIDKey, strconv.FormatUint(m.ParentID, 10))
+	}
+	return span, tracer.ContextWithSpan(ctx, span)
+}
+
+// commonProducer returns the producer context shared by all the given messages, if any.
+func commonProducer(msgs []Message) (Message, bool) {
+	if len(msgs) == 0 || !msgs[0].valid() {
+		return Message{}, false
+	}
+	for _, m := range msgs[1:] {
+		if m != msgs[0] {
+			return Message{}, false
+		}
+	}
+	return msgs[0], true
+}
+
+// encodeLinks returns the distinct producer contexts of the given messages, in the format
+// of linksKey, and the number of those which were left out because of maxLinks.
+func encodeLinks(msgs []Message) (links string, dropped int) {
+	var (
+		sb   bytes.Buffer
+		seen = make(map[Message]bool, len(msgs))
+	)
+	for _, m := range msgs {
+		if !m.valid() || seen[m] {
+			continue
+		}
+		seen[m] = true
+		if len(seen) > maxLinks {
+			dropped++
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatUint(m.TraceID, 10))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatUint(m.ParentID, 10))
+	}
+	return sb.String(), dropped
+}
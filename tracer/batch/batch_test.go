@@ -0,0 +1,121 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/tracertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	msgs := []Message{{1, 2}, {3, 4}, {1, 2}, {}}
+	b, ctx := Start(context.Background(), "kafka.consume.batch", msgs,
+		WithTracer(testTracer), WithServiceName("consumer"), WithResource("orders"), WithMessageSpans("kafka.process"))
+	for i := range msgs {
+		span, _ := b.StartMessage(ctx, i)
+		if i == 1 {
+			span.SetError(errors.New("bad order"))
+		}
+		span.Finish()
+	}
+	b.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 5)
+
+	var root = spans[0]
+	for _, s := range spans {
+		if s.Name == "kafka.consume.batch" {
+			root = s
+		}
+	}
+	assert.Equal(uint64(0), root.ParentID)
+	assert.Equal("consumer", root.Service)
+	assert.Equal("orders", root.Resource)
+	assert.Equal("1:2,3:4", root.GetMeta(linksKey))
+	assert.Equal(4.0, root.Metrics[sizeKey])
+
+	for _, s := range spans {
+		if s == root {
+			continue
+		}
+		assert.Equal("kafka.process", s.Name)
+		assert.Equal("consumer", s.Service)
+		assert.Equal(root.SpanID, s.ParentID)
+		switch s.Metrics[messageIndexKey] {
+		case 1:
+			assert.Equal("3", s.GetMeta(messageTraceIDKey))
+			assert.Equal("4", s.GetMeta(messageParentIDKey))
+			assert.Equal(int32(1), s.Error)
+		case 3:
+			assert.Equal("", s.GetMeta(messageTraceIDKey))
+		}
+	}
+}
+
+func TestStartChild(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	parent := testTracer.NewRootSpan("poll", "consumer", "poll")
+	b, _ := Start(parent.Context(context.Background()), "sqs.batch", []Message{{1, 2}}, WithTracer(testTracer))
+	span, _ := b.StartMessage(context.Background(), 0)
+	assert.Nil(span)
+	span.Finish() // no-op
+	b.Finish()
+	parent.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	spans := traces[0]
+	assert.Len(spans, 2)
+	for _, s := range spans {
+		if s.Name == "sqs.batch" {
+			assert.Equal(parent.TraceID, s.TraceID)
+			assert.Equal(parent.SpanID, s.ParentID)
+			assert.Equal("1:2", s.GetMeta(linksKey))
+		}
+	}
+}
+
+func TestStartSingleProducer(t *testing.T) {
+	assert := assert.New(t)
+	testTracer, testTransport := tracertest.GetTestTracer()
+
+	b, _ := Start(context.Background(), "sqs.batch", []Message{{7, 8}, {7, 8}}, WithTracer(testTracer))
+	b.Finish()
+
+	testTracer.ForceFlush()
+	traces := testTransport.Traces()
+	assert.Len(traces, 1)
+	s := traces[0][0]
+	assert.Equal(uint64(7), s.TraceID)
+	assert.Equal(uint64(8), s.ParentID)
+}
+
+func TestEncodeLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	links, dropped := encodeLinks(nil)
+	assert.Equal("", links)
+	assert.Equal(0, dropped)
+
+	msgs := make([]Message, maxLinks+10)
+	for i := range msgs {
+		msgs[i] = Message{uint64(i + 1), 1}
+	}
+	links, dropped = encodeLinks(msgs)
+	assert.Equal(10, dropped)
+	assert.Contains(links, fmt.Sprintf(",%d:1", maxLinks))
+	assert.NotContains(links, fmt.Sprintf(",%d:1", maxLinks+1))
+}
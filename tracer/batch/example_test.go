@@ -0,0 +1,33 @@
+package batch_test
+
+import (
+	"context"
+
+	"github.com/DataDog/dd-trace-go/tracer/batch"
+)
+
+type record struct {
+	traceID, parentID uint64
+	value             []byte
+}
+
+func process(ctx context.Context, value []byte) error { return nil }
+
+func Example() {
+	var records []record // e.g. polled from a Kafka topic
+
+	msgs := make([]batch.Message, len(records))
+	for i, r := range records {
+		msgs[i] = batch.Message{TraceID: r.traceID, ParentID: r.parentID}
+	}
+	b, ctx := batch.Start(context.Background(), "kafka.consume", msgs,
+		batch.WithServiceName("order-consumer"),
+		batch.WithResource("orders"),
+		batch.WithMessageSpans("kafka.process"),
+	)
+	for i, r := range records {
+		span, ctx := b.StartMessage(ctx, i)
+		span.FinishWithErr(process(ctx, r.value))
+	}
+	b.Finish()
+}
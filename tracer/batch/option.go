@@ -0,0 +1,48 @@
+package batch
+
+import "github.com/DataDog/dd-trace-go/tracer"
+
+type config struct {
+	serviceName  string
+	resource     string
+	tracer       *tracer.Tracer
+	messageSpans bool
+	messageName  string
+}
+
+// Option represents an option that can be passed to Start.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "batch"
+	cfg.tracer = tracer.DefaultTracer
+}
+
+// WithServiceName sets the service name of the batch span and of the message spans.
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithResource sets the resource of the batch span, e.g. the name of the topic or queue.
+func WithResource(resource string) Option {
+	return func(cfg *config) {
+		cfg.resource = resource
+	}
+}
+
+// WithTracer sets the tracer to use.
+func WithTracer(t *tracer.Tracer) Option {
+	return func(cfg *config) {
+		cfg.tracer = t
+	}
+}
+
+// WithMessageSpans enables the spans started by Span.StartMessage, with the given name.
+func WithMessageSpans(name string) Option {
+	return func(cfg *config) {
+		cfg.messageSpans = true
+		cfg.messageName = name
+	}
+}
@@ -17,15 +17,27 @@ const (
 	spanBufferDefaultMaxSize = 1e5
 )
 
+// spanBuffer groups the spans of a trace created in this process, and
+// pushes them as one trace once its local root, the first span pushed, and
+// all the other spans are finished. The spans finishing after the root was
+// pushed are pushed on their own. When some spans never finish, the finished
+// ones are pushed by flushFinished, see Tracer.SetTraceTimeout.
 type spanBuffer struct {
 	channels tracerChans
 
+	root          *Span
+	rootFinished  bool
 	spans         []*Span
-	finishedSpans int
+	finishedSpans int // number of spans which were acked
 
 	initSize int
 	maxSize  int
 
+	// keepOnError is true when the trace was dropped by the sampler, but
+	// has to be kept if any of its spans has an error. It is set when the
+	// buffer is created, and unset if the trace is kept after all.
+	keepOnError bool
+
 	sync.RWMutex
 }
 
@@ -67,54 +79,162 @@ func (tb *spanBuffer) Push(span *Span) {
 	if tb.spans == nil {
 		tb.spans = make([]*Span, 0, tb.initSize)
 	}
+	if tb.root == nil {
+		tb.root = span
+	}
 
 	tb.spans = append(tb.spans, span)
 }
 
+// flushable reports whether the trace is complete: the buffer must be locked.
 func (tb *spanBuffer) flushable() bool {
-	tb.RLock()
-	defer tb.RUnlock()
-
-	if len(tb.spans) == 0 {
-		return false
-	}
-
-	return tb.finishedSpans == len(tb.spans)
+	return len(tb.spans) > 0 && tb.rootFinished && tb.finishedSpans == len(tb.spans)
 }
 
-func (tb *spanBuffer) ack() {
-	tb.Lock()
-	defer tb.Unlock()
-
+// ack records that the given span of the buffer is finished: the buffer must
+// be locked.
+func (tb *spanBuffer) ack(span *Span) {
 	tb.finishedSpans++
+	span.acked = true
+	if span == tb.root {
+		tb.rootFinished = true
+	}
 }
 
+// doFlush pushes the trace if it is complete: the buffer must be locked.
 func (tb *spanBuffer) doFlush() {
 	if !tb.flushable() {
 		return
 	}
+	tb.push(tb.spans)
+	tb.spans = nil
+	tb.finishedSpans = 0 // important, because a buffer can be used for several flushes
+}
 
+// flushFinished pushes the finished spans of the trace, keeping the others
+// in the buffer.
+func (tb *spanBuffer) flushFinished() {
+	if tb == nil {
+		return
+	}
 	tb.Lock()
 	defer tb.Unlock()
 
-	tb.channels.pushTrace(tb.spans)
-	tb.spans = nil
-	tb.finishedSpans = 0 // important, because a buffer can be used for several flushes
+	var finished, open []*Span
+	for _, span := range tb.spans {
+		if span.acked {
+			finished = append(finished, span)
+		} else {
+			open = append(open, span)
+		}
+	}
+	if len(finished) > 0 {
+		tb.push(finished)
+	}
+	tb.spans = open
+	tb.finishedSpans = 0
+}
+
+// push pushes the given finished spans of the trace, unless it is dropped:
+// the buffer must be locked.
+func (tb *spanBuffer) push(spans []*Span) {
+	n := countErrors(spans)
+	if spans[0] == tb.root {
+		// the spans finishing after the root are pushed on their own
+		setErrorCount(tb.root, n)
+	}
+	if !tb.root.isSampled() {
+		switch {
+		case tb.keepOnError && n > 0:
+			unsetSampleRate(tb.root)
+		case tb.sendsDropped():
+			// the agent computes the statistics of the traces with a
+			// sampling priority of zero before dropping them
+		default:
+			// dropped by the sampler, and no error to report: only the
+			// spans matching a span sampling rule are kept, and the
+			// statistics of the others, as the agent computes those of
+			// the kept ones
+			for _, span := range spans {
+				if !span.sampleSingleSpan() && span.tracer != nil {
+					span.tracer.stats.addDropped(span)
+				}
+			}
+			return
+		}
+	}
+	tb.channels.pushTrace(spans)
+}
+
+// sendsDropped reports whether the dropped trace is sent to the agent anyway:
+// it has a sampling priority, telling the agent to drop it, and the agent
+// computes its statistics, as the tracer doesn't.
+func (tb *spanBuffer) sendsDropped() bool {
+	t := tb.root.tracer
+	return t != nil && tb.root.HasSamplingPriority() && !t.stats.computesDropped()
 }
 
 func (tb *spanBuffer) Flush() {
 	if tb == nil {
 		return
 	}
+	tb.Lock()
+	defer tb.Unlock()
+	tb.doFlush()
+}
+
+// AckFinish records that the given span of the buffer is finished, and pushes
+// the trace if it is complete. It reports whether the span is the local root
+// and the trace is still incomplete, waiting for other spans to finish.
+func (tb *spanBuffer) AckFinish(span *Span) (incomplete bool) {
+	if tb == nil {
+		return false
+	}
+	tb.Lock()
+	defer tb.Unlock()
+	tb.ack(span)
 	tb.doFlush()
+	return span == tb.root && len(tb.spans) > 0
 }
 
-func (tb *spanBuffer) AckFinish() {
+// keep makes the buffer keep its trace, even without errors, when it was
+// dropped by the sampler.
+func (tb *spanBuffer) keep() {
 	if tb == nil {
 		return
 	}
-	tb.ack()
-	tb.doFlush()
+	tb.Lock()
+	tb.keepOnError = false
+	tb.Unlock()
+}
+
+// keepsOnError reports whether the trace was dropped by the sampler, but is
+// kept if any of its spans has an error.
+func (tb *spanBuffer) keepsOnError() bool {
+	tb.RLock()
+	defer tb.RUnlock()
+	return tb.keepOnError
+}
+
+// openSpans returns the spans of the buffer which aren't finished yet.
+func (tb *spanBuffer) openSpans() []*Span {
+	if tb == nil {
+		return nil
+	}
+	tb.RLock()
+	spans := append([]*Span(nil), tb.spans...)
+	tb.RUnlock()
+
+	open := spans[:0]
+	for _, s := range spans {
+		s.RLock()
+		finished := s.finished
+		s.RUnlock()
+		if !finished {
+			open = append(open, s)
+		}
+	}
+	return open
 }
 
 func (tb *spanBuffer) Len() int {
@@ -125,3 +245,42 @@ func (tb *spanBuffer) Len() int {
 	defer tb.RUnlock()
 	return len(tb.spans)
 }
+
+// countErrors returns the number of the given spans which have an error.
+func countErrors(spans []*Span) int {
+	var n int
+	for _, s := range spans {
+		s.tagsMu.RLock()
+		if s.Error != 0 {
+			n++
+		}
+		s.tagsMu.RUnlock()
+	}
+	return n
+}
+
+// setErrorCount rolls up the number of spans with an error into a metric of
+// the local root span, so that the trace can be found even when only a deep
+// child failed. It is called before the root is pushed, with the number of
+// spans pushed along with it: the spans finishing after the root was pushed
+// aren't counted.
+func setErrorCount(root *Span, n int) {
+	if n == 0 {
+		return
+	}
+	root.tagsMu.Lock()
+	if root.Metrics == nil {
+		root.Metrics = make(map[string]float64, 1)
+	}
+	root.Metrics[traceErrorCountKey] = float64(n)
+	root.tagsMu.Unlock()
+}
+
+// unsetSampleRate removes the sample rate from a root span whose trace is
+// kept for its errors despite the sampler, since it wasn't kept at that rate.
+func unsetSampleRate(root *Span) {
+	root.tagsMu.Lock()
+	delete(root.Metrics, sampleRateMetricKey)
+	delete(root.Metrics, rulesRateMetricKey)
+	root.tagsMu.Unlock()
+}
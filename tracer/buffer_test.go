@@ -1,6 +1,7 @@
 package tracer
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -103,3 +104,62 @@ func TestSpanBufferPushSeveral(t *testing.T) {
 		assert.Fail("unexpected error:", err.Error())
 	}
 }
+
+func TestSpanBufferErrorCount(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	grandchild := tracer.NewChildSpan("redis.dial", child)
+	grandchild.FinishWithErr(errors.New("connection refused"))
+	child.FinishWithErr(errors.New("connection refused"))
+	root.Finish()
+
+	ok := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.NewChildSpan("redis.command", ok).Finish()
+	ok.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 2)
+	assert.Equal(2.0, traces[0][0].Metrics[traceErrorCountKey])
+	_, found := traces[0][1].Metrics[traceErrorCountKey]
+	assert.False(found)
+	_, found = traces[1][0].Metrics[traceErrorCountKey]
+	assert.False(found)
+}
+
+func TestSpanBufferErrorCountLate(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	tracer.SetTraceTimeout(time.Second)
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.NewChildSpan("redis.command", root).FinishWithErr(errors.New("connection refused"))
+	late := tracer.NewChildSpan("redis.command", root)
+	root.Finish()
+	clock.advance(2 * time.Second)
+	tracer.pushIncomplete()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(root, traces[0][0])
+	assert.Equal(1.0, root.Metrics[traceErrorCountKey])
+
+	// the spans finishing after the root was pushed aren't counted, and the
+	// first of them isn't mistaken for the root
+	late.FinishWithErr(errors.New("connection refused"))
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal([]*Span{late}, traces[0])
+	_, found := late.Metrics[traceErrorCountKey]
+	assert.False(found)
+}
@@ -6,6 +6,10 @@ const (
 	// If it's full, then data is simply dropped and ignored, with a log message.
 	// This only happens under heavy load,
 	traceChanLen = 1000
+	// traceBatchSize is the maximum number of traces drained from the trace
+	// channel to make a single payload. The worker drains as many batches as
+	// needed on each wake up, keeping payloads reasonably sized during bursts.
+	traceBatchSize = traceChanLen / 2
 	// serviceChanLen is the length of the service channel. As for the trace channel,
 	// it's emptied by worker thread or when it reaches 50%. Note that there should
 	// be much less data here, as service data does not be to be updated that often.
@@ -29,6 +33,10 @@ type tracerChans struct {
 	traceFlush   chan struct{}
 	serviceFlush chan struct{}
 	errFlush     chan struct{}
+
+	// errCounts counts the pushed errors per category, including the ones
+	// dropped because the error channel is full.
+	errCounts *errorCounts
 }
 
 func newTracerChans() tracerChans {
@@ -39,6 +47,7 @@ func newTracerChans() tracerChans {
 		traceFlush:   make(chan struct{}, 1),
 		serviceFlush: make(chan struct{}, 1),
 		errFlush:     make(chan struct{}, 1),
+		errCounts:    new(errorCounts),
 	}
 }
 
@@ -71,6 +80,7 @@ func (tc *tracerChans) pushService(service Service) {
 }
 
 func (tc *tracerChans) pushErr(err error) {
+	tc.errCounts.add(err)
 	if len(tc.err) >= cap(tc.err)/2 { // starts being full, anticipate, try and flush soon
 		select {
 		case tc.errFlush <- struct{}{}:
@@ -0,0 +1,67 @@
+package tracer
+
+import "time"
+
+// Clock tells the time to a tracer. The durations of the spans are computed
+// from the difference of two of its readings, so clocks returning times with
+// a monotonic reading, as time.Now does, keep the durations right when the
+// wall clock is adjusted, e.g. by NTP.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function returning the current time to a Clock, e.g.
+// to control the time of a tracer in tests without sleeping:
+//
+//	var current = time.Unix(1500000000, 0)
+//	tracer.SetClock(tracer.ClockFunc(func() time.Time { return current }))
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// clockHolder holds the clock of a tracer in an atomic.Value, which needs a
+// consistent concrete type.
+type clockHolder struct {
+	Clock
+}
+
+// SetClock replaces the clock of the tracer, which is the system clock by
+// default, so that simulations and tests control the start times and
+// durations of the spans, as well as the time windows of the samplers, such
+// as the rate limit of the sampling rules. Passing nil restores the system
+// clock.
+func (t *Tracer) SetClock(c Clock) {
+	t.clock.Store(clockHolder{c})
+}
+
+// startTime returns the wall-clock start time of a new span, in nanoseconds,
+// along with the clock reading its duration is measured from.
+func (t *Tracer) startTime() (int64, time.Time) {
+	if c := t.getClock(); c != nil {
+		at := c.Now()
+		return at.UnixNano(), at
+	}
+	// now may be more precise than time.Now, which holds a monotonic reading
+	return now(), time.Now()
+}
+
+// clockNow returns the current reading of the clock of the tracer.
+func (t *Tracer) clockNow() time.Time {
+	if c := t.getClock(); c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// getClock returns the custom clock of the tracer, or nil for the system
+// clock.
+func (t *Tracer) getClock() Clock {
+	if t == nil {
+		return nil
+	}
+	h, _ := t.clock.Load().(clockHolder)
+	return h.Clock
+}
@@ -0,0 +1,94 @@
+package tracer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock which only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestTracerSetClock(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.Equal(clock.now.UnixNano(), span.Start)
+	clock.advance(5 * time.Millisecond)
+	span.Finish()
+	assert.Equal(int64(5*time.Millisecond), span.Duration)
+
+	// the system clock is restored
+	tracer.SetClock(nil)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.True(span.Start > clock.now.UnixNano())
+}
+
+func TestSpanDurationMonotonic(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	// the duration is measured from the monotonic reading of the start
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.clockStart = span.clockStart.Add(-time.Second)
+	span.Finish()
+	assert.True(span.Duration >= int64(time.Second))
+
+	// unless the start time was changed
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Start -= int64(time.Hour)
+	span.Finish()
+	assert.True(span.Duration >= int64(time.Hour))
+
+	// or the finish time is given
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.FinishWithTime(span.Start + 42)
+	assert.Equal(int64(42), span.Duration)
+}
+
+func TestTracerClockFunc(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	current := time.Unix(1500000000, 0)
+	tracer.SetClock(ClockFunc(func() time.Time { return current }))
+	tracer.SetSamplingRules(SamplingRule{Rate: 1})
+	tracer.SetSamplingRateLimit(2)
+
+	// the rate limit windows follow the clock of the tracer
+	sampled := func() (n int) {
+		for i := 0; i < 5; i++ {
+			if tracer.NewRootSpan("pylons.request", "pylons", "/").Sampled {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(2, sampled())
+	assert.Equal(0, sampled())
+	current = current.Add(time.Second)
+	assert.Equal(2, sampled())
+}
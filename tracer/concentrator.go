@@ -0,0 +1,384 @@
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// statsBucketDuration is the duration of the time buckets the statistics
+	// are aggregated in.
+	statsBucketDuration = 10 * time.Second
+	// clientStatsHeader tells the agent that the tracer computes the
+	// statistics, so that it doesn't compute them again from the traces.
+	clientStatsHeader = "Datadog-Client-Computed-Stats"
+)
+
+// statsKey identifies the spans aggregated together.
+type statsKey struct {
+	Service    string
+	Name       string
+	Resource   string
+	Type       string
+	StatusCode uint32
+}
+
+// groupedStats holds the aggregates of the spans sharing a statsKey, in a
+// time bucket. It is encoded as expected by the stats endpoint of the agent.
+type groupedStats struct {
+	Service        string
+	Name           string
+	Resource       string
+	HTTPStatusCode uint32
+	Type           string
+	Hits           uint64
+	Errors         uint64
+	Duration       uint64
+	OkSummary      []byte // sketch of the latencies of the spans without error
+	ErrorSummary   []byte // sketch of the latencies of the spans in error
+	TopLevelHits   uint64
+}
+
+// statsGroup aggregates the spans sharing a statsKey in a time bucket, along
+// with the sketches of their latencies.
+type statsGroup struct {
+	groupedStats
+	ok, errors sketch
+}
+
+// statsHandle encodes the statistics payloads, whose byte slices, the
+// latency sketches, are expected in the msgpack bin format.
+var statsHandle = codec.MsgpackHandle{WriteExt: true}
+
+// statsBucket holds the statistics of the spans which ended in a time bucket.
+type statsBucket struct {
+	Start    uint64
+	Duration uint64
+	Stats    []groupedStats
+}
+
+// statsPayload is the payload sent to the stats endpoint of the agent.
+type statsPayload struct {
+	Hostname      string
+	Env           string
+	Version       string
+	Lang          string
+	TracerVersion string
+	Stats         []statsBucket
+}
+
+// concentrator computes the hits, errors, total duration and latency sketches
+// of the top-level spans, per service, operation, resource, type and HTTP status code, in time
+// buckets of statsBucketDuration. It sees all the spans, including the ones
+// of the traces dropped by the sampler, so that the metrics derived from the
+// statistics are accurate whatever the sample rate. When only the statistics
+// of the dropped traces are enabled, it sees the spans of those only, the
+// agent computing the statistics of the other traces.
+type concentrator struct {
+	// enabled and active should only be accessed atomically. enabled is 1
+	// when the statistics computation is enabled, and active is 1 once the
+	// agent is known to accept them as well: spans are aggregated from then
+	// on. droppedEnabled and droppedActive are their counterparts for the
+	// statistics of the dropped traces.
+	enabled        uint32
+	active         uint32
+	droppedEnabled uint32
+	droppedActive  uint32
+
+	mu      sync.Mutex
+	buckets map[int64]map[statsKey]*statsGroup // bucket start -> key -> stats
+}
+
+func newConcentrator() *concentrator {
+	return &concentrator{buckets: make(map[int64]map[statsKey]*statsGroup)}
+}
+
+// setEnabled enables or disables the statistics computation.
+func (c *concentrator) setEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&c.enabled, 1)
+	} else {
+		atomic.StoreUint32(&c.enabled, 0)
+	}
+}
+
+// isEnabled reports whether the statistics computation is enabled.
+func (c *concentrator) isEnabled() bool {
+	return atomic.LoadUint32(&c.enabled) == 1
+}
+
+// setActive starts or stops the aggregation of the spans.
+func (c *concentrator) setActive(active bool) {
+	if active {
+		atomic.StoreUint32(&c.active, 1)
+	} else {
+		atomic.StoreUint32(&c.active, 0)
+	}
+}
+
+// isActive reports whether spans are aggregated.
+func (c *concentrator) isActive() bool {
+	return c != nil && atomic.LoadUint32(&c.active) == 1
+}
+
+// setDroppedEnabled enables or disables the statistics of the dropped traces.
+func (c *concentrator) setDroppedEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&c.droppedEnabled, 1)
+	} else {
+		atomic.StoreUint32(&c.droppedEnabled, 0)
+	}
+}
+
+// isDroppedEnabled reports whether the statistics of the dropped traces are
+// enabled.
+func (c *concentrator) isDroppedEnabled() bool {
+	return atomic.LoadUint32(&c.droppedEnabled) == 1
+}
+
+// setDroppedActive starts or stops the aggregation of the spans of the
+// dropped traces.
+func (c *concentrator) setDroppedActive(active bool) {
+	if active {
+		atomic.StoreUint32(&c.droppedActive, 1)
+	} else {
+		atomic.StoreUint32(&c.droppedActive, 0)
+	}
+}
+
+// isDroppedActive reports whether the spans of the dropped traces are
+// aggregated.
+func (c *concentrator) isDroppedActive() bool {
+	return c != nil && atomic.LoadUint32(&c.droppedActive) == 1
+}
+
+// computesDropped reports whether the statistics of the dropped traces are
+// computed by the tracer, as negotiated with the agent, so that the traces
+// with a sampling priority of zero needn't be sent to the agent for them.
+func (c *concentrator) computesDropped() bool {
+	return c.isActive() || c.isDroppedActive()
+}
+
+// addDropped aggregates the finished span of a dropped trace, unless all the
+// spans are aggregated already.
+func (c *concentrator) addDropped(s *Span) {
+	if c.isDroppedActive() && !c.isActive() {
+		c.add(s)
+	}
+}
+
+// add aggregates the finished span, if it is the top-level span of its
+// service in the trace.
+func (c *concentrator) add(s *Span) {
+	s.RLock()
+	s.tagsMu.RLock()
+	_, topLevel := s.Metrics[topLevelKey]
+	topLevel = topLevel || s.parent == nil || s.parent.Service != s.Service
+	if !topLevel {
+		s.tagsMu.RUnlock()
+		s.RUnlock()
+		return
+	}
+	key := statsKey{
+		Service:  s.Service,
+		Name:     s.Name,
+		Resource: s.Resource,
+		Type:     s.Type,
+	}
+	if code, err := strconv.ParseUint(s.Meta[ext.HTTPCode], 10, 32); err == nil {
+		key.StatusCode = uint32(code)
+	}
+	end, duration, isError := s.Start+s.Duration, s.Duration, s.Error != 0
+	s.tagsMu.RUnlock()
+	s.RUnlock()
+
+	bucket := end - end%int64(statsBucketDuration)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats, ok := c.buckets[bucket]
+	if !ok {
+		stats = make(map[statsKey]*statsGroup)
+		c.buckets[bucket] = stats
+	}
+	gs, ok := stats[key]
+	if !ok {
+		gs = &statsGroup{groupedStats: groupedStats{
+			Service:        key.Service,
+			Name:           key.Name,
+			Resource:       key.Resource,
+			HTTPStatusCode: key.StatusCode,
+			Type:           key.Type,
+		}}
+		stats[key] = gs
+	}
+	gs.Hits++
+	gs.TopLevelHits++
+	if duration < 0 {
+		duration = 0
+	}
+	gs.Duration += uint64(duration)
+	if isError {
+		gs.Errors++
+		gs.errors.add(float64(duration))
+	} else {
+		gs.ok.add(float64(duration))
+	}
+}
+
+// flush returns the buckets which ended before the given time, in
+// nanoseconds since epoch, or all of them if force is true, and forgets them.
+func (c *concentrator) flush(now int64, force bool) []statsBucket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var buckets []statsBucket
+	for start, stats := range c.buckets {
+		if !force && start+int64(statsBucketDuration) > now {
+			continue
+		}
+		b := statsBucket{
+			Start:    uint64(start),
+			Duration: uint64(statsBucketDuration),
+			Stats:    make([]groupedStats, 0, len(stats)),
+		}
+		for _, gs := range stats {
+			gs.OkSummary = gs.ok.encode()
+			gs.ErrorSummary = gs.errors.encode()
+			b.Stats = append(b.Stats, gs.groupedStats)
+		}
+		buckets = append(buckets, b)
+		delete(c.buckets, start)
+	}
+	return buckets
+}
+
+// SetStatsComputation enables or disables the computation of the statistics
+// of the traces by the tracer, rather than by the agent. They are computed
+// from all the spans, including the ones of the traces dropped by the
+// sampler, so that the hits, errors and latencies reported for the services
+// and resources are accurate whatever the sample rate. The statistics are
+// only computed when the agent supports them, as found in AgentFeatures once
+// the tracer has started; the agent computes them from the traces otherwise.
+// It has no effect with a custom transport.
+func (t *Tracer) SetStatsComputation(enabled bool) {
+	t.stats.setEnabled(enabled)
+	if enabled {
+		t.activateStats()
+		return
+	}
+	if t.stats.isActive() {
+		t.stats.setActive(false)
+		t.transport.SetHeader(clientStatsHeader, "")
+	}
+}
+
+// StatsComputationEnabled returns true if the tracer computes the statistics
+// of the traces, i.e. they are enabled and the agent supports them.
+func (t *Tracer) StatsComputationEnabled() bool {
+	return t.stats.isActive()
+}
+
+// SetDroppedTraceStats enables or disables the statistics of the traces
+// dropped by the tracer, such as the ones dropped by the sampler, when it
+// doesn't compute the statistics of all the traces, see SetStatsComputation.
+// The top-level spans of those traces are aggregated as lightweight counters
+// and sent to the agent with the statistics payloads, while the agent keeps
+// computing the statistics of the traces it receives, so that the request
+// rates reported for the services and resources account for all the
+// requests. They are only sent when the agent supports client statistics,
+// as found in AgentFeatures once the tracer has started. It has no effect
+// with a custom transport.
+func (t *Tracer) SetDroppedTraceStats(enabled bool) {
+	t.stats.setDroppedEnabled(enabled)
+	if enabled {
+		t.activateStats()
+		return
+	}
+	t.stats.setDroppedActive(false)
+}
+
+// DroppedTraceStatsEnabled returns true if the tracer computes the
+// statistics of the traces it drops, i.e. they are enabled and the agent
+// supports them.
+func (t *Tracer) DroppedTraceStatsEnabled() bool {
+	return t.stats.isDroppedActive()
+}
+
+// activateStats starts computing the statistics if they are enabled and the
+// agent supports them.
+func (t *Tracer) activateStats() {
+	c := t.stats
+	if !c.isEnabled() && !c.isDroppedEnabled() || !t.AgentFeatures().ClientStats {
+		return
+	}
+	if c.isDroppedEnabled() {
+		// the agent computes the statistics of the traces it receives, so
+		// the client stats header isn't set
+		c.setDroppedActive(true)
+	}
+	if c.isEnabled() && !c.isActive() {
+		c.setActive(true)
+		t.transport.SetHeader(clientStatsHeader, "yes")
+	}
+}
+
+// flushStats sends the statistics of the buckets which are over, or of all
+// the buckets if force is true.
+func (t *Tracer) flushStats(force bool) {
+	c := t.stats
+	if !c.isActive() && !c.isDroppedActive() || !t.Enabled() {
+		return
+	}
+	buckets := c.flush(t.clockNow().UnixNano(), force)
+	if len(buckets) == 0 {
+		return
+	}
+	ht, ok := t.transport.(*httpTransport)
+	if !ok {
+		return
+	}
+	meta := t.getAllMeta()
+	hostname, _ := os.Hostname()
+	payload := statsPayload{
+		Hostname:      hostname,
+		Env:           meta["env"],
+		Version:       meta["version"],
+		Lang:          ext.Lang,
+		TracerVersion: ext.TracerVersion,
+		Stats:         buckets,
+	}
+	if err := ht.sendStats(payload); err != nil {
+		t.channels.pushErr(&errorFlushLostStats{Nb: len(buckets), Err: err})
+	}
+}
+
+// sendStats sends the statistics payload to the agent.
+func (t *httpTransport) sendStats(payload statsPayload) error {
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, &statsHandle).Encode(payload); err != nil {
+		return &errorEncoding{Err: err}
+	}
+	req, err := http.NewRequest("POST", t.statsURL, &buf)
+	if err != nil {
+		return fmt.Errorf("cannot create http request: %v", err)
+	}
+	t.setHeaders(req)
+	req.Header.Set("Content-Type", msgpackContentType)
+	response, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if sc := response.StatusCode; sc != 200 {
+		return fmt.Errorf("sendStats expected response code 200, received %v", sc)
+	}
+	return nil
+}
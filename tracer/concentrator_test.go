@@ -0,0 +1,226 @@
+package tracer
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+func TestConcentrator(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	tracer.SetClock(clock)
+	c := newConcentrator()
+
+	request := func(resource, code string, err error) *Span {
+		root := tracer.NewRootSpan("http.request", "web", resource)
+		root.SetMeta(ext.HTTPCode, code)
+		child := tracer.NewChildSpan("db.query", root)
+		cache := tracer.NewServiceSpan("cache.get", "cache", root)
+		clock.advance(time.Second)
+		for _, span := range []*Span{child, cache} {
+			span.Finish()
+			c.add(span)
+		}
+		root.FinishWithErr(err)
+		c.add(root)
+		return root
+	}
+	request("/users", "200", nil)
+	request("/users", "200", nil)
+	request("/users", "500", errors.New("timeout"))
+	clock.advance(10 * time.Second)
+	request("/users", "200", nil)
+
+	// only the buckets which are over are flushed
+	buckets := c.flush(clock.Now().UnixNano(), false)
+	assert.Len(buckets, 1)
+	b := buckets[0]
+	assert.Equal(uint64(time.Unix(1500000000, 0).UnixNano()), b.Start)
+	assert.Equal(uint64(statsBucketDuration), b.Duration)
+	assert.Len(b.Stats, 3)
+	stats := make(map[statsKey]groupedStats)
+	for _, gs := range b.Stats {
+		stats[statsKey{gs.Service, gs.Name, gs.Resource, gs.Type, gs.HTTPStatusCode}] = gs
+	}
+	// the latencies are summed up in sketches
+	var ok sketch
+	ok.add(float64(time.Second))
+	ok.add(float64(time.Second))
+	gs := stats[statsKey{"web", "http.request", "/users", "", 200}]
+	assert.Equal(ok.encode(), gs.OkSummary)
+	assert.Nil(gs.ErrorSummary)
+	assert.NotNil(stats[statsKey{"web", "http.request", "/users", "", 500}].ErrorSummary)
+	gs.OkSummary = nil
+	assert.Equal(groupedStats{
+		Service:        "web",
+		Name:           "http.request",
+		Resource:       "/users",
+		HTTPStatusCode: 200,
+		Hits:           2,
+		TopLevelHits:   2,
+		Duration:       uint64(2 * time.Second),
+	}, gs)
+	assert.Equal(uint64(1), stats[statsKey{"web", "http.request", "/users", "", 500}].Errors)
+	// the children of the same service aren't counted
+	assert.Equal(uint64(3), stats[statsKey{"cache", "cache.get", "cache.get", "", 0}].Hits)
+
+	assert.Len(c.flush(clock.Now().UnixNano(), false), 0)
+	assert.Len(c.flush(clock.Now().UnixNano(), true), 1)
+	assert.Len(c.buckets, 0)
+}
+
+func TestTracerStatsComputation(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu       sync.Mutex
+		payloads []statsPayload
+		headers  []string
+	)
+	agent := newInfoServer(`{"endpoints": ["/v0.3/traces", "/v0.6/stats"], "client_drop_p0s": true}`)
+	defer agent.Close()
+	mux := agent.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("/v0.3/traces", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Get(clientStatsHeader))
+		mu.Unlock()
+	})
+	mux.HandleFunc(statsPath, func(w http.ResponseWriter, r *http.Request) {
+		var p statsPayload
+		assert.NoError(codec.NewDecoder(r.Body, &statsHandle).Decode(&p))
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	})
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = agent.URL
+	cfg.StatsComputation = true
+	cfg.Tags = map[string]string{"env": "prod"}
+	tracer := NewTracerWithConfig(cfg)
+	tracer.Flush()
+	assert.True(tracer.StatsComputationEnabled())
+
+	// the traces dropped by the sampler are counted too
+	tracer.SetSampleRate(0.5)
+	for i := 0; i < 100; i++ {
+		tracer.NewRootSpan("http.request", "web", "/").Finish()
+	}
+	tracer.Flush()
+	tracer.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(payloads, 1)
+	assert.Equal("prod", payloads[0].Env)
+	assert.Equal(ext.Lang, payloads[0].Lang)
+	var hits uint64
+	for _, b := range payloads[0].Stats {
+		for _, gs := range b.Stats {
+			hits += gs.Hits
+			assert.NotEmpty(gs.OkSummary)
+		}
+	}
+	assert.Equal(uint64(100), hits)
+	assert.NotEmpty(headers)
+	for _, h := range headers {
+		assert.Equal("yes", h)
+	}
+}
+
+func TestTracerStatsComputationUnsupported(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := newInfoServer(`{"endpoints": ["/v0.3/traces"]}`, "/v0.3/traces")
+	defer agent.Close()
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = agent.URL
+	cfg.StatsComputation = true
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+	tracer.Flush()
+
+	assert.False(tracer.StatsComputationEnabled())
+	assert.True(tracer.Config().StatsComputation)
+
+	// custom transports don't support them either
+	tracer, _ = getTestTracer()
+	defer tracer.Stop()
+	tracer.SetStatsComputation(true)
+	assert.False(tracer.StatsComputationEnabled())
+}
+
+func TestTracerDroppedTraceStats(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu       sync.Mutex
+		payloads []statsPayload
+		headers  []string
+	)
+	agent := newInfoServer(`{"endpoints": ["/v0.3/traces", "/v0.6/stats"], "client_drop_p0s": true}`)
+	defer agent.Close()
+	mux := agent.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("/v0.3/traces", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Get(clientStatsHeader))
+		mu.Unlock()
+	})
+	mux.HandleFunc(statsPath, func(w http.ResponseWriter, r *http.Request) {
+		var p statsPayload
+		assert.NoError(codec.NewDecoder(r.Body, &statsHandle).Decode(&p))
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	})
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = agent.URL
+	cfg.DroppedTraceStats = true
+	tracer := NewTracerWithConfig(cfg)
+	tracer.Flush()
+	assert.True(tracer.DroppedTraceStatsEnabled())
+	assert.False(tracer.StatsComputationEnabled())
+
+	// only the traces dropped by the sampler are counted, at creation or
+	// when they are flushed
+	tracer.SetSampleRate(0.5)
+	var dropped uint64
+	for i := 0; i < 100; i++ {
+		root := tracer.NewRootSpan("http.request", "web", "/")
+		tracer.NewChildSpan("sql.query", root).Finish()
+		if !root.Sampled {
+			dropped++
+		}
+		root.Finish()
+	}
+	tracer.Flush()
+	tracer.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(payloads, 1)
+	var hits uint64
+	for _, b := range payloads[0].Stats {
+		for _, gs := range b.Stats {
+			assert.Equal("http.request", gs.Name)
+			hits += gs.Hits
+		}
+	}
+	assert.Equal(dropped, hits)
+	assert.NotEmpty(headers)
+	for _, h := range headers {
+		assert.Equal("", h)
+	}
+}
@@ -0,0 +1,235 @@
+package tracer
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Config describes the effective configuration of a tracer, as returned by
+// Tracer.Config. It is a snapshot: changing it has no effect on the tracer.
+type Config struct {
+	// Enabled tells whether the tracer submits spans.
+	Enabled bool
+	// Debug tells whether debug logging is enabled.
+	Debug bool
+	// AgentURL is the URL of the agent traces are sent to, made of its scheme,
+	// host and port only, as the tracer picks the endpoints of the agent API
+	// itself. It is empty when the tracer uses a custom transport.
+	AgentURL string
+	// Sampler is the name of the sampler applied to the traces which match
+	// no sampling rule, "all", "rate", "priority" or "custom".
+	Sampler string
+	// SampleRate is the ratio of traces kept by the sampler, when they don't
+	// match any sampling rule.
+	SampleRate float64
+	// SamplingRules holds the sampling rules, evaluated in order.
+	SamplingRules []SamplingRule
+	// RateLimit is the maximum number of traces per second kept by the
+	// sampling rules, 100 by default, 0 meaning no limit.
+	RateLimit float64
+	// SpanSamplingRules holds the rules keeping spans of dropped traces,
+	// evaluated in order.
+	SpanSamplingRules []SpanSamplingRule
+	// KeepErrors tells whether the traces with errors are always kept.
+	KeepErrors bool
+	// ConcurrentSends is the maximum number of payloads sent at the same time.
+	ConcurrentSends int
+	// FlushInterval is the period of the flushes of the traces to the agent.
+	FlushInterval time.Duration
+	// TraceTimeout is the time after which the finished spans of a trace
+	// are sent when some of its spans aren't finished, 0 meaning never, see
+	// Tracer.SetTraceTimeout.
+	TraceTimeout time.Duration
+	// MaxSpanTags is the maximum number of meta of a span, 0 meaning
+	// unlimited, see Tracer.SetMaxSpanTags.
+	MaxSpanTags int
+	// MaxPayloadSize is the size in bytes above which the payloads sent to
+	// the agent are split, it is 0 when the tracer uses a custom transport.
+	MaxPayloadSize int
+	// PayloadCompression tells whether the trace payloads are gzipped,
+	// when the agent supports it.
+	PayloadCompression bool
+	// PayloadEncoding is the name of the encoding of the trace payloads,
+	// empty for the default one, see SetPayloadEncoding.
+	PayloadEncoding string
+	// StatsComputation tells whether the tracer computes the statistics of
+	// the traces, when the agent supports it.
+	StatsComputation bool
+	// DroppedTraceStats tells whether the tracer computes the statistics of
+	// the traces it drops, when the agent supports it.
+	DroppedTraceStats bool
+	// TraceID128Generation tells whether new traces have 128-bit IDs.
+	TraceID128Generation bool
+	// DebugBaggage tells whether the ext.DebugBaggage baggage item keeps
+	// the traces it is set on.
+	DebugBaggage bool
+	// DogStatsDAddr is the address of the DogStatsD server the gauges are
+	// sent to, empty if they are disabled, see SetDogStatsDAddr.
+	DogStatsDAddr string
+	// Services holds the services reported so far, by name.
+	Services map[string]Service
+	// Tags holds the meta set at the tracer level, applied to all its spans.
+	Tags map[string]string
+	// Transport is the transport used by NewTracerWithConfig, instead of
+	// the one sending traces to AgentURL, when it is set. It is always nil
+	// in the configurations returned by Tracer.Config.
+	Transport Transport
+	// HTTPClient is the HTTP client used by NewTracerWithConfig to send
+	// traces to AgentURL, when it is set and Transport isn't, see
+	// NewTransportWithHTTPClient. It is always nil in the configurations
+	// returned by Tracer.Config.
+	HTTPClient *http.Client
+}
+
+// DefaultConfig returns the configuration of a new tracer, before it reads
+// the environment.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:         true,
+		AgentURL:        newDefaultTransport().(*httpTransport).baseURL,
+		Sampler:         "all",
+		SampleRate:      1,
+		RateLimit:       defaultRateLimit,
+		ConcurrentSends: defaultConcurrentSends,
+		FlushInterval:   defaultFlushInterval,
+		TraceTimeout:    defaultTraceTimeout,
+		MaxSpanTags:     defaultMaxSpanTags,
+		MaxPayloadSize:  maxPayloadSize,
+		Services:        make(map[string]Service),
+		Tags:            make(map[string]string),
+	}
+}
+
+// NewTracerWithConfig returns a new tracer set up from the given
+// configuration only, which usually starts from DefaultConfig: unlike
+// NewTracer, it doesn't read the environment. The tracer is independent from
+// the DefaultTracer and from any other tracer, so that plugin hosts and test
+// harnesses can run isolated tracers in one process; it has to be given to
+// the integrations with their WithTracer option. A "custom" sampler can't be
+// restored from a configuration, SetSampler has to be called instead.
+func NewTracerWithConfig(cfg Config) *Tracer {
+	transport := cfg.Transport
+	if transport == nil {
+		var hostname, port string
+		if u, err := url.Parse(cfg.AgentURL); err == nil {
+			hostname, port = u.Hostname(), u.Port()
+			if u.Path != "" && u.Path != "/" {
+				logf(logWarn, "tracer", "ignoring the path of the agent URL %q, the endpoints are picked by the tracer", cfg.AgentURL)
+			}
+		} else {
+			logf(logWarn, "tracer", "ignoring invalid agent URL %q: %v", cfg.AgentURL, err)
+		}
+		transport = NewTransportWithHTTPClient(hostname, port, cfg.HTTPClient)
+	}
+	t := newTracer(transport)
+	t.SetEnabled(cfg.Enabled)
+	t.SetDebugLogging(cfg.Debug)
+	switch cfg.Sampler {
+	case "rate":
+		t.SetSampleRate(cfg.SampleRate)
+	case "priority":
+		t.SetPrioritySampling(true)
+	}
+	t.SetSamplingRateLimit(cfg.RateLimit)
+	t.SetSamplingRules(cfg.SamplingRules...)
+	t.SetSpanSamplingRules(cfg.SpanSamplingRules...)
+	t.SetKeepErrors(cfg.KeepErrors)
+	if cfg.ConcurrentSends > 0 {
+		t.SetConcurrentSends(cfg.ConcurrentSends)
+	}
+	if cfg.FlushInterval > 0 {
+		t.flushInterval = cfg.FlushInterval
+	}
+	t.SetTraceTimeout(cfg.TraceTimeout)
+	t.SetMaxSpanTags(cfg.MaxSpanTags)
+	if cfg.MaxPayloadSize > 0 {
+		t.setMaxPayloadSize(cfg.MaxPayloadSize)
+	}
+	t.SetPayloadCompression(cfg.PayloadCompression)
+	t.SetPayloadEncoding(cfg.PayloadEncoding)
+	t.SetStatsComputation(cfg.StatsComputation)
+	t.SetDroppedTraceStats(cfg.DroppedTraceStats)
+	t.SetTraceID128Generation(cfg.TraceID128Generation)
+	t.SetDebugBaggage(cfg.DebugBaggage)
+	t.SetDogStatsDAddr(cfg.DogStatsDAddr)
+	for _, s := range cfg.Services {
+		t.SetServiceInfo(s.Name, s.App, s.AppType)
+	}
+	for k, v := range cfg.Tags {
+		t.SetMeta(k, v)
+	}
+	t.start()
+	return t
+}
+
+// Config returns the configuration the tracer actually uses, so that it can
+// be displayed by debug endpoints or ops tooling.
+func (t *Tracer) Config() Config {
+	cfg := Config{
+		Enabled:    t.Enabled(),
+		Debug:      t.DebugLoggingEnabled(),
+		Sampler:    "all",
+		SampleRate: 1,
+		Tags:       t.getAllMeta(),
+		KeepErrors: t.KeepErrorsEnabled(),
+	}
+	s := t.sampler
+	if rs, ok := s.(*rulesSampler); ok {
+		cfg.SamplingRules = append([]SamplingRule(nil), rs.rules...)
+		s = rs.fallback
+	}
+	if l := t.limiter; l != nil {
+		cfg.RateLimit = l.rate
+	}
+	cfg.SpanSamplingRules = t.spanSamplingRules()
+	switch s := s.(type) {
+	case *rateSampler:
+		cfg.Sampler = "rate"
+		cfg.SampleRate = s.SampleRate
+	case *prioritySampler:
+		cfg.Sampler = "priority"
+	case customSampler:
+		cfg.Sampler = "custom"
+	}
+	cfg.FlushInterval = t.flushInterval
+	cfg.TraceTimeout = time.Duration(atomic.LoadInt64(&t.traceTimeout))
+	cfg.MaxSpanTags = int(atomic.LoadInt64(&t.maxSpanTags))
+	cfg.StatsComputation = t.stats.isEnabled()
+	cfg.DroppedTraceStats = t.stats.isDroppedEnabled()
+	cfg.TraceID128Generation = t.TraceID128GenerationEnabled()
+	cfg.DebugBaggage = t.DebugBaggageEnabled()
+	cfg.DogStatsDAddr = t.DogStatsDAddr()
+	if ht, ok := t.transport.(*httpTransport); ok {
+		cfg.AgentURL = ht.baseURL
+		cfg.MaxPayloadSize = ht.maxPayloadSize
+		cfg.PayloadCompression = ht.compressionEnabled()
+		cfg.PayloadEncoding = ht.encodingName()
+	}
+	if cfg.Tags == nil {
+		cfg.Tags = make(map[string]string)
+	}
+
+	t.sendSemMu.RLock()
+	cfg.ConcurrentSends = cap(t.sendSem)
+	t.sendSemMu.RUnlock()
+
+	t.servicesMu.RLock()
+	cfg.Services = make(map[string]Service, len(t.services))
+	for name, s := range t.services {
+		cfg.Services[name] = s
+	}
+	t.servicesMu.RUnlock()
+
+	return cfg
+}
+
+// setMaxPayloadSize sets the size above which the payloads sent by the HTTP
+// transport are split, before the tracer starts. Custom transports are left
+// untouched.
+func (t *Tracer) setMaxPayloadSize(n int) {
+	if ht, ok := t.transport.(*httpTransport); ok {
+		ht.maxPayloadSize = n
+	}
+}
@@ -0,0 +1,138 @@
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerConfigDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer := NewTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.True(cfg.Enabled)
+	assert.False(cfg.Debug)
+	assert.Equal("http://localhost:8126", cfg.AgentURL)
+	assert.Equal("all", cfg.Sampler)
+	assert.Equal(1.0, cfg.SampleRate)
+	assert.Equal(defaultConcurrentSends, cfg.ConcurrentSends)
+	assert.Equal(defaultFlushInterval, cfg.FlushInterval)
+	assert.Equal(defaultTraceTimeout, cfg.TraceTimeout)
+	assert.Equal(defaultMaxSpanTags, cfg.MaxSpanTags)
+	assert.Equal(maxPayloadSize, cfg.MaxPayloadSize)
+	assert.Len(cfg.Services, 0)
+	assert.Len(cfg.Tags, 0)
+}
+
+func TestTracerConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	tracer.SetEnabled(false)
+	tracer.SetDebugLogging(true)
+	tracer.SetSampleRate(0.5)
+	tracer.SetConcurrentSends(2)
+	tracer.SetKeepErrors(true)
+	tracer.SetMeta("env", "staging")
+	tracer.SetServiceInfo("api-intake", "gin", "web")
+	tracer.ForceFlush()
+
+	cfg := tracer.Config()
+	assert.False(cfg.Enabled)
+	assert.True(cfg.Debug)
+	assert.Equal("", cfg.AgentURL)
+	assert.Equal("rate", cfg.Sampler)
+	assert.Equal(0.5, cfg.SampleRate)
+	assert.Equal(2, cfg.ConcurrentSends)
+	assert.True(cfg.KeepErrors)
+	assert.Equal(map[string]Service{"api-intake": Service{Name: "api-intake", App: "gin", AppType: "web"}}, cfg.Services)
+	assert.Equal(map[string]string{"env": "staging"}, cfg.Tags)
+
+	// the configuration is a snapshot
+	cfg.Tags["env"] = "prod"
+	assert.Equal(map[string]string{"env": "staging"}, tracer.Config().Tags)
+}
+
+func TestNewTracerWithConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = "http://datadog-agent:8127"
+	cfg.Sampler = "rate"
+	cfg.SampleRate = 0.5
+	cfg.SamplingRules = []SamplingRule{{Service: "db", Rate: 0.1}}
+	cfg.RateLimit = 10
+	cfg.KeepErrors = true
+	cfg.ConcurrentSends = 2
+	cfg.FlushInterval = time.Second
+	cfg.TraceTimeout = 0
+	cfg.MaxSpanTags = 10
+	cfg.MaxPayloadSize = 1024 * 1024
+	cfg.PayloadCompression = true
+	cfg.Services = map[string]Service{"db": Service{Name: "db", App: "postgres", AppType: "db"}}
+	cfg.Tags = map[string]string{"env": "staging"}
+
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+	tracer.ForceFlush()
+
+	cfg.Transport = nil
+	assert.Equal(cfg, tracer.Config())
+	assert.NotEqual(DefaultTracer.Config(), tracer.Config())
+
+	// the default configuration is the one of a new tracer
+	other := NewTracerWithConfig(DefaultConfig())
+	defer other.Stop()
+	assert.Equal(DefaultConfig(), other.Config())
+}
+
+func TestNewTracerWithConfigTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &dummyTransport{getEncoder: msgpackEncoderFactory}
+	cfg := DefaultConfig()
+	cfg.Transport = transport
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+	assert.Equal("", tracer.Config().AgentURL)
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+}
+
+func TestNewTracerWithConfigAgentURL(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var paths []string
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer agent.Close()
+
+	// the path of the URL is ignored, the tracer picks the endpoints
+	cfg := DefaultConfig()
+	cfg.AgentURL = agent.URL + "/v0.3/traces"
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+	assert.Equal(agent.URL, tracer.Config().AgentURL)
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(paths, "/v0.3/traces")
+	assert.NotContains(paths, "/v0.3/traces/v0.3/traces")
+}
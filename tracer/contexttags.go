@@ -0,0 +1,46 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+)
+
+// contextTags maps context keys to the tags set from their values, it is
+// stored in an atomic.Value.
+type contextTags map[interface{}]string
+
+// SetContextTags makes the spans created from a context, e.g. by the contrib
+// middlewares and clients, tagged with the values the context holds for the
+// given keys, such as a tenant or request ID set by an earlier middleware.
+// The map associates each context key with the name of the tag, the values
+// being formatted with fmt.Sprint; keys missing from the context are
+// skipped. Calling it with an empty map removes the mapping.
+//
+//	tracer.SetContextTags(map[interface{}]string{tenantKey{}: "tenant.id"})
+func (t *Tracer) SetContextTags(tags map[interface{}]string) {
+	ct := make(contextTags, len(tags))
+	for k, v := range tags {
+		ct[k] = v
+	}
+	t.contextTags.Store(ct)
+}
+
+// setContextTags tags the span with the values of the context for the keys
+// set with SetContextTags.
+func (t *Tracer) setContextTags(span *Span, ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	tags, _ := t.contextTags.Load().(contextTags)
+	for key, tag := range tags {
+		if v := ctx.Value(key); v != nil {
+			span.SetMeta(tag, fmt.Sprint(v))
+		}
+	}
+}
+
+// SetContextTags sets the context tags of the default tracer, see
+// Tracer.SetContextTags.
+func SetContextTags(tags map[interface{}]string) {
+	DefaultTracer.SetContextTags(tags)
+}
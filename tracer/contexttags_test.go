@@ -0,0 +1,47 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantKey struct{}
+
+type requestIDKey struct{}
+
+func TestContextTags(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	tracer.SetContextTags(map[interface{}]string{
+		tenantKey{}:    "tenant.id",
+		requestIDKey{}: "request.id",
+	})
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = context.WithValue(ctx, requestIDKey{}, 42)
+	root := tracer.NewChildSpanFromContext("http.request", ctx)
+	assert.Equal("acme", root.GetMeta("tenant.id"))
+	assert.Equal("42", root.GetMeta("request.id"))
+
+	// keys missing from the context are skipped
+	ctx = context.WithValue(ContextWithSpan(context.Background(), root), tenantKey{}, "acme")
+	child := tracer.NewChildSpanFromContext("sql.query", ctx)
+	assert.Equal(root.SpanID, child.ParentID)
+	assert.Equal("acme", child.GetMeta("tenant.id"))
+	_, ok := child.Meta["request.id"]
+	assert.False(ok)
+
+	// an empty mapping removes the tags
+	tracer.SetContextTags(nil)
+	span := tracer.NewChildSpanFromContext("http.request", ctx)
+	assert.Equal("", span.GetMeta("tenant.id"))
+
+	// a nil context is tolerated
+	tracer.SetContextTags(map[interface{}]string{tenantKey{}: "tenant.id"})
+	span = tracer.NewChildSpanFromContext("http.request", nil)
+	assert.Equal("", span.GetMeta("tenant.id"))
+}
@@ -0,0 +1,54 @@
+package tracer
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+// TraceIDString returns the trace ID of the span in decimal, as expected in
+// the ext.LogKeyTraceID attribute of the logs correlated with the trace.
+func (s *Span) TraceIDString() string {
+	if s == nil {
+		return ""
+	}
+	return strconv.FormatUint(s.TraceID, 10)
+}
+
+// SpanIDString returns the span ID of the span in decimal, as expected in the
+// ext.LogKeySpanID attribute of the logs correlated with the span.
+func (s *Span) SpanIDString() string {
+	if s == nil {
+		return ""
+	}
+	return strconv.FormatUint(s.SpanID, 10)
+}
+
+// TraceIDHex returns the trace ID of the span as a 128-bit ID, in 32
+// lowercase hexadecimal characters, as expected by log pipelines correlating
+// logs with OpenTelemetry or W3C traces. The 64 upper bits are zero unless
+// the trace has a 128-bit ID, see TraceIDHigh.
+func (s *Span) TraceIDHex() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%016x%016x", s.TraceIDHigh(), s.TraceID)
+}
+
+// LogFields returns the attributes correlating a log record with the span,
+// keyed by ext.LogKeyTraceID and ext.LogKeySpanID, which can be added as is
+// to structured loggers:
+//
+//	for k, v := range span.LogFields() {
+//		entry = entry.WithField(k, v)
+//	}
+func (s *Span) LogFields() map[string]string {
+	if s == nil {
+		return nil
+	}
+	return map[string]string{
+		ext.LogKeyTraceID: s.TraceIDString(),
+		ext.LogKeySpanID:  s.SpanIDString(),
+	}
+}
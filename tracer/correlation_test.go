@@ -0,0 +1,28 @@
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func TestSpanIDFormatting(t *testing.T) {
+	assert := assert.New(t)
+
+	span := &Span{TraceID: 18446744073709551615, SpanID: 42}
+	assert.Equal("18446744073709551615", span.TraceIDString())
+	assert.Equal("42", span.SpanIDString())
+	assert.Equal("0000000000000000ffffffffffffffff", span.TraceIDHex())
+	assert.Equal(map[string]string{
+		ext.LogKeyTraceID: "18446744073709551615",
+		ext.LogKeySpanID:  "42",
+	}, span.LogFields())
+
+	var nilSpan *Span
+	assert.Equal("", nilSpan.TraceIDString())
+	assert.Equal("", nilSpan.SpanIDString())
+	assert.Equal("", nilSpan.TraceIDHex())
+	assert.Nil(nilSpan.LogFields())
+}
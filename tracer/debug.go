@@ -0,0 +1,73 @@
+package tracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// debugState is the tracer state rendered by the debug handler.
+type debugState struct {
+	Enabled        bool                        `json:"enabled"`
+	AgentURL       string                      `json:"agent_url,omitempty"`
+	Sampler        string                      `json:"sampler"`
+	SampleRate     float64                     `json:"sample_rate"`
+	BufferedTraces int                         `json:"buffered_traces"`
+	LastFlush      string                      `json:"last_flush,omitempty"`
+	LastFlushError string                      `json:"last_flush_error,omitempty"`
+	Errors         map[string]uint64           `json:"errors"`
+	WorkerStalls   uint64                      `json:"worker_stalls"`
+	Services       map[string]Service          `json:"services"`
+	Integrations   map[string]IntegrationStats `json:"integrations"`
+	Loaded         []Integration               `json:"loaded_integrations"`
+	Pools          map[string]PoolStats        `json:"pools"`
+	Tags           map[string]string           `json:"tags"`
+}
+
+// DebugHandler returns an http.Handler rendering the live state of the
+// tracer as JSON: buffered traces, last flush, error and drop counters,
+// sampler, reported services, the loaded and active integrations, and the
+// usage of the instrumented pools. It is meant to be mounted under a private
+// path, e.g.:
+//
+//	http.Handle("/debug/datadog", tracer.DefaultTracer.DebugHandler())
+func (t *Tracer) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := t.Config()
+		stats := t.Stats()
+		state := debugState{
+			Enabled:        cfg.Enabled,
+			AgentURL:       cfg.AgentURL,
+			Sampler:        cfg.Sampler,
+			SampleRate:     cfg.SampleRate,
+			BufferedTraces: stats.BufferedTraces,
+			Errors:         make(map[string]uint64, len(stats.Errors)),
+			WorkerStalls:   stats.WorkerStalls,
+			Services:       cfg.Services,
+			Integrations:   stats.Integrations,
+			Loaded:         Integrations(),
+			Pools:          stats.Pools,
+			Tags:           cfg.Tags,
+		}
+		if !stats.LastFlush.IsZero() {
+			state.LastFlush = stats.LastFlush.UTC().Format(time.RFC3339Nano)
+		}
+		if stats.LastFlushErr != nil {
+			state.LastFlushError = stats.LastFlushErr.Error()
+		}
+		for c, n := range stats.Errors {
+			state.Errors[c.String()] = n
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(state)
+	})
+}
+
+// DebugHandler returns an http.Handler rendering the live state of the
+// default tracer.
+func DebugHandler() http.Handler {
+	return DefaultTracer.DebugHandler()
+}
@@ -0,0 +1,46 @@
+package tracer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetServiceInfo("api-intake", "gin", "web")
+	tracer.NewPool("workers", "api-intake", 4)
+	tracer.channels.pushErr(&errorTraceChanFull{Len: traceChanLen})
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+	assert.False(tracer.Stats().LastFlush.IsZero())
+	assert.NoError(tracer.Stats().LastFlushErr)
+
+	tracer.SetSampleRate(0.5)
+	tracer.lastFlush.record(time.Now(), errors.New("connection refused"))
+
+	w := httptest.NewRecorder()
+	tracer.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/datadog", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+	var state debugState
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &state))
+	assert.True(state.Enabled)
+	assert.Equal("rate", state.Sampler)
+	assert.Equal(0.5, state.SampleRate)
+	assert.NotEmpty(state.LastFlush)
+	assert.Equal("connection refused", state.LastFlushError)
+	assert.Equal(uint64(1), state.Errors["buffer_overflow"])
+	assert.Equal(uint64(0), state.Errors["transport"])
+	assert.Contains(state.Services, "api-intake")
+	assert.Equal(PoolStats{Size: 4}, state.Pools["workers"])
+}
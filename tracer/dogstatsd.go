@@ -0,0 +1,63 @@
+package tracer
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// dogstatsd sends gauges to a DogStatsD server, such as the one of the agent,
+// over UDP: the gauges which can't be sent are dropped.
+type dogstatsd struct {
+	addr string
+	conn net.Conn
+}
+
+func newDogStatsD(addr string) (*dogstatsd, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &dogstatsd{addr: addr, conn: conn}, nil
+}
+
+// gauge sends the value of the gauge of the given name, with the given tags.
+func (d *dogstatsd) gauge(name string, value float64, tags ...string) {
+	msg := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|g"
+	if len(tags) > 0 {
+		msg += "|#" + strings.Join(tags, ",")
+	}
+	d.conn.Write([]byte(msg))
+}
+
+// SetDogStatsDAddr sets the address of the DogStatsD server the tracer sends
+// its gauges to, such as the usage of the pools, e.g. "localhost:8125". The
+// empty address, the default, disables them.
+func (t *Tracer) SetDogStatsDAddr(addr string) {
+	var d *dogstatsd
+	if addr != "" {
+		var err error
+		if d, err = newDogStatsD(addr); err != nil {
+			logf(logWarn, "tracer", "ignoring the DogStatsD address %q: %v", addr, err)
+			return
+		}
+	}
+	if prev, _ := t.dogstatsd.Swap(d).(*dogstatsd); prev != nil {
+		prev.conn.Close()
+	}
+}
+
+// DogStatsDAddr returns the address of the DogStatsD server the tracer sends
+// its gauges to, empty if they are disabled.
+func (t *Tracer) DogStatsDAddr() string {
+	if d := t.dogStatsD(); d != nil {
+		return d.addr
+	}
+	return ""
+}
+
+// dogStatsD returns the DogStatsD client of the tracer, nil if unset.
+func (t *Tracer) dogStatsD() *dogstatsd {
+	d, _ := t.dogstatsd.Load().(*dogstatsd)
+	return d
+}
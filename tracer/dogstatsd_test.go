@@ -0,0 +1,54 @@
+package tracer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newDogStatsDServer returns a UDP connection receiving the gauges sent to
+// its address.
+func newDogStatsDServer(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readGauge returns the next gauge received by the server.
+func readGauge(conn *net.UDPConn) string {
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err.Error()
+	}
+	return string(buf[:n])
+}
+
+func TestDogStatsD(t *testing.T) {
+	assert := assert.New(t)
+	server := newDogStatsDServer(t)
+	defer server.Close()
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal("", tracer.DogStatsDAddr())
+
+	addr := server.LocalAddr().String()
+	tracer.SetDogStatsDAddr(addr)
+	assert.Equal(addr, tracer.DogStatsDAddr())
+	assert.Equal(addr, tracer.Config().DogStatsDAddr)
+	tracer.dogStatsD().gauge("pool.size", 2, "pool:conns", "service:db")
+	assert.Equal("pool.size:2|g|#pool:conns,service:db", readGauge(server))
+	tracer.dogStatsD().gauge("pool.waiting", 0.5)
+	assert.Equal("pool.waiting:0.5|g", readGauge(server))
+
+	tracer.SetDogStatsDAddr("localhost:notaport")
+	assert.Equal(addr, tracer.DogStatsDAddr())
+	tracer.SetDogStatsDAddr("")
+	assert.Equal("", tracer.DogStatsDAddr())
+}
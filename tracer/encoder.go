@@ -3,6 +3,8 @@ package tracer
 import (
 	"bytes"
 	"encoding/json"
+	"io"
+	"sync"
 
 	"github.com/ugorji/go/codec"
 )
@@ -10,6 +12,10 @@ import (
 const (
 	jsonContentType    = "application/json"
 	msgpackContentType = "application/msgpack"
+
+	// maxRetainedBufferSize is the capacity above which an encoder buffer is
+	// not reused, so that a burst of traces doesn't pin memory forever.
+	maxRetainedBufferSize = 4 * 1024 * 1024
 )
 
 // Encoder is a generic interface that expects encoding methods for traces and
@@ -21,11 +27,86 @@ type Encoder interface {
 	ContentType() string
 }
 
+// BufferedEncoder is an Encoder holding its whole payload, such as the
+// built-in ones: the transport splits its payloads when they are too large
+// for the agent, and releases it once they have been sent. The payloads of
+// the other encoders are streamed as they are read.
+type BufferedEncoder interface {
+	Encoder
+	// Len returns the number of unread bytes of the encoded payload.
+	Len() int
+	// Close releases the encoder once the payload has been sent, allowing
+	// its resources to be reused. The encoder must not be used afterwards.
+	Close() error
+}
+
+// encoderLen returns the number of unread bytes of the payload of the
+// encoder, -1 if it isn't known.
+func encoderLen(e Encoder) int {
+	if be, ok := e.(BufferedEncoder); ok {
+		return be.Len()
+	}
+	return -1
+}
+
+// closeEncoder releases the encoder, if it is a BufferedEncoder.
+func closeEncoder(e Encoder) {
+	if be, ok := e.(BufferedEncoder); ok {
+		be.Close()
+	}
+}
+
 var mh codec.MsgpackHandle
 
+// encoderBuffer is the buffer holding an encoded payload. It is safe to read
+// and close concurrently, which is required since the HTTP transport may
+// close a request body while it is still being read.
+type encoderBuffer struct {
+	buffer *bytes.Buffer
+	mu     sync.Mutex
+	closed bool
+}
+
+// Read values from the internal buffer
+func (b *encoderBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, io.EOF
+	}
+	return b.buffer.Read(p)
+}
+
+// Len returns the number of unread bytes in the internal buffer
+func (b *encoderBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0
+	}
+	return b.buffer.Len()
+}
+
+// release marks the buffer as closed and reports whether it can be reused
+// by another encoder. It returns false if it was already released or if the
+// buffer grew too big to be worth keeping around.
+func (b *encoderBuffer) release() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	b.closed = true
+	if b.buffer.Cap() > maxRetainedBufferSize {
+		return false
+	}
+	b.buffer.Reset()
+	return true
+}
+
 // msgpackEncoder encodes a list of traces in Msgpack format
 type msgpackEncoder struct {
-	buffer      *bytes.Buffer
+	encoderBuffer
 	encoder     *codec.Encoder
 	contentType string
 }
@@ -35,9 +116,9 @@ func newMsgpackEncoder() *msgpackEncoder {
 	encoder := codec.NewEncoder(buffer, &mh)
 
 	return &msgpackEncoder{
-		buffer:      buffer,
-		encoder:     encoder,
-		contentType: msgpackContentType,
+		encoderBuffer: encoderBuffer{buffer: buffer},
+		encoder:       encoder,
+		contentType:   msgpackContentType,
 	}
 }
 
@@ -52,19 +133,28 @@ func (e *msgpackEncoder) EncodeServices(services map[string]Service) error {
 	return e.encoder.Encode(services)
 }
 
-// Read values from the internal buffer
-func (e *msgpackEncoder) Read(p []byte) (int, error) {
-	return e.buffer.Read(p)
-}
-
 // ContentType return the msgpackEncoder content-type
 func (e *msgpackEncoder) ContentType() string {
 	return e.contentType
 }
 
+// Close gives the internal buffer back to the pool so that it can be reused
+// by a later flush. A new encoder wraps it, so that this one can't access
+// the buffer anymore.
+func (e *msgpackEncoder) Close() error {
+	if e.release() {
+		msgpackEncoderPool.Put(&msgpackEncoder{
+			encoderBuffer: encoderBuffer{buffer: e.buffer},
+			encoder:       e.encoder,
+			contentType:   e.contentType,
+		})
+	}
+	return nil
+}
+
 // jsonEncoder encodes a list of traces in JSON format
 type jsonEncoder struct {
-	buffer      *bytes.Buffer
+	encoderBuffer
 	encoder     *json.Encoder
 	contentType string
 }
@@ -75,9 +165,9 @@ func newJSONEncoder() *jsonEncoder {
 	encoder := json.NewEncoder(buffer)
 
 	return &jsonEncoder{
-		buffer:      buffer,
-		encoder:     encoder,
-		contentType: jsonContentType,
+		encoderBuffer: encoderBuffer{buffer: buffer},
+		encoder:       encoder,
+		contentType:   jsonContentType,
 	}
 }
 
@@ -92,23 +182,39 @@ func (e *jsonEncoder) EncodeServices(services map[string]Service) error {
 	return e.encoder.Encode(services)
 }
 
-// Read values from the internal buffer
-func (e *jsonEncoder) Read(p []byte) (int, error) {
-	return e.buffer.Read(p)
-}
-
 // ContentType return the jsonEncoder content-type
 func (e *jsonEncoder) ContentType() string {
 	return e.contentType
 }
 
+// Close gives the internal buffer back to the pool so that it can be reused
+// by a later flush. A new encoder wraps it, so that this one can't access
+// the buffer anymore.
+func (e *jsonEncoder) Close() error {
+	if e.release() {
+		jsonEncoderPool.Put(&jsonEncoder{
+			encoderBuffer: encoderBuffer{buffer: e.buffer},
+			encoder:       e.encoder,
+			contentType:   e.contentType,
+		})
+	}
+	return nil
+}
+
 // encoderFactory will provide a new encoder each time we want to flush traces or services.
 type encoderFactory func() Encoder
 
+// encoder pools hold released encoders, their buffers being reused across
+// flushes to reduce the pressure on the garbage collector.
+var (
+	msgpackEncoderPool = sync.Pool{New: func() interface{} { return newMsgpackEncoder() }}
+	jsonEncoderPool    = sync.Pool{New: func() interface{} { return newJSONEncoder() }}
+)
+
 func jsonEncoderFactory() Encoder {
-	return newJSONEncoder()
+	return jsonEncoderPool.Get().(*jsonEncoder)
 }
 
 func msgpackEncoderFactory() Encoder {
-	return newMsgpackEncoder()
+	return msgpackEncoderPool.Get().(*msgpackEncoder)
 }
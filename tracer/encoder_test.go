@@ -2,6 +2,8 @@ package tracer
 
 import (
 	"encoding/json"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,6 +19,7 @@ func TestEncoderContentType(t *testing.T) {
 	}{
 		{newJSONEncoder(), "application/json"},
 		{newMsgpackEncoder(), "application/msgpack"},
+		{newMsgpackV05Encoder(), "application/msgpack"},
 	}
 
 	for _, tc := range testCases {
@@ -112,3 +115,59 @@ func TestMsgpackEncoding(t *testing.T) {
 		}
 	}
 }
+
+func TestEncoderClose(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, factory := range []encoderFactory{msgpackEncoderFactory, msgpackV05EncoderFactory, jsonEncoderFactory} {
+		encoder := factory().(BufferedEncoder)
+		assert.Equal(0, encoder.Len(), "encoders from the pool should be empty")
+		assert.Nil(encoder.EncodeTraces(getTestTrace(3, 3)))
+		assert.NotEqual(0, encoder.Len())
+
+		// once closed, the encoder doesn't give access to its buffer anymore
+		assert.Nil(encoder.Close())
+		assert.Nil(encoder.Close())
+		assert.Equal(0, encoder.Len())
+		n, err := encoder.Read(make([]byte, 16))
+		assert.Equal(0, n)
+		assert.Equal(io.EOF, err)
+
+		// the next encoder starts from scratch
+		encoder = factory().(BufferedEncoder)
+		assert.Equal(0, encoder.Len())
+		encoder.Close()
+	}
+}
+
+func TestEncoderCloseLargeBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	encoder := newMsgpackEncoder()
+	encoder.buffer.Grow(maxRetainedBufferSize + 1)
+	assert.False(encoder.release(), "large buffers should not be reused")
+
+	encoder = newMsgpackEncoder()
+	assert.True(encoder.release())
+	assert.False(encoder.release(), "buffers are released once")
+}
+
+func TestEncoderConcurrentReadClose(t *testing.T) {
+	encoder := newMsgpackEncoder()
+	encoder.EncodeTraces(getTestTrace(10, 10))
+
+	// the HTTP transport may close the body while it's still being read
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p := make([]byte, 8)
+		for {
+			if _, err := encoder.Read(p); err != nil {
+				return
+			}
+		}
+	}()
+	encoder.Close()
+	wg.Wait()
+}
@@ -0,0 +1,251 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+)
+
+// v05SpanFields is the number of fields of a span in the v0.5 format.
+const v05SpanFields = 12
+
+// msgpackV05Encoder encodes a list of traces in the v0.5 format of the agent
+// API: a msgpack array holding a table of all the strings of the payload,
+// followed by the traces, whose spans are arrays of fields referring to the
+// strings by their index in the table:
+//
+//	[service, name, resource, trace_id, span_id, parent_id, start, duration,
+//	 error, meta, metrics, type]
+//
+// Since services, operations and tag values repeat a lot across spans, the
+// payloads are much smaller than the ones of the v0.3 format. The services
+// are encoded as they are in msgpack, the v0.5 API has no services endpoint.
+type msgpackV05Encoder struct {
+	encoderBuffer
+	encoder *codec.Encoder
+
+	// strings maps the strings of the payload to their index in the table.
+	strings map[string]uint32
+	// table holds the strings of the payload, in order.
+	table []string
+	// spans is the buffer the traces are encoded to before the table.
+	spans *bytes.Buffer
+}
+
+func newMsgpackV05Encoder() *msgpackV05Encoder {
+	buffer := &bytes.Buffer{}
+	return &msgpackV05Encoder{
+		encoderBuffer: encoderBuffer{buffer: buffer},
+		encoder:       codec.NewEncoder(buffer, &mh),
+		strings:       make(map[string]uint32),
+		spans:         &bytes.Buffer{},
+	}
+}
+
+// EncodeTraces serializes the given trace list into the internal buffer,
+// returning the error if any.
+func (e *msgpackV05Encoder) EncodeTraces(traces [][]*Span) error {
+	// the empty string always comes first
+	e.index("")
+	writeArrayHeader(e.spans, len(traces))
+	for _, trace := range traces {
+		writeArrayHeader(e.spans, len(trace))
+		for _, span := range trace {
+			e.encodeSpan(span)
+		}
+	}
+
+	writeArrayHeader(e.buffer, 2)
+	writeArrayHeader(e.buffer, len(e.table))
+	for _, s := range e.table {
+		writeString(e.buffer, s)
+	}
+	_, err := e.spans.WriteTo(e.buffer)
+	e.reset()
+	return err
+}
+
+// encodeSpan appends the span to the encoded traces.
+func (e *msgpackV05Encoder) encodeSpan(span *Span) {
+	b := e.spans
+	writeArrayHeader(b, v05SpanFields)
+	writeUint(b, uint64(e.index(span.Service)))
+	writeUint(b, uint64(e.index(span.Name)))
+	writeUint(b, uint64(e.index(span.Resource)))
+	writeUint(b, span.TraceID)
+	writeUint(b, span.SpanID)
+	writeUint(b, span.ParentID)
+	writeInt(b, span.Start)
+	writeInt(b, span.Duration)
+	writeInt(b, int64(span.Error))
+	writeMapHeader(b, len(span.Meta))
+	for k, v := range span.Meta {
+		writeUint(b, uint64(e.index(k)))
+		writeUint(b, uint64(e.index(v)))
+	}
+	writeMapHeader(b, len(span.Metrics))
+	for k, v := range span.Metrics {
+		writeUint(b, uint64(e.index(k)))
+		writeFloat(b, v)
+	}
+	writeUint(b, uint64(e.index(span.Type)))
+}
+
+// index returns the index of the string in the table, adding it if needed.
+func (e *msgpackV05Encoder) index(s string) uint32 {
+	if i, ok := e.strings[s]; ok {
+		return i
+	}
+	i := uint32(len(e.table))
+	e.strings[s] = i
+	e.table = append(e.table, s)
+	return i
+}
+
+// reset clears the string table and the traces buffer, which are only needed
+// while encoding.
+func (e *msgpackV05Encoder) reset() {
+	for s := range e.strings {
+		delete(e.strings, s)
+	}
+	e.table = e.table[:0]
+	e.spans.Reset()
+}
+
+// EncodeServices serializes a service map into the internal buffer.
+func (e *msgpackV05Encoder) EncodeServices(services map[string]Service) error {
+	return e.encoder.Encode(services)
+}
+
+// ContentType return the msgpackV05Encoder content-type
+func (e *msgpackV05Encoder) ContentType() string {
+	return msgpackContentType
+}
+
+// Close gives the internal buffers back to the pool so that they can be
+// reused by a later flush. A new encoder wraps them, so that this one can't
+// access the buffer anymore.
+func (e *msgpackV05Encoder) Close() error {
+	if e.release() {
+		msgpackV05EncoderPool.Put(&msgpackV05Encoder{
+			encoderBuffer: encoderBuffer{buffer: e.buffer},
+			encoder:       e.encoder,
+			strings:       e.strings,
+			table:         e.table,
+			spans:         e.spans,
+		})
+	}
+	return nil
+}
+
+var msgpackV05EncoderPool = sync.Pool{New: func() interface{} { return newMsgpackV05Encoder() }}
+
+func msgpackV05EncoderFactory() Encoder {
+	return msgpackV05EncoderPool.Get().(*msgpackV05Encoder)
+}
+
+// writeArrayHeader writes the header of a msgpack array of n elements.
+func writeArrayHeader(b *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		b.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(0xdc)
+		writeBigEndian(b, uint64(n), 2)
+	default:
+		b.WriteByte(0xdd)
+		writeBigEndian(b, uint64(n), 4)
+	}
+}
+
+// writeMapHeader writes the header of a msgpack map of n entries.
+func writeMapHeader(b *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		b.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(0xde)
+		writeBigEndian(b, uint64(n), 2)
+	default:
+		b.WriteByte(0xdf)
+		writeBigEndian(b, uint64(n), 4)
+	}
+}
+
+// writeString writes a msgpack string.
+func writeString(b *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		b.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		b.WriteByte(0xd9)
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(0xda)
+		writeBigEndian(b, uint64(n), 2)
+	default:
+		b.WriteByte(0xdb)
+		writeBigEndian(b, uint64(n), 4)
+	}
+	b.WriteString(s)
+}
+
+// writeUint writes a msgpack unsigned integer, in as few bytes as possible.
+func writeUint(b *bytes.Buffer, v uint64) {
+	switch {
+	case v < 128:
+		b.WriteByte(byte(v))
+	case v <= math.MaxUint8:
+		b.WriteByte(0xcc)
+		b.WriteByte(byte(v))
+	case v <= math.MaxUint16:
+		b.WriteByte(0xcd)
+		writeBigEndian(b, v, 2)
+	case v <= math.MaxUint32:
+		b.WriteByte(0xce)
+		writeBigEndian(b, v, 4)
+	default:
+		b.WriteByte(0xcf)
+		writeBigEndian(b, v, 8)
+	}
+}
+
+// writeInt writes a msgpack signed integer, in as few bytes as possible.
+func writeInt(b *bytes.Buffer, v int64) {
+	if v >= 0 {
+		writeUint(b, uint64(v))
+		return
+	}
+	switch {
+	case v >= -32:
+		b.WriteByte(byte(v))
+	case v >= math.MinInt8:
+		b.WriteByte(0xd0)
+		b.WriteByte(byte(v))
+	case v >= math.MinInt16:
+		b.WriteByte(0xd1)
+		writeBigEndian(b, uint64(v), 2)
+	case v >= math.MinInt32:
+		b.WriteByte(0xd2)
+		writeBigEndian(b, uint64(v), 4)
+	default:
+		b.WriteByte(0xd3)
+		writeBigEndian(b, uint64(v), 8)
+	}
+}
+
+// writeFloat writes a msgpack 64-bit float.
+func writeFloat(b *bytes.Buffer, v float64) {
+	b.WriteByte(0xcb)
+	writeBigEndian(b, math.Float64bits(v), 8)
+}
+
+// writeBigEndian writes the n lower bytes of v, most significant first.
+func writeBigEndian(b *bytes.Buffer, v uint64, n int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	b.Write(buf[8-n:])
+}
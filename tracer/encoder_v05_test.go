@@ -0,0 +1,206 @@
+package tracer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// errBadV05Payload is returned when a payload doesn't follow the v0.5
+// format.
+var errBadV05Payload = errors.New("malformed v0.5 payload")
+
+// decodePayloadV05 decodes a payload encoded by msgpackV05Encoder.
+func decodePayloadV05(payload []byte) (traces [][]*Span, err error) {
+	defer func() {
+		// the payload doesn't have the expected types
+		if r := recover(); r != nil {
+			traces, err = nil, fmt.Errorf("%v: %v", errBadV05Payload, r)
+		}
+	}()
+	var raw []interface{}
+	if err := codec.NewDecoderBytes(payload, &mh).Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw) != 2 {
+		return nil, errBadV05Payload
+	}
+	var table []string
+	for _, s := range raw[0].([]interface{}) {
+		table = append(table, string(s.([]byte)))
+	}
+	if len(table) == 0 || table[0] != "" {
+		return nil, errBadV05Payload
+	}
+	str := func(v interface{}) string { return table[toUint(v)] }
+
+	for _, rawTrace := range raw[1].([]interface{}) {
+		var trace []*Span
+		for _, rawSpan := range rawTrace.([]interface{}) {
+			fields := rawSpan.([]interface{})
+			if len(fields) != v05SpanFields {
+				return nil, errBadV05Payload
+			}
+			span := &Span{
+				Service:  str(fields[0]),
+				Name:     str(fields[1]),
+				Resource: str(fields[2]),
+				TraceID:  toUint(fields[3]),
+				SpanID:   toUint(fields[4]),
+				ParentID: toUint(fields[5]),
+				Start:    toInt(fields[6]),
+				Duration: toInt(fields[7]),
+				Error:    int32(toInt(fields[8])),
+				Meta:     make(map[string]string),
+				Metrics:  make(map[string]float64),
+				Type:     str(fields[11]),
+			}
+			for k, v := range fields[9].(map[interface{}]interface{}) {
+				span.Meta[str(k)] = str(v)
+			}
+			for k, v := range fields[10].(map[interface{}]interface{}) {
+				span.Metrics[str(k)] = v.(float64)
+			}
+			trace = append(trace, span)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// toUint returns the given decoded msgpack integer.
+func toUint(v interface{}) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	}
+	panic("not an integer")
+}
+
+func toInt(v interface{}) int64 {
+	return int64(toUint(v))
+}
+
+// decodeV05 decodes a v0.5 payload into traces.
+func decodeV05(t *testing.T, payload []byte) [][]*Span {
+	traces, err := decodePayloadV05(payload)
+	assert.NoError(t, err)
+	return traces
+}
+
+func TestMsgpackV05Encoding(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tc := range []struct{ traces, size int }{{1, 1}, {3, 1}, {1, 3}, {3, 3}} {
+		payload := getTestTrace(tc.traces, tc.size)
+		encoder := newMsgpackV05Encoder()
+		assert.NoError(encoder.EncodeTraces(payload))
+		assert.Equal(payload, decodeV05(t, encoder.buffer.Bytes()))
+	}
+
+	// repeated strings are only encoded once
+	encoder := newMsgpackV05Encoder()
+	assert.NoError(encoder.EncodeTraces(getTestTrace(10, 10)))
+	assert.Equal(1, bytes.Count(encoder.buffer.Bytes(), []byte("high.throughput")))
+	v03 := newMsgpackEncoder()
+	assert.NoError(v03.EncodeTraces(getTestTrace(10, 10)))
+	assert.True(encoder.Len() < v03.Len()/2, "%d >= %d/2", encoder.Len(), v03.Len())
+}
+
+func TestMsgpackV05EncodingValues(t *testing.T) {
+	assert := assert.New(t)
+
+	span := &Span{
+		Service:  strings.Repeat("s", 40),
+		Name:     strings.Repeat("n", 300),
+		Resource: strings.Repeat("r", 70000),
+		TraceID:  math.MaxUint64,
+		SpanID:   1 << 40,
+		ParentID: 200,
+		Start:    -1,
+		Duration: math.MaxInt64,
+		Error:    1,
+		Meta:     make(map[string]string),
+		Metrics:  map[string]float64{"neg": -1000, "min": math.MinInt32},
+	}
+	for i := 0; i < 20; i++ {
+		span.Meta[strings.Repeat("k", i+1)] = "v"
+	}
+	trace := make([]*Span, 20)
+	for i := range trace {
+		trace[i] = span
+	}
+	encoder := newMsgpackV05Encoder()
+	assert.NoError(encoder.EncodeTraces([][]*Span{trace}))
+	decoded := decodeV05(t, encoder.buffer.Bytes())
+	assert.Len(decoded[0], 20)
+	assert.Equal(span, decoded[0][19])
+
+	// the encoder is reset for the next payload
+	encoder.buffer.Reset()
+	payload := getTestTrace(1, 1)
+	assert.NoError(encoder.EncodeTraces(payload))
+	assert.Equal(payload, decodeV05(t, encoder.buffer.Bytes()))
+}
+
+func TestTransportV05(t *testing.T) {
+	assert := assert.New(t)
+
+	var received [][]*Span
+	agent := newInfoServer(`{"endpoints": ["/v0.3/traces", "/v0.4/traces", "/v0.5/traces"]}`, "/v0.3/traces")
+	defer agent.Close()
+	agent.Config.Handler.(*http.ServeMux).HandleFunc(v05TracesPath, func(w http.ResponseWriter, r *http.Request) {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r.Body)
+		received = decodeV05(t, buf.Bytes())
+	})
+	u, err := url.Parse(agent.URL)
+	assert.NoError(err)
+
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	_, err = transport.discover()
+	assert.NoError(err)
+	assert.Equal(agent.URL+v05TracesPath, transport.endpoint())
+
+	payload := getTestTrace(2, 2)
+	response, err := transport.SendTraces(payload)
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal(payload, received)
+}
+
+func TestTransportV05Fallback(t *testing.T) {
+	assert := assert.New(t)
+
+	var paths []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == v05TracesPath {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer receiver.Close()
+	u, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+
+	// the agent advertised the v0.5 API, but rejects it
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	transport.useV05(true)
+	response, err := transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{v05TracesPath, "/v0.3/traces"}, paths)
+	assert.Equal(receiver.URL+"/v0.3/traces", transport.endpoint())
+	assert.False(transport.compatibilityMode)
+}
@@ -0,0 +1,128 @@
+package tracer
+
+import (
+	"sort"
+	"sync"
+)
+
+// PayloadEncoding describes a format the traces can be sent to the agent in:
+// the encoder producing the payloads and the endpoint of the agent receiving
+// them. The built-in encodings are "msgpack", "v0.5" and "json", others can
+// be added with RegisterPayloadEncoding.
+type PayloadEncoding struct {
+	// Name identifies the encoding, such as "v0.5".
+	Name string
+	// Path is the path of the agent endpoint receiving the payloads, such
+	// as "/v0.5/traces".
+	Path string
+	// NewEncoder returns the encoder of the next payload, it is called for
+	// each of them and may be called concurrently. A BufferedEncoder is
+	// closed once the payload is sent, so that it can be pooled.
+	NewEncoder func() Encoder
+}
+
+// the built-in encodings
+var (
+	// EncodingMsgpack is the default encoding, the v0.3 msgpack format.
+	EncodingMsgpack = PayloadEncoding{Name: "msgpack", Path: "/v0.3/traces", NewEncoder: msgpackEncoderFactory}
+	// EncodingV05 is the v0.5 format, whose strings are deduplicated in a
+	// table, used on its own with the agents supporting it.
+	EncodingV05 = PayloadEncoding{Name: "v0.5", Path: v05TracesPath, NewEncoder: msgpackV05EncoderFactory}
+	// EncodingJSON is the JSON format, meant for debugging.
+	EncodingJSON = PayloadEncoding{Name: "json", Path: "/v0.3/traces", NewEncoder: jsonEncoderFactory}
+)
+
+// encodingRegistry holds the payload encodings by name. It is safe for
+// concurrent use.
+type encodingRegistry struct {
+	mu        sync.RWMutex
+	encodings map[string]PayloadEncoding
+}
+
+// payloadEncodings is the registry of the encodings available to the tracers,
+// it is global as the encodings are provided by the program.
+var payloadEncodings encodingRegistry
+
+func init() {
+	for _, e := range []PayloadEncoding{EncodingMsgpack, EncodingV05, EncodingJSON} {
+		payloadEncodings.register(e)
+	}
+}
+
+func (r *encodingRegistry) register(e PayloadEncoding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.encodings == nil {
+		r.encodings = make(map[string]PayloadEncoding)
+	}
+	r.encodings[e.Name] = e
+}
+
+func (r *encodingRegistry) get(name string) (PayloadEncoding, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.encodings[name]
+	return e, ok
+}
+
+// names returns the names of the encodings, sorted.
+func (r *encodingRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.encodings))
+	for name := range r.encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterPayloadEncoding makes an encoding available to SetPayloadEncoding
+// and to the DD_TRACE_PAYLOAD_ENCODING environment variable, under its name.
+// Registering an encoding with the name of another one replaces it. Encodings
+// without a name, a path or an encoder are ignored.
+func RegisterPayloadEncoding(e PayloadEncoding) {
+	if e.Name == "" || e.Path == "" || e.NewEncoder == nil {
+		logf(logWarn, "tracer", "ignoring the payload encoding %q: it needs a name, a path and an encoder", e.Name)
+		return
+	}
+	payloadEncodings.register(e)
+}
+
+// PayloadEncodings returns the names of the registered encodings, sorted.
+func PayloadEncodings() []string {
+	return payloadEncodings.names()
+}
+
+// SetPayloadEncoding sets the encoding of the payloads sent to the agent, by
+// name, see RegisterPayloadEncoding. The empty name restores the default
+// behavior: msgpack, switching to v0.5 when the agent supports it. Unknown
+// encodings are ignored. If the agent rejects the payloads, the tracer falls
+// back to the default behavior, from the v0.5 format to the v0.3 one, and to
+// the legacy JSON API otherwise, and the encoding is unset. It has no effect
+// with a custom transport.
+func (t *Tracer) SetPayloadEncoding(name string) {
+	ht, ok := t.transport.(*httpTransport)
+	if !ok {
+		return
+	}
+	if name == "" {
+		ht.setEncoding(nil)
+		return
+	}
+	e, ok := payloadEncodings.get(name)
+	if !ok {
+		logf(logWarn, "tracer", "ignoring unknown payload encoding %q, use one of %v", name, PayloadEncodings())
+		return
+	}
+	ht.setEncoding(&e)
+}
+
+// PayloadEncoding returns the name of the encoding set with
+// SetPayloadEncoding, empty for the default behavior.
+func (t *Tracer) PayloadEncoding() string {
+	if ht, ok := t.transport.(*httpTransport); ok {
+		return ht.encodingName()
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// textEncoder is a custom encoder, the JSON one with its own content type.
+type textEncoder struct {
+	*jsonEncoder
+}
+
+func (e textEncoder) ContentType() string { return "text/plain" }
+
+func TestPayloadEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	type request struct{ path, contentType string }
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == infoPath {
+			// the agent doesn't tell what it supports
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, request{r.URL.Path, r.Header.Get("Content-Type")})
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+	u, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+
+	RegisterPayloadEncoding(PayloadEncoding{
+		Name:       "text",
+		Path:       "/v0.3/traces/text",
+		NewEncoder: func() Encoder { return textEncoder{newJSONEncoder()} },
+	})
+	defer func() {
+		payloadEncodings.mu.Lock()
+		delete(payloadEncodings.encodings, "text")
+		payloadEncodings.mu.Unlock()
+	}()
+	assert.Contains(PayloadEncodings(), "text")
+	assert.Contains(PayloadEncodings(), "v0.5")
+
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	assert.Equal("", tracer.PayloadEncoding())
+
+	tracer.SetPayloadEncoding("text")
+	assert.Equal("text", tracer.PayloadEncoding())
+	assert.Equal("text", tracer.Config().PayloadEncoding)
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+
+	// the encoding set sticks, whatever the agent supports
+	transport.useV05(true)
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+
+	// unknown encodings are ignored
+	tracer.SetPayloadEncoding("protobuf")
+	assert.Equal("text", tracer.PayloadEncoding())
+
+	// the default behavior is restored, with the features of the agent
+	tracer.SetPayloadEncoding("")
+	assert.Equal("", tracer.PayloadEncoding())
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	transport.useV05(false)
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal([]request{
+		{"/v0.3/traces/text", "text/plain"},
+		{"/v0.3/traces/text", "text/plain"},
+		{v05TracesPath, msgpackContentType},
+		{"/v0.3/traces", msgpackContentType},
+	}, requests)
+}
+
+func TestPayloadEncodingRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == infoPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path != "/v0.2/traces" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer receiver.Close()
+	u, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+
+	// the v0.5 format set explicitly falls back to v0.3, then to legacy JSON
+	tracer.SetPayloadEncoding("v0.5")
+	assert.Equal("v0.5", tracer.PayloadEncoding())
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal("", tracer.PayloadEncoding())
+
+	// other encodings are unset on downgrade
+	transport = newHTTPTransport(u.Hostname(), u.Port())
+	tracer = NewTracerTransport(transport)
+	defer tracer.Stop()
+	tracer.SetPayloadEncoding("json")
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal("", tracer.PayloadEncoding())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal([]string{
+		v05TracesPath, "/v0.3/traces", "/v0.2/traces",
+		"/v0.3/traces", "/v0.2/traces",
+	}, paths)
+}
+
+func TestRegisterPayloadEncodingInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	RegisterPayloadEncoding(PayloadEncoding{Name: "nopath", NewEncoder: msgpackEncoderFactory})
+	RegisterPayloadEncoding(PayloadEncoding{Path: "/v0.3/traces", NewEncoder: msgpackEncoderFactory})
+	RegisterPayloadEncoding(PayloadEncoding{Name: "noencoder", Path: "/v0.3/traces"})
+	assert.NotContains(PayloadEncodings(), "nopath")
+	assert.NotContains(PayloadEncodings(), "noencoder")
+	assert.NotContains(PayloadEncodings(), "")
+}
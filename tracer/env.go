@@ -0,0 +1,184 @@
+package tracer
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// envSampleRate is the environment variable holding the sample rate
+	// applied to all the traces, as a sampling rule.
+	envSampleRate = "DD_TRACE_SAMPLE_RATE"
+	// envSamplingRules is the environment variable holding the sampling
+	// rules, as the JSON array of their JSON form, see SamplingRule.
+	envSamplingRules = "DD_TRACE_SAMPLING_RULES"
+	// envSpanSamplingRules is the environment variable holding the span
+	// sampling rules, as the JSON array of their JSON form, see
+	// SpanSamplingRule.
+	envSpanSamplingRules = "DD_SPAN_SAMPLING_RULES"
+	// envRateLimit is the environment variable holding the maximum number of
+	// traces per second kept by the sampling rules.
+	envRateLimit = "DD_TRACE_RATE_LIMIT"
+	// envFlushInterval is the environment variable holding the period of the
+	// flushes of the traces, as a duration such as "500ms".
+	envFlushInterval = "DD_TRACE_FLUSH_INTERVAL"
+	// envMaxPayloadSize is the environment variable holding the size in bytes
+	// above which the payloads sent to the agent are split.
+	envMaxPayloadSize = "DD_TRACE_MAX_PAYLOAD_SIZE"
+	// envStatsComputation is the environment variable enabling the
+	// computation of the statistics of the traces by the tracer.
+	envStatsComputation = "DD_TRACE_STATS_COMPUTATION_ENABLED"
+	// envDroppedTraceStats is the environment variable enabling the
+	// statistics of the dropped traces.
+	envDroppedTraceStats = "DD_TRACE_DROPPED_STATS_ENABLED"
+	// envMaxSpanTags is the environment variable holding the maximum number
+	// of meta of a span.
+	envMaxSpanTags = "DD_TRACE_MAX_SPAN_TAGS"
+	// envTraceID128 is the environment variable enabling the generation of
+	// 128-bit trace IDs.
+	envTraceID128 = "DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED"
+	// envPayloadEncoding is the environment variable holding the name of the
+	// encoding of the trace payloads.
+	envPayloadEncoding = "DD_TRACE_PAYLOAD_ENCODING"
+	// envDebugBaggage is the environment variable enabling the debug
+	// baggage item.
+	envDebugBaggage = "DD_TRACE_DEBUG_BAGGAGE_ENABLED"
+	// envDogStatsDAddr is the environment variable holding the address of
+	// the DogStatsD server the gauges are sent to.
+	envDogStatsDAddr = "DD_DOGSTATSD_ADDR"
+
+	// defaultRateLimit is the number of traces per second kept by the
+	// sampling rules, unless SetSamplingRateLimit changes it.
+	defaultRateLimit = 100
+)
+
+// loadEnv configures the tracer from the environment variables shared with
+// the tracers of other languages. DD_TRACE_SAMPLING_RULES sets the sampling
+// rules and DD_TRACE_SAMPLE_RATE a last rule matching all the traces, which
+// take precedence over SetSampleRate, and DD_TRACE_RATE_LIMIT limits the
+// traces kept by the sampling rules. DD_SPAN_SAMPLING_RULES sets the rules
+// keeping spans of dropped traces. It also reads DD_TRACE_FLUSH_INTERVAL
+// and DD_TRACE_MAX_PAYLOAD_SIZE, which tune the flushes of high-throughput
+// services, before the tracer starts, DD_TRACE_STATS_COMPUTATION_ENABLED,
+// see SetStatsComputation, DD_TRACE_DROPPED_STATS_ENABLED, see
+// SetDroppedTraceStats, DD_TRACE_MAX_SPAN_TAGS, see SetMaxSpanTags,
+// DD_TRACE_128_BIT_TRACEID_GENERATION_ENABLED, see SetTraceID128Generation,
+// DD_TRACE_PAYLOAD_ENCODING, see SetPayloadEncoding,
+// DD_TRACE_DEBUG_BAGGAGE_ENABLED, see SetDebugBaggage, and
+// DD_DOGSTATSD_ADDR, see SetDogStatsDAddr.
+func (t *Tracer) loadEnv() {
+	var rules []SamplingRule
+	if v := os.Getenv(envSamplingRules); v != "" {
+		rules = parseSamplingRules(v)
+	}
+	if v := os.Getenv(envSampleRate); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a number between 0 and 1", envSampleRate, v)
+		} else {
+			rules = append(rules, SamplingRule{Rate: rate})
+		}
+	}
+	if len(rules) > 0 {
+		t.SetSamplingRules(rules...)
+	}
+	if v := os.Getenv(envSpanSamplingRules); v != "" {
+		var rules []SpanSamplingRule
+		if err := json.Unmarshal([]byte(v), &rules); err != nil {
+			logf(logWarn, "tracer", "ignoring %s=%q: %v", envSpanSamplingRules, v, err)
+		} else {
+			t.SetSpanSamplingRules(rules...)
+		}
+	}
+	if v := os.Getenv(envRateLimit); v != "" {
+		l, err := strconv.ParseFloat(v, 64)
+		if err != nil || l < 0 {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a positive number", envRateLimit, v)
+		} else {
+			t.SetSamplingRateLimit(l)
+		}
+	}
+	if v := os.Getenv(envFlushInterval); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a positive duration", envFlushInterval, v)
+		} else {
+			t.flushInterval = d
+		}
+	}
+	if v := os.Getenv(envMaxPayloadSize); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a positive number of bytes", envMaxPayloadSize, v)
+		} else {
+			t.setMaxPayloadSize(n)
+		}
+	}
+	if v := os.Getenv(envStatsComputation); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a boolean", envStatsComputation, v)
+		} else {
+			t.SetStatsComputation(enabled)
+		}
+	}
+	if v := os.Getenv(envDroppedTraceStats); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a boolean", envDroppedTraceStats, v)
+		} else {
+			t.SetDroppedTraceStats(enabled)
+		}
+	}
+	if v := os.Getenv(envMaxSpanTags); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a positive number", envMaxSpanTags, v)
+		} else {
+			t.SetMaxSpanTags(n)
+		}
+	}
+	if v := os.Getenv(envTraceID128); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a boolean", envTraceID128, v)
+		} else {
+			t.SetTraceID128Generation(enabled)
+		}
+	}
+	if v := os.Getenv(envPayloadEncoding); v != "" {
+		t.SetPayloadEncoding(v)
+	}
+	if v := os.Getenv(envDebugBaggage); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logf(logWarn, "tracer", "ignoring %s=%q, it must be a boolean", envDebugBaggage, v)
+		} else {
+			t.SetDebugBaggage(enabled)
+		}
+	}
+	if v := os.Getenv(envDogStatsDAddr); v != "" {
+		t.SetDogStatsDAddr(v)
+	}
+}
+
+// parseSamplingRules parses the value of DD_TRACE_SAMPLING_RULES, dropping
+// the rules whose rate isn't between 0 and 1.
+func parseSamplingRules(v string) []SamplingRule {
+	var rules []SamplingRule
+	if err := json.Unmarshal([]byte(v), &rules); err != nil {
+		logf(logWarn, "tracer", "ignoring %s=%q: %v", envSamplingRules, v, err)
+		return nil
+	}
+	valid := rules[:0]
+	for _, r := range rules {
+		if r.Rate < 0 || r.Rate > 1 {
+			logf(logWarn, "tracer", "ignoring sampling rule %+v of %s, its rate must be between 0 and 1", r, envSamplingRules)
+			continue
+		}
+		valid = append(valid, r)
+	}
+	return valid
+}
@@ -0,0 +1,239 @@
+package tracer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets the given environment variables, and returns a function
+// restoring them.
+func setenv(vars map[string]string) func() {
+	old := make(map[string]string, len(vars))
+	for k, v := range vars {
+		old[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func TestTracerEnvSampleRate(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envSampleRate: "0.5"})()
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.Equal([]SamplingRule{{Rate: 0.5}}, cfg.SamplingRules)
+	assert.Equal(float64(defaultRateLimit), cfg.RateLimit)
+
+	// the rule takes precedence over the sample rate
+	tracer.SetSampleRate(1)
+	var sampled int
+	for i := 0; i < 100; i++ {
+		span := tracer.NewRootSpan("http.request", "web", "GET /")
+		if span.Sampled {
+			sampled++
+			assert.Equal(0.5, span.Metrics[rulesRateMetricKey])
+		}
+	}
+	assert.InDelta(50, sampled, 25)
+}
+
+func TestTracerEnvSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{
+		envSamplingRules: `[{"service": "web-*", "name": "http.request"}, {"service": "db", "sample_rate": 2}]`,
+		envSampleRate:    "0",
+	})()
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.Equal([]SamplingRule{{Service: "web-*", Name: "http.request", Rate: 1}, {Rate: 0}}, cfg.SamplingRules)
+	assert.Equal(float64(defaultRateLimit), cfg.RateLimit)
+	assert.True(tracer.NewRootSpan("http.request", "web-api", "GET /").Sampled)
+	assert.False(tracer.NewRootSpan("grpc.server", "web-api", "Hello").Sampled)
+
+	os.Setenv(envSamplingRules, "{")
+	os.Setenv(envSampleRate, "")
+	tracer, _ = getTestTracer()
+	defer tracer.Stop()
+	assert.Len(tracer.Config().SamplingRules, 0)
+	assert.Equal(float64(defaultRateLimit), tracer.Config().RateLimit)
+}
+
+func TestTracerEnvSpanSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envSpanSamplingRules: `[{"name": "grpc.server"}]`})()
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	assert.Equal([]SpanSamplingRule{{Name: "grpc.server", Rate: 1}}, tracer.Config().SpanSamplingRules)
+
+	os.Setenv(envSpanSamplingRules, "grpc.server")
+	tracer, _ = getTestTracer()
+	defer tracer.Stop()
+	assert.Len(tracer.Config().SpanSamplingRules, 0)
+}
+
+func TestTracerEnvRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envSampleRate: "1", envRateLimit: "10"})()
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	assert.Equal(10.0, tracer.Config().RateLimit)
+	var sampled int
+	var span *Span
+	for i := 0; i < 100; i++ {
+		span = tracer.NewRootSpan("http.request", "web", "GET /")
+		if span.Sampled {
+			sampled++
+		}
+	}
+	assert.InDelta(10, sampled, 1)
+	assert.InDelta(0.55, span.Metrics[limitRateMetricKey], 0.01)
+
+	tracer.SetSamplingRateLimit(0)
+	assert.Equal(0.0, tracer.Config().RateLimit)
+	assert.True(tracer.NewRootSpan("http.request", "web", "GET /").Sampled)
+}
+
+func TestTracerEnvInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envSampleRate: "2", envRateLimit: "many"})()
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.Len(cfg.SamplingRules, 0)
+	assert.Equal(float64(defaultRateLimit), cfg.RateLimit)
+}
+
+func TestTracerEnvFlushing(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envFlushInterval: "500ms", envMaxPayloadSize: "1048576"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.Equal(500*time.Millisecond, cfg.FlushInterval)
+	assert.Equal(1048576, cfg.MaxPayloadSize)
+}
+
+func TestTracerEnvFlushingInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envFlushInterval: "500", envMaxPayloadSize: "-1"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+
+	cfg := tracer.Config()
+	assert.Equal(defaultFlushInterval, cfg.FlushInterval)
+	assert.Equal(maxPayloadSize, cfg.MaxPayloadSize)
+}
+
+func TestTracerEnvStatsComputation(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envStatsComputation: "true"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.True(tracer.Config().StatsComputation)
+
+	os.Setenv(envStatsComputation, "maybe")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.False(tracer.Config().StatsComputation)
+}
+
+func TestTracerEnvDroppedTraceStats(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envDroppedTraceStats: "true"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.True(tracer.Config().DroppedTraceStats)
+
+	os.Setenv(envDroppedTraceStats, "maybe")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.False(tracer.Config().DroppedTraceStats)
+}
+
+func TestTracerEnvMaxSpanTags(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envMaxSpanTags: "64"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.Equal(64, tracer.Config().MaxSpanTags)
+
+	os.Setenv(envMaxSpanTags, "-1")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.Equal(defaultMaxSpanTags, tracer.Config().MaxSpanTags)
+}
+
+func TestTracerEnvTraceID128(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envTraceID128: "true"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.True(tracer.Config().TraceID128Generation)
+
+	os.Setenv(envTraceID128, "maybe")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.False(tracer.Config().TraceID128Generation)
+}
+
+func TestTracerEnvDebugBaggage(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envDebugBaggage: "true"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.True(tracer.Config().DebugBaggage)
+
+	os.Setenv(envDebugBaggage, "maybe")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.False(tracer.Config().DebugBaggage)
+}
+
+func TestTracerEnvDogStatsDAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envDogStatsDAddr: "localhost:8125"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.Equal("localhost:8125", tracer.Config().DogStatsDAddr)
+}
+
+func TestTracerEnvPayloadEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	defer setenv(map[string]string{envPayloadEncoding: "json"})()
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.Equal("json", tracer.Config().PayloadEncoding)
+
+	os.Setenv(envPayloadEncoding, "protobuf")
+	tracer = NewTracer()
+	defer tracer.Stop()
+	assert.Equal("", tracer.Config().PayloadEncoding)
+}
@@ -1,14 +1,57 @@
 package tracer
 
 import (
-	"log"
+	"errors"
 	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	errorPrefix = "Datadog Tracer Error: "
 )
 
+// ErrorCategory classifies the errors encountered by the tracer. Most of them
+// result in spans, traces or services being dropped.
+type ErrorCategory int
+
+const (
+	// ErrorCategoryOther is for errors which don't fit in any other category.
+	ErrorCategoryOther ErrorCategory = iota
+	// ErrorCategoryBufferOverflow is for data dropped because an internal
+	// buffer or channel was full.
+	ErrorCategoryBufferOverflow
+	// ErrorCategoryEncoding is for payloads which could not be encoded.
+	ErrorCategoryEncoding
+	// ErrorCategoryTransport is for payloads which could not be sent to the agent.
+	ErrorCategoryTransport
+	// ErrorCategoryOversizedTrace is for spans dropped because their trace
+	// holds too many spans.
+	ErrorCategoryOversizedTrace
+	// ErrorCategoryInvalidSpan is for spans which are not properly set up,
+	// for instance because they were created outside of a tracer.
+	ErrorCategoryInvalidSpan
+
+	numErrorCategories = iota
+)
+
+var errorCategoryNames = [numErrorCategories]string{
+	ErrorCategoryOther:          "other",
+	ErrorCategoryBufferOverflow: "buffer_overflow",
+	ErrorCategoryEncoding:       "encoding",
+	ErrorCategoryTransport:      "transport",
+	ErrorCategoryOversizedTrace: "oversized_trace",
+	ErrorCategoryInvalidSpan:    "invalid_span",
+}
+
+// String returns the name of the category.
+func (c ErrorCategory) String() string {
+	if c < 0 || int(c) >= len(errorCategoryNames) {
+		return "unknown"
+	}
+	return errorCategoryNames[c]
+}
+
 // errorSpanBufFull is raised when there's no more room in the buffer
 type errorSpanBufFull struct {
 	// Len is the length of the buffer (which is full)
@@ -70,31 +113,134 @@ func (e *errorNoSpanBuf) Error() string {
 	return "no span buffer (span name: '" + e.SpanName + "')"
 }
 
-// errorFlushLostTraces is raised when trying to finish/push a span that has no buffer associated to it.
+// errStopDeadline is the reason given for the traces abandoned when the tracer
+// could not flush them before its stop deadline.
+var errStopDeadline = errors.New("stop deadline exceeded")
+
+// errorEncoding is raised when a payload can't be encoded.
+type errorEncoding struct {
+	// Err is the error returned by the encoder.
+	Err error
+}
+
+// Error provides a readable error message.
+func (e *errorEncoding) Error() string {
+	return "unable to encode payload: " + e.Err.Error()
+}
+
+// errorChunks is returned by the transport when only some of the chunks of a
+// payload it split could be sent.
+type errorChunks struct {
+	// Traces holds the traces of the chunks which could not be sent.
+	Traces [][]*Span
+	// Err is the error of the last chunk which could not be sent.
+	Err error
+}
+
+// Error provides a readable error message.
+func (e *errorChunks) Error() string {
+	return "unable to send " + strconv.Itoa(len(e.Traces)) + " traces of the payload: " + e.Err.Error()
+}
+
+// errorFlushLostTraces is raised when traces could not be sent to the agent.
 type errorFlushLostTraces struct {
 	// Nb is the number of traces lost in that flush
 	Nb int
+	// Err is the reason why the traces could not be sent, if known.
+	Err error
 }
 
 // Error provides a readable error message.
 func (e *errorFlushLostTraces) Error() string {
-	return "unable to flush traces, lost " + strconv.Itoa(e.Nb) + " traces"
+	msg := "unable to flush traces, lost " + strconv.Itoa(e.Nb) + " traces"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
 }
 
-// errorFlushLostServices is raised when trying to finish/push a span that has no buffer associated to it.
+// errorFlushLostServices is raised when services could not be sent to the agent.
 type errorFlushLostServices struct {
 	// Nb is the number of services lost in that flush
 	Nb int
+	// Err is the reason why the services could not be sent, if known.
+	Err error
 }
 
 // Error provides a readable error message.
 func (e *errorFlushLostServices) Error() string {
-	return "unable to flush services, lost " + strconv.Itoa(e.Nb) + " services"
+	msg := "unable to flush services, lost " + strconv.Itoa(e.Nb) + " services"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// errorFlushLostStats is raised when statistics could not be sent to the agent.
+type errorFlushLostStats struct {
+	// Nb is the number of time buckets lost in that flush
+	Nb int
+	// Err is the reason why the statistics could not be sent, if known.
+	Err error
+}
+
+// Error provides a readable error message.
+func (e *errorFlushLostStats) Error() string {
+	msg := "unable to flush stats, lost " + strconv.Itoa(e.Nb) + " buckets"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
 }
 
 type errorSummary struct {
-	Count   int
-	Example string
+	Category ErrorCategory
+	Count    int
+	Example  string
+}
+
+// errorCategory returns the category of the given error.
+func errorCategory(err error) ErrorCategory {
+	switch e := err.(type) {
+	case *errorTraceChanFull, *errorServiceChanFull:
+		return ErrorCategoryBufferOverflow
+	case *errorSpanBufFull:
+		return ErrorCategoryOversizedTrace
+	case *errorTraceIDMismatch, *errorNoSpanBuf:
+		return ErrorCategoryInvalidSpan
+	case *errorEncoding:
+		return ErrorCategoryEncoding
+	case *errorFlushLostTraces:
+		return flushErrorCategory(e.Err)
+	case *errorFlushLostServices:
+		return flushErrorCategory(e.Err)
+	case *errorFlushLostStats:
+		return flushErrorCategory(e.Err)
+	}
+	return ErrorCategoryOther
+}
+
+// flushErrorCategory returns the category of an error which prevented a
+// payload from being sent.
+func flushErrorCategory(err error) ErrorCategory {
+	if _, ok := err.(*errorEncoding); ok {
+		return ErrorCategoryEncoding
+	}
+	return ErrorCategoryTransport
+}
+
+// errorCounts holds the number of errors per category. It is safe for
+// concurrent use.
+type errorCounts [numErrorCategories]uint64
+
+// add counts the given error.
+func (c *errorCounts) add(err error) {
+	atomic.AddUint64(&c[errorCategory(err)], 1)
+}
+
+// get returns the number of errors of the given category.
+func (c *errorCounts) get(category ErrorCategory) uint64 {
+	return atomic.LoadUint64(&c[category])
 }
 
 // errorKey returns a unique key for each error type
@@ -117,6 +263,10 @@ func errorKey(err error) string {
 		return "ErrorFlushLostTraces"
 	case *errorFlushLostServices:
 		return "ErrorFlushLostServices"
+	case *errorFlushLostStats:
+		return "ErrorFlushLostStats"
+	case *errorEncoding:
+		return "ErrorEncoding"
 	}
 	return err.Error() // possibly high cardinality, but this is unexpected
 }
@@ -130,6 +280,7 @@ func aggregateErrors(errChan <-chan error) map[string]errorSummary {
 			if err != nil { // double-checking, we don't want to panic here...
 				key := errorKey(err)
 				summary := errs[key]
+				summary.Category = errorCategory(err)
 				summary.Count++
 				summary.Example = err.Error()
 				errs[key] = summary
@@ -140,18 +291,44 @@ func aggregateErrors(errChan <-chan error) map[string]errorSummary {
 	}
 }
 
-// logErrors logs the errors, preventing log file flooding, when there
-// are many messages, it caps them and shows a quick summary.
-// As of today it only logs using standard golang log package, but
+// errorLogger logs the errors along with their category, preventing log file
+// flooding: errors are aggregated over a window of time and a single summary
+// line per kind of error is logged when the window expires, e.g. during an
+// agent outage. As of today it only logs locally, see SetJSONLogging, but
 // later we could send those stats to agent [TODO:christian].
-func logErrors(errChan <-chan error) {
-	errs := aggregateErrors(errChan)
+type errorLogger struct {
+	window time.Duration
+	errs   map[string]errorSummary // errors pending until the window expires
+	start  time.Time               // time the oldest pending error was added
+	last   time.Time               // time errors were last logged
+}
 
-	for _, v := range errs {
-		var repeat string
-		if v.Count > 1 {
-			repeat = " (repeated " + strconv.Itoa(v.Count) + " times)"
-		}
-		log.Println(errorPrefix + v.Example + repeat)
+func newErrorLogger(window time.Duration) *errorLogger {
+	return &errorLogger{
+		window: window,
+		errs:   make(map[string]errorSummary),
+	}
+}
+
+// log adds the given errors to the pending ones, and logs them if the window
+// has expired since the last time errors were logged, or if force is true.
+func (l *errorLogger) log(errs map[string]errorSummary, now time.Time, force bool) {
+	if len(l.errs) == 0 {
+		l.start = now
+	}
+	for k, v := range errs {
+		summary := l.errs[k]
+		summary.Category = v.Category
+		summary.Count += v.Count
+		summary.Example = v.Example
+		l.errs[k] = summary
+	}
+	if len(l.errs) == 0 || (!force && now.Sub(l.last) < l.window) {
+		return
+	}
+	for k, v := range l.errs {
+		logErrorSummary(k, v, now.Sub(l.start))
 	}
+	l.errs = make(map[string]errorSummary, len(l.errs))
+	l.last = now
 }
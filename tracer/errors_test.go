@@ -1,8 +1,13 @@
 package tracer
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,6 +65,49 @@ func TestErrorFlushLostServices(t *testing.T) {
 	assert.Equal("unable to flush services, lost 100 services", err.Error())
 }
 
+func TestErrorFlushLostStats(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &errorFlushLostStats{Nb: 2, Err: fmt.Errorf("timeout")}
+	assert.Equal("unable to flush stats, lost 2 buckets: timeout", err.Error())
+	assert.Equal("ErrorFlushLostStats", errorKey(err))
+	assert.Equal(ErrorCategoryTransport, errorCategory(err))
+}
+
+func TestErrorEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &errorEncoding{Err: fmt.Errorf("bad value")}
+	assert.Equal("unable to encode payload: bad value", err.Error())
+	assert.Equal("ErrorEncoding", errorKey(err))
+}
+
+func TestErrorFlushLostTracesCause(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &errorFlushLostTraces{Nb: 100, Err: fmt.Errorf("connection refused")}
+	assert.Equal("unable to flush traces, lost 100 traces: connection refused", err.Error())
+	assert.Equal("ErrorFlushLostTraces", errorKey(err))
+}
+
+func TestErrorCategory(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ErrorCategoryBufferOverflow, errorCategory(&errorTraceChanFull{Len: 1000}))
+	assert.Equal(ErrorCategoryBufferOverflow, errorCategory(&errorServiceChanFull{Len: 1000}))
+	assert.Equal(ErrorCategoryOversizedTrace, errorCategory(&errorSpanBufFull{Len: 1000}))
+	assert.Equal(ErrorCategoryInvalidSpan, errorCategory(&errorNoSpanBuf{SpanName: "do"}))
+	assert.Equal(ErrorCategoryInvalidSpan, errorCategory(&errorTraceIDMismatch{Expected: 1, Actual: 2}))
+	assert.Equal(ErrorCategoryEncoding, errorCategory(&errorEncoding{Err: fmt.Errorf("bad value")}))
+	assert.Equal(ErrorCategoryTransport, errorCategory(&errorFlushLostTraces{Nb: 1}))
+	assert.Equal(ErrorCategoryEncoding, errorCategory(&errorFlushLostTraces{Nb: 1, Err: &errorEncoding{Err: fmt.Errorf("bad value")}}))
+	assert.Equal(ErrorCategoryTransport, errorCategory(&errorFlushLostServices{Nb: 1, Err: fmt.Errorf("timeout")}))
+	assert.Equal(ErrorCategoryOther, errorCategory(fmt.Errorf("this is something unexpected")))
+
+	assert.Equal("buffer_overflow", ErrorCategoryBufferOverflow.String())
+	assert.Equal("unknown", ErrorCategory(-1).String())
+}
+
 func TestErrorKey(t *testing.T) {
 	assert := assert.New(t)
 
@@ -83,16 +131,66 @@ func TestAggregateErrors(t *testing.T) {
 
 	assert.Equal(map[string]errorSummary{
 		"ErrorSpanBufFull": errorSummary{
-			Count:   4,
-			Example: "span buffer is full (length: 1000)",
+			Category: ErrorCategoryOversizedTrace,
+			Count:    4,
+			Example:  "span buffer is full (length: 1000)",
 		},
 		"ErrorTraceIDMismatch": errorSummary{
-			Count:   2,
-			Example: "trace ID mismatch (expected: 2a actual: fff)",
+			Category: ErrorCategoryInvalidSpan,
+			Count:    2,
+			Example:  "trace ID mismatch (expected: 2a actual: fff)",
 		},
 		"ErrorFlushLostTraces": errorSummary{
-			Count:   1,
-			Example: "unable to flush traces, lost 42 traces",
+			Category: ErrorCategoryTransport,
+			Count:    1,
+			Example:  "unable to flush traces, lost 42 traces",
 		},
 	}, errs)
 }
+
+func TestErrorLoggerWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	start := time.Now()
+	l := newErrorLogger(time.Minute)
+	lost := map[string]errorSummary{
+		"ErrorFlushLostTraces": errorSummary{
+			Category: ErrorCategoryTransport,
+			Count:    1,
+			Example:  "unable to flush traces, lost 1 traces: connection refused",
+		},
+	}
+
+	// the first error is logged straight away
+	l.log(lost, start, false)
+	assert.Equal(1, strings.Count(buf.String(), "\n"))
+	assert.Contains(buf.String(), "[transport] unable to flush traces, lost 1 traces: connection refused")
+
+	// the following ones are summarized once the window expires
+	buf.Reset()
+	for i := 1; i < 30; i++ {
+		l.log(lost, start.Add(time.Duration(i)*2*time.Second), false)
+	}
+	l.log(nil, start.Add(59*time.Second), false)
+	assert.Equal(0, buf.Len())
+	l.log(nil, start.Add(61*time.Second), false)
+	assert.Equal(1, strings.Count(buf.String(), "\n"))
+	assert.Contains(buf.String(), "[transport] 29 times in the last 59s: unable to flush traces, lost 1 traces: connection refused")
+
+	// nothing is logged when there are no errors
+	buf.Reset()
+	l.log(nil, start.Add(200*time.Second), false)
+	assert.Equal(0, buf.Len())
+
+	// pending errors can be logged before the window expires
+	l.log(lost, start.Add(201*time.Second), false)
+	buf.Reset()
+	l.log(lost, start.Add(202*time.Second), false)
+	assert.Equal(0, buf.Len())
+	l.log(nil, start.Add(202*time.Second), true)
+	assert.Contains(buf.String(), "[transport] unable to flush traces, lost 1 traces: connection refused")
+}
@@ -0,0 +1,116 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// spanEventsKey is the meta holding the events of a span, as a JSON
+	// array, see Span.AddEvent.
+	spanEventsKey = "events"
+	// maxSpanEvents is the maximum number of events of a span, the events
+	// added afterwards are dropped.
+	maxSpanEvents = 128
+	// droppedEventsMetricKey is the metric counting the events dropped from
+	// a span.
+	droppedEventsMetricKey = "_dd.span_events.dropped"
+)
+
+// SpanEvent is a timestamped event which happened during a span, such as a
+// cache miss or a retry attempt, see Span.AddEvent.
+type SpanEvent struct {
+	// Name is the name of the event.
+	Name string `json:"name"`
+	// Time is the time of the event, in nanoseconds since epoch.
+	Time int64 `json:"time_unix_nano"`
+	// Attributes holds the attributes of the event, whose values are
+	// strings, booleans or numbers.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SpanEventOption sets a field of an event added with Span.AddEvent.
+type SpanEventOption func(*SpanEvent)
+
+// WithEventTime sets the time of the event, which is now by default.
+func WithEventTime(t time.Time) SpanEventOption {
+	return func(e *SpanEvent) {
+		e.Time = t.UnixNano()
+	}
+}
+
+// WithEventAttributes adds the given attributes to the event. Values other
+// than strings, booleans and numbers are formatted as strings.
+func WithEventAttributes(attrs map[string]interface{}) SpanEventOption {
+	return func(e *SpanEvent) {
+		if e.Attributes == nil {
+			e.Attributes = make(map[string]interface{}, len(attrs))
+		}
+		for k, v := range attrs {
+			switch v.(type) {
+			case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			default:
+				v = fmt.Sprint(v)
+			}
+			e.Attributes[k] = v
+		}
+	}
+}
+
+// AddEvent records an intermediate milestone of the span, such as a cache
+// miss or a retry attempt, without creating a child span for it. The events
+// are sent in the "events" meta of the span, as a JSON array, once it is
+// finished. A span holds at most 128 events, the next ones are dropped and
+// counted in the "_dd.span_events.dropped" metric. If the Span has been
+// finished, it will not be modified by this method.
+func (s *Span) AddEvent(name string, opts ...SpanEventOption) {
+	if s == nil {
+		return
+	}
+	e := SpanEvent{Name: name}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	if e.Time == 0 {
+		e.Time = s.tracer.clockNow().UnixNano()
+	}
+
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+	if s.finished || s.lightweight {
+		return
+	}
+	if len(s.events) >= maxSpanEvents {
+		if s.Metrics == nil {
+			s.Metrics = make(map[string]float64)
+		}
+		s.Metrics[droppedEventsMetricKey]++
+		return
+	}
+	s.events = append(s.events, e)
+}
+
+// Events returns the events added to the span.
+func (s *Span) Events() []SpanEvent {
+	if s == nil {
+		return nil
+	}
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
+	return append([]SpanEvent(nil), s.events...)
+}
+
+// setEventsMeta sets the meta holding the events of the span, if any. It is
+// called when the span finishes, with tagsMu held.
+func (s *Span) setEventsMeta() {
+	if len(s.events) == 0 {
+		return
+	}
+	b, err := json.Marshal(s.events)
+	if err != nil {
+		logf(logWarn, "tracer", "dropping the events of span %q (id: %d): %v", s.Name, s.SpanID, err)
+		return
+	}
+	s.setMeta(spanEventsKey, string(b))
+}
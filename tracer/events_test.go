@@ -0,0 +1,73 @@
+package tracer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanAddEvent(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.AddEvent("cache.miss")
+	clock.advance(time.Millisecond)
+	span.AddEvent("retry", WithEventAttributes(map[string]interface{}{
+		"attempt": 2,
+		"final":   false,
+		"error":   errors.New("timeout"),
+	}))
+	span.AddEvent("backfill", WithEventTime(clock.now.Add(-time.Hour)))
+	assert.Equal("", span.GetMeta(spanEventsKey))
+	assert.Len(span.Events(), 3)
+
+	span.Finish()
+	span.AddEvent("ignored")
+	var events []SpanEvent
+	assert.NoError(json.Unmarshal([]byte(span.GetMeta(spanEventsKey)), &events))
+	assert.Equal([]SpanEvent{
+		{Name: "cache.miss", Time: clock.now.Add(-time.Millisecond).UnixNano()},
+		{Name: "retry", Time: clock.now.UnixNano(), Attributes: map[string]interface{}{
+			"attempt": 2.0,
+			"final":   false,
+			"error":   "timeout",
+		}},
+		{Name: "backfill", Time: clock.now.Add(-time.Hour).UnixNano()},
+	}, events)
+
+	// spans without events have no meta
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+	_, found := span.Meta[spanEventsKey]
+	assert.False(found)
+
+	var nilSpan *Span
+	nilSpan.AddEvent("cache.miss")
+	assert.Nil(nilSpan.Events())
+}
+
+func TestSpanAddEventLimit(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	for i := 0; i < maxSpanEvents+10; i++ {
+		span.AddEvent("retry")
+	}
+	assert.Len(span.Events(), maxSpanEvents)
+	assert.Equal(10.0, span.Metrics[droppedEventsMetricKey])
+
+	// dropped traces don't record events
+	tracer.SetSampleRate(0)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.AddEvent("retry")
+	assert.Len(span.Events(), 0)
+}
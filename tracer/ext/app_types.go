@@ -2,8 +2,9 @@ package ext
 
 // Application types for services.
 const (
-	AppTypeWeb   = "web"
-	AppTypeDB    = "db"
-	AppTypeCache = "cache"
-	AppTypeRPC   = "rpc"
+	AppTypeWeb    = "web"
+	AppTypeDB     = "db"
+	AppTypeCache  = "cache"
+	AppTypeRPC    = "rpc"
+	AppTypeWorker = "worker"
 )
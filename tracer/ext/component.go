@@ -0,0 +1,6 @@
+package ext
+
+// Component is the meta key holding the name of the integration which
+// produced a span, such as "net/http" or "database/sql", so that traces can
+// be filtered by instrumentation source. It is set by Span.SetIntegration.
+const Component = "component"
@@ -6,4 +6,29 @@ const (
 	HTTPMethod = "http.method"
 	HTTPCode   = "http.status_code"
 	HTTPURL    = "http.url"
+	HTTPRoute  = "http.route" // the route template matched by the request, e.g. "/users/:id"
+
+	// HTTPStreaming is set on spans of streaming or long-polling requests, which
+	// are finished as soon as the response header is written.
+	HTTPStreaming = "http.streaming"
+
+	// HTTPRequestSize and HTTPResponseSize are the metrics holding the number
+	// of bytes read from the request body and written in the response body.
+	HTTPRequestSize  = "http.request.size"
+	HTTPResponseSize = "http.response.size"
+
+	// HTTPHeaderForceKeep is the request header which, when set to a true
+	// value such as "1" or "true", makes the HTTP server integrations
+	// configured to honor it keep the trace of the request whatever the
+	// sampler decides, to trace a specific request on demand.
+	HTTPHeaderForceKeep = "x-datadog-force-keep"
+	// HTTPHeaderOrigin is the request header holding the origin of the
+	// request, such as "synthetics" for Synthetic tests, whose traces are
+	// kept along with the ones asked for with HTTPHeaderForceKeep.
+	HTTPHeaderOrigin = "x-datadog-origin"
+	// OriginSynthetics is the origin of the requests of Synthetic tests.
+	OriginSynthetics = "synthetics"
+	// Origin is the tag holding the origin of the request, see
+	// HTTPHeaderOrigin.
+	Origin = "_dd.origin"
 )
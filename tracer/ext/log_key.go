@@ -0,0 +1,10 @@
+package ext
+
+// Log keys are the attributes of the log records which correlate them with
+// the trace and span they were emitted in.
+const (
+	// LogKeyTraceID is the log attribute holding the trace ID.
+	LogKeyTraceID = "dd.trace_id"
+	// LogKeySpanID is the log attribute holding the span ID.
+	LogKeySpanID = "dd.span_id"
+)
@@ -3,6 +3,21 @@ package ext
 // Priority is a hint given to the backend so that it knows which traces to reject or kept.
 // In a distributed context, it should be set before any context propagation (fork, RPC calls) to be effective.
 
+const (
+	// ManualKeep is the tag which, set on any span with any value, keeps
+	// its local trace whatever the sampler decided, see tracer.KeepTrace.
+	ManualKeep = "manual.keep"
+	// ManualDrop is the tag which, set on any span with any value, drops
+	// its local trace whatever the sampler decided, see tracer.DropTrace.
+	ManualDrop = "manual.drop"
+	// DebugBaggage is the baggage item which, set to a true value such as
+	// "1" or "true" on any span, keeps its trace in this process and in all
+	// the downstream services it is propagated to, whatever the samplers
+	// decide, to trace a request end-to-end on demand. The tracers have to
+	// be configured to honor it, see tracer.SetDebugBaggage.
+	DebugBaggage = "dd.debug"
+)
+
 const (
 	// PriorityUserReject informs the backend that a trace should be rejected and not stored.
 	// This should be used by user code overriding default priority.
@@ -17,3 +32,23 @@ const (
 	// This should be used by user code overriding default priority.
 	PriorityUserKeep = 2
 )
+
+// Sampling mechanisms tell what made the sampling decision of a trace. The
+// mechanism is recorded on the root span, see the "_dd.p.dm" tag.
+const (
+	// SamplingMechanismDefault is used when the trace is kept by default.
+	SamplingMechanismDefault = 0
+	// SamplingMechanismAgentRate is used when the decision is made from a
+	// rate given by the agent.
+	SamplingMechanismAgentRate = 1
+	// SamplingMechanismRule is used when the decision is made from a sample
+	// rate or a sampling rule configured by the user.
+	SamplingMechanismRule = 3
+	// SamplingMechanismManual is used when the user set the sampling
+	// priority of the trace.
+	SamplingMechanismManual = 4
+	// SamplingMechanismSingleSpan is used when a span is kept on its own by
+	// a span sampling rule, its trace being dropped. It is recorded on the
+	// span, see the "_dd.span_sampling.mechanism" metric.
+	SamplingMechanismSingleSpan = 8
+)
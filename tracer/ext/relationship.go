@@ -0,0 +1,12 @@
+package ext
+
+// Span relationships tell how a span relates to its parent when it isn't a
+// plain child, whose parent waits for it to complete.
+const (
+	// SpanRelationship is the meta key holding the relationship of a span
+	// to its parent.
+	SpanRelationship = "span.relationship"
+	// RelationshipFollowsFrom is used for spans which are caused by their
+	// parent but which it doesn't wait for, such as fire-and-forget goroutines.
+	RelationshipFollowsFrom = "follows_from"
+)
@@ -0,0 +1,58 @@
+package tracer
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// SetGoroutineChecks enables a debugging check which logs a warning when a
+// span is finished on another goroutine than the one which created it while
+// its parent is already finished. This usually means that the span was handed
+// to a goroutine outliving the operation of its parent, which breaks the
+// trace tree. Recording the goroutine of every span is slow, so the check
+// shouldn't be left enabled in production.
+func (t *Tracer) SetGoroutineChecks(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&t.goroutineChecks, 1)
+	} else {
+		atomic.StoreUint32(&t.goroutineChecks, 0)
+	}
+}
+
+// goroutineChecksEnabled returns whether goroutine checks are enabled.
+func (t *Tracer) goroutineChecksEnabled() bool {
+	return atomic.LoadUint32(&t.goroutineChecks) == 1
+}
+
+// checkGoroutine logs a warning if the span is finished after its parent on
+// another goroutine than its owner, the one which created or attached it.
+func (s *Span) checkGoroutine(owner uint64) {
+	p := s.parent
+	if p == nil {
+		return
+	}
+	p.tagsMu.RLock()
+	parentFinished := p.finished
+	p.tagsMu.RUnlock()
+	if !parentFinished {
+		return
+	}
+	if id := goroutineID(); id != owner {
+		logf(logWarn, "tracer", "span %q (id: %d) owned by goroutine %d was finished on goroutine %d after its parent %q (id: %d), from %s",
+			s.Name, s.SpanID, owner, id, p.Name, p.SpanID, finishCaller())
+	}
+}
+
+// goroutineID returns the id of the calling goroutine, as found in its stack
+// trace. It is slow and only meant for debugging.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
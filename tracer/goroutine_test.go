@@ -0,0 +1,76 @@
+package tracer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoroutineID(t *testing.T) {
+	assert := assert.New(t)
+
+	id := goroutineID()
+	assert.NotEqual(uint64(0), id)
+	assert.Equal(id, goroutineID())
+
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	assert.NotEqual(id, <-other)
+}
+
+func TestGoroutineChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	// disabled by default
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("async.work", parent)
+	assert.Equal(uint64(0), child.goroutine)
+	parent.Finish()
+	finishIn(child)
+	assert.Equal(0, buf.Len())
+
+	tracer.SetGoroutineChecks(true)
+
+	// finished on another goroutine, after the parent
+	parent = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child = tracer.NewChildSpan("async.work", parent)
+	parent.Finish()
+	finishIn(child)
+	assert.Contains(buf.String(), `span "async.work"`)
+	assert.Contains(buf.String(), `after its parent "pylons.request"`)
+
+	// finished on another goroutine, before the parent
+	buf.Reset()
+	parent = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child = tracer.NewChildSpan("async.work", parent)
+	finishIn(child)
+	parent.Finish()
+	assert.Equal(0, buf.Len())
+
+	// finished on the same goroutine, after the parent
+	parent = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child = tracer.NewChildSpan("async.work", parent)
+	parent.Finish()
+	child.Finish()
+	assert.Equal(0, buf.Len())
+}
+
+// finishIn finishes the given span on a new goroutine.
+func finishIn(span *Span) {
+	done := make(chan struct{})
+	go func() {
+		span.Finish()
+		close(done)
+	}()
+	<-done
+}
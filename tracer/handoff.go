@@ -0,0 +1,89 @@
+package tracer
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// DetachedSpan is a span handed over to another goroutine, which takes it
+// with Attach. See Span.Detach.
+type DetachedSpan struct {
+	span *Span
+	// attached is set atomically to 1 once the span is attached.
+	attached uint32
+}
+
+// Detach hands the span over to another goroutine, which becomes its owner
+// once it calls Attach on the result: the span is then expected to be
+// finished there, even after its parent, without the warning of the
+// goroutine checks. The calling goroutine mustn't use the span afterwards.
+//
+//	d := span.Detach()
+//	go func() {
+//		span, ctx := d.Attach(context.Background())
+//		defer span.Finish()
+//		process(ctx)
+//	}()
+//
+// With goroutine checks or debug logging enabled, a warning is logged when a
+// finished span is detached or attached, when a span is attached twice, or
+// when the detaching goroutine tags or finishes the span afterwards.
+func (s *Span) Detach() *DetachedSpan {
+	if s.handoffChecks() {
+		s.RLock()
+		finished := s.finished
+		s.RUnlock()
+		if finished {
+			logf(logWarn, "tracer", "span %q (id: %d) detached after it was finished, from %s", s.Name, s.SpanID, finishCaller())
+		}
+		atomic.StoreUint64(&s.detachedBy, goroutineID())
+	}
+	return &DetachedSpan{span: s}
+}
+
+// Attach makes the calling goroutine the owner of the detached span, and
+// returns it along with a copy of ctx holding it, so that the work done on
+// behalf of the span is traced as its children. It is meant to be called
+// once.
+func (d *DetachedSpan) Attach(ctx context.Context) (*Span, context.Context) {
+	s := d.span
+	if s == nil {
+		return nil, ctx
+	}
+	twice := atomic.AddUint32(&d.attached, 1) > 1
+	s.Lock()
+	finished := s.finished
+	if s.goroutine != 0 {
+		s.goroutine = goroutineID()
+	}
+	s.Unlock()
+	if s.handoffChecks() {
+		// the span is the detaching goroutine's again if it attaches it
+		atomic.CompareAndSwapUint64(&s.detachedBy, goroutineID(), 0)
+		switch {
+		case finished:
+			logf(logWarn, "tracer", "span %q (id: %d) attached after it was finished, from %s", s.Name, s.SpanID, finishCaller())
+		case twice:
+			logf(logWarn, "tracer", "span %q (id: %d) attached twice, from %s", s.Name, s.SpanID, finishCaller())
+		}
+	}
+	return s, s.Context(ctx)
+}
+
+// handoffChecks returns whether the misuses of Detach and Attach are logged.
+func (s *Span) handoffChecks() bool {
+	if s == nil || s.tracer == nil {
+		return false
+	}
+	return s.tracer.goroutineChecksEnabled() || s.tracer.DebugLoggingEnabled()
+}
+
+// checkDetached logs a warning if the span is used, as described by op, by the
+// goroutine which detached it.
+func (s *Span) checkDetached(op string) {
+	id := atomic.LoadUint64(&s.detachedBy)
+	if id == 0 || id != goroutineID() {
+		return
+	}
+	logf(logWarn, "tracer", "span %q (id: %d) %s by goroutine %d after it detached it, from %s", s.Name, s.SpanID, op, id, finishCaller())
+}
@@ -0,0 +1,123 @@
+package tracer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanDetachAttach(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetGoroutineChecks(true)
+
+	// the span attached by another goroutine is finished there, after its
+	// parent, without warnings
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	d := tracer.NewChildSpan("async.work", parent).Detach()
+	parent.Finish()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		span, ctx := d.Attach(context.Background())
+		assert.Equal("async.work", span.Name)
+		inCtx, ok := SpanFromContext(ctx)
+		assert.True(ok)
+		assert.Equal(span, inCtx)
+		span.Finish()
+	}()
+	<-done
+	assert.Equal(0, buf.Len())
+
+	// attached again, once finished
+	span, _ := d.Attach(context.Background())
+	assert.Contains(buf.String(), `span "async.work"`)
+	assert.Contains(buf.String(), "attached after it was finished")
+
+	buf.Reset()
+	d = tracer.NewRootSpan("pylons.request", "pylons", "/").Detach()
+	span, _ = d.Attach(context.Background())
+	d.Attach(context.Background())
+	assert.Contains(buf.String(), "attached twice")
+
+	// detached after it was finished
+	buf.Reset()
+	span.Finish()
+	span.Detach()
+	assert.Contains(buf.String(), "detached after it was finished")
+}
+
+func TestSpanDetachOwnerUse(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetGoroutineChecks(true)
+
+	// the detaching goroutine mustn't use the span anymore
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	d := span.Detach()
+	span.SetMeta("key", "value")
+	assert.Contains(buf.String(), `span "pylons.request"`)
+	assert.Contains(buf.String(), "tagged by goroutine")
+	assert.Contains(buf.String(), "after it detached it")
+
+	buf.Reset()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		span, _ := d.Attach(context.Background())
+		span.SetMetric("retries", 1)
+	}()
+	<-done
+	assert.Equal(0, buf.Len())
+	span.Finish()
+	assert.Contains(buf.String(), "finished by goroutine")
+
+	// unless it attaches it again
+	buf.Reset()
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span, _ = span.Detach().Attach(context.Background())
+	span.SetMeta("key", "value")
+	span.Finish()
+	assert.Equal(0, buf.Len())
+}
+
+func TestSpanDetachAttachUnchecked(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Detach()
+	span.SetMeta("key", "value")
+	span.Finish()
+	d := span.Detach()
+	d.Attach(context.Background())
+	d.Attach(context.Background())
+	assert.Equal(0, buf.Len())
+
+	var nilSpan *Span
+	attached, ctx := nilSpan.Detach().Attach(context.Background())
+	assert.Nil(attached)
+	assert.Equal(context.Background(), ctx)
+}
@@ -0,0 +1,62 @@
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HealthCheckResult holds the outcome of a HealthCheck.
+type HealthCheckResult struct {
+	// OK is true when the agent accepted the probe.
+	OK bool
+	// StatusCode is the status code returned by the agent, or 0 if it could
+	// not be reached.
+	StatusCode int
+	// Latency is the time it took to get an answer from the agent.
+	Latency time.Duration
+	// Err is the reason why the probe failed, if any.
+	Err error
+}
+
+// HealthCheck performs an end-to-end probe of the connection to the agent by
+// encoding and sending an empty trace payload through the tracer's transport.
+// It gives up when the given context is done. The probe doesn't submit any
+// span nor changes the agent API the traces are sent to when it is rejected,
+// so it can safely be used in readiness probes.
+func (t *Tracer) HealthCheck(ctx context.Context) HealthCheckResult {
+	done := make(chan HealthCheckResult, 1)
+	start := time.Now()
+	go func() {
+		var res HealthCheckResult
+		var (
+			response *http.Response
+			err      error
+		)
+		if ht, ok := t.transport.(*httpTransport); ok {
+			response, err = ht.probe()
+		} else {
+			response, err = t.transport.SendTraces([][]*Span{})
+		}
+		res.Latency = time.Since(start)
+		if response != nil {
+			res.StatusCode = response.StatusCode
+		}
+		res.Err = err
+		res.OK = err == nil
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		return HealthCheckResult{Latency: time.Since(start), Err: ctx.Err()}
+	}
+}
+
+// HealthCheck performs an end-to-end probe of the connection to the agent
+// using the default tracer.
+func HealthCheck(ctx context.Context) HealthCheckResult {
+	return DefaultTracer.HealthCheck(ctx)
+}
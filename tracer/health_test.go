@@ -0,0 +1,108 @@
+package tracer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu    sync.Mutex
+		count string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == infoPath {
+			// queried by the tracer when it starts
+			return
+		}
+		mu.Lock()
+		count = r.Header.Get(traceCountHeader)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	tracer := NewTracerTransport(newHTTPTransport(parsedURL.Hostname(), parsedURL.Port()))
+	defer tracer.Stop()
+
+	res := tracer.HealthCheck(context.Background())
+	assert.True(res.OK)
+	assert.NoError(res.Err)
+	assert.Equal(200, res.StatusCode)
+	assert.True(res.Latency > 0)
+	mu.Lock()
+	assert.Equal("0", count)
+	mu.Unlock()
+}
+
+func TestHealthCheckFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	tracer := NewTracerTransport(newHTTPTransport(parsedURL.Hostname(), parsedURL.Port()))
+	defer tracer.Stop()
+
+	res := tracer.HealthCheck(context.Background())
+	assert.False(res.OK)
+	assert.Equal(500, res.StatusCode)
+	assert.Equal(fmt.Errorf("SendTraces expected response code 200, received 500"), res.Err)
+}
+
+func TestHealthCheckContext(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &blockingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		started:        make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	defer close(transport.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res := tracer.HealthCheck(ctx)
+	assert.False(res.OK)
+	assert.Equal(context.DeadlineExceeded, res.Err)
+	assert.Equal(0, res.StatusCode)
+}
+
+func TestHealthCheckNoDowngrade(t *testing.T) {
+	assert := assert.New(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+
+	// a rejected probe fails without downgrading the API
+	endpoint := transport.endpoint()
+	res := tracer.HealthCheck(context.Background())
+	assert.False(res.OK)
+	assert.Equal(404, res.StatusCode)
+	assert.Equal(endpoint, transport.endpoint())
+}
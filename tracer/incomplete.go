@@ -0,0 +1,99 @@
+package tracer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTraceTimeout is the time after which the finished spans of a trace
+// are sent, when its local root finished but some of its spans didn't.
+const defaultTraceTimeout = time.Minute
+
+// incompleteTraces keeps track of the traces whose local root is finished,
+// but which wait for some of their spans to finish. It is safe for
+// concurrent use.
+type incompleteTraces struct {
+	mu     sync.Mutex
+	traces map[*spanBuffer]time.Time // buffer -> time its root finished
+}
+
+// add starts tracking the trace held by the given buffer, the root of which
+// finished at the given time.
+func (it *incompleteTraces) add(buffer *spanBuffer, now time.Time) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.traces == nil {
+		it.traces = make(map[*spanBuffer]time.Time)
+	}
+	it.traces[buffer] = now
+}
+
+// expired returns the traces which have waited for longer than the given
+// timeout, and forgets them as well as the traces which were flushed.
+func (it *incompleteTraces) expired(now time.Time, timeout time.Duration) []*spanBuffer {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	var expired []*spanBuffer
+	for buffer, finished := range it.traces {
+		switch {
+		case buffer.Len() == 0:
+			delete(it.traces, buffer)
+		case now.Sub(finished) >= timeout:
+			expired = append(expired, buffer)
+			delete(it.traces, buffer)
+		}
+	}
+	return expired
+}
+
+// SetTraceTimeout sets the time after which the finished spans of a trace are
+// sent, when its local root finished but some of its spans didn't, such as
+// spans of background work which outlive the request, or spans which are
+// never finished by mistake. The spans finishing afterwards are sent on
+// their own. It is one minute by default, and a zero timeout makes the
+// traces wait for all their spans, without limit. The timeout is checked by
+// the flushes, so it is rounded up to the flush interval.
+func (t *Tracer) SetTraceTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		logf(logWarn, "tracer", "tracer.SetTraceTimeout timeout must not be negative, now: %s", timeout)
+		return
+	}
+	atomic.StoreInt64(&t.traceTimeout, int64(timeout))
+}
+
+// pushIncomplete pushes the finished spans of the traces which waited for
+// their other spans for longer than the trace timeout.
+func (t *Tracer) pushIncomplete() {
+	timeout := time.Duration(atomic.LoadInt64(&t.traceTimeout))
+	if timeout == 0 {
+		return
+	}
+	for _, buffer := range t.incomplete.expired(t.clockNow(), timeout) {
+		buffer.flushFinished()
+	}
+}
+
+// groupTraces merges the traces sharing the same trace ID, such as the spans
+// of a trace finishing after its local root, which are pushed on their own,
+// so that they are sent together when they are flushed at the same time.
+func groupTraces(traces [][]*Span) [][]*Span {
+	if len(traces) < 2 {
+		return traces
+	}
+	index := make(map[uint64]int, len(traces)) // trace ID -> index in grouped
+	grouped := traces[:0:0]
+	for _, trace := range traces {
+		if len(trace) == 0 {
+			continue
+		}
+		id := trace[0].TraceID
+		if i, ok := index[id]; ok {
+			grouped[i] = append(grouped[i][:len(grouped[i]):len(grouped[i])], trace...)
+			continue
+		}
+		index[id] = len(grouped)
+		grouped = append(grouped, trace)
+	}
+	return grouped
+}
@@ -0,0 +1,104 @@
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerChildFinishAfterRoot(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("http.request", "web", "/")
+	child := tracer.NewChildSpan("sql.query", root)
+	root.Finish()
+
+	// the trace waits for the child
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	child.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+}
+
+func TestTracerTraceTimeout(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	tracer.SetTraceTimeout(time.Second)
+
+	root := tracer.NewRootSpan("http.request", "web", "/")
+	child := tracer.NewChildSpan("sql.query", root)
+	tracer.NewChildSpan("sql.query", root).Finish()
+	root.Finish()
+
+	clock.advance(time.Second / 2)
+	tracer.pushIncomplete()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	// the finished spans are sent after the timeout
+	clock.advance(time.Second)
+	tracer.pushIncomplete()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.Equal(root, traces[0][0])
+	assert.Len(tracer.incomplete.traces, 0)
+
+	// and the late ones on their own
+	child.Finish()
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal(child, traces[0][0])
+}
+
+func TestTracerTraceTimeoutDisabled(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	tracer.SetTraceTimeout(0)
+
+	root := tracer.NewRootSpan("http.request", "web", "/")
+	tracer.NewChildSpan("sql.query", root)
+	root.Finish()
+
+	clock.advance(time.Hour)
+	tracer.pushIncomplete()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	// negative timeouts are ignored
+	tracer.SetTraceTimeout(-time.Second)
+	tracer.pushIncomplete()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestGroupTraces(t *testing.T) {
+	assert := assert.New(t)
+
+	a1 := &Span{TraceID: 1, SpanID: 1}
+	a2 := &Span{TraceID: 1, SpanID: 2}
+	a3 := &Span{TraceID: 1, SpanID: 3}
+	b1 := &Span{TraceID: 2, SpanID: 4}
+
+	assert.Equal([][]*Span{{a1}}, groupTraces([][]*Span{{a1}}))
+	traces := [][]*Span{{a1, a2}, {b1}, {a3}}
+	assert.Equal([][]*Span{{a1, a2, a3}, {b1}}, groupTraces(traces))
+	// the traces given are left as is
+	assert.Equal([][]*Span{{a1, a2}, {b1}, {a3}}, traces)
+}
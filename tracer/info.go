@@ -0,0 +1,169 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// infoPath is the path of the endpoint describing what the agent
+	// supports.
+	infoPath = "/info"
+	// v05TracesPath is the path of the endpoint receiving traces in the
+	// v0.5 format.
+	v05TracesPath = "/v0.5/traces"
+	// statsPath is the path of the endpoint receiving the statistics
+	// computed by the tracers.
+	statsPath = "/v0.6/stats"
+	// gzipFeatureFlag is the feature flag of the agents accepting gzipped
+	// trace payloads.
+	gzipFeatureFlag = "gzip_traces"
+)
+
+// AgentFeatures describes what the agent supports, as reported by its /info
+// endpoint.
+type AgentFeatures struct {
+	// Discovered is true when the agent reported its features. Agents which
+	// predate the /info endpoint are assumed to support the endpoints used
+	// so far, but none of the optional features.
+	Discovered bool
+	// Version is the version of the agent.
+	Version string
+	// Endpoints holds the paths of the endpoints served by the agent, such
+	// as "/v0.4/traces".
+	Endpoints []string
+	// ClientStats is true when the agent accepts the statistics computed by
+	// the tracers, so that they can drop the traces they don't keep.
+	ClientStats bool
+	// PayloadCompression is true when the agent accepts gzipped trace
+	// payloads, as told by its "gzip_traces" feature flag.
+	PayloadCompression bool
+	// ObfuscationVersion is the version of the obfuscation done by the
+	// agent, 0 if the agent doesn't tell.
+	ObfuscationVersion int
+	// FeatureFlags holds the feature flags enabled in the agent.
+	FeatureFlags []string
+}
+
+// HasEndpoint reports whether the agent serves the endpoint with the given
+// path, such as "/v0.5/traces".
+func (f AgentFeatures) HasEndpoint(path string) bool {
+	for _, e := range f.Endpoints {
+		if e == path {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFeatureFlag reports whether the given feature flag is enabled in the
+// agent.
+func (f AgentFeatures) HasFeatureFlag(flag string) bool {
+	for _, ff := range f.FeatureFlags {
+		if ff == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// agentInfo is the payload returned by the /info endpoint of the agent.
+type agentInfo struct {
+	Version            string   `json:"version"`
+	Endpoints          []string `json:"endpoints"`
+	ClientDropP0s      bool     `json:"client_drop_p0s"`
+	ObfuscationVersion int      `json:"obfuscation_version"`
+	FeatureFlags       []string `json:"feature_flags"`
+}
+
+// features returns the features described by the info payload.
+func (info agentInfo) features() AgentFeatures {
+	f := AgentFeatures{
+		Discovered:         true,
+		Version:            info.Version,
+		Endpoints:          info.Endpoints,
+		ObfuscationVersion: info.ObfuscationVersion,
+		FeatureFlags:       info.FeatureFlags,
+	}
+	f.ClientStats = info.ClientDropP0s && f.HasEndpoint(statsPath)
+	f.PayloadCompression = f.HasFeatureFlag(gzipFeatureFlag)
+	return f
+}
+
+// discover queries the /info endpoint of the agent and records its features,
+// adjusting the transport to them: the v0.5 API is used when the agent
+// supports it. Agents without the endpoint are left alone, the transport
+// keeps on adjusting to their answers.
+func (t *httpTransport) discover() (AgentFeatures, error) {
+	response, err := t.client.Get(t.infoURL)
+	if err != nil {
+		return AgentFeatures{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return AgentFeatures{}, nil
+	}
+	if sc := response.StatusCode; sc != 200 {
+		return AgentFeatures{}, fmt.Errorf("discover expected response code 200, received %v", sc)
+	}
+	var info agentInfo
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		return AgentFeatures{}, fmt.Errorf("cannot decode the agent info: %v", err)
+	}
+	f := info.features()
+
+	t.mu.Lock()
+	t.features = f
+	downgrade := !t.compatibilityMode && !f.HasEndpoint(urlPath(t.traceURL)) && f.HasEndpoint(urlPath(t.legacyTraceURL))
+	t.mu.Unlock()
+	switch {
+	case downgrade:
+		// fall back now rather than failing the first payload
+		t.apiDowngrade()
+	case f.HasEndpoint(v05TracesPath):
+		t.useV05(true)
+	}
+	return f, nil
+}
+
+// agentFeatures returns the features of the agent found by discover.
+func (t *httpTransport) agentFeatures() AgentFeatures {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.features
+}
+
+// urlPath returns the path of the given agent URL, such as "/v0.3/traces".
+func urlPath(u string) string {
+	u = strings.TrimPrefix(u, "http://")
+	if i := strings.IndexByte(u, '/'); i >= 0 {
+		return u[i:]
+	}
+	return ""
+}
+
+// discoverFeatures queries the features of the agent, when the tracer sends
+// traces to one.
+func (t *Tracer) discoverFeatures() {
+	ht, ok := t.transport.(*httpTransport)
+	if !ok || !t.Enabled() {
+		return
+	}
+	// an unreachable agent is reported by the flushes already
+	if _, err := ht.discover(); err != nil && t.DebugLoggingEnabled() {
+		logf(logDebug, "tracer", "cannot discover the features of the agent: %v", err)
+	}
+}
+
+// AgentFeatures returns what the agent supports, as found when the tracer
+// started, so that optional behavior can be enabled only when the agent
+// supports it. It is the zero value while the agent hasn't been queried yet,
+// when it couldn't be reached or with a custom transport.
+func (t *Tracer) AgentFeatures() AgentFeatures {
+	if ht, ok := t.transport.(*httpTransport); ok {
+		return ht.agentFeatures()
+	}
+	return AgentFeatures{}
+}
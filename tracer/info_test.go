@@ -0,0 +1,112 @@
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newInfoServer returns an agent serving the given /info payload, or no /info
+// endpoint at all if it is empty, and accepting the given trace endpoints.
+func newInfoServer(info string, traceEndpoints ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	if info != "" {
+		mux.HandleFunc(infoPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(info))
+		})
+	}
+	for _, e := range traceEndpoints {
+		mux.HandleFunc(e, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	return httptest.NewServer(mux)
+}
+
+func TestTransportDiscover(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := newInfoServer(`{
+		"version": "7.42.0",
+		"endpoints": ["/v0.3/traces", "/v0.4/traces", "/v0.5/traces", "/v0.6/stats"],
+		"client_drop_p0s": true,
+		"obfuscation_version": 1,
+		"feature_flags": ["discovery", "gzip_traces"]
+	}`, "/v0.3/traces")
+	defer agent.Close()
+	u, err := url.Parse(agent.URL)
+	assert.NoError(err)
+
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	f, err := transport.discover()
+	assert.NoError(err)
+	assert.Equal(f, transport.agentFeatures())
+	assert.True(f.Discovered)
+	assert.Equal("7.42.0", f.Version)
+	assert.True(f.HasEndpoint("/v0.5/traces"))
+	assert.False(f.HasEndpoint("/v0.7/traces"))
+	assert.True(f.ClientStats)
+	assert.Equal(1, f.ObfuscationVersion)
+	assert.True(f.HasFeatureFlag("discovery"))
+	assert.False(f.HasFeatureFlag("other"))
+	assert.True(f.PayloadCompression)
+	assert.False(transport.compatibilityMode)
+}
+
+func TestTransportDiscoverLegacy(t *testing.T) {
+	assert := assert.New(t)
+
+	// the agent only serves the legacy API: it is used from the start
+	agent := newInfoServer(`{"endpoints": ["/v0.2/traces"]}`, "/v0.2/traces")
+	defer agent.Close()
+	u, err := url.Parse(agent.URL)
+	assert.NoError(err)
+
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	f, err := transport.discover()
+	assert.NoError(err)
+	assert.False(f.ClientStats)
+	assert.True(transport.compatibilityMode)
+	assert.Equal(agent.URL+"/v0.2/traces", transport.endpoint())
+
+	// agents without the /info endpoint are left alone
+	agent = newInfoServer("", "/v0.3/traces")
+	defer agent.Close()
+	u, err = url.Parse(agent.URL)
+	assert.NoError(err)
+
+	transport = newHTTPTransport(u.Hostname(), u.Port())
+	f, err = transport.discover()
+	assert.NoError(err)
+	assert.Equal(AgentFeatures{}, f)
+	assert.False(transport.compatibilityMode)
+
+	// as are the unreachable ones
+	agent.Close()
+	_, err = transport.discover()
+	assert.Error(err)
+	assert.False(transport.compatibilityMode)
+}
+
+func TestTracerAgentFeatures(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := newInfoServer(`{"version": "7.42.0", "endpoints": ["/v0.3/traces"]}`, "/v0.3/traces")
+	defer agent.Close()
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = agent.URL
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+
+	// the worker queries the agent before it starts flushing
+	tracer.Flush()
+	f := tracer.AgentFeatures()
+	assert.True(f.Discovered)
+	assert.Equal("7.42.0", f.Version)
+
+	tracer, _ = getTestTracer()
+	defer tracer.Stop()
+	assert.Equal(AgentFeatures{}, tracer.AgentFeatures())
+}
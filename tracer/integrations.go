@@ -0,0 +1,160 @@
+package tracer
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Integration describes a contrib integration loaded in the program, see
+// RegisterIntegration.
+type Integration struct {
+	// Name is the name of the integration, the one given to
+	// Span.SetIntegration, such as "net/http".
+	Name string `json:"name"`
+	// Library is the import path of the instrumented library, such as
+	// "github.com/gorilla/mux".
+	Library string `json:"library,omitempty"`
+	// Version is the version of the instrumented library the integration
+	// was tested against. When it isn't pinned, it is the version of the
+	// library built in the program, as found in its module information,
+	// or the Go version for the standard library.
+	Version string `json:"version,omitempty"`
+	// Options holds the options the integration was last configured with.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// integrationRegistry holds the integrations loaded in the program. It is
+// safe for concurrent use.
+type integrationRegistry struct {
+	mu           sync.RWMutex
+	integrations map[string]Integration
+}
+
+// loadedIntegrations is the registry of the integrations of the program, it
+// is global as the integrations are loaded by the program and not by a
+// tracer.
+var loadedIntegrations integrationRegistry
+
+// register records the integration, keeping the version and the options
+// previously registered when they are unset.
+func (r *integrationRegistry) register(i Integration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.integrations == nil {
+		r.integrations = make(map[string]Integration)
+	}
+	if prev, ok := r.integrations[i.Name]; ok {
+		if i.Library == "" {
+			i.Library = prev.Library
+		}
+		if i.Version == "" {
+			i.Version = prev.Version
+		}
+		if i.Options == nil {
+			i.Options = prev.Options
+		}
+	}
+	if i.Version == "" && i.Library != "" {
+		i.Version = libraryVersion(i.Library)
+	}
+	r.integrations[i.Name] = i
+}
+
+var (
+	buildDepsOnce sync.Once
+	buildDeps     []*debug.Module
+)
+
+// libraryVersion returns the version of the library of the given import path
+// built in the program, empty if it isn't known, such as in GOPATH builds.
+func libraryVersion(path string) string {
+	if first := strings.SplitN(path, "/", 2)[0]; !strings.Contains(first, ".") {
+		// the standard library
+		return runtime.Version()
+	}
+	buildDepsOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			buildDeps = info.Deps
+		}
+	})
+	var version, module string
+	for _, m := range buildDeps {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if (path == m.Path || strings.HasPrefix(path, m.Path+"/")) && len(m.Path) > len(module) {
+			version, module = m.Version, m.Path
+		}
+	}
+	return version
+}
+
+// list returns the integrations sorted by name.
+func (r *integrationRegistry) list() []Integration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Integration, 0, len(r.integrations))
+	for _, i := range r.integrations {
+		opts := make(map[string]string, len(i.Options))
+		for k, v := range i.Options {
+			opts[k] = v
+		}
+		if len(opts) == 0 {
+			opts = nil
+		}
+		i.Options = opts
+		list = append(list, i)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+	return list
+}
+
+// RegisterIntegration records that an integration is loaded in the program,
+// so that what is actually instrumented in a binary can be audited with
+// Integrations, the debug handler, the startup span of a Lifecycle or the
+// debug logs of the tracer, which list them once it has been running for a
+// flush interval. The contrib packages register themselves when they are
+// imported, and register their options when they are configured.
+// Registering an integration again updates it, the library, the version and
+// the options which are left unset keeping their previous values.
+func RegisterIntegration(i Integration) {
+	if i.Name == "" {
+		return
+	}
+	loadedIntegrations.register(i)
+}
+
+// Integrations returns the integrations loaded in the program, sorted by
+// name.
+func Integrations() []Integration {
+	return loadedIntegrations.list()
+}
+
+// integrationNames returns the comma separated names of the loaded
+// integrations, followed by their version when it is known, such as
+// "gorilla/mux@v1.6.0,net/http@go1.10".
+func integrationNames() string {
+	list := Integrations()
+	names := make([]string, len(list))
+	for i, integration := range list {
+		names[i] = integration.Name
+		if integration.Version != "" {
+			names[i] += "@" + integration.Version
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// logIntegrations logs the loaded integrations, along with their version and
+// options, if debug logging is enabled.
+func (t *Tracer) logIntegrations() {
+	if !t.DebugLoggingEnabled() {
+		return
+	}
+	for _, i := range Integrations() {
+		logf(logDebug, "tracer", "loaded integration %s (library: %s, version: %s, options: %v)", i.Name, i.Library, i.Version, i.Options)
+	}
+}
@@ -0,0 +1,72 @@
+package tracer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrationRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	var r integrationRegistry
+	assert.Len(r.list(), 0)
+
+	r.register(Integration{Name: "net/http"})
+	r.register(Integration{Name: "gorilla/mux", Version: "v1.6.0"})
+	r.register(Integration{Name: "gorilla/mux", Options: map[string]string{"service": "web"}})
+	list := r.list()
+	assert.Len(list, 2)
+	assert.Equal(Integration{Name: "gorilla/mux", Version: "v1.6.0", Options: map[string]string{"service": "web"}}, list[0])
+	assert.Equal(Integration{Name: "net/http"}, list[1])
+
+	// the version of the library is the one built in the program
+	r.register(Integration{Name: "net/smtp", Library: "net/smtp"})
+	r.register(Integration{Name: "example/lib", Library: "example.com/lib", Version: "v1.0.0"})
+	r.register(Integration{Name: "example/lib", Options: map[string]string{"service": "lib"}})
+	list = r.list()
+	assert.Equal(Integration{Name: "example/lib", Library: "example.com/lib", Version: "v1.0.0", Options: map[string]string{"service": "lib"}}, list[0])
+	assert.Equal(Integration{Name: "net/smtp", Library: "net/smtp", Version: runtime.Version()}, list[3])
+	assert.Equal("", libraryVersion("example.com/unknown"))
+
+	// the options returned are copies
+	list[1].Options["service"] = "api"
+	assert.Equal("web", r.list()[1].Options["service"])
+}
+
+func TestRegisterIntegration(t *testing.T) {
+	assert := assert.New(t)
+
+	RegisterIntegration(Integration{})
+	RegisterIntegration(Integration{Name: "test/integration"})
+	var found bool
+	for _, i := range Integrations() {
+		assert.NotEqual("", i.Name)
+		if i.Name == "test/integration" {
+			found = true
+		}
+	}
+	assert.True(found)
+	assert.Contains(integrationNames(), "test/integration")
+}
+
+func TestTracerLogIntegrations(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	RegisterIntegration(Integration{Name: "test/logged", Library: "net/http", Options: map[string]string{"service": "web"}})
+	tracer.logIntegrations()
+	assert.Equal("", buf.String())
+
+	tracer.SetDebugLogging(true)
+	tracer.logIntegrations()
+	assert.Contains(buf.String(), "loaded integration test/logged (library: net/http, version: "+runtime.Version()+", options: map[service:web])")
+}
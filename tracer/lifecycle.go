@@ -0,0 +1,121 @@
+package tracer
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// lifecycleStartupName is the name of the span covering the startup of a
+	// process, until it is ready.
+	lifecycleStartupName = "process.startup"
+	// lifecycleShutdownName is the name of the span covering the graceful
+	// shutdown of a process.
+	lifecycleShutdownName = "process.shutdown"
+	// lifecycleIntegrationsKey is the tag of the startup span listing the
+	// integrations loaded in the program, see RegisterIntegration.
+	lifecycleIntegrationsKey = "process.integrations"
+)
+
+// Lifecycle traces the startup and the graceful shutdown of a process, such
+// as loading its configuration or dialing its dependencies, and draining its
+// connections. Both belong to a single trace, so that slow deployments can be
+// diagnosed from it:
+//
+//	lc := tracer.StartLifecycle("web")
+//	cfg, err := loadConfig()
+//	lc.Phase("config.load").FinishWithErr(err)
+//	db := dial(lc.Context(ctx))
+//	lc.Ready()
+//	...
+//	span := lc.Shutdown()
+//	server.Shutdown(lc.Context(ctx))
+//	span.Finish()
+type Lifecycle struct {
+	tracer *Tracer
+
+	mu       sync.Mutex
+	startup  *Span
+	shutdown *Span
+}
+
+// StartLifecycle starts tracing the lifecycle of the process, reported under
+// the given service, with a "process.startup" root span which lasts until
+// Ready is called. The span lists the integrations loaded in the program.
+func (t *Tracer) StartLifecycle(service string) *Lifecycle {
+	startup := t.NewRootSpan(lifecycleStartupName, service, lifecycleStartupName)
+	if names := integrationNames(); names != "" {
+		startup.SetMeta(lifecycleIntegrationsKey, names)
+		if t.DebugLoggingEnabled() {
+			logf(logDebug, "tracer", "loaded integrations: %s", names)
+		}
+	}
+	return &Lifecycle{
+		tracer:  t,
+		startup: startup,
+	}
+}
+
+// StartLifecycle starts tracing the lifecycle of the process with the
+// default tracer, see Tracer.StartLifecycle.
+func StartLifecycle(service string) *Lifecycle {
+	return DefaultTracer.StartLifecycle(service)
+}
+
+// current returns the span of the current stage of the lifecycle.
+func (l *Lifecycle) current() *Span {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.shutdown != nil {
+		return l.shutdown
+	}
+	return l.startup
+}
+
+// Phase starts a span for a phase of the current stage of the lifecycle, the
+// startup or the shutdown, named after the phase, such as "config.load". The
+// span has to be finished by the caller.
+func (l *Lifecycle) Phase(name string) *Span {
+	return l.tracer.NewChildSpan(name, l.current())
+}
+
+// Context returns a copy of ctx holding the span of the current stage of the
+// lifecycle, so that the spans of the integrations, or the ones created with
+// Measure, are recorded as its phases.
+func (l *Lifecycle) Context(ctx context.Context) context.Context {
+	return l.current().Context(ctx)
+}
+
+// Ready marks the end of the startup of the process, finishing its span.
+// Calling it more than once has no effect.
+func (l *Lifecycle) Ready() {
+	l.mu.Lock()
+	startup := l.startup
+	l.mu.Unlock()
+	startup.finishOnce()
+}
+
+// Shutdown starts the "process.shutdown" span of the graceful shutdown of the
+// process, which has to be finished by the caller once it is done. It ends
+// the startup if Ready wasn't called. The span follows from the startup, in
+// the same trace, and the phases started afterwards are its children.
+func (l *Lifecycle) Shutdown() *Span {
+	l.Ready()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.shutdown == nil {
+		l.shutdown = l.tracer.NewFollowsFromSpan(lifecycleShutdownName, l.startup)
+	}
+	return l.shutdown
+}
+
+// finishOnce finishes the span unless it is already finished, without
+// logging it as finished twice.
+func (s *Span) finishOnce() {
+	s.RLock()
+	finished := s.finished
+	s.RUnlock()
+	if !finished {
+		s.Finish()
+	}
+}
@@ -0,0 +1,71 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	RegisterIntegration(Integration{Name: "test/integration"})
+
+	lc := tracer.StartLifecycle("web")
+	lc.Phase("config.load").Finish()
+	tracer.Measure(lc.Context(context.Background()), "db.dial", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	lc.Ready()
+	lc.Ready()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	startup := traces[0][0]
+	assert.Equal(lifecycleStartupName, startup.Name)
+	assert.Equal("web", startup.Service)
+	assert.Contains(startup.Meta[lifecycleIntegrationsKey], "test/integration")
+	assert.Equal("config.load", traces[0][1].Name)
+	assert.Equal(startup.SpanID, traces[0][1].ParentID)
+	assert.Equal("db.dial", traces[0][2].Name)
+	assert.Equal(startup.SpanID, traces[0][2].ParentID)
+	assert.Equal(int32(1), traces[0][2].Error)
+
+	span := lc.Shutdown()
+	assert.Equal(span, lc.Shutdown())
+	lc.Phase("http.drain").Finish()
+	span.Finish()
+
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	shutdown := traces[0][0]
+	assert.Equal(lifecycleShutdownName, shutdown.Name)
+	assert.Equal("web", shutdown.Service)
+	assert.Equal(startup.TraceID, shutdown.TraceID)
+	assert.Equal(startup.SpanID, shutdown.ParentID)
+	assert.Equal(ext.RelationshipFollowsFrom, shutdown.Meta[ext.SpanRelationship])
+	assert.Equal(shutdown.SpanID, traces[0][1].ParentID)
+}
+
+func TestLifecycleShutdownBeforeReady(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	lc := tracer.StartLifecycle("worker")
+	lc.Shutdown().Finish()
+
+	// both spans belong to the same trace, sent at once
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+}
@@ -0,0 +1,67 @@
+package tracer
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket allowing a number of events per second, with
+// bursts of at most that many events.
+type rateLimiter struct {
+	rate float64 // events allowed per second
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// the events of the current one second window, and the ratio of the
+	// events allowed in the previous one, to compute the effective rate
+	window    time.Time
+	seen      float64
+	allowed   float64
+	prevRatio float64
+}
+
+// newRateLimiter returns a rateLimiter allowing rate events per second.
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{
+		rate:      rate,
+		tokens:    rate,
+		prevRatio: 1,
+	}
+}
+
+// allow reports whether an event happening at the given time is allowed,
+// consuming a token if so. It also returns the effective rate of the
+// limiter, the ratio of the events it allowed over the current and the
+// previous second.
+func (l *rateLimiter) allow(now time.Time) (bool, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() && now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+	if d := now.Sub(l.window); l.window.IsZero() || d >= time.Second {
+		if d < 2*time.Second && l.seen > 0 {
+			l.prevRatio = l.allowed / l.seen
+		} else {
+			// nothing was seen during the previous second
+			l.prevRatio = 1
+		}
+		l.window, l.seen, l.allowed = now, 0, 0
+	}
+	l.seen++
+	allowed := l.tokens >= 1
+	if allowed {
+		l.tokens--
+		l.allowed++
+	}
+	return allowed, (l.prevRatio + l.allowed/l.seen) / 2
+}
@@ -0,0 +1,65 @@
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	l := newRateLimiter(10)
+	now := time.Now()
+
+	// bursts are allowed up to the rate
+	for i := 0; i < 10; i++ {
+		assertAllow(assert, l, now, true)
+	}
+	assertAllow(assert, l, now, false)
+
+	// tokens are refilled over time
+	now = now.Add(100 * time.Millisecond)
+	assertAllow(assert, l, now, true)
+	assertAllow(assert, l, now, false)
+
+	// but never beyond the rate
+	now = now.Add(time.Hour)
+	for i := 0; i < 10; i++ {
+		assertAllow(assert, l, now, true)
+	}
+	assertAllow(assert, l, now, false)
+
+	// a clock going backwards refills nothing
+	assertAllow(assert, l, now.Add(-time.Second), false)
+}
+
+func assertAllow(assert *assert.Assertions, l *rateLimiter, now time.Time, want bool) {
+	allowed, _ := l.allow(now)
+	assert.Equal(want, allowed)
+}
+
+func TestRateLimiterEffectiveRate(t *testing.T) {
+	assert := assert.New(t)
+
+	l := newRateLimiter(10)
+	now := time.Now()
+	var rate float64
+	for i := 0; i < 20; i++ {
+		_, rate = l.allow(now)
+	}
+	assert.Equal(0.75, rate) // half of the events of this second, none before
+
+	// the ratio of the previous second is taken into account
+	now = now.Add(time.Second)
+	for i := 0; i < 10; i++ {
+		_, rate = l.allow(now)
+	}
+	assert.Equal(0.75, rate)
+
+	// but forgotten after a second without events
+	now = now.Add(2 * time.Second)
+	_, rate = l.allow(now)
+	assert.Equal(1.0, rate)
+}
@@ -0,0 +1,125 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLevel is the severity of a tracer-internal log message.
+type logLevel string
+
+const (
+	logDebug logLevel = "debug"
+	logInfo  logLevel = "info"
+	logWarn  logLevel = "warn"
+	logError logLevel = "error"
+)
+
+// jsonLog holds the writer JSON logs are written to. When it is nil, the
+// tracer logs free-form messages through the standard log package.
+var jsonLog struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// SetJSONLogging makes all tracers emit their internal logs as JSON objects,
+// one per line, to the given writer, so that they can be parsed by log
+// pipelines. Each object holds the time, level, component and message of the
+// log, and for errors, their kind, category and number of occurrences.
+// Passing a nil writer restores the default logging through the standard
+// log package.
+func SetJSONLogging(w io.Writer) {
+	jsonLog.Lock()
+	jsonLog.w = w
+	jsonLog.Unlock()
+}
+
+// logEntry is a tracer-internal log message.
+type logEntry struct {
+	Time      string   `json:"time"`
+	Level     logLevel `json:"level"`
+	Component string   `json:"component"`
+	Message   string   `json:"message"`
+
+	// set for errors only
+	Kind     string `json:"error_kind,omitempty"`
+	Category string `json:"error_category,omitempty"`
+	Count    int    `json:"count,omitempty"`
+	Period   string `json:"period,omitempty"`
+}
+
+// write writes the entry as JSON, it returns false if JSON logging is disabled.
+func (e *logEntry) write() bool {
+	jsonLog.Lock()
+	defer jsonLog.Unlock()
+	if jsonLog.w == nil {
+		return false
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.Message = strings.TrimSuffix(e.Message, "\n")
+	b, err := json.Marshal(e)
+	if err != nil {
+		return true
+	}
+	jsonLog.w.Write(append(b, '\n'))
+	return true
+}
+
+// logf logs a message emitted by the given component. The free-form error
+// messages are prefixed with errorPrefix.
+func logf(level logLevel, component, format string, a ...interface{}) {
+	e := logEntry{Level: level, Component: component, Message: fmt.Sprintf(format, a...)}
+	if e.write() {
+		return
+	}
+	if level == logError {
+		log.Print(errorPrefix + e.Message)
+		return
+	}
+	log.Print(e.Message)
+}
+
+// Warnf logs a warning emitted by the given component, such as a package
+// built on the tracer, through the tracer's logger: it is written as JSON when
+// SetJSONLogging is set.
+func Warnf(component, format string, a ...interface{}) {
+	logf(logWarn, component, format, a...)
+}
+
+// logErrorSummary logs an aggregated error, as returned by aggregateErrors,
+// which occurred over the given period of time.
+func logErrorSummary(kind string, s errorSummary, period time.Duration) {
+	e := logEntry{
+		Level:     logError,
+		Component: "tracer",
+		Message:   s.Example,
+		Kind:      kind,
+		Category:  s.Category.String(),
+		Count:     s.Count,
+	}
+	if s.Count > 1 {
+		e.Period = formatPeriod(period)
+	}
+	if e.write() {
+		return
+	}
+	msg := s.Example
+	if s.Count > 1 {
+		msg = fmt.Sprintf("%d times in the last %s: %s", s.Count, e.Period, s.Example)
+	}
+	log.Println(errorPrefix + "[" + e.Category + "] " + msg)
+}
+
+// formatPeriod formats the given period in seconds, rounded up.
+func formatPeriod(d time.Duration) string {
+	if d < time.Second {
+		return "1s"
+	}
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10) + "s"
+}
@@ -0,0 +1,86 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogText(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	logf(logWarn, "tracer", "rate must be between 0 and 1, now: %f", 1.5)
+	assert.Contains(buf.String(), "rate must be between 0 and 1, now: 1.500000")
+	assert.NotContains(buf.String(), errorPrefix)
+
+	// warnings of other packages aren't prefixed either
+	buf.Reset()
+	Warnf("opentracing", "ignoring the propagation style %q", "jaeger")
+	assert.Contains(buf.String(), `ignoring the propagation style "jaeger"`)
+	assert.NotContains(buf.String(), errorPrefix)
+
+	// errors are prefixed
+	buf.Reset()
+	logf(logError, "rand", "cannot generate random seed: %v; using current time\n", "EOF")
+	assert.Contains(buf.String(), errorPrefix+"cannot generate random seed: EOF; using current time\n")
+
+	buf.Reset()
+	logErrorSummary("ErrorSpanBufFull", errorSummary{Category: ErrorCategoryOversizedTrace, Count: 3, Example: "span buffer is full (length: 1000)"}, 59500*time.Millisecond)
+	assert.Contains(buf.String(), errorPrefix+"[oversized_trace] 3 times in the last 60s: span buffer is full (length: 1000)")
+
+	buf.Reset()
+	logErrorSummary("ErrorSpanBufFull", errorSummary{Category: ErrorCategoryOversizedTrace, Count: 1, Example: "span buffer is full (length: 1000)"}, 0)
+	assert.Contains(buf.String(), errorPrefix+"[oversized_trace] span buffer is full (length: 1000)\n")
+}
+
+func TestLogJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf, std bytes.Buffer
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+	SetJSONLogging(&buf)
+	defer SetJSONLogging(nil)
+
+	logf(logWarn, "transport", "calling the endpoint '%s' but received %d; downgrading the API\n", "/v0.3/traces", 404)
+	logf(logError, "rand", "cannot generate random seed: %v; using current time\n", "EOF")
+	logErrorSummary("ErrorSpanBufFull", errorSummary{Category: ErrorCategoryOversizedTrace, Count: 3, Example: "span buffer is full (length: 1000)"}, time.Minute)
+	assert.Equal(0, std.Len())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(lines, 3)
+
+	var e logEntry
+	assert.NoError(json.Unmarshal([]byte(lines[0]), &e))
+	assert.NotEmpty(e.Time)
+	assert.Equal(logWarn, e.Level)
+	assert.Equal("transport", e.Component)
+	assert.Equal("calling the endpoint '/v0.3/traces' but received 404; downgrading the API", e.Message)
+	assert.Equal("", e.Kind)
+
+	// the message of errors has no prefix
+	e = logEntry{}
+	assert.NoError(json.Unmarshal([]byte(lines[1]), &e))
+	assert.Equal(logError, e.Level)
+	assert.Equal("cannot generate random seed: EOF; using current time", e.Message)
+
+	e = logEntry{}
+	assert.NoError(json.Unmarshal([]byte(lines[2]), &e))
+	assert.Equal(logError, e.Level)
+	assert.Equal("tracer", e.Component)
+	assert.Equal("span buffer is full (length: 1000)", e.Message)
+	assert.Equal("ErrorSpanBufFull", e.Kind)
+	assert.Equal("oversized_trace", e.Category)
+	assert.Equal(3, e.Count)
+	assert.Equal("60s", e.Period)
+}
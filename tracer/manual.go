@@ -0,0 +1,105 @@
+package tracer
+
+import "github.com/DataDog/dd-trace-go/tracer/ext"
+
+// KeepTrace keeps the local trace of the span, the spans of this process
+// which belong to the same trace, whatever the sampler decided, e.g. to
+// retain the trace of a failed checkout or of a debug session. The trace is
+// given the ext.PriorityUserKeep priority, so that the downstream services
+// keep it too if it is set before the trace is propagated. In a trace dropped
+// when it started, only the local root, the unfinished ancestors of the span,
+// the span itself and the spans created from them afterwards are recorded,
+// without the tags set beforehand, and the trace stays dropped if its local
+// root has finished already. Setting the ext.ManualKeep tag on a span does
+// the same.
+func KeepTrace(span *Span) {
+	setManualPriority(span, ext.PriorityUserKeep)
+}
+
+// DropTrace drops the local trace of the span, whatever the sampler decided,
+// giving it the ext.PriorityUserReject priority. Setting the ext.ManualDrop
+// tag on a span does the same.
+func DropTrace(span *Span) {
+	setManualPriority(span, ext.PriorityUserReject)
+}
+
+// setManualPriority sets the sampling priority chosen by the user on the
+// local root of the span, and keeps or drops its trace accordingly.
+func setManualPriority(span *Span, priority int) {
+	if span == nil {
+		return
+	}
+	root := span.localRoot()
+	root.RLock()
+	lightweight, finished, t := root.lightweight, root.finished, root.tracer
+	root.RUnlock()
+	if lightweight {
+		if priority > 0 && finished {
+			// there's no trace left to record, as its root was dropped
+			logf(logWarn, "tracer", "span %q (id: %d) can't be kept: its trace was dropped and its local root has finished", span.Name, span.SpanID)
+			return
+		}
+		if priority > 0 && t != nil {
+			// the trace was dropped when it started, record it from now on
+			t.SampleWithPriority(root, priority)
+			root.SetSamplingPriority(priority)
+			revive(span, root)
+		}
+		return
+	}
+	root.Lock()
+	root.Sampled = priority > 0
+	root.Unlock()
+	root.SetSamplingPriority(priority)
+	if priority > 0 {
+		// the trace may have been recorded for its errors only
+		root.buffer.keep()
+	}
+}
+
+// revive records the spans between the span and the local root of its trace,
+// which was dropped when it started and is kept after all, so that their
+// children are recorded as well. The finished ones stay dropped.
+func revive(span, root *Span) {
+	root.RLock()
+	buffer := root.buffer
+	root.RUnlock()
+	for s := span; s != root; {
+		s.Lock()
+		s.tagsMu.Lock()
+		parent, revived := s.parent, s.lightweight && !s.finished
+		if revived {
+			s.lightweight = false
+			s.Sampled = true
+			s.buffer = buffer
+		}
+		s.tagsMu.Unlock()
+		s.Unlock()
+		if revived {
+			buffer.Push(s)
+		}
+		s = parent
+	}
+}
+
+// localRoot returns the first span of the trace of the span created in this
+// process, following its parents.
+func (s *Span) localRoot() *Span {
+	root := s
+	for {
+		root.RLock()
+		parent := root.parent
+		root.RUnlock()
+		if parent == nil {
+			return root
+		}
+		root = parent
+	}
+}
+
+// isSampled reports whether the trace started by the span is kept.
+func (s *Span) isSampled() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Sampled
+}
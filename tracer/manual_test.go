@@ -0,0 +1,100 @@
+package tracer
+
+import (
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepTrace(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetPrioritySampling(true)
+	tracer.priority.readRates(map[string]float64{"service:web,env:": 0})
+
+	root := tracer.NewRootSpan("http.request", "web", "POST /checkout")
+	assert.False(root.Sampled)
+	child := tracer.NewChildSpan("payment.charge", root)
+	child.Finish()
+	child = tracer.NewChildSpan("order.save", root)
+	KeepTrace(child)
+	child.Finish()
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	assert.Equal(ext.PriorityUserKeep, traces[0][0].GetSamplingPriority())
+	assert.Equal("-4", traces[0][0].Meta[samplingDecisionKey])
+}
+
+func TestKeepTraceDropped(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	root := tracer.NewRootSpan("http.request", "web", "POST /checkout")
+	tracer.NewChildSpan("payment.charge", root).Finish()
+	root.SetMeta(ext.ManualKeep, "true")
+	tracer.NewChildSpan("order.save", root).Finish()
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2) // the span created before isn't recorded
+	assert.Equal("http.request", traces[0][0].Name)
+	assert.Equal("order.save", traces[0][1].Name)
+	assert.Equal(ext.PriorityUserKeep, traces[0][0].GetSamplingPriority())
+	assert.NotContains(traces[0][0].Meta, ext.ManualKeep)
+}
+
+func TestKeepTraceDroppedFinished(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	root := tracer.NewRootSpan("http.request", "web", "POST /checkout")
+	child := tracer.NewChildSpan("order.save", root)
+	root.Finish()
+	KeepTrace(child)
+	child.Finish()
+
+	assert.True(root.lightweight)
+	assert.True(child.lightweight)
+	assert.Nil(root.buffer)
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestDropTrace(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("http.request", "web", "GET /debug")
+	child := tracer.NewChildSpan("db.query", root)
+	child.SetMeta(ext.ManualDrop, "true")
+	child.Finish()
+	root.Finish()
+	assert.Equal(ext.PriorityUserReject, root.GetSamplingPriority())
+
+	// the agent drops the trace once it has computed its statistics
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(float64(ext.PriorityUserReject), traces[0][0].Metrics[samplingPriorityKey])
+
+	// unless the tracer computes them
+	tracer.stats.setDroppedActive(true)
+	root = tracer.NewRootSpan("http.request", "web", "GET /debug")
+	root.SetMeta(ext.ManualDrop, "true")
+	root.Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
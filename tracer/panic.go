@@ -0,0 +1,102 @@
+package tracer
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+)
+
+// panicKey is the tag set on the local root span of a trace interrupted by
+// an unrecovered panic.
+const panicKey = "error.panic"
+
+// ReportPanic reports an unrecovered panic before the process dies. It has to
+// be deferred directly, at the top of main or of a goroutine:
+//
+//	func main() {
+//		span := tracer.NewRootSpan("batch.run", "batch", "nightly")
+//		defer tracer.DefaultTracer.ReportPanic(span, 5*time.Second)
+//		...
+//	}
+//
+// When the function panics, the panic and its stack are set as the error of
+// the given span, which is usually the active one, and of its local root
+// span. The spans of its trace which are still open are finished, and the
+// tracer is stopped, unless it already is, flushing the buffered traces for
+// at most the given timeout. Then the function panics again, with the same value, so that the
+// process still dies. It does nothing when the function returns normally. The
+// span may be nil, then the traces are only flushed.
+func (t *Tracer) ReportPanic(span *Span, timeout time.Duration) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	t.reportPanic(r, span, timeout)
+	panic(r)
+}
+
+// reportPanic reports the panic with the value r on the given span, and
+// stops the tracer, see ReportPanic.
+func (t *Tracer) reportPanic(r interface{}, span *Span, timeout time.Duration) {
+	// the stack of the deferred call still holds the frames of the panic
+	stack := string(debug.Stack())
+	if span != nil {
+		root := span
+		for root.parent != nil {
+			root = root.parent
+		}
+		span.setPanic(r, stack)
+		root.setPanic(r, stack)
+		root.SetMeta(panicKey, "true")
+
+		// finish the whole trace, the root last, so that it's sent
+		span.Finish()
+		for _, s := range span.buffer.openSpans() {
+			if s != root {
+				s.Finish()
+			}
+		}
+		root.Finish()
+	}
+	select {
+	case <-t.exit:
+		// already stopped, e.g. by an inner ReportPanic
+		return
+	default:
+	}
+	if n := t.StopWithTimeout(timeout); n > 0 {
+		logf(logError, "tracer", "panic: %v, %d traces abandoned while stopping", r, n)
+	}
+}
+
+// setPanic sets the panic with the value r and the given stack as the error
+// of the span.
+func (s *Span) setPanic(r interface{}, stack string) {
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+	if s.finished {
+		return
+	}
+	s.Error = 1
+	msg := fmt.Sprint(r)
+	if err, ok := r.(error); ok {
+		msg = err.Error()
+	}
+	s.setMeta(errorMsgKey, msg)
+	s.setMeta(errorTypeKey, reflect.TypeOf(r).String())
+	s.setMeta(errorStackKey, stack)
+}
+
+// ReportPanic reports an unrecovered panic on the given span with the default
+// tracer, see Tracer.ReportPanic. It has to be deferred directly as well:
+//
+//	defer tracer.ReportPanic(span, 5*time.Second)
+func ReportPanic(span *Span, timeout time.Duration) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	DefaultTracer.reportPanic(r, span, timeout)
+	panic(r)
+}
@@ -0,0 +1,86 @@
+package tracer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportPanic(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+
+	var root, child *Span
+	crash := func() {
+		root = tracer.NewRootSpan("batch.run", "batch", "nightly")
+		defer tracer.ReportPanic(root, time.Second)
+		child = tracer.NewChildSpan("batch.step", root)
+		grandchild := tracer.NewChildSpan("batch.query", child)
+		defer tracer.ReportPanic(grandchild, time.Second)
+		tracer.NewChildSpan("batch.setup", root).Finish()
+		panic(errors.New("out of cheese"))
+	}
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		crash()
+	}()
+
+	// the process still panics
+	assert.Equal(errors.New("out of cheese"), r)
+	// the tracer is stopped, once
+	_, open := <-tracer.exit
+	assert.False(open)
+
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 4)
+	for _, span := range traces[0] {
+		assert.True(span.finished)
+	}
+	assert.Equal(root, traces[0][0])
+	assert.Equal(int32(1), root.Error)
+	assert.Equal("out of cheese", root.Meta[errorMsgKey])
+	assert.Equal("*errors.errorString", root.Meta[errorTypeKey])
+	assert.Contains(root.Meta[errorStackKey], "TestReportPanic")
+	assert.Equal("true", root.Meta[panicKey])
+	assert.Equal("batch.query", traces[0][2].Name)
+	assert.Equal(int32(1), traces[0][2].Error)
+	assert.Equal(int32(0), traces[0][3].Error)
+	assert.Equal(int32(0), child.Error)
+}
+
+func TestReportPanicNoPanic(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	func() {
+		span := tracer.NewRootSpan("batch.run", "batch", "nightly")
+		defer span.Finish()
+		defer tracer.ReportPanic(span, time.Second)
+	}()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Equal(int32(0), traces[0][0].Error)
+}
+
+func TestReportPanicNoSpan(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		defer tracer.ReportPanic(nil, time.Second)
+		tracer.NewRootSpan("batch.run", "batch", "nightly").Finish()
+		panic("boom")
+	}()
+
+	assert.Equal("boom", r)
+	assert.Len(transport.Traces(), 1)
+}
@@ -0,0 +1,200 @@
+package tracer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// poolWaitName is the name of the spans covering the wait for a
+	// resource of a pool.
+	poolWaitName = "pool.wait"
+
+	// the metrics of the pool.wait spans, giving the usage of the pool
+	// when the resource was obtained
+	poolSizeKey    = "pool.size"
+	poolInUseKey   = "pool.in_use"
+	poolWaitingKey = "pool.waiting"
+)
+
+// PoolStats holds the usage of a resource pool, see Tracer.NewPool.
+type PoolStats struct {
+	// Size is the maximum number of resources of the pool, 0 if unbounded.
+	Size int `json:"size"`
+	// InUse is the number of resources currently in use.
+	InUse int64 `json:"in_use"`
+	// Waiting is the number of callers currently waiting for a resource.
+	Waiting int64 `json:"waiting"`
+	// Waits is the number of resources obtained since the pool was created.
+	Waits uint64 `json:"waits"`
+	// WaitDuration is the total time spent waiting for those resources.
+	WaitDuration time.Duration `json:"wait_duration"`
+}
+
+// Pool instruments a pool of resources, such as connections or workers, so
+// that its saturation is visible: the wait for a resource is traced by a
+// "pool.wait" span, which holds the usage of the pool as metrics, and the
+// usage of the pools is reported by Tracer.Stats, the debug handler and, at
+// each flush, as the "pool.size", "pool.in_use" and "pool.waiting" gauges
+// sent to DogStatsD when Tracer.SetDogStatsDAddr is set, tagged with the
+// name of the pool and its service. It is safe for concurrent use.
+//
+//	done := pool.Wait(ctx)
+//	conn, err := conns.Get(ctx)
+//	done(err)
+//	if err != nil {
+//		return err
+//	}
+//	defer pool.Release()
+type Pool struct {
+	// the counters are accessed atomically and come first to be 64-bit
+	// aligned
+	inUse     int64
+	waiting   int64
+	waits     uint64
+	waitNanos int64
+
+	tracer  *Tracer
+	name    string
+	service string
+	size    int
+}
+
+// NewPool returns a Pool instrumenting the pool with the given name, such as
+// "postgres.conns", of at most size resources, 0 meaning unbounded. The wait
+// spans are reported under the given service, their resource being the name
+// of the pool. Creating a pool with the name of an existing one replaces it
+// in the statistics. The pool must be closed once it is discarded, so that it
+// is no longer reported.
+func (t *Tracer) NewPool(name, service string, size int) *Pool {
+	p := &Pool{
+		tracer:  t,
+		name:    name,
+		service: service,
+		size:    size,
+	}
+	t.pools.add(p)
+	return p
+}
+
+// Wait records that a caller starts waiting for a resource of the pool. The
+// wait is traced as a child of the span found in ctx, if any: no trace is
+// started for a resource obtained out of a trace. The returned function must
+// be called once the wait is over, with the error which ended it if the
+// resource couldn't be obtained, which isn't counted as in use then.
+func (p *Pool) Wait(ctx context.Context) func(err error) {
+	start := p.tracer.clockNow()
+	atomic.AddInt64(&p.waiting, 1)
+	var span *Span
+	if parent, ok := SpanFromContext(ctx); ok && parent != nil {
+		span = p.tracer.NewChildSpanFromContext(poolWaitName, ctx)
+		span.Service = p.service
+		span.Resource = p.name
+	}
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			atomic.AddInt64(&p.waiting, -1)
+			atomic.AddUint64(&p.waits, 1)
+			atomic.AddInt64(&p.waitNanos, int64(p.tracer.clockNow().Sub(start)))
+			if err == nil {
+				atomic.AddInt64(&p.inUse, 1)
+			}
+			if span == nil {
+				return
+			}
+			stats := p.Stats()
+			span.SetMetric(poolSizeKey, float64(stats.Size))
+			span.SetMetric(poolInUseKey, float64(stats.InUse))
+			span.SetMetric(poolWaitingKey, float64(stats.Waiting))
+			span.FinishWithErr(err)
+		})
+	}
+}
+
+// Release records that a resource obtained with Wait is given back to the
+// pool.
+func (p *Pool) Release() {
+	atomic.AddInt64(&p.inUse, -1)
+}
+
+// Close stops reporting the pool, once it is discarded. Its pending waits
+// are traced as usual.
+func (p *Pool) Close() {
+	p.tracer.pools.remove(p)
+}
+
+// Stats returns the usage of the pool.
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Size:         p.size,
+		InUse:        atomic.LoadInt64(&p.inUse),
+		Waiting:      atomic.LoadInt64(&p.waiting),
+		Waits:        atomic.LoadUint64(&p.waits),
+		WaitDuration: time.Duration(atomic.LoadInt64(&p.waitNanos)),
+	}
+}
+
+// poolRegistry holds the pools of a tracer by name. It is safe for
+// concurrent use.
+type poolRegistry struct {
+	mu    sync.RWMutex
+	pools map[string]*Pool
+}
+
+func (r *poolRegistry) add(p *Pool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pools == nil {
+		r.pools = make(map[string]*Pool)
+	}
+	r.pools[p.name] = p
+}
+
+// remove removes the pool, unless it has been replaced by another one.
+func (r *poolRegistry) remove(p *Pool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pools[p.name] == p {
+		delete(r.pools, p.name)
+	}
+}
+
+// list returns the pools.
+func (r *poolRegistry) list() []*Pool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pools := make([]*Pool, 0, len(r.pools))
+	for _, p := range r.pools {
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// get returns the usage of the pools, by name.
+func (r *poolRegistry) get() map[string]PoolStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := make(map[string]PoolStats, len(r.pools))
+	for name, p := range r.pools {
+		stats[name] = p.Stats()
+	}
+	return stats
+}
+
+// reportPools sends the usage of the pools to DogStatsD, if it is set.
+func (t *Tracer) reportPools() {
+	d := t.dogStatsD()
+	if d == nil {
+		return
+	}
+	for _, p := range t.pools.list() {
+		stats := p.Stats()
+		tags := []string{"pool:" + p.name, "service:" + p.service}
+		d.gauge(poolSizeKey, float64(stats.Size), tags...)
+		d.gauge(poolInUseKey, float64(stats.InUse), tags...)
+		d.gauge(poolWaitingKey, float64(stats.Waiting), tags...)
+	}
+}
@@ -0,0 +1,89 @@
+package tracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	pool := tracer.NewPool("postgres.conns", "postgres", 2)
+	root := tracer.NewRootSpan("http.request", "web", "GET /")
+	ctx := root.Context(context.Background())
+
+	done := pool.Wait(ctx)
+	assert.Equal(PoolStats{Size: 2, Waiting: 1}, pool.Stats())
+	done(nil)
+	done(nil)
+	pool.Wait(ctx)(errors.New("timeout"))
+	pool.Wait(context.Background())(nil)
+	stats := pool.Stats()
+	assert.Equal(int64(2), stats.InUse)
+	assert.Equal(int64(0), stats.Waiting)
+	assert.Equal(uint64(3), stats.Waits)
+	assert.Equal(stats, tracer.Stats().Pools["postgres.conns"])
+
+	pool.Release()
+	assert.Equal(int64(1), pool.Stats().InUse)
+
+	root.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	wait := traces[0][1]
+	assert.Equal(poolWaitName, wait.Name)
+	assert.Equal("postgres", wait.Service)
+	assert.Equal("postgres.conns", wait.Resource)
+	assert.Equal(root.SpanID, wait.ParentID)
+	assert.Equal(2.0, wait.Metrics[poolSizeKey])
+	assert.Equal(1.0, wait.Metrics[poolInUseKey])
+	assert.Equal(0.0, wait.Metrics[poolWaitingKey])
+	assert.Equal(int32(0), wait.Error)
+	assert.Equal(int32(1), traces[0][2].Error)
+}
+
+func TestPoolClose(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	pool := tracer.NewPool("workers", "jobs", 4)
+	replaced := tracer.NewPool("conns", "db", 2)
+	tracer.NewPool("conns", "db", 8)
+	assert.Len(tracer.Stats().Pools, 2)
+
+	// closing a replaced pool leaves its replacement
+	replaced.Close()
+	assert.Equal(8, tracer.Stats().Pools["conns"].Size)
+	pool.Close()
+	assert.Len(tracer.Stats().Pools, 1)
+	assert.NotContains(tracer.Stats().Pools, "workers")
+}
+
+func TestPoolGauges(t *testing.T) {
+	assert := assert.New(t)
+	server := newDogStatsDServer(t)
+	defer server.Close()
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	pool := tracer.NewPool("postgres.conns", "postgres", 2)
+	defer pool.Close()
+	pool.Wait(context.Background())(nil)
+
+	// nothing is sent until DogStatsD is set
+	tracer.reportPools()
+	tracer.SetDogStatsDAddr(server.LocalAddr().String())
+	tracer.reportPools()
+	tags := "|g|#pool:postgres.conns,service:postgres"
+	assert.Equal("pool.size:2"+tags, readGauge(server))
+	assert.Equal("pool.in_use:1"+tags, readGauge(server))
+	assert.Equal("pool.waiting:0"+tags, readGauge(server))
+}
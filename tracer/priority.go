@@ -0,0 +1,143 @@
+package tracer
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+const (
+	// agentRateMetricKey is the metric key holding the sample rate given by
+	// the agent which was applied to a trace.
+	agentRateMetricKey = "_dd.agent_psr"
+
+	// defaultRateByServiceKey is the key of the rate applied to the services
+	// the agent gave no rate for.
+	defaultRateByServiceKey = "service:,env:"
+
+	// maxRatesResponseSize caps the size of the agent responses read to find
+	// the sample rates.
+	maxRatesResponseSize = 1024 * 1024
+)
+
+// prioritySampler samples traces with the rates computed by the agent for
+// each service and environment, which it returns in its responses to the
+// trace payloads, so that the traffic of each service is kept within the
+// target of the agent. It sets the sampling priority of the traces, which is
+// propagated to the downstream services so that they make the same decision.
+type prioritySampler struct {
+	mu          sync.RWMutex
+	rates       map[string]float64 // "service:<service>,env:<env>" -> rate
+	defaultRate float64
+}
+
+func newPrioritySampler() *prioritySampler {
+	return &prioritySampler{
+		rates:       make(map[string]float64),
+		defaultRate: 1,
+	}
+}
+
+// readRates records the rates by service given by the agent.
+func (ps *prioritySampler) readRates(rates map[string]float64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.rates = make(map[string]float64, len(rates))
+	ps.defaultRate = 1
+	for key, rate := range rates {
+		if key == defaultRateByServiceKey {
+			ps.defaultRate = rate
+			continue
+		}
+		ps.rates[key] = rate
+	}
+}
+
+// rate returns the sample rate of the given service and environment.
+func (ps *prioritySampler) rate(service, env string) float64 {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	if rate, ok := ps.rates["service:"+service+",env:"+env]; ok {
+		return rate
+	}
+	return ps.defaultRate
+}
+
+// Sample samples a span, setting its sampling priority.
+func (ps *prioritySampler) Sample(span *Span) {
+	rate := ps.rate(span.Service, span.tracer.env())
+	span.Sampled = sampleByRate(span.TraceID, rate)
+	priority := ext.PriorityAutoReject
+	if span.Sampled {
+		priority = ext.PriorityAutoKeep
+	}
+	span.SetMetric(samplingPriorityKey, float64(priority))
+	span.SetMetric(agentRateMetricKey, rate)
+}
+
+// ratesResponse is the response of the agent to a trace payload.
+type ratesResponse struct {
+	RateByService map[string]float64 `json:"rate_by_service"`
+}
+
+// readRatesResponse decodes the rates by service from the response body of
+// the agent. It returns nil if the body holds none, as do legacy agents.
+func readRatesResponse(body io.Reader) map[string]float64 {
+	var resp ratesResponse
+	b, err := ioutil.ReadAll(io.LimitReader(body, maxRatesResponseSize))
+	if err != nil || json.Unmarshal(b, &resp) != nil {
+		return nil
+	}
+	return resp.RateByService
+}
+
+// SetPrioritySampling makes the tracer sample the traces which don't match
+// any sampling rule with the rates the agent computes for each service, and
+// returns along with its responses to the trace payloads, instead of the
+// rate set by SetSampleRate, which disables it. The sampling decision is
+// recorded as the sampling priority of the traces, so that the downstream
+// services make the same. Until the agent gives a rate, or with a custom
+// transport, all the traces are kept.
+func (t *Tracer) SetPrioritySampling(enabled bool) {
+	if !enabled {
+		if _, ok := t.fallbackSampler().(*prioritySampler); ok {
+			t.SetSampleRate(1)
+		}
+		return
+	}
+	if rs, ok := t.sampler.(*rulesSampler); ok {
+		// the sampling rules take precedence
+		t.sampler = newRulesSampler(rs.rules, t.priority, rs.limiter)
+		return
+	}
+	t.sampler = t.priority
+}
+
+// PrioritySamplingEnabled returns true if the traces are sampled with the
+// rates given by the agent, see SetPrioritySampling.
+func (t *Tracer) PrioritySamplingEnabled() bool {
+	_, ok := t.fallbackSampler().(*prioritySampler)
+	return ok
+}
+
+// fallbackSampler returns the sampler applied to the traces which match no
+// sampling rule.
+func (t *Tracer) fallbackSampler() sampler {
+	if rs, ok := t.sampler.(*rulesSampler); ok {
+		return rs.fallback
+	}
+	return t.sampler
+}
+
+// env returns the environment set in the tracer meta, if any.
+func (t *Tracer) env() string {
+	if t == nil {
+		return ""
+	}
+	t.metaMu.RLock()
+	defer t.metaMu.RUnlock()
+	return t.meta["env"]
+}
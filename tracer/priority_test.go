@@ -0,0 +1,111 @@
+package tracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrioritySampler(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetMeta("env", "prod")
+
+	ps := newPrioritySampler()
+	span := tracer.NewRootSpan("http.request", "web", "/")
+	ps.Sample(span)
+	assert.True(span.Sampled)
+	assert.Equal(ext.PriorityAutoKeep, span.GetSamplingPriority())
+	assert.Equal(1., span.Metrics[agentRateMetricKey])
+
+	ps.readRates(map[string]float64{
+		"service:web,env:prod":  0,
+		"service:web,env:dev":   1,
+		defaultRateByServiceKey: 0.5,
+	})
+	assert.Equal(0., ps.rate("web", "prod"))
+	assert.Equal(0.5, ps.rate("db", "prod"))
+	span = tracer.NewRootSpan("http.request", "web", "/")
+	ps.Sample(span)
+	assert.False(span.Sampled)
+	assert.Equal(ext.PriorityAutoReject, span.GetSamplingPriority())
+	assert.Equal(0., span.Metrics[agentRateMetricKey])
+
+	// the default rate is reset with the rates
+	ps.readRates(map[string]float64{"service:web,env:prod": 0})
+	assert.Equal(1., ps.rate("db", "prod"))
+}
+
+func TestTransportRatesByService(t *testing.T) {
+	assert := assert.New(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rate_by_service": {"service:web,env:": 0.25}}`))
+	}))
+	defer receiver.Close()
+	u, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+
+	var rates map[string]float64
+	transport := newHTTPTransport(u.Hostname(), u.Port())
+	transport.setRatesHandler(func(r map[string]float64) { rates = r })
+	_, err = transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal(map[string]float64{"service:web,env:": 0.25}, rates)
+
+	// legacy agents answer "OK"
+	assert.Nil(readRatesResponse(strings.NewReader("OK")))
+}
+
+func TestTracerPrioritySampling(t *testing.T) {
+	assert := assert.New(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rate_by_service": {"service:web,env:": 0, "service:,env:": 1}}`))
+	}))
+	defer receiver.Close()
+
+	cfg := DefaultConfig()
+	cfg.AgentURL = receiver.URL
+	cfg.Sampler = "priority"
+	tracer := NewTracerWithConfig(cfg)
+	defer tracer.Stop()
+	assert.True(tracer.PrioritySamplingEnabled())
+	assert.Equal("priority", tracer.Config().Sampler)
+
+	// all the traces are kept until the agent gives rates
+	span := tracer.NewRootSpan("http.request", "web", "/")
+	assert.True(span.Sampled)
+	assert.Equal(ext.PriorityAutoKeep, span.GetSamplingPriority())
+	assert.Equal("-1", span.GetMeta(samplingDecisionKey))
+	span.Finish()
+	tracer.Flush()
+
+	span = tracer.NewRootSpan("http.request", "web", "/")
+	assert.False(span.Sampled)
+	assert.Equal(ext.PriorityAutoReject, span.GetSamplingPriority())
+	span.Finish()
+	span = tracer.NewRootSpan("sql.query", "db", "SELECT 1")
+	assert.True(span.Sampled)
+	span.Finish()
+
+	// the sampling rules take precedence
+	tracer.SetSamplingRules(SamplingRule{Service: "db", Rate: 0})
+	assert.True(tracer.PrioritySamplingEnabled())
+	span = tracer.NewRootSpan("sql.query", "db", "SELECT 1")
+	assert.False(span.Sampled)
+	tracer.SetSamplingRules()
+
+	tracer.SetPrioritySampling(false)
+	assert.False(tracer.PrioritySamplingEnabled())
+	assert.Equal("all", tracer.Config().Sampler)
+	span = tracer.NewRootSpan("http.request", "web", "/")
+	assert.True(span.Sampled)
+	assert.False(span.HasSamplingPriority())
+}
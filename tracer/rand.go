@@ -2,7 +2,6 @@ package tracer
 
 import (
 	cryptorand "crypto/rand"
-	"log"
 	"math"
 	"math/big"
 	"math/rand"
@@ -26,7 +25,7 @@ func newRandSource() *randSource {
 	if err == nil {
 		seed = n.Int64()
 	} else {
-		log.Printf("%scannot generate random seed: %v; using current time\n", errorPrefix, err)
+		logf(logError, "rand", "cannot generate random seed: %v; using current time\n", err)
 		seed = time.Now().UnixNano()
 	}
 
@@ -0,0 +1,83 @@
+package tracer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// rareMetricKey is the metric set on the root spans of traces kept by
+	// the rare sampler.
+	rareMetricKey = "_dd.rare"
+
+	// rareMaxEntries is the maximum number of service/resource combinations
+	// tracked in a window, bounding the memory used by the rare sampler.
+	rareMaxEntries = 10000
+)
+
+// rareKey identifies a service/resource combination.
+type rareKey struct {
+	service, resource string
+}
+
+// rareSampler keeps the first trace of each service/resource combination
+// in a window of time, so that rarely hit endpoints are still represented
+// with low sample rates. It is safe for concurrent use.
+type rareSampler struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time // start of the current window
+	seen  map[rareKey]struct{}
+}
+
+func newRareSampler(window time.Duration) *rareSampler {
+	return &rareSampler{
+		window: window,
+		seen:   make(map[rareKey]struct{}),
+	}
+}
+
+// Sample samples a root span which went through the tracer sampler: it keeps
+// it if no trace of the same combination was kept in the current window.
+func (s *rareSampler) Sample(span *Span, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.start) >= s.window {
+		s.start = now
+		s.seen = make(map[rareKey]struct{}, len(s.seen))
+	}
+	k := rareKey{span.Service, span.Resource}
+	_, seen := s.seen[k]
+	if !seen && len(s.seen) < rareMaxEntries {
+		s.seen[k] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	if seen || span.Sampled {
+		return
+	}
+	// the rate wasn't applied to that trace
+	span.Sampled = true
+	unsetSampleRate(span)
+	span.SetMetric(rareMetricKey, 1)
+}
+
+// SetRareSampling makes the tracer keep at least one trace per distinct
+// service and resource in each window of the given duration, even when the
+// sampler drops them, so that rarely hit endpoints are still represented.
+// A zero duration disables it.
+func (t *Tracer) SetRareSampling(window time.Duration) {
+	if window <= 0 {
+		t.rare = nil
+		return
+	}
+	t.rare = newRareSampler(window)
+}
+
+// rareKept reports whether the span was kept by the rare sampler.
+func rareKept(span *Span) bool {
+	span.tagsMu.RLock()
+	defer span.tagsMu.RUnlock()
+	_, ok := span.Metrics[rareMetricKey]
+	return ok
+}
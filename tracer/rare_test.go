@@ -0,0 +1,94 @@
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRareSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newRareSampler(time.Minute)
+	start := time.Now()
+	newDropped := func(service, resource string) *Span {
+		span := newSpan("http.request", service, resource, 1, 1, 0, nil)
+		span.Sampled = false
+		span.SetMetric(sampleRateMetricKey, 0.01)
+		return span
+	}
+
+	// the first trace of a combination is kept
+	span := newDropped("web", "GET /")
+	s.Sample(span, start)
+	assert.True(span.Sampled)
+	assert.Equal(1.0, span.Metrics[rareMetricKey])
+	_, found := span.Metrics[sampleRateMetricKey]
+	assert.False(found)
+
+	// the next ones are left to the sampler
+	span = newDropped("web", "GET /")
+	s.Sample(span, start.Add(time.Second))
+	assert.False(span.Sampled)
+	span = newDropped("web", "GET /admin")
+	s.Sample(span, start.Add(time.Second))
+	assert.True(span.Sampled)
+
+	// a combination already sampled doesn't need to be kept again
+	span = newSpan("http.request", "web", "GET /users", 1, 1, 0, nil)
+	s.Sample(span, start.Add(time.Second))
+	_, found = span.Metrics[rareMetricKey]
+	assert.False(found)
+	span = newDropped("web", "GET /users")
+	s.Sample(span, start.Add(2*time.Second))
+	assert.False(span.Sampled)
+
+	// a new window starts over
+	span = newDropped("web", "GET /")
+	s.Sample(span, start.Add(time.Minute))
+	assert.True(span.Sampled)
+}
+
+func TestTracerRareSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetRareSampling(time.Hour)
+
+	for i := 0; i < 10; i++ {
+		tracer.NewRootSpan("http.request", "web", "GET /").Finish()
+		tracer.NewRootSpan("http.request", "web", "GET /rare").Finish()
+	}
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 2)
+
+	tracer.SetRareSampling(0)
+	tracer.NewRootSpan("http.request", "web", "GET /other").Finish()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestTracerRareSamplingResample(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetRareSampling(time.Hour)
+
+	// a root continued from upstream is sampled again, which must not undo
+	// the keep of the rare sampler
+	span := tracer.NewRootSpan("http.request", "web", "GET /")
+	assert.True(span.Sampled)
+	tracer.SampleWithParams(span, SamplingParams{Remote: true})
+	assert.True(span.Sampled)
+	assert.Equal(1.0, span.Metrics[rareMetricKey])
+	span.Finish()
+
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 1)
+}
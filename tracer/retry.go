@@ -0,0 +1,143 @@
+package tracer
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy tells how the trace payloads which could not be sent to the
+// agent are retried, with a jittered exponential backoff.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a payload is retried at most, 0
+	// disabling retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, which doubles on
+	// each retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between two retries.
+	MaxBackoff time.Duration
+	// MaxRetainedTraces is the number of traces which can be held in memory
+	// for retries at the same time. The payloads failing while it is reached
+	// are dropped rather than retried.
+	MaxRetainedTraces int
+}
+
+// defaultRetryPolicy is the retry policy of new tracers.
+var defaultRetryPolicy = RetryPolicy{
+	MaxRetries:        3,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        time.Second,
+	MaxRetainedTraces: traceChanLen,
+}
+
+// backoff returns the delay before the given retry, starting from 1. It is
+// drawn at random between half and all of the exponential backoff, so that
+// the tracers of many processes failing at once don't retry together.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(randGen.Int63n(int64(d/2)))
+}
+
+// SetRetryPolicy sets how the trace payloads which could not be sent to the
+// agent are retried. Failures which retrying can't fix, such as encoding
+// errors or payloads rejected by the agent, are never retried. Retries are
+// given up when the tracer stops.
+func (t *Tracer) SetRetryPolicy(p RetryPolicy) {
+	t.retryMu.Lock()
+	t.retry = p
+	t.retryMu.Unlock()
+}
+
+// retryPolicy returns the retry policy of the tracer.
+func (t *Tracer) retryPolicy() RetryPolicy {
+	t.retryMu.RLock()
+	defer t.retryMu.RUnlock()
+	return t.retry
+}
+
+// retryable reports whether sending a payload which failed with the given
+// response and error may succeed when retried.
+func retryable(response *http.Response, err error) bool {
+	if _, ok := err.(*errorEncoding); ok {
+		return false
+	}
+	if response == nil || response.StatusCode == 0 {
+		// the agent couldn't be reached
+		return true
+	}
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
+
+// send sends the traces to the transport, retrying as allowed by the retry
+// policy. It returns the error of the last attempt, as an *errorChunks when
+// only some of the traces couldn't be sent: the ones which were sent aren't
+// retried. The caller holds a slot of the given send semaphore, if not nil,
+// which send releases: it is given back while backing off, so that the
+// retries don't hold back the sends of the next payloads.
+func (t *Tracer) send(traces [][]*Span, sem chan struct{}) (err error) {
+	p := t.retryPolicy()
+	n := int64(len(traces))
+	retained := false
+	held := sem != nil
+	pending := traces
+	defer func() {
+		if held {
+			<-sem
+		}
+		if retained {
+			atomic.AddInt64(&t.retainedTraces, -n)
+		}
+		if err != nil && len(pending) < len(traces) {
+			err = &errorChunks{Traces: pending, Err: err}
+		}
+	}()
+	for retry := 1; ; retry++ {
+		var response *http.Response
+		response, err = t.transport.SendTraces(pending)
+		if ce, ok := err.(*errorChunks); ok {
+			pending, err = ce.Traces, ce.Err
+		}
+		t.lastFlush.record(time.Now(), err)
+		if err == nil || retry > p.MaxRetries || !retryable(response, err) {
+			return err
+		}
+		if !retained {
+			if atomic.AddInt64(&t.retainedTraces, n) > int64(p.MaxRetainedTraces) {
+				// too many traces are waiting to be retried already
+				atomic.AddInt64(&t.retainedTraces, -n)
+				return err
+			}
+			retained = true
+		}
+		if held {
+			<-sem
+			held = false
+		}
+		timer := time.NewTimer(p.backoff(retry))
+		select {
+		case <-timer.C:
+		case <-t.exit:
+			timer.Stop()
+			return err
+		}
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+				held = true
+			case <-t.exit:
+				return err
+			}
+		}
+		atomic.AddUint64(&t.retriedPayloads, 1)
+	}
+}
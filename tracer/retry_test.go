@@ -0,0 +1,204 @@
+package tracer
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingTransport is a dummyTransport failing the given number of sends
+// with the given response and error before succeeding.
+type failingTransport struct {
+	dummyTransport
+	failures int
+	response *http.Response
+	err      error
+	attempts int
+}
+
+func (t *failingTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	t.Lock()
+	t.attempts++
+	fail := t.attempts <= t.failures
+	t.Unlock()
+	if fail {
+		return t.response, t.err
+	}
+	return t.dummyTransport.SendTraces(traces)
+}
+
+// chunkFailingTransport is a dummyTransport failing to send the last trace
+// of the payloads of more than one trace, as if the chunk holding it failed.
+type chunkFailingTransport struct {
+	dummyTransport
+	sizes []int
+}
+
+func (t *chunkFailingTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	t.Lock()
+	t.sizes = append(t.sizes, len(traces))
+	t.Unlock()
+	if n := len(traces); n > 1 {
+		t.dummyTransport.SendTraces(traces[:n-1])
+		return &http.Response{StatusCode: 500}, &errorChunks{Traces: traces[n-1:], Err: errors.New("internal error")}
+	}
+	return t.dummyTransport.SendTraces(traces)
+}
+
+// testRetryPolicy retries quickly.
+var testRetryPolicy = RetryPolicy{
+	MaxRetries:        3,
+	InitialBackoff:    time.Millisecond,
+	MaxBackoff:        5 * time.Millisecond,
+	MaxRetainedTraces: 10,
+}
+
+func TestTracerRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &failingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		failures:       2,
+		response:       &http.Response{StatusCode: 0},
+		err:            errors.New("connection refused"),
+	}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	tracer.SetRetryPolicy(testRetryPolicy)
+
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.ForceFlush()
+	assert.Equal(3, transport.attempts)
+	assert.Len(transport.Traces(), 1)
+	stats := tracer.Stats()
+	assert.Equal(uint64(2), stats.RetriedPayloads)
+	assert.Equal(uint64(0), stats.DroppedPayloads)
+	assert.NoError(stats.LastFlushErr)
+}
+
+func TestTracerRetryGivesUp(t *testing.T) {
+	for name, tt := range map[string]struct {
+		policy   RetryPolicy
+		response *http.Response
+		err      error
+		attempts int
+	}{
+		"max-retries": {testRetryPolicy, nil, errors.New("timeout"), 4},
+		"no-retries":  {RetryPolicy{}, nil, errors.New("timeout"), 1},
+		"encoding":    {testRetryPolicy, nil, &errorEncoding{Err: errors.New("bad value")}, 1},
+		"rejected":    {testRetryPolicy, &http.Response{StatusCode: 400}, errors.New("bad request"), 1},
+		"overloaded":  {testRetryPolicy, &http.Response{StatusCode: 503}, errors.New("unavailable"), 4},
+		"retention": {
+			RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxRetainedTraces: 0},
+			nil, errors.New("timeout"), 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			transport := &failingTransport{
+				dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+				failures:       10,
+				response:       tt.response,
+				err:            tt.err,
+			}
+			tracer := NewTracerTransport(transport)
+			defer tracer.Stop()
+			tracer.SetRetryPolicy(tt.policy)
+
+			tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+			tracer.ForceFlush()
+			assert.Equal(tt.attempts, transport.attempts)
+			assert.Equal(uint64(tt.attempts-1), tracer.Stats().RetriedPayloads)
+			assert.Equal(uint64(1), tracer.Stats().DroppedPayloads)
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		d := p.backoff(retry + 1)
+		assert.True(d >= max/2 && d <= max, "retry %d: %s", retry+1, d)
+	}
+}
+
+func TestTracerRetryStop(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &failingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		failures:       10,
+		err:            errors.New("connection refused"),
+	}
+	tracer := NewTracerTransport(transport)
+	tracer.SetRetryPolicy(RetryPolicy{MaxRetries: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour, MaxRetainedTraces: 10})
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	// the retries are given up when the tracer stops
+	start := time.Now()
+	tracer.Stop()
+	assert.True(time.Since(start) < time.Minute)
+}
+
+func TestTracerRetryChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &chunkFailingTransport{dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory}}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+
+	// only the traces of the failed chunk are retried
+	tracer.SetRetryPolicy(testRetryPolicy)
+	assert.NoError(tracer.send(getTestTrace(3, 1), nil))
+	assert.Equal([]int{3, 1}, transport.sizes)
+	assert.Len(transport.Traces(), 3)
+
+	// and only them are lost
+	tracer.SetRetryPolicy(RetryPolicy{})
+	err := tracer.send(getTestTrace(3, 1), nil)
+	assert.IsType(&errorChunks{}, err)
+	assert.Len(err.(*errorChunks).Traces, 1)
+	assert.Equal("internal error", err.(*errorChunks).Err.Error())
+}
+
+func TestTracerRetryReleasesSend(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &failingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		failures:       1,
+		err:            errors.New("connection refused"),
+	}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	tracer.SetConcurrentSends(1)
+	tracer.SetRetryPolicy(RetryPolicy{MaxRetries: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour, MaxRetainedTraces: 10})
+
+	// the payload backing off doesn't hold back the next one
+	tracer.sendTraces(getTestTrace(1, 1))
+	done := make(chan struct{})
+	go func() {
+		tracer.sendTraces(getTestTrace(1, 1))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail("the second payload wasn't sent")
+		return
+	}
+	var traces [][]*Span
+	deadline := time.Now().Add(5 * time.Second)
+	for len(traces) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		traces = transport.Traces()
+	}
+	assert.Len(traces, 1)
+}
@@ -0,0 +1,153 @@
+package tracer
+
+import "encoding/json"
+
+const (
+	// rulesRateMetricKey is the metric key holding the sample rate of the
+	// sampling rule matched by a trace.
+	rulesRateMetricKey = "_dd.rule_psr"
+	// limitRateMetricKey is the metric key holding the effective rate of the
+	// limiter of the sampling rules, when it applies to a trace.
+	limitRateMetricKey = "_dd.limit_psr"
+)
+
+// SamplingRule applies a sample rate to the traces whose root span matches
+// it. Service, Name and Resource are glob patterns, in which '*' matches any
+// sequence of characters and '?' any single character, e.g. "web-*" or
+// "GET /api/*". An empty pattern matches everything. Its JSON form is the one
+// of DD_TRACE_SAMPLING_RULES, in which the rate defaults to 1, e.g.
+//
+//	[{"service": "web-*", "name": "http.request", "sample_rate": 0.5}]
+type SamplingRule struct {
+	Service  string  `json:"service,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Resource string  `json:"resource,omitempty"`
+	Rate     float64 `json:"sample_rate"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, defaulting the rate to 1.
+func (r *SamplingRule) UnmarshalJSON(b []byte) error {
+	type rule SamplingRule // without this method
+	v := rule{Rate: 1}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = SamplingRule(v)
+	return nil
+}
+
+// match reports whether the rule applies to the given root span.
+func (r *SamplingRule) match(span *Span) bool {
+	return globMatch(r.Service, span.Service) &&
+		globMatch(r.Name, span.Name) &&
+		globMatch(r.Resource, span.Resource)
+}
+
+// rulesSampler samples traces using the rate of the first rule matching their
+// root span, and falls back to another sampler when none does. The traces
+// kept by the rules are rate limited when limiter is set, their root span
+// then holding the effective rate of the limiter.
+type rulesSampler struct {
+	rules    []SamplingRule
+	fallback sampler
+	limiter  *rateLimiter
+}
+
+// newRulesSampler returns a rulesSampler for the given rules.
+func newRulesSampler(rules []SamplingRule, fallback sampler, limiter *rateLimiter) *rulesSampler {
+	return &rulesSampler{
+		rules:    rules,
+		fallback: fallback,
+		limiter:  limiter,
+	}
+}
+
+// Sample samples a span
+func (s *rulesSampler) Sample(span *Span) {
+	for i := range s.rules {
+		if !s.rules[i].match(span) {
+			continue
+		}
+		rate := s.rules[i].Rate
+		span.Sampled = sampleByRate(span.TraceID, rate)
+		span.SetMetric(rulesRateMetricKey, rate)
+		if span.Sampled && s.limiter != nil {
+			allowed, limitRate := s.limiter.allow(span.tracer.clockNow())
+			span.Sampled = allowed
+			span.SetMetric(limitRateMetricKey, limitRate)
+		}
+		return
+	}
+	s.fallback.Sample(span)
+}
+
+// SetSamplingRules sets the rules applying sample rates to the future traces
+// depending on the service, name and resource of their root span. Rules are
+// evaluated in order and the first one matching is applied. Traces which
+// don't match any rule are sampled with the rate set by SetSampleRate.
+// Calling it without rules removes them.
+func (t *Tracer) SetSamplingRules(rules ...SamplingRule) {
+	fallback := t.sampler
+	if rs, ok := fallback.(*rulesSampler); ok {
+		fallback = rs.fallback
+	}
+	for _, r := range rules {
+		if r.Rate < 0 || r.Rate > 1 {
+			logf(logWarn, "tracer", "tracer.SetSamplingRules rate must be between 0 and 1, now: %f", r.Rate)
+			return
+		}
+	}
+	if len(rules) == 0 {
+		t.sampler = fallback
+		return
+	}
+	t.sampler = newRulesSampler(append([]SamplingRule(nil), rules...), fallback, t.limiter)
+}
+
+// SetSamplingRateLimit limits the number of traces per second kept by the
+// sampling rules, 100 by default, the traces sampled with the rate set by
+// SetSampleRate are not limited. Setting it to 0 removes the limit.
+func (t *Tracer) SetSamplingRateLimit(perSecond float64) {
+	if perSecond < 0 {
+		logf(logWarn, "tracer", "tracer.SetSamplingRateLimit limit must be positive, now: %f", perSecond)
+		return
+	}
+	t.limiter = nil
+	if perSecond > 0 {
+		t.limiter = newRateLimiter(perSecond)
+	}
+	if rs, ok := t.sampler.(*rulesSampler); ok {
+		t.sampler = newRulesSampler(rs.rules, rs.fallback, t.limiter)
+	}
+}
+
+// globMatch reports whether subject matches the given glob pattern, in which
+// '*' matches any sequence of characters and '?' any single character. An
+// empty pattern matches everything.
+func globMatch(pattern, subject string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	var p, s int
+	star, next := -1, 0 // position of the last '*' in pattern and where to resume in subject
+	for s < len(subject) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == subject[s]):
+			p++
+			s++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, s
+			p++
+		case star >= 0:
+			// backtrack: let the last '*' match one more character
+			next++
+			p, s = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
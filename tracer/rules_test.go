@@ -0,0 +1,127 @@
+package tracer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tt := range []struct {
+		pattern, subject string
+		match            bool
+	}{
+		{"", "anything", true},
+		{"*", "", true},
+		{"web", "web", true},
+		{"web", "web-api", false},
+		{"web-*", "web-api", true},
+		{"web-*", "web-", true},
+		{"web-*", "worker", false},
+		{"GET /api/*", "GET /api/users/42", true},
+		{"GET /api/*", "POST /api/users", false},
+		{"*/users/*", "GET /api/users/42", true},
+		{"*-db", "users-db", true},
+		{"*-db", "users-db-replica", false},
+		{"web-?", "web-1", true},
+		{"web-?", "web-12", false},
+		{"a*b*c", "aXXbYYbc", true},
+		{"a*b*c", "aXXbYYbd", false},
+		{"**", "x", true},
+	} {
+		assert.Equal(tt.match, globMatch(tt.pattern, tt.subject), "%q %q", tt.pattern, tt.subject)
+	}
+}
+
+func TestSamplingRuleJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var rules []SamplingRule
+	assert.NoError(json.Unmarshal([]byte(`[{"service": "web-*"}, {"name": "db.*", "sample_rate": 0}]`), &rules))
+	assert.Equal([]SamplingRule{
+		{Service: "web-*", Rate: 1},
+		{Name: "db.*", Rate: 0},
+	}, rules)
+}
+
+func TestTracerSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0.5)
+	tracer.SetSamplingRules(
+		SamplingRule{Service: "web-*", Resource: "GET /health*", Rate: 0},
+		SamplingRule{Service: "web-*", Name: "grpc.*", Rate: 0},
+		SamplingRule{Service: "web-*", Rate: 1},
+	)
+
+	for i := 0; i < 100; i++ {
+		span := tracer.NewRootSpan("http.request", "web-api", "GET /api/users")
+		assert.True(span.Sampled)
+		assert.Equal(1.0, span.Metrics[rulesRateMetricKey])
+		assert.Equal("-3", span.GetMeta(samplingDecisionKey))
+
+		span = tracer.NewRootSpan("http.request", "web-api", "GET /healthz")
+		assert.False(span.Sampled)
+
+		span = tracer.NewRootSpan("grpc.server", "web-api", "Hello")
+		assert.False(span.Sampled)
+	}
+
+	// traces matching no rule use the sample rate
+	var sampled int
+	for i := 0; i < 1000; i++ {
+		span := tracer.NewRootSpan("sql.query", "users-db", "SELECT 1")
+		if span.Sampled {
+			sampled++
+			assert.Equal(0.5, span.Metrics[sampleRateMetricKey])
+			_, found := span.Metrics[rulesRateMetricKey]
+			assert.False(found)
+		}
+	}
+	assert.InDelta(500, sampled, 100)
+
+	// setting the sample rate keeps the rules
+	tracer.SetSampleRate(1)
+	cfg := tracer.Config()
+	assert.Equal("all", cfg.Sampler)
+	assert.Len(cfg.SamplingRules, 3)
+	assert.True(tracer.NewRootSpan("sql.query", "users-db", "SELECT 1").Sampled)
+	assert.False(tracer.NewRootSpan("http.request", "web-api", "GET /healthz").Sampled)
+
+	// rules can be removed
+	tracer.SetSamplingRules()
+	assert.Len(tracer.Config().SamplingRules, 0)
+	span := tracer.NewRootSpan("http.request", "web-api", "GET /healthz")
+	assert.True(span.Sampled)
+	assert.Equal("-0", span.GetMeta(samplingDecisionKey))
+
+	// invalid rules are ignored
+	tracer.SetSamplingRules(SamplingRule{Service: "web-*", Rate: 2})
+	assert.Len(tracer.Config().SamplingRules, 0)
+}
+
+func TestTracerSamplingRulesDefaultLimit(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	current := time.Unix(1500000000, 0)
+	tracer.SetClock(ClockFunc(func() time.Time { return current }))
+	tracer.SetSamplingRules(SamplingRule{Rate: 1})
+	assert.Equal(float64(defaultRateLimit), tracer.Config().RateLimit)
+
+	// the traces kept by the rules are limited without SetSamplingRateLimit
+	var sampled int
+	for i := 0; i < 2*defaultRateLimit; i++ {
+		if tracer.NewRootSpan("http.request", "web", "GET /").Sampled {
+			sampled++
+		}
+	}
+	assert.Equal(defaultRateLimit, sampled)
+}
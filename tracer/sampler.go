@@ -15,6 +15,43 @@ type sampler interface {
 	Sample(span *Span) // Tells if a trace is sampled and sets `span.Sampled`
 }
 
+// Sampler is implemented by custom samplers, see Tracer.SetSampler.
+type Sampler interface {
+	// Sample tells whether the trace starting with the given parameters
+	// has to be kept.
+	Sample(params SamplingParams) bool
+}
+
+// SamplingParams holds what is known about a trace when a Sampler decides
+// whether to keep it.
+type SamplingParams struct {
+	// Span is the root span of the trace in this process. Its name,
+	// service, resource, IDs and sampling priority, if any, are set.
+	Span *Span
+	// Remote is true when the trace was started in another process, from
+	// which its context was extracted.
+	Remote bool
+	// Baggage holds the baggage items received from the parent process.
+	Baggage map[string]string
+	// Tags holds the tags given when the span was started, e.g. the HTTP route.
+	Tags map[string]interface{}
+}
+
+// customSampler adapts a Sampler to the sampler interface.
+type customSampler struct {
+	Sampler
+}
+
+// Sample samples a span
+func (s customSampler) Sample(span *Span) {
+	s.sample(SamplingParams{Span: span})
+}
+
+// sample samples the root span of the given parameters.
+func (s customSampler) sample(params SamplingParams) {
+	params.Span.Sampled = s.Sampler.Sample(params)
+}
+
 // allSampler samples all the traces
 type allSampler struct{}
 
@@ -47,6 +84,22 @@ func (s *rateSampler) Sample(span *Span) {
 	}
 }
 
+// dropsAll reports whether the given sampler drops every trace.
+func dropsAll(s sampler) bool {
+	switch s := s.(type) {
+	case *rateSampler:
+		return s.SampleRate == 0
+	case *rulesSampler:
+		for _, r := range s.rules {
+			if r.Rate > 0 {
+				return false
+			}
+		}
+		return dropsAll(s.fallback)
+	}
+	return false
+}
+
 // sampleByRate tells if a trace (from its ID) with a given rate should be sampled.
 // Its implementation has to be the same as the Trace Agent.
 func sampleByRate(traceID uint64, sampleRate float64) bool {
@@ -0,0 +1,60 @@
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serviceSampler keeps the traces of a single service and records the
+// parameters it has been given.
+type serviceSampler struct {
+	service string
+	params  []SamplingParams
+}
+
+func (s *serviceSampler) Sample(params SamplingParams) bool {
+	s.params = append(s.params, params)
+	return params.Span.Service == s.service
+}
+
+func TestTracerSetSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	sampler := &serviceSampler{service: "web"}
+	tracer.SetSampler(sampler)
+	assert.Equal("custom", tracer.Config().Sampler)
+
+	span := tracer.NewRootSpan("http.request", "web", "GET /")
+	assert.True(span.Sampled)
+	assert.Len(sampler.params, 1)
+	assert.Equal(span, sampler.params[0].Span)
+	assert.False(sampler.params[0].Remote)
+
+	span = tracer.NewRootSpan("sql.query", "db", "SELECT 1")
+	assert.False(span.Sampled)
+
+	// children follow the decision made for the root
+	child := tracer.NewChildSpan("sql.query", span)
+	assert.False(child.Sampled)
+	assert.Len(sampler.params, 2)
+
+	// the parameters given when sampling again reach the sampler
+	span = tracer.NewRootSpan("http.request", "web", "GET /")
+	span.ParentID = 42
+	tracer.SampleWithParams(span, SamplingParams{
+		Remote:  true,
+		Baggage: map[string]string{"user": "bob"},
+	})
+	assert.True(span.Sampled)
+	params := sampler.params[len(sampler.params)-1]
+	assert.Equal(span, params.Span)
+	assert.True(params.Remote)
+	assert.Equal("bob", params.Baggage["user"])
+
+	tracer.SetSampler(nil)
+	assert.Equal("all", tracer.Config().Sampler)
+	assert.True(tracer.NewRootSpan("sql.query", "db", "SELECT 1").Sampled)
+}
@@ -0,0 +1,64 @@
+package tracer
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// raise delivers the given signal to the current process, so that it gets
+// its default behavior once the tracer is stopped.
+var raise = func(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		// signals can't be sent on every platform, exit as the signal would have
+		os.Exit(1)
+	}
+}
+
+// StopOnSignal installs handlers for the given signals, os.Interrupt and
+// SIGTERM by default, which stop the tracer, flushing the buffered traces for
+// at most the given timeout, before letting the signal terminate the process.
+// This way the traces of the last seconds of a process aren't lost when it is
+// shut down, e.g. during rollouts. It is meant for programs which don't handle
+// these signals themselves, and returns a function removing the handlers,
+// which can be called several times.
+func (t *Tracer) StopOnSignal(timeout time.Duration, sigs ...os.Signal) (cancel func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+
+	go func() {
+		select {
+		case sig := <-c:
+			signal.Stop(c)
+			if n := t.StopWithTimeout(timeout); n > 0 {
+				logf(logError, "tracer", "received %s, %d traces abandoned while stopping", sig, n)
+			}
+			raise(sig)
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
+
+// StopOnSignal installs handlers stopping the default tracer on the given
+// signals, see Tracer.StopOnSignal.
+func StopOnSignal(timeout time.Duration, sigs ...os.Signal) (cancel func()) {
+	return DefaultTracer.StopOnSignal(timeout, sigs...)
+}
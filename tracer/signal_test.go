@@ -0,0 +1,58 @@
+// +build !windows
+
+package tracer
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopOnSignal(t *testing.T) {
+	assert := assert.New(t)
+
+	raised := make(chan os.Signal, 1)
+	defer func(r func(os.Signal)) { raise = r }(raise)
+	raise = func(sig os.Signal) { raised <- sig }
+
+	tracer, transport := getTestTracer()
+	tracer.StopOnSignal(time.Second, syscall.SIGUSR1)
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	assert.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	select {
+	case sig := <-raised:
+		assert.Equal(syscall.SIGUSR1, sig)
+	case <-time.After(time.Second):
+		assert.Fail("signal not handled")
+	}
+	// the tracer was stopped, flushing its traces
+	assert.Len(transport.Traces(), 1)
+}
+
+func TestStopOnSignalCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	raised := make(chan os.Signal, 1)
+	defer func(r func(os.Signal)) { raise = r }(raise)
+	raise = func(sig os.Signal) { raised <- sig }
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	cancel := tracer.StopOnSignal(time.Second, syscall.SIGUSR2)
+	cancel()
+	cancel() // cancelling again is a no-op
+
+	// the handler is removed, ignore the signal not to be terminated
+	signal.Ignore(syscall.SIGUSR2)
+	assert.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	select {
+	case <-raised:
+		assert.Fail("signal handled after cancel")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,142 @@
+package tracer
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const (
+	// sketchRelativeAccuracy is the relative accuracy of the quantiles of
+	// the latency sketches, as expected by the agent.
+	sketchRelativeAccuracy = 0.01
+	// sketchMaxBins is the maximum number of bins of a latency sketch, the
+	// lowest ones are collapsed beyond it.
+	sketchMaxBins = 2048
+)
+
+var (
+	// sketchGamma is the ratio between the bounds of a bin of the sketches.
+	sketchGamma = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	// sketchMultiplier turns the logarithm of a value into its bin index.
+	sketchMultiplier = 1 / math.Log(sketchGamma)
+)
+
+// sketch is a DDSketch of the latencies of spans, in nanoseconds, with a
+// logarithmic mapping: its quantiles are accurate to 1%. It is sent to the
+// agent in the statistics payloads, encoded as the protobuf message of the
+// DDSketch library.
+type sketch struct {
+	bins      map[int]float64 // bin index -> count
+	zeroCount float64
+}
+
+// add adds a value to the sketch.
+func (s *sketch) add(v float64) {
+	if v <= 0 {
+		s.zeroCount++
+		return
+	}
+	if s.bins == nil {
+		s.bins = make(map[int]float64)
+	}
+	s.bins[sketchIndex(v)]++
+}
+
+// sketchIndex returns the index of the bin of the given positive value.
+func sketchIndex(v float64) int {
+	return int(math.Floor(math.Log(v) * sketchMultiplier))
+}
+
+// sketchValue returns the value of the bin of the given index: the values of
+// the bin are within the relative accuracy of it.
+func sketchValue(index int) float64 {
+	return math.Exp(float64(index)/sketchMultiplier) * (1 + sketchRelativeAccuracy)
+}
+
+// encode returns the sketch as a protobuf DDSketch message, or nil if it is
+// empty. Its positive values are stored contiguously from the lowest bin
+// index, at most sketchMaxBins of them.
+func (s *sketch) encode() []byte {
+	if len(s.bins) == 0 && s.zeroCount == 0 {
+		return nil
+	}
+	var counts []float64
+	var offset int
+	if len(s.bins) > 0 {
+		lo, hi := math.MaxInt32, math.MinInt32
+		for i := range s.bins {
+			if i < lo {
+				lo = i
+			}
+			if i > hi {
+				hi = i
+			}
+		}
+		if hi-lo >= sketchMaxBins {
+			lo = hi - sketchMaxBins + 1
+		}
+		offset = lo
+		counts = make([]float64, hi-lo+1)
+		for i, n := range s.bins {
+			if i < lo {
+				// collapsed into the lowest bin
+				i = lo
+			}
+			counts[i-lo] += n
+		}
+	}
+
+	// IndexMapping{gamma: 1}
+	var mapping []byte
+	mapping = appendProtoDouble(mapping, 1, sketchGamma)
+	// Store{contiguousBinCounts: 2, contiguousBinIndexOffset: 3}
+	var store []byte
+	if len(counts) > 0 {
+		packed := make([]byte, 0, 8*len(counts))
+		for _, n := range counts {
+			packed = appendFloat64(packed, n)
+		}
+		store = appendProtoBytes(store, 2, packed)
+	}
+	if offset != 0 {
+		store = appendProtoVarint(store, 3, 0, zigzag(offset))
+	}
+	// DDSketch{mapping: 1, positiveValues: 2, negativeValues: 3, zeroCount: 4}
+	var b []byte
+	b = appendProtoBytes(b, 1, mapping)
+	b = appendProtoBytes(b, 2, store)
+	b = appendProtoBytes(b, 3, nil)
+	if s.zeroCount != 0 {
+		b = appendProtoDouble(b, 4, s.zeroCount)
+	}
+	return b
+}
+
+// zigzag encodes a sint32 value of a protobuf message.
+func zigzag(v int) uint64 {
+	return uint64(uint32((int32(v) << 1) ^ (int32(v) >> 31)))
+}
+
+// appendProtoVarint appends a protobuf field of the given wire type, 0 for
+// varints and 2 for length-delimited ones, followed by the varint v.
+func appendProtoVarint(b []byte, field, wireType int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireType))
+	return binary.AppendUvarint(b, v)
+}
+
+// appendProtoBytes appends a length-delimited protobuf field.
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendProtoVarint(b, field, 2, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendProtoDouble appends a double protobuf field.
+func appendProtoDouble(b []byte, field int, v float64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|1))
+	return appendFloat64(b, v)
+}
+
+// appendFloat64 appends v in little-endian order.
+func appendFloat64(b []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
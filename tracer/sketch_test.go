@@ -0,0 +1,74 @@
+package tracer
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, v := range []float64{1, 2, 999, 1e3, float64(time.Millisecond), float64(time.Hour)} {
+		assert.InDelta(v, sketchValue(sketchIndex(v)), v*sketchRelativeAccuracy*1.000001, v)
+	}
+	assert.True(sketchIndex(1e3) < sketchIndex(1e6))
+}
+
+func TestSketchEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	var s sketch
+	assert.Nil(s.encode())
+
+	s.add(0)
+	s.add(1e6)
+	s.add(1e6)
+	s.add(2e6)
+	b := s.encode()
+
+	// mapping
+	assert.Equal([]byte{0x0a, 9, 0x09}, b[:3])
+	assert.Equal(sketchGamma, math.Float64frombits(binary.LittleEndian.Uint64(b[3:11])))
+	b = b[11:]
+	// positive values, from the bin of 1e6 to the one of 2e6
+	lo, hi := sketchIndex(1e6), sketchIndex(2e6)
+	n := hi - lo + 1
+	assert.Equal(byte(0x12), b[0])
+	m, k := binary.Uvarint(b[1:])
+	store, b := b[1+k:1+k+int(m)], b[1+k+int(m):]
+	assert.Equal(byte(0x12), store[0])
+	size, k := binary.Uvarint(store[1:])
+	assert.Equal(uint64(8*n), size)
+	counts := store[1+k : 1+k+int(size)]
+	assert.Equal(2.0, math.Float64frombits(binary.LittleEndian.Uint64(counts)))
+	assert.Equal(1.0, math.Float64frombits(binary.LittleEndian.Uint64(counts[8*(n-1):])))
+	offset, _ := binary.Uvarint(store[2+k+int(size):])
+	assert.Equal(byte(0x18), store[1+k+int(size)])
+	assert.Equal(zigzag(lo), offset)
+	// no negative values, and the zero count
+	assert.Equal([]byte{0x1a, 0, 0x21}, b[:3])
+	assert.Equal(1.0, math.Float64frombits(binary.LittleEndian.Uint64(b[3:])))
+
+	assert.Equal(uint64(1), zigzag(-1))
+	assert.Equal(uint64(2), zigzag(1))
+}
+
+func TestSketchMaxBins(t *testing.T) {
+	assert := assert.New(t)
+
+	var s sketch
+	s.add(1e-10)
+	s.add(float64(100 * time.Hour))
+	b := s.encode()
+	assert.Equal(byte(0x12), b[11])
+	n, k := binary.Uvarint(b[12:])
+	store := b[12+k : 12+k+int(n)]
+	size, k := binary.Uvarint(store[1:])
+	assert.Equal(uint64(8*sketchMaxBins), size)
+	// the lowest value is collapsed into the lowest bin
+	assert.Equal(1.0, math.Float64frombits(binary.LittleEndian.Uint64(store[1+k:])))
+}
@@ -0,0 +1,141 @@
+package tracer
+
+import (
+	"sync"
+	"time"
+)
+
+// partialSnapshotKey is the metric marking the copies of unfinished spans
+// sent by partial snapshots. Their duration is the time elapsed so far, and
+// each of them has its own ID, its parent being the span it is a copy of,
+// which is sent once it is finished.
+const partialSnapshotKey = "_dd.partial_snapshot"
+
+// snapshotter keeps track of the traces being recorded, to send snapshots of
+// their unfinished spans when they have been open for too long.
+type snapshotter struct {
+	threshold time.Duration
+
+	mu     sync.Mutex
+	traces map[*spanBuffer]time.Time // buffer -> time of its last snapshot
+}
+
+func newSnapshotter(threshold time.Duration) *snapshotter {
+	return &snapshotter{
+		threshold: threshold,
+		traces:    make(map[*spanBuffer]time.Time),
+	}
+}
+
+// track starts tracking the trace held by the given buffer, which started at
+// the given time.
+func (s *snapshotter) track(buffer *spanBuffer, start time.Time) {
+	s.mu.Lock()
+	s.traces[buffer] = start
+	s.mu.Unlock()
+}
+
+// snapshot returns copies of the unfinished spans of the traces which have
+// been open for longer than the threshold since they started or since their
+// last snapshot. The traces which were flushed are forgotten.
+func (s *snapshotter) snapshot(now time.Time) [][]*Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snapshots [][]*Span
+	for buffer, last := range s.traces {
+		buffer.RLock()
+		spans := buffer.spans
+		if len(spans) > 0 {
+			spans = append([]*Span(nil), spans...)
+		}
+		keepOnError := buffer.keepOnError
+		buffer.RUnlock()
+
+		if len(spans) == 0 {
+			delete(s.traces, buffer)
+			continue
+		}
+		if keepOnError || now.Sub(last) < s.threshold {
+			// wait until the trace is known to be kept, or long-running
+			continue
+		}
+		var trace []*Span
+		for _, span := range spans {
+			if snap := span.snapshot(now); snap != nil {
+				trace = append(trace, snap)
+			}
+		}
+		if len(trace) > 0 {
+			snapshots = append(snapshots, trace)
+		}
+		s.traces[buffer] = now
+	}
+	return snapshots
+}
+
+// snapshot returns a copy of the span as it would be sent if it finished at
+// the given time, as a child of the span, or nil if it is already finished.
+func (s *Span) snapshot(now time.Time) *Span {
+	s.RLock()
+	defer s.RUnlock()
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
+	if s.finished {
+		return nil
+	}
+
+	snap := &Span{
+		Name:     s.Name,
+		Service:  s.Service,
+		Resource: s.Resource,
+		Type:     s.Type,
+		Start:    s.Start,
+		Duration: s.elapsed(now),
+		Meta:     make(map[string]string, len(s.Meta)),
+		Metrics:  make(map[string]float64, len(s.Metrics)+1),
+		SpanID:   NextSpanID(),
+		TraceID:  s.TraceID,
+		ParentID: s.SpanID,
+		Error:    s.Error,
+		Sampled:  s.Sampled,
+		tracer:   s.tracer,
+		finished: true,
+	}
+	for k, v := range s.Meta {
+		snap.Meta[k] = v
+	}
+	for k, v := range s.Metrics {
+		snap.Metrics[k] = v
+	}
+	snap.Metrics[partialSnapshotKey] = 1
+	return snap
+}
+
+// SetPartialSnapshots makes the tracer send, every given threshold, snapshots
+// of the spans of the traces which have been open for longer than it, so that
+// long-running operations, such as big batch jobs, are visible before they
+// finish. Snapshots are copies of the unfinished spans, with the time elapsed
+// so far as their duration and the "_dd.partial_snapshot" metric set, sent as
+// children of the spans, so that the spans themselves are sent once, when
+// they finish. They are taken by the flushes, so the threshold is rounded up to the flush
+// interval. It applies to the traces started afterwards and a zero threshold
+// disables it. It must be called before the tracer is used.
+func (t *Tracer) SetPartialSnapshots(threshold time.Duration) {
+	if threshold <= 0 {
+		t.snapshots = nil
+		return
+	}
+	t.snapshots = newSnapshotter(threshold)
+}
+
+// pushSnapshots pushes the snapshots of the long-running traces, if enabled.
+func (t *Tracer) pushSnapshots() {
+	s := t.snapshots
+	if s == nil || !t.Enabled() {
+		return
+	}
+	for _, trace := range s.snapshot(t.clockNow()) {
+		t.channels.pushTrace(trace)
+	}
+}
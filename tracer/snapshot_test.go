@@ -0,0 +1,88 @@
+package tracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialSnapshots(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	tracer.SetPartialSnapshots(time.Minute)
+
+	root := tracer.NewRootSpan("batch.run", "batch", "nightly")
+	child := tracer.NewChildSpan("batch.step", root)
+	done := tracer.NewChildSpan("batch.setup", root)
+	done.Finish()
+
+	// too early
+	clock.advance(30 * time.Second)
+	tracer.pushSnapshots()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	clock.advance(30 * time.Second)
+	tracer.pushSnapshots()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	for _, snap := range traces[0] {
+		assert.Equal(int64(time.Minute), snap.Duration)
+		assert.Equal(1., snap.Metrics[partialSnapshotKey])
+		assert.NotEqual(done.SpanID, snap.ParentID)
+	}
+	// the snapshots are children of the spans, with their own IDs
+	assert.Equal(root.SpanID, traces[0][0].ParentID)
+	assert.Equal(child.SpanID, traces[0][1].ParentID)
+	assert.NotEqual(root.SpanID, traces[0][0].SpanID)
+	assert.NotEqual(child.SpanID, traces[0][1].SpanID)
+	assert.Equal(root.TraceID, traces[0][0].TraceID)
+	assert.Equal(int64(0), root.Duration)
+	assert.NotContains(root.Metrics, partialSnapshotKey)
+	snapID := traces[0][0].SpanID
+
+	// one snapshot per threshold
+	tracer.pushSnapshots()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+
+	clock.advance(time.Minute)
+	child.Finish()
+	tracer.pushSnapshots()
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal(int64(2*time.Minute), traces[0][0].Duration)
+	assert.Equal(root.SpanID, traces[0][0].ParentID)
+	assert.NotEqual(traces[0][0].SpanID, snapID)
+
+	// the finished trace is sent as usual, and forgotten
+	root.Finish()
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	assert.NotContains(traces[0][0].Metrics, partialSnapshotKey)
+	clock.advance(time.Minute)
+	tracer.pushSnapshots()
+	tracer.snapshots.mu.Lock()
+	assert.Len(tracer.snapshots.traces, 0)
+	tracer.snapshots.mu.Unlock()
+
+	// disabled
+	tracer.SetPartialSnapshots(0)
+	span := tracer.NewRootSpan("batch.run", "batch", "nightly")
+	clock.advance(time.Hour)
+	tracer.pushSnapshots()
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+	span.Finish()
+}
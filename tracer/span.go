@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"runtime/debug"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
 )
 
 const (
@@ -16,6 +19,23 @@ const (
 	errorStackKey = "error.stack"
 
 	samplingPriorityKey = "_sampling_priority_v1"
+
+	// samplingDecisionKey is the tag of the root span holding the mechanism
+	// which made the sampling decision, see ext.SamplingMechanism*.
+	samplingDecisionKey = "_dd.p.dm"
+
+	// propagatedTagPrefix is the prefix of the tags of the root span which
+	// are propagated to the downstream services, see Span.PropagatedTags.
+	propagatedTagPrefix = "_dd.p."
+
+	// topLevelKey is the metric marking the spans which are the entry point
+	// of their service in a trace, from which the statistics of the service
+	// are computed.
+	topLevelKey = "_dd.top_level"
+
+	// traceErrorCountKey is the metric of the local root span holding the
+	// number of spans of the trace which have an error.
+	traceErrorCountKey = "trace.error_count"
 )
 
 // Span represents a computation. Callers must call Finish when a span is
@@ -61,33 +81,90 @@ type Span struct {
 
 	sync.RWMutex
 	tracer   *Tracer // the tracer that generated this span
-	finished bool    // true if the span has been submitted to a tracer.
+	finished bool    // true if the span has been submitted to a tracer; guarded by both locks.
+
+	// tagsMu guards Meta, Metrics and Error so that tagging a span doesn't
+	// contend with the span lock taken to create children or to finish it.
+	// When both are needed, the span lock is acquired first.
+	tagsMu sync.RWMutex
+
+	// lightweight is true when the span belongs to a trace which was dropped
+	// by the sampler at creation time. Such spans skip meta, metrics and
+	// buffering altogether since they will never be sent to the agent.
+	lightweight bool
+
+	// baggage holds the baggage items of the span, see SetBaggageItem. The
+	// map is shared with the parent and the children of the span, and is
+	// copied rather than changed; guarded by the span lock.
+	baggage map[string]string
+
+	// events holds the events added with AddEvent, guarded by tagsMu.
+	events []SpanEvent
+
+	// traceIDHigh holds the 64 upper bits of the 128-bit trace ID of the
+	// trace on its local root, see TraceIDHigh.
+	traceIDHigh uint64
+
+	// integration is the name of the integration which produced the span,
+	// if any. It is only used to aggregate per-integration statistics.
+	integration string
+
+	// clockStart is the reading of the clock of the tracer when the span
+	// started, which its duration is measured from unless Start is changed
+	// from wallStart, the start time it was given at creation.
+	clockStart time.Time
+	wallStart  int64
+
+	// goroutine is the id of the goroutine which created the span, only
+	// recorded when goroutine checks are enabled.
+	goroutine uint64
+	// detachedBy is the id of the goroutine which detached the span, until
+	// it is attached by another one, only recorded when the handoff checks
+	// are enabled. It is accessed atomically.
+	detachedBy uint64
 
 	// parent contains a link to the parent. In most cases, ParentID can be inferred from this.
 	// However, ParentID can technically be overridden (typical usage: distributed tracing)
 	// and also, parent == nil is used to identify root and top-level ("local root") spans.
 	parent *Span
 	buffer *spanBuffer
+	// acked is true once the span is finished and acknowledged by its
+	// buffer; guarded by the lock of the buffer.
+	acked bool
 }
 
 // NewSpan creates a new span. This is a low-level function, required for testing and advanced usage.
 // Most of the time one should prefer the Tracer NewRootSpan or NewChildSpan methods.
 func NewSpan(name, service, resource string, spanID, traceID, parentID uint64, tracer *Tracer) *Span {
-	return &Span{
-		Name:     name,
-		Service:  service,
-		Resource: resource,
-		Meta:     tracer.getAllMeta(),
-		SpanID:   spanID,
-		TraceID:  traceID,
-		ParentID: parentID,
-		Start:    now(),
-		Sampled:  true,
-		tracer:   tracer,
+	span := newSpan(name, service, resource, spanID, traceID, parentID, tracer)
+	span.Meta = tracer.getAllMeta()
+	return span
+}
+
+// newSpan creates a new span without any meta. It is used by the tracer which
+// only applies its meta when the span is actually going to be recorded.
+func newSpan(name, service, resource string, spanID, traceID, parentID uint64, tracer *Tracer) *Span {
+	start, clockStart := tracer.startTime()
+	span := &Span{
+		Name:       name,
+		Service:    service,
+		Resource:   resource,
+		SpanID:     spanID,
+		TraceID:    traceID,
+		ParentID:   parentID,
+		Start:      start,
+		Sampled:    true,
+		tracer:     tracer,
+		clockStart: clockStart,
+		wallStart:  start,
+	}
+	if tracer != nil && tracer.goroutineChecksEnabled() {
+		span.goroutine = goroutineID()
 	}
+	return span
 }
 
-// setMeta adds an arbitrary meta field to the current Span. The span
+// setMeta adds an arbitrary meta field to the current Span. The span tags
 // must be locked outside of this function
 func (s *Span) setMeta(key, value string) {
 	if s == nil {
@@ -97,7 +174,7 @@ func (s *Span) setMeta(key, value string) {
 	// We don't lock spans when flushing, so we could have a data race when
 	// modifying a span as it's being flushed. This protects us against that
 	// race, since spans are marked `finished` before we flush them.
-	if s.finished {
+	if s.finished || s.lightweight {
 		return
 	}
 
@@ -110,14 +187,29 @@ func (s *Span) setMeta(key, value string) {
 
 // SetMeta adds an arbitrary meta field to the current Span.
 // If the Span has been finished, it will not be modified by the method.
+// Setting the ext.ManualKeep or ext.ManualDrop tag keeps or drops the trace
+// of the span instead, see KeepTrace and DropTrace. New keys are dropped
+// once the span has too many meta, see Tracer.SetMaxSpanTags.
 func (s *Span) SetMeta(key, value string) {
 	if s == nil {
 		return
 	}
+	switch key {
+	case ext.ManualKeep:
+		KeepTrace(s)
+		return
+	case ext.ManualDrop:
+		DropTrace(s)
+		return
+	}
 
-	s.Lock()
-	defer s.Unlock()
+	s.checkDetached("tagged")
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
 
+	if !s.allowMeta(key) {
+		return
+	}
 	s.setMeta(key, value)
 
 }
@@ -136,14 +228,37 @@ func (s *Span) GetMeta(key string) string {
 	if s == nil {
 		return ""
 	}
-	s.RLock()
-	defer s.RUnlock()
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
 	if s.Meta == nil {
 		return ""
 	}
 	return s.Meta[key]
 }
 
+// PropagatedTags returns the trace-level tags of the trace of the span, which
+// are the meta of its local root prefixed with "_dd.p.", such as the sampling
+// decision maker. They are meant to be propagated to the downstream services.
+func (s *Span) PropagatedTags() map[string]string {
+	if s == nil {
+		return nil
+	}
+	root := s.localRoot()
+	root.tagsMu.RLock()
+	defer root.tagsMu.RUnlock()
+	var tags map[string]string
+	for k, v := range root.Meta {
+		if !strings.HasPrefix(k, propagatedTagPrefix) {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[k] = v
+	}
+	return tags
+}
+
 // SetMetrics adds a metric field to the current Span.
 // DEPRECATED: Use SetMetric
 func (s *Span) SetMetrics(key string, value float64) {
@@ -161,13 +276,14 @@ func (s *Span) SetMetric(key string, val float64) {
 		return
 	}
 
-	s.Lock()
-	defer s.Unlock()
+	s.checkDetached("tagged")
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
 
 	// We don't lock spans when flushing, so we could have a data race when
 	// modifying a span as it's being flushed. This protects us against that
 	// race, since spans are marked `finished` before we flush them.
-	if s.finished {
+	if s.finished || s.lightweight {
 		return
 	}
 
@@ -177,6 +293,30 @@ func (s *Span) SetMetric(key string, val float64) {
 	s.Metrics[key] = val
 }
 
+// AddMetric adds delta to the metric with the given key, which starts from 0,
+// such as a number of bytes processed or of retries accumulated over the life
+// of the span. Unlike reading the metric and setting it again, it is safe to
+// call from several goroutines at once. If the Span has been finished, it
+// will not be modified by this method.
+func (s *Span) AddMetric(key string, delta float64) {
+	if s == nil {
+		return
+	}
+
+	s.checkDetached("tagged")
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+
+	if s.finished || s.lightweight {
+		return
+	}
+
+	if s.Metrics == nil {
+		s.Metrics = make(map[string]float64)
+	}
+	s.Metrics[key] += delta
+}
+
 // SetError stores an error object within the span meta. The Error status is
 // updated and the error.Error() string is included with a default meta key.
 // If the Span has been finished, it will not be modified by this method.
@@ -185,8 +325,9 @@ func (s *Span) SetError(err error) {
 		return
 	}
 
-	s.Lock()
-	defer s.Unlock()
+	s.checkDetached("tagged")
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
 	// We don't lock spans when flushing, so we could have a data race when
 	// modifying a span as it's being flushed. This protects us against that
 	// race, since spans are marked `finished` before we flush them.
@@ -194,11 +335,16 @@ func (s *Span) SetError(err error) {
 		return
 	}
 	s.Error = 1
+	if s.lightweight {
+		// the trace is dropped, don't bother collecting the stack
+		return
+	}
 
 	s.setMeta(errorMsgKey, err.Error())
 	s.setMeta(errorTypeKey, reflect.TypeOf(err).String())
-	stack := debug.Stack()
-	s.setMeta(errorStackKey, string(stack))
+	if stack := errorStack(); stack != "" {
+		s.setMeta(errorStackKey, stack)
+	}
 }
 
 // Finish closes this Span (but not its children) providing the duration
@@ -207,32 +353,98 @@ func (s *Span) SetError(err error) {
 // current Span. Once a Span has been finished, methods that modify the Span
 // will become no-ops.
 func (s *Span) Finish() {
-	s.finish(now())
+	if s == nil {
+		return
+	}
+	s.finish(s.elapsed(s.tracer.clockNow()))
+}
+
+// elapsed returns the time elapsed between the start of the span and the
+// given reading of the clock of its tracer, in nanoseconds.
+func (s *Span) elapsed(now time.Time) int64 {
+	if !s.clockStart.IsZero() && s.Start == s.wallStart {
+		// measure the duration with the clock, which may be monotonic
+		return int64(now.Sub(s.clockStart))
+	}
+	// the start time was changed, the clock readings can't be compared
+	return now.UnixNano() - s.Start
 }
 
 // FinishWithTime closes this Span at the given `finishTime`. The
 // behavior is the same as `Finish()`.
 func (s *Span) FinishWithTime(finishTime int64) {
-	s.finish(finishTime)
+	if s == nil {
+		return
+	}
+	s.finish(finishTime - s.Start)
+}
+
+// SetIntegration records the name of the integration which produced the
+// span, e.g. "net/http" or "database/sql", so that the tracer can report
+// span and error counts per integration. The name is also set as the
+// ext.Component meta, so that traces can be filtered by integration. It has
+// to be called before the span is finished.
+func (s *Span) SetIntegration(name string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	s.integration = name
+	s.Unlock()
+	if name != "" {
+		s.SetMeta(ext.Component, name)
+	}
 }
 
-func (s *Span) finish(finishTime int64) {
+// finish closes the span, which lasted the given duration, in nanoseconds.
+func (s *Span) finish(duration int64) {
 	if s == nil {
 		return
 	}
 
 	s.Lock()
+	s.tagsMu.Lock()
 	finished := s.finished
 	if !finished {
 		if s.Duration == 0 {
-			s.Duration = finishTime - s.Start
+			s.Duration = duration
 		}
+		s.setEventsMeta()
 		s.finished = true
 	}
+	isError, integration := s.Error != 0, s.integration
+	goroutine := s.goroutine
+	s.tagsMu.Unlock()
 	s.Unlock()
 
 	if finished {
 		// no-op, called twice, no state change...
+		if s.tracer != nil && s.tracer.DebugLoggingEnabled() {
+			logf(logWarn, "tracer", "span %q (id: %d) finished twice, second Finish called from %s", s.Name, s.SpanID, finishCaller())
+		}
+		return
+	}
+	if goroutine != 0 {
+		s.checkGoroutine(goroutine)
+	}
+	s.checkDetached("finished")
+
+	if integration != "" && s.tracer != nil {
+		s.tracer.integrations.count(integration, isError)
+	}
+
+	if s.tracer != nil && s.tracer.stats.isActive() {
+		// computed from all the spans, whatever the sampling decision
+		s.tracer.stats.add(s)
+	}
+
+	if s.lightweight {
+		// the trace was dropped at creation, there's nothing to submit but
+		// the span itself if a span sampling rule keeps it, or its
+		// statistics otherwise
+		if !s.sampleSingleSpan() && s.tracer != nil {
+			s.tracer.stats.addDropped(s)
+		}
 		return
 	}
 
@@ -248,13 +460,12 @@ func (s *Span) finish(finishTime int64) {
 		return
 	}
 
-	// If not sampled, drop it
-	if !s.Sampled {
-		return
+	// the trace is kept or dropped when it is flushed, following its root
+	// put data in channel only if trace is completely finished
+	if s.buffer.AckFinish(s) && s.tracer != nil {
+		s.tracer.incomplete.add(s.buffer, s.tracer.clockNow())
 	}
 
-	s.buffer.AckFinish() // put data in channel only if trace is completely finished
-
 	// It's important that when Finish() exits, the data is put in
 	// the channel for real, when the trace is finished.
 	// Otherwise, tests could become flaky (because you never know in what state
@@ -288,12 +499,12 @@ func (s *Span) String() string {
 		"Tags:",
 	}
 
-	s.RLock()
+	s.tagsMu.RLock()
 	for key, val := range s.Meta {
 		lines = append(lines, fmt.Sprintf("\t%s:%s", key, val))
 
 	}
-	s.RUnlock()
+	s.tagsMu.RUnlock()
 
 	return strings.Join(lines, "\n")
 }
@@ -315,20 +526,33 @@ func (s *Span) Tracer() *Tracer {
 	return s.tracer
 }
 
-// SetSamplingPriority sets the sampling priority.
+// SetSamplingPriority sets the sampling priority. When the priority is set
+// by the user, it records that the sampling decision was made manually.
 func (s *Span) SetSamplingPriority(priority int) {
 	s.SetMetric(samplingPriorityKey, float64(priority))
+	if priority == ext.PriorityUserKeep || priority == ext.PriorityUserReject {
+		s.setSamplingMechanism(ext.SamplingMechanismManual)
+	}
+}
+
+// setSamplingMechanism records the mechanism which made the sampling decision.
+func (s *Span) setSamplingMechanism(mechanism int) {
+	s.SetMeta(samplingDecisionKey, "-"+strconv.Itoa(mechanism))
 }
 
 // HasSamplingPriority returns true if sampling priority is set.
 // It can be defined to either zero or non-zero.
 func (s *Span) HasSamplingPriority() bool {
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
 	_, hasSamplingPriority := s.Metrics[samplingPriorityKey]
 	return hasSamplingPriority
 }
 
 // GetSamplingPriority gets the sampling priority.
 func (s *Span) GetSamplingPriority() int {
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
 	return int(s.Metrics[samplingPriorityKey])
 }
 
@@ -336,3 +560,22 @@ func (s *Span) GetSamplingPriority() int {
 func NextSpanID() uint64 {
 	return uint64(randGen.Int63())
 }
+
+// finishCaller returns the location of the code which called Finish, skipping
+// the Span methods in between.
+func finishCaller() string {
+	pc := make([]uintptr, 16)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "dd-trace-go/tracer.(*Span).") &&
+			!strings.Contains(frame.Function, "dd-trace-go/tracer.(*DetachedSpan).") &&
+			!strings.Contains(frame.Function, "dd-trace-go/opentracing.(*Span).") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package tracer
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -57,6 +62,7 @@ func TestSpanSetMetas(t *testing.T) {
 		"error.type":  "*errors.errorString",
 		"status.code": "200",
 		"system.pid":  "29176",
+		"_dd.p.dm":    "-0",
 	}
 	extraMetas := map[string]string{
 		"custom.1": "something custom",
@@ -93,6 +99,22 @@ func TestSpanSetMetas(t *testing.T) {
 
 }
 
+func TestSpanPropagatedTags(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	root.SetMeta("_dd.p.team", "intake")
+	root.SetMeta("local", "not propagated")
+	child := tracer.NewChildSpan("redis.command", root)
+	child.SetMeta("_dd.p.child", "not on the root")
+
+	tags := child.PropagatedTags()
+	assert.Equal("intake", tags["_dd.p.team"])
+	assert.Equal(root.GetMeta(samplingDecisionKey), tags[samplingDecisionKey])
+	assert.NotContains(tags, "local")
+	assert.NotContains(tags, "_dd.p.child")
+}
+
 func TestSpanSetMetric(t *testing.T) {
 	assert := assert.New(t)
 	tracer := NewTracer()
@@ -110,6 +132,28 @@ func TestSpanSetMetric(t *testing.T) {
 	assert.Equal(0.0, span.Metrics["finished.test"])
 }
 
+func TestSpanAddMetric(t *testing.T) {
+	assert := assert.New(t)
+	tracer := NewTracer()
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			span.AddMetric("bytes", 512)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(5120.0, span.Metrics["bytes"])
+
+	// operating on a finished span is a no-op
+	span.Finish()
+	span.AddMetric("bytes", 1)
+	assert.Equal(5120.0, span.Metrics["bytes"])
+}
+
 func TestSpanError(t *testing.T) {
 	assert := assert.New(t)
 	tracer := NewTracer()
@@ -209,6 +253,36 @@ func TestSpanFinishTwice(t *testing.T) {
 	assert.Len(tracer.channels.trace, 1)
 }
 
+func TestSpanFinishTwiceDebug(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+	span.Finish()
+	assert.Equal(0, buf.Len())
+
+	tracer.SetDebugLogging(true)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.Finish()
+	span.FinishWithErr(errors.New("late"))
+	assert.Contains(buf.String(), "span \"pylons.request\" (id: "+strconv.FormatUint(span.SpanID, 10)+") finished twice")
+	assert.Contains(buf.String(), "TestSpanFinishTwiceDebug")
+	assert.Contains(buf.String(), "span_test.go:")
+	tracer.SetDebugLogging(false)
+
+	// the span wasn't modified by the second call
+	assert.Equal(int32(0), span.Error)
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 2)
+}
+
 func TestSpanContext(t *testing.T) {
 	ctx := context.Background()
 	_, ok := SpanFromContext(ctx)
@@ -287,3 +361,56 @@ func TestSpanSamplingPriority(t *testing.T) {
 type boomError struct{}
 
 func (e *boomError) Error() string { return "boom" }
+
+func TestSpanConcurrentTagging(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	// tagging, creating children and reading the span don't step on
+	// each other's toes
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			root.SetMeta("key."+strconv.Itoa(i), "value")
+			root.SetMetric("metric."+strconv.Itoa(i), float64(i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			tracer.NewChildSpan("redis.command", root).Finish()
+		}()
+		go func() {
+			defer wg.Done()
+			root.GetMeta("key.0")
+			root.HasSamplingPriority()
+		}()
+	}
+	wg.Wait()
+	root.Finish()
+	root.SetMeta("finished.test", "true")
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 11)
+	assert.Equal("value", root.GetMeta("key.9"))
+	assert.Equal("", root.GetMeta("finished.test"))
+}
+
+func BenchmarkSpanSetMetaParallel(b *testing.B) {
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			root.SetMeta("key", "value")
+			tracer.NewChildSpan("redis.command", root)
+		}
+	})
+}
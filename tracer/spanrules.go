@@ -0,0 +1,138 @@
+package tracer
+
+import (
+	"encoding/json"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+const (
+	// spanSamplingMechanismKey is the metric key marking the spans kept by
+	// a span sampling rule, see ext.SamplingMechanismSingleSpan.
+	spanSamplingMechanismKey = "_dd.span_sampling.mechanism"
+	// spanSamplingRuleRateKey is the metric key holding the sample rate of
+	// the span sampling rule which kept a span.
+	spanSamplingRuleRateKey = "_dd.span_sampling.rule_rate"
+	// spanSamplingMaxPerSecondKey is the metric key holding the limit of the
+	// span sampling rule which kept a span, when it has one.
+	spanSamplingMaxPerSecondKey = "_dd.span_sampling.max_per_second"
+)
+
+// SpanSamplingRule keeps the spans matching it even when their trace is
+// dropped, so that the metrics computed from them by the backend, such as
+// the ones of grpc.server spans, stay accurate. Service and Name are glob
+// patterns, as in SamplingRule. Its JSON form is the one of
+// DD_SPAN_SAMPLING_RULES, in which the rate defaults to 1, e.g.
+//
+//	[{"service": "web-*", "name": "grpc.server", "max_per_second": 50}]
+type SpanSamplingRule struct {
+	Service string  `json:"service,omitempty"`
+	Name    string  `json:"name,omitempty"`
+	Rate    float64 `json:"sample_rate"`
+	// MaxPerSecond is the maximum number of spans kept per second by the
+	// rule, 0 meaning no limit.
+	MaxPerSecond float64 `json:"max_per_second,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, defaulting the rate to 1.
+func (r *SpanSamplingRule) UnmarshalJSON(b []byte) error {
+	type rule SpanSamplingRule // without this method
+	v := rule{Rate: 1}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = SpanSamplingRule(v)
+	return nil
+}
+
+// spanSampler keeps the spans of dropped traces matching its rules.
+type spanSampler struct {
+	rules    []SpanSamplingRule
+	limiters []*rateLimiter // limiters of the rules, nil when unlimited
+}
+
+// match returns the first rule matching the span, nil if none does or if
+// the span is dropped by the rate or the limit of that rule.
+func (ss *spanSampler) match(span *Span) *SpanSamplingRule {
+	for i := range ss.rules {
+		r := &ss.rules[i]
+		if !globMatch(r.Service, span.Service) || !globMatch(r.Name, span.Name) {
+			continue
+		}
+		if !sampleByRate(span.SpanID, r.Rate) {
+			return nil
+		}
+		if l := ss.limiters[i]; l != nil {
+			if allowed, _ := l.allow(span.tracer.clockNow()); !allowed {
+				return nil
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// SetSpanSamplingRules sets the rules keeping the spans of the future dropped
+// traces depending on their service and name. Rules are evaluated in order
+// and the first one matching a span is applied: the span is sent on its own,
+// marked with the span sampling metrics. Calling it without rules removes
+// them.
+func (t *Tracer) SetSpanSamplingRules(rules ...SpanSamplingRule) {
+	for _, r := range rules {
+		if r.Rate < 0 || r.Rate > 1 || r.MaxPerSecond < 0 {
+			logf(logWarn, "tracer", "tracer.SetSpanSamplingRules rate must be between 0 and 1 and limit positive, now: %f, %f", r.Rate, r.MaxPerSecond)
+			return
+		}
+	}
+	if len(rules) == 0 {
+		t.spanSampler.Store((*spanSampler)(nil))
+		return
+	}
+	ss := &spanSampler{
+		rules:    append([]SpanSamplingRule(nil), rules...),
+		limiters: make([]*rateLimiter, len(rules)),
+	}
+	for i, r := range rules {
+		if r.MaxPerSecond > 0 {
+			ss.limiters[i] = newRateLimiter(r.MaxPerSecond)
+		}
+	}
+	t.spanSampler.Store(ss)
+}
+
+// spanSamplingRules returns the span sampling rules of the tracer.
+func (t *Tracer) spanSamplingRules() []SpanSamplingRule {
+	if ss, _ := t.spanSampler.Load().(*spanSampler); ss != nil {
+		return append([]SpanSamplingRule(nil), ss.rules...)
+	}
+	return nil
+}
+
+// sampleSingleSpan sends the finished span on its own when it matches a span
+// sampling rule, its trace being dropped. It reports whether it was sent.
+func (s *Span) sampleSingleSpan() bool {
+	t := s.tracer
+	if t == nil || !t.Enabled() {
+		return false
+	}
+	ss, _ := t.spanSampler.Load().(*spanSampler)
+	if ss == nil {
+		return false
+	}
+	r := ss.match(s)
+	if r == nil {
+		return false
+	}
+	s.tagsMu.Lock()
+	if s.Metrics == nil {
+		s.Metrics = make(map[string]float64)
+	}
+	s.Metrics[spanSamplingMechanismKey] = ext.SamplingMechanismSingleSpan
+	s.Metrics[spanSamplingRuleRateKey] = r.Rate
+	if r.MaxPerSecond > 0 {
+		s.Metrics[spanSamplingMaxPerSecondKey] = r.MaxPerSecond
+	}
+	s.tagsMu.Unlock()
+	t.channels.pushTrace([]*Span{s})
+	return true
+}
@@ -0,0 +1,117 @@
+package tracer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanSamplingRuleJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var rules []SpanSamplingRule
+	assert.NoError(json.Unmarshal([]byte(`[{"service": "web-*", "name": "grpc.server", "max_per_second": 50}, {"name": "db.*", "sample_rate": 0.5}]`), &rules))
+	assert.Equal([]SpanSamplingRule{
+		{Service: "web-*", Name: "grpc.server", Rate: 1, MaxPerSecond: 50},
+		{Name: "db.*", Rate: 0.5},
+	}, rules)
+}
+
+func TestTracerSpanSamplingRules(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetSpanSamplingRules(
+		SpanSamplingRule{Service: "web-*", Name: "grpc.server", Rate: 1, MaxPerSecond: 2},
+		SpanSamplingRule{Name: "http.*", Rate: 0},
+	)
+	assert.Len(tracer.Config().SpanSamplingRules, 2)
+
+	for i := 0; i < 3; i++ {
+		root := tracer.NewRootSpan("grpc.server", "web-api", "Hello")
+		child := tracer.NewChildSpan("http.request", root)
+		child.Finish()
+		root.Finish()
+	}
+	tracer.NewRootSpan("grpc.server", "worker", "Hello").Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 2) // limited to 2 spans per second
+	for _, trace := range traces {
+		assert.Len(trace, 1)
+		span := trace[0]
+		assert.Equal("grpc.server", span.Name)
+		assert.Equal(float64(ext.SamplingMechanismSingleSpan), span.Metrics[spanSamplingMechanismKey])
+		assert.Equal(1.0, span.Metrics[spanSamplingRuleRateKey])
+		assert.Equal(2.0, span.Metrics[spanSamplingMaxPerSecondKey])
+	}
+
+	// the spans of kept traces are left as is
+	tracer.SetSampleRate(1)
+	tracer.NewRootSpan("grpc.server", "web-api", "Hello").Finish()
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.NotContains(traces[0][0].Metrics, spanSamplingMechanismKey)
+
+	tracer.SetSpanSamplingRules()
+	assert.Len(tracer.Config().SpanSamplingRules, 0)
+}
+
+func TestTracerSpanSamplingRulesKeepErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetKeepErrors(true)
+	tracer.SetSpanSamplingRules(SpanSamplingRule{Name: "grpc.server", Rate: 1})
+
+	root := tracer.NewRootSpan("grpc.server", "web-api", "Hello")
+	tracer.NewChildSpan("sql.query", root).Finish()
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal("grpc.server", traces[0][0].Name)
+}
+
+func TestTracerSpanSamplingRulesDroppedStats(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.stats.setDroppedActive(true)
+	tracer.SetSpanSamplingRules(SpanSamplingRule{Name: "grpc.server", Rate: 1})
+
+	// the statistics of the spans sent on their own are computed by the
+	// agent, whether their trace is dropped at creation or once finished
+	tracer.SetSampleRate(0)
+	tracer.NewRootSpan("grpc.server", "web", "Hello").Finish()
+	tracer.NewRootSpan("http.request", "web", "/").Finish()
+	tracer.SetSampleRate(1)
+	root := tracer.NewRootSpan("grpc.server", "web", "Hello")
+	root.SetMeta(ext.ManualDrop, "true")
+	root.Finish()
+	root = tracer.NewRootSpan("http.request", "web", "/")
+	root.SetMeta(ext.ManualDrop, "true")
+	root.Finish()
+
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 2)
+	var names []string
+	for _, b := range tracer.stats.flush(0, true) {
+		for _, gs := range b.Stats {
+			names = append(names, gs.Name)
+			assert.Equal(uint64(2), gs.Hits)
+		}
+	}
+	assert.Equal([]string{"http.request"}, names)
+}
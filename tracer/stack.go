@@ -0,0 +1,78 @@
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// maxStackDepth is the number of frames recorded at most when the stack
+// traces are skipping frames without a depth limit.
+const maxStackDepth = 512
+
+// errorStacks configures the stack traces recorded by SetError, for all the
+// tracers.
+var errorStacks struct {
+	sync.RWMutex
+	disabled bool
+	depth    int
+	skip     int
+}
+
+// SetDebugStack sets whether SetError records the stack trace of the errors
+// in the "error.stack" tag of the spans, for all the tracers. It is enabled
+// by default; collecting the frames shows in the CPU profiles of services
+// returning many errors, which may rather do without.
+func SetDebugStack(enabled bool) {
+	errorStacks.Lock()
+	errorStacks.disabled = !enabled
+	errorStacks.Unlock()
+}
+
+// SetStackFrames limits the stack traces recorded by SetError, for all the
+// tracers, to the given number of frames, 0 meaning no limit, after skipping
+// the given number of frames above the caller of SetError, such as the ones
+// of error handling helpers. By default, the whole stack of the goroutine is
+// recorded.
+func SetStackFrames(depth, skip int) {
+	if depth < 0 || skip < 0 {
+		logf(logWarn, "tracer", "tracer.SetStackFrames depth and skip must be positive, now: %d, %d", depth, skip)
+		return
+	}
+	errorStacks.Lock()
+	errorStacks.depth = depth
+	errorStacks.skip = skip
+	errorStacks.Unlock()
+}
+
+// errorStack returns the stack trace to record for an error set by the caller
+// of SetError, which has to call it directly, or "" when stacks are disabled.
+func errorStack() string {
+	errorStacks.RLock()
+	disabled, depth, skip := errorStacks.disabled, errorStacks.depth, errorStacks.skip
+	errorStacks.RUnlock()
+	if disabled {
+		return ""
+	}
+	if depth == 0 && skip == 0 {
+		return string(debug.Stack())
+	}
+	if depth == 0 {
+		depth = maxStackDepth
+	}
+	pc := make([]uintptr, depth)
+	// skip runtime.Callers, errorStack and SetError
+	n := runtime.Callers(3+skip, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var b bytes.Buffer
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package tracer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setErrorHelper sets an error on the span, as an error handling helper would.
+func setErrorHelper(span *Span) {
+	span.SetError(errors.New("boom"))
+}
+
+func TestSetDebugStack(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	SetDebugStack(false)
+	defer SetDebugStack(true)
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span.SetError(errors.New("boom"))
+	assert.Equal("boom", span.GetMeta(errorMsgKey))
+	_, ok := span.Meta[errorStackKey]
+	assert.False(ok)
+}
+
+func TestSetStackFrames(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	SetStackFrames(1, 0)
+	defer SetStackFrames(0, 0)
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	setErrorHelper(span)
+	stack := span.GetMeta(errorStackKey)
+	assert.Equal(2, strings.Count(stack, "\n"))
+	assert.Contains(stack, "setErrorHelper")
+
+	// the helper is skipped
+	SetStackFrames(1, 1)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	setErrorHelper(span)
+	assert.Contains(span.GetMeta(errorStackKey), "TestSetStackFrames")
+
+	// invalid values are ignored
+	SetStackFrames(-1, 0)
+	span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	setErrorHelper(span)
+	assert.Contains(span.GetMeta(errorStackKey), "TestSetStackFrames")
+}
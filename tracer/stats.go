@@ -0,0 +1,133 @@
+package tracer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds statistics about the internals of a tracer, for monitoring and
+// troubleshooting purposes.
+type Stats struct {
+	// Errors holds the number of errors encountered since the tracer was
+	// created, per category.
+	Errors map[ErrorCategory]uint64
+	// WorkerStalls is the number of times the worker was detected as stalled.
+	WorkerStalls uint64
+	// BufferedTraces is the number of finished traces waiting to be flushed.
+	BufferedTraces int
+	// LastFlush is the time the last trace payload was sent, zero if none was.
+	LastFlush time.Time
+	// LastFlushErr is the error returned when sending the last trace payload.
+	LastFlushErr error
+	// Integrations holds the span counts per integration, see Span.SetIntegration.
+	Integrations map[string]IntegrationStats
+	// RetriedPayloads is the number of times a trace payload was sent again
+	// after a failure, see SetRetryPolicy.
+	RetriedPayloads uint64
+	// DroppedPayloads is the number of trace payloads given up on.
+	DroppedPayloads uint64
+	// Pools holds the usage of the pools instrumented with NewPool, by name.
+	Pools map[string]PoolStats
+}
+
+// IntegrationStats holds the number of spans finished by an integration.
+type IntegrationStats struct {
+	// Spans is the number of spans finished.
+	Spans uint64 `json:"spans"`
+	// Errors is the number of spans finished with an error.
+	Errors uint64 `json:"errors"`
+}
+
+// ErrorRate returns the ratio of spans finished with an error.
+func (s IntegrationStats) ErrorRate() float64 {
+	if s.Spans == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Spans)
+}
+
+// Stats returns a snapshot of the tracer statistics.
+func (t *Tracer) Stats() Stats {
+	errs := make(map[ErrorCategory]uint64, numErrorCategories)
+	for c := ErrorCategory(0); c < numErrorCategories; c++ {
+		errs[c] = t.channels.errCounts.get(c)
+	}
+	lastFlush, lastFlushErr := t.lastFlush.get()
+	return Stats{
+		Errors:         errs,
+		WorkerStalls:   t.watchdog.stallCount(),
+		BufferedTraces: len(t.channels.trace),
+		LastFlush:      lastFlush,
+		LastFlushErr:   lastFlushErr,
+		Integrations:   t.integrations.get(),
+		Pools:          t.pools.get(),
+
+		RetriedPayloads: atomic.LoadUint64(&t.retriedPayloads),
+		DroppedPayloads: atomic.LoadUint64(&t.droppedPayloads),
+	}
+}
+
+// flushStatus holds the outcome of the last flush. It is safe for concurrent use.
+type flushStatus struct {
+	mu   sync.RWMutex
+	time time.Time
+	err  error
+}
+
+// record records a flush which happened at the given time.
+func (s *flushStatus) record(now time.Time, err error) {
+	s.mu.Lock()
+	s.time, s.err = now, err
+	s.mu.Unlock()
+}
+
+// get returns the time and error of the last flush.
+func (s *flushStatus) get() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.time, s.err
+}
+
+// integrationCounts counts the spans finished per integration. It is safe
+// for concurrent use.
+type integrationCounts struct {
+	mu     sync.RWMutex
+	counts map[string]*IntegrationStats // fields are only accessed atomically
+}
+
+// count counts a span finished by the given integration.
+func (c *integrationCounts) count(integration string, isError bool) {
+	c.mu.RLock()
+	stats, ok := c.counts[integration]
+	c.mu.RUnlock()
+	if !ok {
+		c.mu.Lock()
+		if c.counts == nil {
+			c.counts = make(map[string]*IntegrationStats)
+		}
+		if stats, ok = c.counts[integration]; !ok {
+			stats = &IntegrationStats{}
+			c.counts[integration] = stats
+		}
+		c.mu.Unlock()
+	}
+	atomic.AddUint64(&stats.Spans, 1)
+	if isError {
+		atomic.AddUint64(&stats.Errors, 1)
+	}
+}
+
+// get returns a snapshot of the counts.
+func (c *integrationCounts) get() map[string]IntegrationStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	counts := make(map[string]IntegrationStats, len(c.counts))
+	for name, stats := range c.counts {
+		counts[name] = IntegrationStats{
+			Spans:  atomic.LoadUint64(&stats.Spans),
+			Errors: atomic.LoadUint64(&stats.Errors),
+		}
+	}
+	return counts
+}
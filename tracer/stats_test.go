@@ -0,0 +1,76 @@
+package tracer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/dd-trace-go/tracer/ext"
+)
+
+func TestTracerStatsErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	stats := tracer.Stats()
+	assert.Len(stats.Errors, int(numErrorCategories))
+	for c, n := range stats.Errors {
+		assert.Equal(uint64(0), n, c.String())
+	}
+
+	tracer.channels.pushErr(&errorTraceChanFull{Len: traceChanLen})
+	tracer.channels.pushErr(&errorServiceChanFull{Len: serviceChanLen})
+	tracer.channels.pushErr(&errorFlushLostTraces{Nb: 2, Err: fmt.Errorf("connection refused")})
+	tracer.channels.pushErr(fmt.Errorf("this is something unexpected"))
+
+	stats = tracer.Stats()
+	assert.Equal(uint64(2), stats.Errors[ErrorCategoryBufferOverflow])
+	assert.Equal(uint64(1), stats.Errors[ErrorCategoryTransport])
+	assert.Equal(uint64(1), stats.Errors[ErrorCategoryOther])
+	assert.Equal(uint64(0), stats.Errors[ErrorCategoryEncoding])
+}
+
+func TestTracerStatsErrorChanFull(t *testing.T) {
+	assert := assert.New(t)
+
+	channels := newTracerChans()
+	for i := 0; i < errChanLen+10; i++ {
+		channels.pushErr(&errorSpanBufFull{Len: 1000})
+	}
+	// errors are counted even when they can't be queued for logging
+	assert.Equal(uint64(errChanLen+10), channels.errCounts.get(ErrorCategoryOversizedTrace))
+}
+
+func TestTracerStatsIntegrations(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	assert.Len(tracer.Stats().Integrations, 0)
+
+	for i := 0; i < 4; i++ {
+		span := tracer.NewRootSpan("http.request", "web", "/")
+		span.SetIntegration("net/http")
+		if i == 0 {
+			span.SetError(fmt.Errorf("internal error"))
+		}
+		span.Finish()
+		span.Finish() // counted once
+	}
+	span := tracer.NewRootSpan("redis.command", "cache", "GET")
+	span.SetIntegration("go-redis/redis")
+	assert.Equal("go-redis/redis", span.GetMeta(ext.Component))
+	span.Finish()
+	tracer.NewRootSpan("custom", "web", "/").Finish()
+
+	stats := tracer.Stats().Integrations
+	assert.Equal(map[string]IntegrationStats{
+		"net/http":       IntegrationStats{Spans: 4, Errors: 1},
+		"go-redis/redis": IntegrationStats{Spans: 1},
+	}, stats)
+	assert.Equal(0.25, stats["net/http"].ErrorRate())
+	assert.Equal(0.0, IntegrationStats{}.ErrorRate())
+}
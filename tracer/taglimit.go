@@ -0,0 +1,54 @@
+package tracer
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// defaultMaxSpanTags is the default maximum number of meta of a span.
+	defaultMaxSpanTags = 1000
+
+	// droppedTagsMetricKey is the metric of the spans counting the meta
+	// dropped because the span had too many of them already.
+	droppedTagsMetricKey = "_dd.span_tags.dropped"
+
+	// internalTagPrefix is the prefix of the meta set by the tracer, which
+	// are never dropped.
+	internalTagPrefix = "_dd."
+)
+
+// SetMaxSpanTags sets the maximum number of meta of a span, above which the
+// new keys set with SetMeta are dropped, the values of the existing ones
+// being still updated. It protects the memory of the process against the
+// code which sets tags with unbounded keys, e.g. in a loop, until the span is
+// flushed. The dropped meta are counted by the "_dd.span_tags.dropped"
+// metric of the span. The limit is 1000 by default, and 0 disables it. The
+// errors and the meta set by the tracer aren't limited.
+func (t *Tracer) SetMaxSpanTags(n int) {
+	if n < 0 {
+		logf(logWarn, "tracer", "tracer.SetMaxSpanTags limit must not be negative, now: %d", n)
+		return
+	}
+	atomic.StoreInt64(&t.maxSpanTags, int64(n))
+}
+
+// allowMeta reports whether the meta with the given key can be set on the
+// span, counting it as dropped otherwise: the span tags must be locked.
+func (s *Span) allowMeta(key string) bool {
+	if s.tracer == nil || s.finished || s.lightweight || strings.HasPrefix(key, internalTagPrefix) {
+		return true
+	}
+	limit := int(atomic.LoadInt64(&s.tracer.maxSpanTags))
+	if limit == 0 || len(s.Meta) < limit {
+		return true
+	}
+	if _, ok := s.Meta[key]; ok {
+		return true
+	}
+	if s.Metrics == nil {
+		s.Metrics = make(map[string]float64)
+	}
+	s.Metrics[droppedTagsMetricKey]++
+	return false
+}
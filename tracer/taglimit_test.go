@@ -0,0 +1,41 @@
+package tracer
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanMaxTags(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetMaxSpanTags(10)
+
+	span := tracer.NewRootSpan("http.request", "web", "/")
+	n := len(span.Meta)
+	for i := 0; i < 20; i++ {
+		span.SetMeta("key."+strconv.Itoa(i), "value")
+	}
+	assert.Len(span.Meta, 10)
+	assert.Equal(float64(10+n), span.Metrics[droppedTagsMetricKey])
+
+	// the existing keys, the internal ones and the errors are still set
+	span.SetMeta("key.0", "updated")
+	assert.Equal("updated", span.GetMeta("key.0"))
+	span.SetMeta("_dd.internal", "yes")
+	assert.Equal("yes", span.GetMeta("_dd.internal"))
+	span.SetError(errors.New("boom"))
+	assert.Equal("boom", span.GetMeta(errorMsgKey))
+
+	// the limit can be disabled
+	tracer.SetMaxSpanTags(0)
+	span.SetMeta("key.20", "value")
+	assert.Equal("value", span.GetMeta("key.20"))
+
+	// negative limits are ignored
+	tracer.SetMaxSpanTags(-1)
+	assert.Equal(0, tracer.Config().MaxSpanTags)
+}
@@ -2,7 +2,6 @@ package tracer
 
 import (
 	"golang.org/x/sys/windows"
-	"log"
 	"time"
 )
 
@@ -25,10 +24,10 @@ var now func() int64
 // precise implementation based on time.Now()
 func init() {
 	if err := windows.LoadGetSystemTimePreciseAsFileTime(); err != nil {
-		log.Printf("Unable to load high precison timer, defaulting to time.Now()")
+		logf(logWarn, "time", "Unable to load high precison timer, defaulting to time.Now()")
 		now = lowPrecisionNow
 	} else {
-		log.Printf("Using high precision timer")
+		logf(logInfo, "time", "Using high precision timer")
 		now = highPrecisionNow
 	}
 }
@@ -0,0 +1,89 @@
+package tracer
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// traceIDHighKey is the trace-level tag holding the 64 upper bits of the
+// 128-bit trace ID of a trace, in 16 lowercase hexadecimal characters. It is
+// set on the local root span.
+const traceIDHighKey = "_dd.p.tid"
+
+// SetTraceID128Generation enables or disables the generation of 128-bit
+// trace IDs, so that the traces correlate with the ones of OpenTelemetry and
+// W3C systems, which use 128-bit IDs. The TraceID of the spans holds the 64
+// lower bits of the ID, which are random, and the 64 upper bits are given by
+// TraceIDHigh: they hold the start time of the trace in seconds, followed
+// by 32 zero bits. They are reported in the "_dd.p.tid" tag of the local
+// root span, and propagated as such.
+func (t *Tracer) SetTraceID128Generation(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&t.traceID128, 1)
+	} else {
+		atomic.StoreUint32(&t.traceID128, 0)
+	}
+}
+
+// TraceID128GenerationEnabled returns true if the tracer generates 128-bit
+// trace IDs.
+func (t *Tracer) TraceID128GenerationEnabled() bool {
+	return atomic.LoadUint32(&t.traceID128) == 1
+}
+
+// newTraceIDHigh returns the 64 upper bits of a new 128-bit trace ID.
+func newTraceIDHigh(now time.Time) uint64 {
+	return uint64(uint32(now.Unix())) << 32
+}
+
+// TraceIDHigh returns the 64 upper bits of the 128-bit trace ID of the
+// trace of the span, or 0 if it has a 64-bit ID.
+func (s *Span) TraceIDHigh() uint64 {
+	if s == nil {
+		return 0
+	}
+	root := s.localRoot()
+	root.RLock()
+	defer root.RUnlock()
+	return root.traceIDHigh
+}
+
+// SetTraceIDHigh sets the 64 upper bits of the 128-bit trace ID of the trace
+// of the span, which has a 64-bit ID when they are 0. It is meant to continue
+// a trace whose context was propagated from another process, along with its
+// TraceID.
+func (s *Span) SetTraceIDHigh(high uint64) {
+	if s == nil {
+		return
+	}
+	root := s.localRoot()
+	root.Lock()
+	root.traceIDHigh = high
+	root.Unlock()
+
+	root.tagsMu.Lock()
+	defer root.tagsMu.Unlock()
+	if high == 0 {
+		delete(root.Meta, traceIDHighKey)
+		return
+	}
+	root.setMeta(traceIDHighKey, formatTraceIDHigh(high))
+}
+
+// formatTraceIDHigh returns the 64 upper bits of a trace ID as the value of
+// the "_dd.p.tid" tag.
+func formatTraceIDHigh(high uint64) string {
+	return fmt.Sprintf("%016x", high)
+}
+
+// ParseTraceIDHigh parses the value of the "_dd.p.tid" tag, as propagated
+// with the trace-level tags, returning false if it is malformed.
+func ParseTraceIDHigh(v string) (uint64, bool) {
+	if len(v) != 16 {
+		return 0, false
+	}
+	high, err := strconv.ParseUint(v, 16, 64)
+	return high, err == nil
+}
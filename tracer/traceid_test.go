@@ -0,0 +1,64 @@
+package tracer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerTraceID128Generation(t *testing.T) {
+	assert := assert.New(t)
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	// trace IDs have 64 bits by default
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(tracer.TraceID128GenerationEnabled())
+	assert.Equal(uint64(0), root.TraceIDHigh())
+	assert.Equal("", root.GetMeta(traceIDHighKey))
+
+	clock := &fakeClock{now: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracer.SetClock(clock)
+	tracer.SetTraceID128Generation(true)
+	assert.True(tracer.TraceID128GenerationEnabled())
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	high := uint64(clock.now.Unix()) << 32
+	assert.Equal(high, root.TraceIDHigh())
+	assert.Equal(high, child.TraceIDHigh())
+	assert.Equal("5868468000000000", root.GetMeta(traceIDHighKey))
+	assert.Equal("", child.GetMeta(traceIDHighKey))
+	assert.Equal(fmt.Sprintf("%016x%016x", high, root.TraceID), child.TraceIDHex())
+
+	// so do the spans following from the trace, which are flushed apart
+	follower := tracer.NewFollowsFromSpan("worker.job", child)
+	assert.Equal(high, follower.TraceIDHigh())
+	assert.Equal("5868468000000000", follower.GetMeta(traceIDHighKey))
+	assert.Equal(root.TraceIDHex(), follower.TraceIDHex())
+
+	// a trace continued from another process takes its ID
+	root.SetTraceIDHigh(42)
+	assert.Equal(uint64(42), child.TraceIDHigh())
+	assert.Equal("000000000000002a", root.GetMeta(traceIDHighKey))
+	root.SetTraceIDHigh(0)
+	assert.Equal("", root.GetMeta(traceIDHighKey))
+	assert.Equal(fmt.Sprintf("%032x", root.TraceID), child.TraceIDHex())
+}
+
+func TestParseTraceIDHigh(t *testing.T) {
+	assert := assert.New(t)
+
+	for v, want := range map[string]uint64{
+		"5b8efff798038103":  0x5b8efff798038103,
+		"000000000000002a":  42,
+		"2a":                0,
+		"5b8efff79803810z":  0,
+		"5b8efff7980381030": 0,
+	} {
+		high, ok := ParseTraceIDHigh(v)
+		assert.Equal(want, high, v)
+		assert.Equal(want != 0, ok, v)
+	}
+}
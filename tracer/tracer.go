@@ -2,7 +2,7 @@ package tracer
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
@@ -14,7 +14,17 @@ import (
 )
 
 const (
-	flushInterval = 2 * time.Second
+	// defaultFlushInterval is the default period of the flushes of the
+	// traces to the agent.
+	defaultFlushInterval = 2 * time.Second
+
+	// errorLogWindow is the period over which repeated errors are
+	// aggregated before being logged.
+	errorLogWindow = time.Minute
+
+	// defaultConcurrentSends is the default number of trace payloads which
+	// can be in-flight to the agent at the same time.
+	defaultConcurrentSends = 4
 )
 
 func init() {
@@ -36,13 +46,54 @@ func (s Service) Equal(s2 Service) bool {
 //
 // When a tracer is disabled, it will not submit spans for processing.
 type Tracer struct {
-	transport Transport // is the transport mechanism used to delivery spans to the agent
-	sampler   sampler   // is the trace sampler to only keep some samples
+	// inflightTraces is the number of traces being sent to the agent. It
+	// is accessed atomically and comes first to be 64-bit aligned, as do
+	// the other counters.
+	inflightTraces int64
+	// retainedTraces is the number of traces held for retries, and
+	// retriedPayloads and droppedPayloads count the payloads which were
+	// retried and the ones given up on.
+	retainedTraces  int64
+	retriedPayloads uint64
+	droppedPayloads uint64
+	// traceTimeout is the trace timeout in nanoseconds, see
+	// SetTraceTimeout.
+	traceTimeout int64
+	// maxSpanTags is the maximum number of meta of a span, see
+	// SetMaxSpanTags.
+	maxSpanTags int64
+
+	transport Transport     // is the transport mechanism used to delivery spans to the agent
+	sampler   sampler       // is the trace sampler to only keep some samples
+	rare      *rareSampler  // keeps the traces of rare endpoints, if enabled
+	snapshots *snapshotter  // sends snapshots of long-running traces, if enabled
+	stats     *concentrator // computes the statistics of the traces, if enabled
+	limiter   *rateLimiter  // limits the traces kept by the sampling rules, if set
+
+	// priority samples the traces with the rates given by the agent, when
+	// it is the sampler, see SetPrioritySampling.
+	priority *prioritySampler
 
 	// debugMode should only be set atomically. It is enabled when it has
 	// a value of 1 and disabled when 0.
 	debugMode uint32
 
+	// goroutineChecks should only be set atomically. When it has a value of
+	// 1, spans record the goroutine creating them, see SetGoroutineChecks.
+	goroutineChecks uint32
+
+	// keepErrors should only be set atomically. When it has a value of 1,
+	// traces dropped by the sampler are still kept if they have errors.
+	keepErrors uint32
+
+	// traceID128 should only be set atomically. When it has a value of 1,
+	// new traces have 128-bit IDs, see SetTraceID128Generation.
+	traceID128 uint32
+
+	// debugBaggage should only be set atomically. When it has a value of 1,
+	// the ext.DebugBaggage item keeps traces, see SetDebugBaggage.
+	debugBaggage uint32
+
 	enableMu sync.RWMutex
 	enabled  bool // defines if the Tracer is enabled or not
 
@@ -50,13 +101,73 @@ type Tracer struct {
 	metaMu sync.RWMutex
 
 	channels tracerChans
-	services map[string]Service // name -> service
+
+	// services is only written by the worker, servicesMu guards its reads
+	// from other goroutines.
+	services   map[string]Service // name -> service
+	servicesMu sync.RWMutex
 
 	exit   chan struct{}
 	exitWG *sync.WaitGroup
+	// stopOnce closes exit on the first stop, which the others wait for.
+	stopOnce sync.Once
+
+	// clock holds the clock set with SetClock, as a clockHolder.
+	clock atomic.Value
+
+	// contextTags holds the tags set from context values, as contextTags,
+	// see SetContextTags.
+	contextTags atomic.Value
+
+	// spanSampler holds the span sampling rules as a *spanSampler, see
+	// SetSpanSamplingRules.
+	spanSampler atomic.Value
+
+	// dogstatsd holds the *dogstatsd client the gauges are sent to, see
+	// SetDogStatsDAddr.
+	dogstatsd atomic.Value
+
+	// retry is how the failed payloads are retried, see SetRetryPolicy.
+	retry   RetryPolicy
+	retryMu sync.RWMutex
+
+	// flushInterval is the period of the flushes of the worker, it is set
+	// before the worker starts.
+	flushInterval time.Duration
+
+	// stopCtx bounds the final flush when the tracer stops, the worker
+	// gives up on flushing when it is done. It is set before exit is closed
+	// and abandoned is set before the worker returns.
+	stopCtx   context.Context
+	abandoned int
 
 	forceFlushIn  chan struct{}
 	forceFlushOut chan struct{}
+
+	// sendSem bounds the number of concurrent trace payload sends, it holds
+	// a token for each send in progress.
+	sendSem   chan struct{}
+	sendSemMu sync.RWMutex
+	sendWG    sync.WaitGroup
+
+	// watchdog reports the worker when it stops servicing its ticker.
+	watchdog *workerWatchdog
+
+	// errLog logs the errors, it is only used by the worker.
+	errLog *errorLogger
+
+	// lastFlush holds the outcome of the last trace payload sent.
+	lastFlush flushStatus
+
+	// integrations counts the spans finished per integration.
+	integrations integrationCounts
+
+	// pools holds the pools instrumented with NewPool.
+	pools poolRegistry
+
+	// incomplete holds the traces waiting for spans finishing after their
+	// local root.
+	incomplete incompleteTraces
 }
 
 // NewTracer creates a new Tracer. Most users should use the package's
@@ -67,6 +178,15 @@ func NewTracer() *Tracer {
 
 // NewTracerTransport create a new Tracer with the given transport.
 func NewTracerTransport(transport Transport) *Tracer {
+	t := newTracer(transport)
+	t.loadEnv()
+	t.start()
+	return t
+}
+
+// newTracer returns a tracer sending traces with the given transport, which
+// has yet to be started.
+func newTracer(transport Transport) *Tracer {
 	t := &Tracer{
 		enabled:   true,
 		transport: transport,
@@ -81,19 +201,63 @@ func NewTracerTransport(transport Transport) *Tracer {
 
 		forceFlushIn:  make(chan struct{}, 0), // must be size 0 (blocking)
 		forceFlushOut: make(chan struct{}, 0), // must be size 0 (blocking)
-	}
 
-	// start a background worker
-	t.exitWG.Add(1)
-	go t.worker()
+		sendSem: make(chan struct{}, defaultConcurrentSends),
+
+		stats: newConcentrator(),
 
+		flushInterval: defaultFlushInterval,
+		traceTimeout:  int64(defaultTraceTimeout),
+		maxSpanTags:   defaultMaxSpanTags,
+		retry:         defaultRetryPolicy,
+		errLog:        newErrorLogger(errorLogWindow),
+	}
+	t.limiter = newRateLimiter(defaultRateLimit)
+	t.priority = newPrioritySampler()
+	if ht, ok := transport.(*httpTransport); ok {
+		ht.setRatesHandler(t.priority.readRates)
+	}
 	return t
 }
 
-// Stop stops the tracer.
+// start starts a background worker, and a watchdog to report it if it stalls.
+func (t *Tracer) start() {
+	t.watchdog = newWorkerWatchdog(t.flushInterval)
+	t.exitWG.Add(2)
+	go t.worker()
+	go t.watch(t.flushInterval)
+}
+
+// Stop stops the tracer, after flushing all the buffered traces.
 func (t *Tracer) Stop() {
-	close(t.exit)
+	t.StopWithTimeout(0)
+}
+
+// StopWithTimeout stops the tracer, flushing the buffered traces for at most
+// the given duration, or without limit if it is 0. It returns the number of
+// traces abandoned because they could not be sent in time.
+func (t *Tracer) StopWithTimeout(timeout time.Duration) (abandoned int) {
+	if timeout <= 0 {
+		return t.StopWithContext(context.Background())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.StopWithContext(ctx)
+}
+
+// StopWithContext stops the tracer, flushing the buffered traces until the
+// given context is done, e.g. when its deadline is reached because the agent
+// is unreachable. It returns the number of traces abandoned because they
+// could not be sent in time. The tracer can be stopped several times, e.g.
+// on a signal and by the program: the calls after the first one wait for it
+// and return the same number, their context is ignored.
+func (t *Tracer) StopWithContext(ctx context.Context) (abandoned int) {
+	t.stopOnce.Do(func() {
+		t.stopCtx = ctx
+		close(t.exit)
+	})
 	t.exitWG.Wait()
+	return t.abandoned
 }
 
 // SetEnabled will enable or disable the tracer.
@@ -110,18 +274,67 @@ func (t *Tracer) Enabled() bool {
 	return t.enabled
 }
 
+// SamplerKeepsTraces returns whether the tracer is enabled and may keep some
+// traces when they start, i.e. it isn't disabled and neither its sampler nor
+// the rare sampler drops every trace. The tags of the spans of the traces
+// dropped when they start are discarded, so it lets applications skip
+// computing expensive tags. It doesn't account for the decisions made once a
+// trace has started, by KeepTrace or the ext.DebugBaggage item: they record
+// the spans of the trace created afterwards only. The spans kept by span
+// sampling rules are recorded without their tags either.
+func (t *Tracer) SamplerKeepsTraces() bool {
+	if !t.Enabled() {
+		return false
+	}
+	// traces with errors are kept whatever the sampler decides
+	return t.KeepErrorsEnabled() || t.rare != nil || !dropsAll(t.sampler)
+}
+
 // SetSampleRate sets a sample rate for all the future traces.
 // sampleRate has to be between 0.0 and 1.0 and represents the ratio of traces
 // that will be sampled. 0.0 means that the tracer won't send any trace. 1.0
 // means that the tracer will send all traces.
 func (t *Tracer) SetSampleRate(sampleRate float64) {
+	var s sampler
 	if sampleRate == 1 {
-		t.sampler = newAllSampler()
+		s = newAllSampler()
 	} else if sampleRate >= 0 && sampleRate < 1 {
-		t.sampler = newRateSampler(sampleRate)
+		s = newRateSampler(sampleRate)
 	} else {
-		log.Printf("tracer.SetSampleRate rate must be between 0 and 1, now: %f", sampleRate)
+		logf(logWarn, "tracer", "tracer.SetSampleRate rate must be between 0 and 1, now: %f", sampleRate)
+		return
+	}
+	if rs, ok := t.sampler.(*rulesSampler); ok {
+		// the sampling rules take precedence
+		t.sampler = newRulesSampler(rs.rules, s, rs.limiter)
+		return
+	}
+	t.sampler = s
+}
+
+// SetSampler replaces the sampler of the tracer, including its sample rate
+// and sampling rules, with a custom one. Passing nil restores the default
+// sampler, which keeps all the traces.
+func (t *Tracer) SetSampler(s Sampler) {
+	if s == nil {
+		t.sampler = newAllSampler()
+		return
 	}
+	t.sampler = customSampler{s}
+}
+
+// SetConcurrentSends sets the maximum number of trace payloads which can be
+// sent to the agent at the same time. While the limit is reached, flushing
+// waits for a send to complete. It defaults to 4; a value of 1 serializes
+// all sends.
+func (t *Tracer) SetConcurrentSends(n int) {
+	if n < 1 {
+		logf(logWarn, "tracer", "tracer.SetConcurrentSends must be at least 1, now: %d", n)
+		return
+	}
+	t.sendSemMu.Lock()
+	t.sendSem = make(chan struct{}, n)
+	t.sendSemMu.Unlock()
 }
 
 // SetServiceInfo update the application and application type for the given
@@ -174,17 +387,95 @@ func (t *Tracer) getAllMeta() map[string]string {
 // assigned.
 func (t *Tracer) NewRootSpan(name, service, resource string) *Span {
 	spanID := NextSpanID()
-	span := NewSpan(name, service, resource, spanID, spanID, 0, t)
+	span := newSpan(name, service, resource, spanID, spanID, 0, t)
+	if t.TraceID128GenerationEnabled() {
+		span.traceIDHigh = newTraceIDHigh(t.clockNow())
+	}
+
+	if t.sampleTrace(span, SamplingParams{}) {
+		t.initRootSpan(span)
+	}
+	return span
+}
+
+// sampleTrace runs the sampler on a span starting a new trace and reports
+// whether the trace is recorded. When the trace is dropped and there's no
+// priority to carry along, nor errors to look for, the span is made
+// lightweight so that it won't record anything.
+func (t *Tracer) sampleTrace(span *Span, params SamplingParams) bool {
+	t.decide(span, params)
+	if !span.Sampled && !span.HasSamplingPriority() && !t.KeepErrorsEnabled() {
+		span.lightweight = true
+		return false
+	}
+	return true
+}
+
+// decide makes the sampling decision of a root span: the sampler applies its
+// rules, limiter and rates, then the rare sampler may keep a dropped trace.
+func (t *Tracer) decide(span *Span, params SamplingParams) {
+	if cs, ok := t.sampler.(customSampler); ok {
+		params.Span = span
+		cs.sample(params)
+	} else {
+		t.sampler.Sample(span)
+	}
+	if t.rare != nil {
+		t.rare.Sample(span, t.clockNow())
+	}
+}
 
+// initTraceSpan sets up the first span of a kept trace: it applies the tracer
+// meta and allocates the trace buffer.
+func (t *Tracer) initTraceSpan(span *Span) {
+	span.Meta = t.getAllMeta()
 	span.buffer = newSpanBuffer(t.channels, 0, 0)
-	t.Sample(span)
+	span.buffer.keepOnError = !span.Sampled && t.KeepErrorsEnabled()
 	// [TODO:christian] introduce distributed sampling here
 	span.buffer.Push(span)
+	if s := t.snapshots; s != nil {
+		s.track(span.buffer, span.clockStart)
+	}
+}
+
+// initRootSpan sets up a kept root span, adding the process id on top of
+// what initTraceSpan does.
+func (t *Tracer) initRootSpan(span *Span) {
+	t.initTraceSpan(span)
 
 	// Add the process id to all root spans
 	span.SetMeta(ext.Pid, strconv.Itoa(os.Getpid()))
+	if high := span.traceIDHigh; high != 0 {
+		span.SetMeta(traceIDHighKey, formatTraceIDHigh(high))
+	}
+	if !span.HasSamplingPriority() || agentRateApplied(span) {
+		span.setSamplingMechanism(samplingMechanism(span))
+	}
+}
 
-	return span
+// samplingMechanism returns the mechanism used by the sampler to make its
+// decision for the given root span, see ext.SamplingMechanism*.
+func samplingMechanism(span *Span) int {
+	span.tagsMu.RLock()
+	defer span.tagsMu.RUnlock()
+	_, rule := span.Metrics[rulesRateMetricKey]
+	_, rate := span.Metrics[sampleRateMetricKey]
+	if rule || rate {
+		return ext.SamplingMechanismRule
+	}
+	if _, agent := span.Metrics[agentRateMetricKey]; agent {
+		return ext.SamplingMechanismAgentRate
+	}
+	return ext.SamplingMechanismDefault
+}
+
+// agentRateApplied reports whether the sampling priority of the root span was
+// set from a rate given by the agent, rather than by the user.
+func agentRateApplied(span *Span) bool {
+	span.tagsMu.RLock()
+	defer span.tagsMu.RUnlock()
+	_, ok := span.Metrics[agentRateMetricKey]
+	return ok
 }
 
 // NewChildSpan returns a new span that is child of the Span passed as
@@ -197,17 +488,25 @@ func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
 	// it's better to be defensive and to produce a wrongly configured span
 	// that is not sent to the trace agent.
 	if parent == nil {
-		span := NewSpan(name, "", name, spanID, spanID, spanID, t)
-
-		span.buffer = newSpanBuffer(t.channels, 0, 0)
-		t.Sample(span)
-		// [TODO:christian] introduce distributed sampling here
-		span.buffer.Push(span)
-
+		span := newSpan(name, "", name, spanID, spanID, spanID, t)
+		if t.sampleTrace(span, SamplingParams{}) {
+			t.initTraceSpan(span)
+		}
 		return span
 	}
 
 	parent.RLock()
+	if parent.lightweight {
+		// the whole trace is dropped, so is this span
+		span := newSpan(name, parent.Service, name, spanID, parent.TraceID, parent.SpanID, parent.tracer)
+		span.Sampled = false
+		span.lightweight = true
+		span.parent = parent
+		span.baggage = parent.baggage
+		parent.RUnlock()
+		return span
+	}
+
 	// child that is correctly configured
 	span := NewSpan(name, parent.Service, name, spanID, parent.TraceID, parent.SpanID, parent.tracer)
 
@@ -219,6 +518,7 @@ func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
 
 	span.parent = parent
 	span.buffer = parent.buffer
+	span.baggage = parent.baggage
 	parent.RUnlock()
 
 	span.buffer.Push(span)
@@ -226,12 +526,73 @@ func (t *Tracer) NewChildSpan(name string, parent *Span) *Span {
 	return span
 }
 
+// NewFollowsFromSpan returns a new span for work which is caused by the span
+// passed as argument but which it doesn't wait for, such as a goroutine left
+// running in the background. The span belongs to the same trace and has from
+// as parent, but it is buffered on its own, so that the rest of the trace is
+// flushed without waiting for it, and it is tagged with the follows-from
+// relationship, see ext.SpanRelationship, so that its duration isn't
+// expected to fit in the one of its parent.
+func (t *Tracer) NewFollowsFromSpan(name string, from *Span) *Span {
+	if from == nil {
+		return t.NewChildSpan(name, nil)
+	}
+	spanID := NextSpanID()
+	high := from.TraceIDHigh()
+
+	from.RLock()
+	span := newSpan(name, from.Service, name, spanID, from.TraceID, from.SpanID, t)
+	span.traceIDHigh = high
+	lightweight := from.lightweight
+	span.Sampled = from.Sampled
+	hasPriority := from.HasSamplingPriority()
+	priority := from.GetSamplingPriority()
+	span.baggage = from.baggage
+	from.RUnlock()
+
+	if lightweight {
+		// the whole trace is dropped, so is this span
+		span.Sampled = false
+		span.lightweight = true
+		return span
+	}
+	t.initTraceSpan(span)
+	if hasPriority {
+		span.SetSamplingPriority(priority)
+	}
+	if high != 0 {
+		// the span is the local root of its own chunk of the trace
+		span.SetMeta(traceIDHighKey, formatTraceIDHigh(high))
+	}
+	span.SetMeta(ext.SpanRelationship, ext.RelationshipFollowsFrom)
+	return span
+}
+
+// NewServiceSpan returns a child of the given parent span which is reported
+// under the given service rather than the one of its parent, for in-process
+// components monitored as services of their own, such as an embedded cache.
+// The span belongs to the trace of the parent and its children inherit its
+// service. It is marked as the entry point of the service so that the
+// statistics of the service are computed from it. The service is described
+// with SetServiceInfo, as any other.
+func (t *Tracer) NewServiceSpan(name, service string, parent *Span) *Span {
+	span := t.NewChildSpan(name, parent)
+	span.Service = service
+	if parent == nil || parent.Service != service {
+		span.SetMetric(topLevelKey, 1)
+	}
+	return span
+}
+
 // NewChildSpanFromContext will create a child span of the span contained in
 // the given context. If the context contains no span, an empty span will be
-// returned.
+// returned. The span is tagged with the context values set with
+// SetContextTags.
 func (t *Tracer) NewChildSpanFromContext(name string, ctx context.Context) *Span {
-	span, _ := SpanFromContext(ctx) // tolerate nil spans
-	return t.NewChildSpan(name, span)
+	parent, _ := SpanFromContext(ctx) // tolerate nil spans
+	span := t.NewChildSpan(name, parent)
+	t.setContextTags(span, ctx)
+	return span
 }
 
 // NewChildSpanWithContext will create and return a child span of the span contained in the given
@@ -243,6 +604,26 @@ func (t *Tracer) NewChildSpanWithContext(name string, ctx context.Context) (*Spa
 	return span, span.Context(ctx)
 }
 
+// Measure runs fn in a child span of the span contained in the given context,
+// named after name. The context passed to fn contains the new span, which is
+// finished with the error returned by fn when it returns. If fn panics, the
+// span is finished with an error describing the panic, which is propagated.
+//
+//	err := tracer.Measure(ctx, "thumbnail.resize", func(ctx context.Context) error {
+//		return resize(ctx, img)
+//	})
+func (t *Tracer) Measure(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	span, ctx := t.NewChildSpanWithContext(name, ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			span.FinishWithErr(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+		span.FinishWithErr(err)
+	}()
+	return fn(ctx)
+}
+
 // SetDebugLogging will set the debug level
 func (t *Tracer) SetDebugLogging(debug bool) {
 	if debug {
@@ -252,15 +633,56 @@ func (t *Tracer) SetDebugLogging(debug bool) {
 	}
 }
 
+// SetKeepErrors makes the tracer keep the traces holding at least one span
+// with an error, even when they are dropped by the sampler, so that low
+// sample rates don't hide failures. Such traces are recorded until they are
+// finished, which has a cost, and they are not counted in the sample rate.
+func (t *Tracer) SetKeepErrors(keep bool) {
+	if keep {
+		atomic.StoreUint32(&t.keepErrors, 1)
+	} else {
+		atomic.StoreUint32(&t.keepErrors, 0)
+	}
+}
+
+// KeepErrorsEnabled returns true if the traces with errors are always kept.
+func (t *Tracer) KeepErrorsEnabled() bool {
+	return atomic.LoadUint32(&t.keepErrors) == 1
+}
+
+// SetPayloadCompression enables or disables the gzip compression of the
+// trace payloads sent to the agent, which saves bandwidth for services
+// emitting a lot of spans, at the cost of some CPU. The payloads are only
+// compressed once the agent has advertised it accepts them, see
+// AgentFeatures, and compression is disabled on its own if the agent rejects
+// them anyway. It has no effect with a custom transport.
+func (t *Tracer) SetPayloadCompression(enabled bool) {
+	if ht, ok := t.transport.(*httpTransport); ok {
+		ht.setCompression(enabled)
+	}
+}
+
+// PayloadCompressionEnabled returns true if the trace payloads are
+// compressed, when the agent supports it.
+func (t *Tracer) PayloadCompressionEnabled() bool {
+	ht, ok := t.transport.(*httpTransport)
+	return ok && ht.compressionEnabled()
+}
+
 // DebugLoggingEnabled returns true if the debug level is enabled and false otherwise.
 func (t *Tracer) DebugLoggingEnabled() bool {
 	return atomic.LoadUint32(&t.debugMode) == 1
 }
 
-func (t *Tracer) getTraces() [][]*Span {
-	traces := make([][]*Span, 0, len(t.channels.trace))
+// getTraces drains at most max traces from the trace channel.
+func (t *Tracer) getTraces(max int) [][]*Span {
+	n := len(t.channels.trace)
+	if n > max {
+		n = max
+	}
+	traces := make([][]*Span, 0, n)
 
-	for {
+	for len(traces) < max {
 		select {
 		case trace := <-t.channels.trace:
 			traces = append(traces, trace)
@@ -268,19 +690,34 @@ func (t *Tracer) getTraces() [][]*Span {
 			return traces
 		}
 	}
+	return traces
 }
 
-// flushTraces will push any currently buffered traces to the server.
+// flushTraces will push any currently buffered traces to the server. Traces
+// are drained in batches of at most traceBatchSize, each of them making its
+// own payload, until all the traces buffered when the flush started are sent.
 func (t *Tracer) flushTraces() {
-	traces := t.getTraces()
+	pending := len(t.channels.trace)
+	for {
+		traces := t.getTraces(traceBatchSize)
+		t.flushTraceBatch(traces)
+
+		pending -= len(traces)
+		if pending <= 0 || len(traces) < traceBatchSize {
+			return
+		}
+	}
+}
 
+// flushTraceBatch sends a batch of traces to the server.
+func (t *Tracer) flushTraceBatch(traces [][]*Span) {
 	if t.DebugLoggingEnabled() {
-		log.Printf("Sending %d traces", len(traces))
+		logf(logDebug, "tracer", "Sending %d traces", len(traces))
 		for _, trace := range traces {
 			if len(trace) > 0 {
-				log.Printf("TRACE: %d\n", trace[0].TraceID)
+				logf(logDebug, "tracer", "TRACE: %d\n", trace[0].TraceID)
 				for _, span := range trace {
-					log.Printf("SPAN:\n%s", span.String())
+					logf(logDebug, "tracer", "SPAN:\n%s", span.String())
 				}
 			}
 		}
@@ -291,11 +728,35 @@ func (t *Tracer) flushTraces() {
 		return
 	}
 
-	_, err := t.transport.SendTraces(traces)
-	if err != nil {
-		t.channels.pushErr(err)
-		t.channels.pushErr(&errorFlushLostTraces{Nb: len(traces)}) // explicit log messages with nb of lost traces
-	}
+	t.sendTraces(groupTraces(traces))
+}
+
+// sendTraces sends the traces to the transport in the background so that the
+// worker can keep on draining and encoding the next payloads. It blocks when
+// the maximum number of concurrent sends has been reached.
+func (t *Tracer) sendTraces(traces [][]*Span) {
+	t.sendSemMu.RLock()
+	sem := t.sendSem
+	t.sendSemMu.RUnlock()
+
+	sem <- struct{}{}
+	t.sendWG.Add(1)
+	atomic.AddInt64(&t.inflightTraces, int64(len(traces)))
+	go func() {
+		defer func() {
+			atomic.AddInt64(&t.inflightTraces, -int64(len(traces)))
+			t.sendWG.Done()
+		}()
+		if err := t.send(traces, sem); err != nil {
+			lost := len(traces)
+			if ce, ok := err.(*errorChunks); ok {
+				// only the traces of the chunks which failed are lost
+				lost, err = len(ce.Traces), ce.Err
+			}
+			atomic.AddUint64(&t.droppedPayloads, 1)
+			t.channels.pushErr(&errorFlushLostTraces{Nb: lost, Err: err}) // explicit log messages with nb of lost traces
+		}
+	}()
 }
 
 func (t *Tracer) updateServices() bool {
@@ -304,7 +765,9 @@ func (t *Tracer) updateServices() bool {
 		select {
 		case service := <-t.channels.service:
 			if s, found := t.services[service.Name]; !found || !s.Equal(service) {
+				t.servicesMu.Lock()
 				t.services[service.Name] = service
+				t.servicesMu.Unlock()
 				servicesModified = true
 			}
 		default: // return when there's no more data
@@ -323,49 +786,185 @@ func (t *Tracer) flushServices() {
 
 	_, err := t.transport.SendServices(t.services)
 	if err != nil {
-		t.channels.pushErr(err)
-		t.channels.pushErr(&errorFlushLostServices{Nb: len(t.services)}) // explicit log messages with nb of lost services
+		t.channels.pushErr(&errorFlushLostServices{Nb: len(t.services), Err: err}) // explicit log messages with nb of lost services
 	}
 }
 
-// flushErrs will process log messages that were queued
-func (t *Tracer) flushErrs() {
-	logErrors(t.channels.err)
+// flushErrs will process log messages that were queued. Unless force is
+// true, they are only logged once per errorLogWindow.
+func (t *Tracer) flushErrs(force bool) {
+	t.errLog.log(aggregateErrors(t.channels.err), time.Now(), force)
 }
 
 func (t *Tracer) flush() {
 	t.flushTraces()
 	t.flushServices()
-	t.flushErrs()
+	t.flushErrs(false)
+}
+
+// flushAndWait flushes all data and waits for the in-flight sends to
+// complete, logging all the pending errors.
+func (t *Tracer) flushAndWait() {
+	t.flush()
+	t.sendWG.Wait()
+	t.flushErrs(true)
+}
+
+// drain flushes all the data before the tracer stops. When the context can
+// be done, it gives up on the remaining traces once it is, including the ones
+// still being sent, and returns how many were abandoned.
+func (t *Tracer) drain(ctx context.Context) int {
+	if ctx.Done() == nil {
+		t.flushAndWait()
+		return 0
+	}
+
+	for len(t.channels.trace) > 0 && ctx.Err() == nil {
+		t.flushTraceBatch(t.getTraces(traceBatchSize))
+	}
+	t.flushServices()
+	abandoned := len(t.channels.trace)
+
+	done := make(chan struct{})
+	go func() {
+		t.sendWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		abandoned += int(atomic.LoadInt64(&t.inflightTraces))
+	}
+
+	if abandoned > 0 {
+		t.channels.pushErr(&errorFlushLostTraces{Nb: abandoned, Err: errStopDeadline})
+	}
+	t.flushErrs(true)
+	return abandoned
 }
 
 // ForceFlush forces a flush of data (traces and services) to the agent.
 // Flushes are done by a background task on a regular basis, so you never
 // need to call this manually, mostly useful for testing and debugging.
 func (t *Tracer) ForceFlush() {
-	t.forceFlushIn <- struct{}{}
-	<-t.forceFlushOut
+	t.Flush()
 }
 
-// Sample samples a span with the internal sampler.
+// Flush sends the finished traces and the services to the agent, and waits
+// until they are sent, so that short-lived processes, such as batch jobs or
+// AWS Lambda functions, don't lose the traces buffered between two periodic
+// flushes. Unlike Stop, the tracer keeps running. It returns immediately if
+// the tracer is stopped.
+func (t *Tracer) Flush() {
+	select {
+	case t.forceFlushIn <- struct{}{}:
+		<-t.forceFlushOut
+	case <-t.exit:
+	}
+}
+
+// Sample samples a span with the internal sampler. A lightweight root span
+// which ends up being sampled (e.g. after its trace ID has been replaced by a
+// distributed one) is turned into a regular root span.
 func (t *Tracer) Sample(span *Span) {
-	t.sampler.Sample(span)
+	t.SampleWithParams(span, SamplingParams{})
+}
+
+// SampleWithParams samples a span as Sample does, giving more context to the
+// sampler set with SetSampler, such as the baggage received from upstream.
+// A root span goes through the same decision as when it was created: rules,
+// limiter, then the rare sampler. A root span which was already kept by the
+// built-in samplers stays kept, so that the decision isn't undone nor charged
+// twice to the limiter, whereas the sampler set with SetSampler is always
+// given the new parameters.
+func (t *Tracer) SampleWithParams(span *Span, params SamplingParams) {
+	if span.parent != nil {
+		if cs, ok := t.sampler.(customSampler); ok {
+			params.Span = span
+			cs.sample(params)
+			return
+		}
+		t.sampler.Sample(span)
+		return
+	}
+	if !span.lightweight {
+		_, custom := t.sampler.(customSampler)
+		if span.Sampled && (!custom || rareKept(span)) {
+			return
+		}
+		// the trace is recorded for its errors or its priority only
+		span.Sampled = true
+		t.decide(span, params)
+		if !span.HasSamplingPriority() || agentRateApplied(span) {
+			span.setSamplingMechanism(samplingMechanism(span))
+		}
+		if span.Sampled {
+			span.buffer.keep()
+		}
+		return
+	}
+	// start over as a new span would, letting the sampler record its metrics
+	span.lightweight = false
+	span.Sampled = true
+	if t.sampleTrace(span, params) {
+		t.initRootSpan(span)
+	}
+}
+
+// SampleWithPriority samples the root span of a trace continued from another
+// process, which made the sampling decision given by the priority: the trace
+// is kept when the priority is positive and dropped otherwise, whatever the
+// sampler of this tracer would decide. It lets a trace dropped upstream stay
+// dropped downstream, rather than being sampled again on its own.
+func (t *Tracer) SampleWithPriority(span *Span, priority int) {
+	span.Sampled = priority > 0
+	if span.lightweight && span.parent == nil {
+		if !span.Sampled {
+			return
+		}
+		// the span was dropped by the sampler when it was created
+		span.lightweight = false
+		span.SetMetric(samplingPriorityKey, float64(priority))
+		t.initRootSpan(span)
+		return
+	}
+	span.SetMetric(samplingPriorityKey, float64(priority))
+	if span.Sampled {
+		// the trace may have been recorded for its errors only
+		span.buffer.keep()
+	}
 }
 
 // worker periodically flushes traces and services to the transport.
 func (t *Tracer) worker() {
 	defer t.exitWG.Done()
+	t.discoverFeatures()
+	t.activateStats()
 
-	flushTicker := time.NewTicker(flushInterval)
+	flushTicker := time.NewTicker(t.flushInterval)
 	defer flushTicker.Stop()
 
+	// the integrations register themselves in the init functions of their
+	// packages, which may run after the tracer is started: they are logged
+	// once the program has been running for a flush interval
+	var integrationsLogged bool
 	for {
 		select {
 		case <-flushTicker.C:
+			if !integrationsLogged {
+				t.logIntegrations()
+				integrationsLogged = true
+			}
+			t.watchdog.beat(time.Now())
+			t.reportPools()
+			t.pushSnapshots()
+			t.pushIncomplete()
 			t.flush()
+			t.flushStats(false)
 
 		case <-t.forceFlushIn:
-			t.flush()
+			t.flushStats(false)
+			t.flushAndWait()
 			t.forceFlushOut <- struct{}{} // caller blocked until this is done
 
 		case <-t.channels.traceFlush:
@@ -375,10 +974,12 @@ func (t *Tracer) worker() {
 			t.flushServices()
 
 		case <-t.channels.errFlush:
-			t.flushErrs()
+			t.flushErrs(false)
 
 		case <-t.exit:
-			t.flush()
+			t.flushStats(true)
+			t.SetDogStatsDAddr("")
+			t.abandoned = t.drain(t.stopCtx)
 			return
 		}
 	}
@@ -419,6 +1020,44 @@ func NewChildSpanWithContext(name string, ctx context.Context) (*Span, context.C
 	return DefaultTracer.NewChildSpanWithContext(name, ctx)
 }
 
+// NewFollowsFromSpan returns a new span for work which is caused by from but
+// which it doesn't wait for, using the default tracer. See
+// Tracer.NewFollowsFromSpan.
+func NewFollowsFromSpan(name string, from *Span) *Span {
+	return DefaultTracer.NewFollowsFromSpan(name, from)
+}
+
+// NewServiceSpan returns a child span reported under another service, using
+// the default tracer. See Tracer.NewServiceSpan.
+func NewServiceSpan(name, service string, parent *Span) *Span {
+	return DefaultTracer.NewServiceSpan(name, service, parent)
+}
+
+// Measure runs fn in a child span of the span contained in the given context,
+// using the default tracer. See Tracer.Measure.
+func Measure(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return DefaultTracer.Measure(ctx, name, fn)
+}
+
+// Flush sends the finished traces of the default tracer to the agent and
+// waits until they are sent, e.g. before a short-lived process exits. See
+// Tracer.Flush.
+func Flush() {
+	DefaultTracer.Flush()
+}
+
+// Enabled returns whether the default tracer is enabled and may keep some
+// traces when they start, see Tracer.SamplerKeepsTraces, so that expensive
+// tags, such as serialized request bodies, can be skipped when tracing is
+// off:
+//
+//	if tracer.Enabled() {
+//		span.SetMeta("request.body", string(body))
+//	}
+func Enabled() bool {
+	return DefaultTracer.SamplerKeepsTraces()
+}
+
 // Enable will enable the default tracer.
 func Enable() {
 	DefaultTracer.SetEnabled(true)
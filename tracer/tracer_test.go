@@ -2,6 +2,7 @@ package tracer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/DataDog/dd-trace-go/tracer/ext"
 	"net/http"
@@ -137,6 +138,45 @@ func TestNewSpanFromContext(t *testing.T) {
 
 }
 
+func TestTracerMeasure(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	ctx := parent.Context(context.Background())
+
+	// the span is passed to the function and finished with its error
+	var measured *Span
+	err := tracer.Measure(ctx, "thumbnail.resize", func(ctx context.Context) error {
+		measured, _ = SpanFromContext(ctx)
+		return errors.New("bad image")
+	})
+	assert.EqualError(err, "bad image")
+	assert.Equal("thumbnail.resize", measured.Name)
+	assert.Equal(parent.SpanID, measured.ParentID)
+	assert.Equal(int32(1), measured.Error)
+	assert.NotEqual(int64(0), measured.Duration)
+
+	// panics are recorded and propagated
+	assert.Panics(func() {
+		tracer.Measure(ctx, "thumbnail.crop", func(ctx context.Context) error {
+			measured, _ = SpanFromContext(ctx)
+			panic("out of bounds")
+		})
+	})
+	assert.Equal("panic: out of bounds", measured.GetMeta(ext.ErrorMsg))
+	assert.NotEqual(int64(0), measured.Duration)
+
+	assert.Nil(tracer.Measure(ctx, "thumbnail.store", func(context.Context) error { return nil }))
+	parent.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 4)
+}
+
 func TestNewSpanChild(t *testing.T) {
 	assert := assert.New(t)
 
@@ -155,6 +195,76 @@ func TestNewSpanChild(t *testing.T) {
 	assert.Equal(tracer, child.tracer)
 }
 
+func TestNewFollowsFromSpan(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	parent.SetSamplingPriority(ext.PriorityUserKeep)
+	span := tracer.NewFollowsFromSpan("cache.warmup", parent)
+	assert.Equal(parent.TraceID, span.TraceID)
+	assert.Equal(parent.SpanID, span.ParentID)
+	assert.Equal("pylons", span.Service)
+	assert.Equal(ext.RelationshipFollowsFrom, span.GetMeta(ext.SpanRelationship))
+	assert.Equal(ext.PriorityUserKeep, span.GetSamplingPriority())
+
+	// the parent is flushed without waiting for the span
+	parent.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal("pylons.request", traces[0][0].Name)
+
+	span.Finish()
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 1)
+	assert.Equal("cache.warmup", traces[0][0].Name)
+
+	// dropped traces stay dropped
+	tracer.SetSampleRate(0)
+	parent = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span = tracer.NewFollowsFromSpan("cache.warmup", parent)
+	assert.False(span.Sampled)
+	assert.True(span.lightweight)
+}
+
+func TestNewServiceSpan(t *testing.T) {
+	assert := assert.New(t)
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	parent := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	span := tracer.NewServiceSpan("cache.get", "pylons-cache", parent)
+	assert.Equal(parent.TraceID, span.TraceID)
+	assert.Equal(parent.SpanID, span.ParentID)
+	assert.Equal("pylons-cache", span.Service)
+	assert.Equal(1.0, span.Metrics[topLevelKey])
+
+	// the children of the span belong to its service
+	child := tracer.NewChildSpan("cache.load", span)
+	assert.Equal("pylons-cache", child.Service)
+	_, ok := child.Metrics[topLevelKey]
+	assert.False(ok)
+
+	// a span of the same service as its parent isn't an entry point
+	same := tracer.NewServiceSpan("cache.evict", "pylons-cache", span)
+	_, ok = same.Metrics[topLevelKey]
+	assert.False(ok)
+
+	same.Finish()
+	child.Finish()
+	span.Finish()
+	parent.Finish()
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 4)
+}
+
 func TestNewRootSpanHasPid(t *testing.T) {
 	assert := assert.New(t)
 
@@ -200,6 +310,38 @@ func TestTracerEnabledAgain(t *testing.T) {
 	assert.Len(tracer.channels.trace, 1)
 }
 
+func TestTracerSamplerKeepsTraces(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer := NewTracer()
+	defer tracer.Stop()
+	assert.True(tracer.SamplerKeepsTraces())
+
+	tracer.SetSampleRate(0)
+	assert.False(tracer.SamplerKeepsTraces())
+	tracer.SetKeepErrors(true)
+	assert.True(tracer.SamplerKeepsTraces())
+	tracer.SetKeepErrors(false)
+
+	// a rule may still keep traces
+	tracer.SetSamplingRules(SamplingRule{Service: "db", Rate: 0.5})
+	assert.True(tracer.SamplerKeepsTraces())
+	tracer.SetSamplingRules(SamplingRule{Service: "db", Rate: 0})
+	assert.False(tracer.SamplerKeepsTraces())
+
+	// the rare sampler keeps some traces
+	tracer.SetRareSampling(time.Minute)
+	assert.True(tracer.SamplerKeepsTraces())
+	tracer.SetRareSampling(0)
+
+	tracer.SetSampleRate(0.1)
+	assert.True(tracer.SamplerKeepsTraces())
+	tracer.SetEnabled(false)
+	assert.False(tracer.SamplerKeepsTraces())
+
+	assert.Equal(DefaultTracer.SamplerKeepsTraces(), Enabled())
+}
+
 func TestTracerSampler(t *testing.T) {
 	assert := assert.New(t)
 
@@ -239,6 +381,144 @@ func TestTracerEdgeSampler(t *testing.T) {
 	tracer1.Stop()
 }
 
+func TestTracerDroppedTraceLightweight(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetMeta("env", "staging")
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	orphan := tracer.NewChildSpan("redis.command", nil)
+	for _, span := range []*Span{root, child, orphan} {
+		assert.False(span.Sampled)
+		assert.True(span.lightweight)
+		assert.Nil(span.buffer)
+
+		// meta and metrics are not recorded on dropped traces
+		span.SetMeta("key", "value")
+		span.SetMetric("bytes", 1024)
+		span.SetError(errors.New("boom"))
+		assert.Nil(span.Meta)
+		assert.Equal(int32(1), span.Error)
+		_, ok := span.Metrics["bytes"]
+		assert.False(ok)
+	}
+	assert.Equal(root.SpanID, child.ParentID)
+	assert.Equal(root.TraceID, child.TraceID)
+
+	child.Finish()
+	root.Finish()
+	orphan.Finish()
+	assert.True(root.finished)
+	assert.NotEqual(int64(0), root.Duration)
+	assert.Len(tracer.channels.err, 0, "dropped spans should not report a missing buffer")
+
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestTracerSampleUpgradesLightweight(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.True(root.lightweight)
+
+	// resampling with a rate which keeps everything turns the span into a
+	// regular root span, as done by distributed tracing
+	tracer.SetSampleRate(1)
+	tracer.Sample(root)
+	assert.True(root.Sampled)
+	assert.False(root.lightweight)
+	assert.Equal(strconv.Itoa(os.Getpid()), root.GetMeta(ext.Pid))
+
+	child := tracer.NewChildSpan("redis.command", root)
+	assert.False(child.lightweight)
+	child.Finish()
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+}
+
+func TestTracerSampleWithPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+
+	// a trace dropped upstream stays dropped, though the sampler keeps it
+	dropped := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	tracer.SampleWithPriority(dropped, ext.PriorityAutoReject)
+	assert.False(dropped.Sampled)
+	assert.Equal(ext.PriorityAutoReject, dropped.GetSamplingPriority())
+	tracer.NewChildSpan("redis.command", dropped).Finish()
+	dropped.Finish()
+
+	// a trace kept upstream is kept, though the sampler drops it
+	tracer.SetSampleRate(0)
+	kept := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.True(kept.lightweight)
+	tracer.SampleWithPriority(kept, ext.PriorityUserKeep)
+	assert.True(kept.Sampled)
+	assert.False(kept.lightweight)
+	assert.Equal(ext.PriorityUserKeep, kept.GetSamplingPriority())
+	tracer.NewChildSpan("redis.command", kept).Finish()
+	kept.Finish()
+
+	// the dropped trace is sent for the agent to compute its statistics
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 2)
+	assert.Equal(dropped.TraceID, traces[0][0].TraceID)
+	assert.Equal(float64(ext.PriorityAutoReject), traces[0][0].Metrics[samplingPriorityKey])
+	assert.Len(traces[1], 2)
+	assert.Equal(kept.TraceID, traces[1][0].TraceID)
+
+	// a trace only recorded for its errors is kept as well
+	tracer.SetKeepErrors(true)
+	kept = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(kept.lightweight)
+	tracer.SampleWithPriority(kept, ext.PriorityUserKeep)
+	tracer.NewChildSpan("redis.command", kept).Finish()
+	kept.Finish()
+
+	tracer.ForceFlush()
+	traces = transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+}
+
+func TestTracerFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	tracer.Flush()
+	assert.Len(transport.Traces(), 1)
+
+	// flushing a stopped tracer doesn't block
+	tracer.Stop()
+	done := make(chan struct{})
+	go func() {
+		tracer.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Flush blocked on a stopped tracer")
+	}
+}
+
 func TestTracerConcurrent(t *testing.T) {
 	assert := assert.New(t)
 	tracer, transport := getTestTracer()
@@ -270,6 +550,204 @@ func TestTracerConcurrent(t *testing.T) {
 	assert.Len(traces[2], 1)
 }
 
+func TestTracerSamplingMechanism(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	span := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.Equal("-0", span.GetMeta(samplingDecisionKey))
+	child := tracer.NewChildSpan("redis.command", span)
+	assert.Equal("", child.GetMeta(samplingDecisionKey))
+
+	span.SetSamplingPriority(ext.PriorityUserKeep)
+	assert.Equal("-4", span.GetMeta(samplingDecisionKey))
+
+	tracer.SetSampleRate(0.99999)
+	for {
+		span = tracer.NewRootSpan("pylons.request", "pylons", "/")
+		if span.Sampled {
+			break
+		}
+	}
+	assert.Equal("-3", span.GetMeta(samplingDecisionKey))
+}
+
+func TestTracerKeepErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	defer tracer.Stop()
+	tracer.SetSampleRate(0)
+	tracer.SetKeepErrors(true)
+	assert.True(tracer.KeepErrorsEnabled())
+
+	// a dropped trace without errors isn't sent
+	root := tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.False(root.Sampled)
+	tracer.NewChildSpan("redis.command", root).Finish()
+	root.Finish()
+
+	// a dropped trace with an error deep down is kept
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	child := tracer.NewChildSpan("redis.command", root)
+	tracer.NewChildSpan("redis.dial", child).FinishWithErr(errors.New("connection refused"))
+	child.Finish()
+	root.Finish()
+
+	tracer.ForceFlush()
+	traces := transport.Traces()
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 3)
+	assert.Equal(root.SpanID, traces[0][0].SpanID)
+	assert.Equal(1.0, traces[0][0].Metrics[traceErrorCountKey])
+	_, found := traces[0][0].Metrics[sampleRateMetricKey]
+	assert.False(found)
+
+	// once disabled, dropped traces are lightweight again
+	tracer.SetKeepErrors(false)
+	root = tracer.NewRootSpan("pylons.request", "pylons", "/")
+	assert.True(root.lightweight)
+	root.FinishWithErr(errors.New("boom"))
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 0)
+}
+
+func TestTracerStopWithTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &blockingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		started:        make(chan struct{}, 10),
+		release:        make(chan struct{}),
+	}
+	defer close(transport.release)
+	tracer := NewTracerTransport(transport)
+
+	for i := 0; i < 3; i++ {
+		tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	}
+
+	// the payload is stuck in the transport when the deadline is reached
+	start := time.Now()
+	abandoned := tracer.StopWithTimeout(50 * time.Millisecond)
+	assert.Equal(3, abandoned)
+	assert.True(time.Since(start) < time.Second)
+	assert.Equal(uint64(1), tracer.Stats().Errors[ErrorCategoryTransport])
+}
+
+func TestTracerStopWithContext(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &blockingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		started:        make(chan struct{}, 10),
+		release:        make(chan struct{}),
+	}
+	defer close(transport.release)
+	tracer := NewTracerTransport(transport)
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	// cancelling the context gives up on the payload stuck in the transport
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-transport.started
+		cancel()
+	}()
+	assert.Equal(1, tracer.StopWithContext(ctx))
+}
+
+func TestTracerStopWithTimeoutDrained(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	for i := 0; i < 3; i++ {
+		tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	}
+
+	assert.Equal(0, tracer.StopWithTimeout(time.Second))
+	assert.Len(transport.Traces(), 3)
+}
+
+func TestTracerStopConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, transport := getTestTracer()
+	tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+
+	// e.g. on a signal and by a deferred Stop
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(0, tracer.StopWithTimeout(time.Second))
+		}()
+	}
+	wg.Wait()
+	tracer.Stop()
+	assert.Len(transport.Traces(), 1)
+}
+
+func TestTracerConcurrentSends(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &blockingTransport{
+		dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory},
+		started:        make(chan struct{}, 10),
+		release:        make(chan struct{}),
+	}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+	tracer.SetConcurrentSends(2)
+
+	// two payloads can be in-flight at the same time
+	tracer.sendTraces(getTestTrace(1, 1))
+	tracer.sendTraces(getTestTrace(1, 1))
+	<-transport.started
+	<-transport.started
+
+	// the third one has to wait for a slot
+	done := make(chan struct{})
+	go func() {
+		tracer.sendTraces(getTestTrace(1, 1))
+		close(done)
+	}()
+	select {
+	case <-done:
+		assert.Fail("send should block while the limit is reached")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(transport.release)
+	<-done
+
+	// a forced flush waits for all in-flight sends
+	tracer.ForceFlush()
+	assert.Len(transport.Traces(), 3)
+}
+
+func TestTracerBatchedFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &batchTransport{dummyTransport: dummyTransport{getEncoder: msgpackEncoderFactory}}
+	tracer := NewTracerTransport(transport)
+	defer tracer.Stop()
+
+	for i := 0; i < traceChanLen; i++ {
+		tracer.NewRootSpan("pylons.request", "pylons", "/").Finish()
+	}
+	tracer.ForceFlush()
+
+	// everything is flushed, in payloads of bounded size
+	assert.Len(transport.Traces(), traceChanLen)
+	assert.True(len(transport.batches) >= traceChanLen/traceBatchSize)
+	for _, n := range transport.batches {
+		assert.True(n <= traceBatchSize)
+	}
+}
+
 func TestTracerParentFinishBeforeChild(t *testing.T) {
 	assert := assert.New(t)
 	tracer, transport := getTestTracer()
@@ -646,3 +1124,48 @@ func (t *dummyTransport) Traces() [][]*Span {
 }
 
 func (t *dummyTransport) SetHeader(key, value string) {}
+
+// blockingTransport is a dummyTransport which signals each send of traces
+// and blocks it until released.
+type blockingTransport struct {
+	dummyTransport
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	t.started <- struct{}{}
+	<-t.release
+	return t.dummyTransport.SendTraces(traces)
+}
+
+// batchTransport is a dummyTransport which records the number of traces of
+// each payload it receives.
+type batchTransport struct {
+	dummyTransport
+	batches []int
+}
+
+func (t *batchTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
+	t.Lock()
+	t.batches = append(t.batches, len(traces))
+	t.Unlock()
+	return t.dummyTransport.SendTraces(traces)
+}
+
+func TestTracerPayloadCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer := NewTracerWithConfig(DefaultConfig())
+	defer tracer.Stop()
+	assert.False(tracer.PayloadCompressionEnabled())
+	tracer.SetPayloadCompression(true)
+	assert.True(tracer.PayloadCompressionEnabled())
+	assert.True(tracer.Config().PayloadCompression)
+
+	// custom transports are left untouched
+	tracer, _ = getTestTracer()
+	defer tracer.Stop()
+	tracer.SetPayloadCompression(true)
+	assert.False(tracer.PayloadCompressionEnabled())
+}
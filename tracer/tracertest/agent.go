@@ -0,0 +1,107 @@
+package tracertest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// Payload is a request received by an Agent.
+type Payload struct {
+	// Path is the path of the endpoint the payload was sent to, such as
+	// "/v0.3/traces".
+	Path string
+	// Header holds the headers of the request.
+	Header http.Header
+	// Body is the body of the request, byte for byte as it was sent.
+	Body []byte
+}
+
+// Traces decodes the traces of the payload, see DecodeRequest.
+func (p Payload) Traces() ([][]*tracer.Span, error) {
+	req, err := http.NewRequest("POST", p.Path, bytes.NewReader(p.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = p.Header
+	return DecodeRequest(req)
+}
+
+// Agent is a fake agent recording the payloads it receives, so that
+// transport-level tests can assert exactly what a tracer sends to the agent,
+// and decode the traces back into spans:
+//
+//	agent := tracertest.NewAgent()
+//	defer agent.Close()
+//	trc := tracer.NewTracerTransport(agent.Transport())
+//	...
+//	trc.Flush()
+//	payloads := agent.Payloads()
+type Agent struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	payloads []Payload
+}
+
+// NewAgent starts a fake agent, which has to be closed once the test is
+// over. It accepts the payloads of any endpoint but /info, which it doesn't
+// implement, so that the tracers use the default API.
+func NewAgent() *Agent {
+	a := &Agent{}
+	a.Server = httptest.NewServer(http.HandlerFunc(a.handle))
+	return a
+}
+
+func (a *Agent) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/info" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	a.payloads = append(a.payloads, Payload{Path: r.URL.Path, Header: r.Header, Body: body})
+	a.mu.Unlock()
+}
+
+// Transport returns a transport sending the payloads to the agent.
+func (a *Agent) Transport() tracer.Transport {
+	u, _ := url.Parse(a.URL)
+	return tracer.NewTransport(u.Hostname(), u.Port())
+}
+
+// Payloads returns the payloads received since the last call, in order.
+func (a *Agent) Payloads() []Payload {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	payloads := a.payloads
+	a.payloads = nil
+	return payloads
+}
+
+// Traces returns the traces of the trace payloads received since the last
+// call to Payloads or Traces, in order. The other payloads are dropped.
+func (a *Agent) Traces() ([][]*tracer.Span, error) {
+	var traces [][]*tracer.Span
+	for _, p := range a.Payloads() {
+		if !strings.HasSuffix(p.Path, "/traces") {
+			continue
+		}
+		t, err := p.Traces()
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, t...)
+	}
+	return traces, nil
+}
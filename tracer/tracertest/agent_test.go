@@ -0,0 +1,39 @@
+package tracertest
+
+import (
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgent(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := NewAgent()
+	defer agent.Close()
+	trc := tracer.NewTracerTransport(agent.Transport())
+	defer trc.Stop()
+
+	root := trc.NewRootSpan("http.request", "web", "/")
+	trc.NewChildSpan("sql.query", root).Finish()
+	root.Finish()
+	trc.Flush()
+
+	payloads := agent.Payloads()
+	assert.Len(payloads, 1)
+	assert.Equal("/v0.3/traces", payloads[0].Path)
+	assert.Equal("application/msgpack", payloads[0].Header.Get("Content-Type"))
+	assert.NotEmpty(payloads[0].Body)
+	traces, err := payloads[0].Traces()
+	assert.NoError(err)
+	assert.Len(traces, 1)
+	assert.Len(traces[0], 2)
+	assert.Equal(root.SpanID, traces[0][0].SpanID)
+	assert.Equal("web", traces[0][1].Service)
+
+	// the payloads are returned once
+	traces, err = agent.Traces()
+	assert.NoError(err)
+	assert.Len(traces, 0)
+}
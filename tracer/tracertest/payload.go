@@ -0,0 +1,131 @@
+package tracertest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/ugorji/go/codec"
+)
+
+// v05SpanFields is the number of fields of the spans of the v0.5 payloads.
+const v05SpanFields = 12
+
+// mh is the codec of the msgpack payloads.
+var mh codec.MsgpackHandle
+
+// DecodeRequest decodes the traces sent by the tracer in the given request
+// to the agent, whatever its API version, format and compression, so that
+// tests running a fake agent can assert what would have been sent to a real
+// one. The request body is consumed.
+func DecodeRequest(r *http.Request) ([][]*tracer.Span, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	}
+	payload, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/v0.5/traces"):
+		return decodePayloadV05(payload)
+	case r.Header.Get("Content-Type") == "application/json":
+		var traces [][]*tracer.Span
+		err := json.NewDecoder(bytes.NewReader(payload)).Decode(&traces)
+		return traces, err
+	default:
+		var traces [][]*tracer.Span
+		err := codec.NewDecoderBytes(payload, &mh).Decode(&traces)
+		return traces, err
+	}
+}
+
+// errBadV05Payload is returned when a payload doesn't follow the v0.5
+// format.
+var errBadV05Payload = errors.New("malformed v0.5 payload")
+
+// decodePayloadV05 decodes a payload of the v0.5 format, see tracer.EncodingV05.
+func decodePayloadV05(payload []byte) (traces [][]*tracer.Span, err error) {
+	defer func() {
+		// the payload doesn't have the expected types
+		if r := recover(); r != nil {
+			traces, err = nil, fmt.Errorf("%v: %v", errBadV05Payload, r)
+		}
+	}()
+	var raw []interface{}
+	if err := codec.NewDecoderBytes(payload, &mh).Decode(&raw); err != nil {
+		return nil, err
+	}
+	if len(raw) != 2 {
+		return nil, errBadV05Payload
+	}
+	var table []string
+	for _, s := range raw[0].([]interface{}) {
+		table = append(table, string(s.([]byte)))
+	}
+	if len(table) == 0 || table[0] != "" {
+		return nil, errBadV05Payload
+	}
+	str := func(v interface{}) string { return table[toUint(v)] }
+
+	for _, rawTrace := range raw[1].([]interface{}) {
+		var trace []*tracer.Span
+		for _, rawSpan := range rawTrace.([]interface{}) {
+			fields := rawSpan.([]interface{})
+			if len(fields) != v05SpanFields {
+				return nil, errBadV05Payload
+			}
+			span := &tracer.Span{
+				Service:  str(fields[0]),
+				Name:     str(fields[1]),
+				Resource: str(fields[2]),
+				TraceID:  toUint(fields[3]),
+				SpanID:   toUint(fields[4]),
+				ParentID: toUint(fields[5]),
+				Start:    toInt(fields[6]),
+				Duration: toInt(fields[7]),
+				Error:    int32(toInt(fields[8])),
+				Meta:     make(map[string]string),
+				Metrics:  make(map[string]float64),
+				Type:     str(fields[11]),
+			}
+			for k, v := range fields[9].(map[interface{}]interface{}) {
+				span.Meta[str(k)] = str(v)
+			}
+			for k, v := range fields[10].(map[interface{}]interface{}) {
+				span.Metrics[str(k)] = v.(float64)
+			}
+			trace = append(trace, span)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// toUint returns the given decoded msgpack integer.
+func toUint(v interface{}) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	}
+	panic("not an integer")
+}
+
+func toInt(v interface{}) int64 {
+	return int64(toUint(v))
+}
@@ -0,0 +1,89 @@
+package tracertest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	var traces [][]*tracer.Span
+	for i := 0; i < 2; i++ {
+		var trace []*tracer.Span
+		for j := 0; j < 3; j++ {
+			span := tracer.NewSpan("pylons.request", "pylons", "/", uint64(i*3+j+1), uint64(i+1), 0, nil)
+			span.Meta = map[string]string{"http.method": "GET"}
+			trace = append(trace, span)
+		}
+		traces = append(traces, trace)
+	}
+	for _, tt := range []struct {
+		path     string
+		encoding tracer.PayloadEncoding
+		compress bool
+	}{
+		{"/v0.3/traces", tracer.EncodingMsgpack, false},
+		{"/v0.3/traces", tracer.EncodingMsgpack, true},
+		{"/v0.2/traces", tracer.EncodingJSON, false},
+		{"/v0.5/traces", tracer.EncodingV05, false},
+		{"/v0.5/traces", tracer.EncodingV05, true},
+	} {
+		encoder := tt.encoding.NewEncoder()
+		assert.NoError(encoder.EncodeTraces(traces))
+		body := &bytes.Buffer{}
+		if tt.compress {
+			zw := gzip.NewWriter(body)
+			_, err := io.Copy(zw, encoder)
+			assert.NoError(err)
+			assert.NoError(zw.Close())
+		} else {
+			_, err := body.ReadFrom(encoder)
+			assert.NoError(err)
+		}
+		req, err := http.NewRequest("POST", "http://localhost:8126"+tt.path, body)
+		assert.NoError(err)
+		req.Header.Set("Content-Type", encoder.ContentType())
+		if tt.compress {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		decoded, err := DecodeRequest(req)
+		assert.NoError(err, tt.path)
+		assert.Len(decoded, 2)
+		for i, trace := range decoded {
+			assert.Len(trace, 3)
+			for j, span := range trace {
+				assert.Equal(traces[i][j].SpanID, span.SpanID)
+				assert.Equal(traces[i][j].Name, span.Name)
+				assert.Equal(traces[i][j].Meta, span.Meta)
+			}
+		}
+	}
+}
+
+func TestDecodeRequestMalformed(t *testing.T) {
+	assert := assert.New(t)
+
+	for path, body := range map[string][]byte{
+		"/v0.3/traces": []byte("garbage"),
+		"/v0.5/traces": {0x92, 0x90, 0x90}, // [[], []]
+	} {
+		req, err := http.NewRequest("POST", path, bytes.NewReader(body))
+		assert.NoError(err)
+		_, err = DecodeRequest(req)
+		assert.Error(err, path)
+	}
+
+	// v0.5 payloads of unexpected types
+	req, err := http.NewRequest("POST", "/v0.5/traces", bytes.NewReader([]byte{0x92, 0x91, 0xa0, 0x91, 0x01}))
+	assert.NoError(err)
+	_, err = DecodeRequest(req)
+	assert.Error(err)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/DataDog/dd-trace-go/tracer"
@@ -45,19 +46,27 @@ func GetTestTracer() (*tracer.Tracer, *DummyTransport) {
 type DummyTransport struct {
 	traces   [][]*tracer.Span
 	services map[string]tracer.Service
+
+	sync.Mutex // payloads may be sent concurrently
 }
 
 func (t *DummyTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	t.Lock()
 	t.traces = append(t.traces, traces...)
+	t.Unlock()
 	return nil, nil
 }
 
 func (t *DummyTransport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	t.Lock()
 	t.services = services
+	t.Unlock()
 	return nil, nil
 }
 
 func (t *DummyTransport) Traces() [][]*tracer.Span {
+	t.Lock()
+	defer t.Unlock()
 	traces := t.traces
 	t.traces = nil
 	return traces
@@ -1,12 +1,15 @@
 package tracer
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/DataDog/dd-trace-go/tracer/ext"
@@ -17,6 +20,7 @@ const (
 	defaultPort        = "8126"
 	defaultHTTPTimeout = time.Second             // defines the current timeout before giving up with the send process
 	traceCountHeader   = "X-Datadog-Trace-Count" // header containing the number of traces in the payload
+	maxPayloadSize     = 10 * 1024 * 1024        // the maximum size of a request body accepted by the agent
 )
 
 // Transport is an interface for span submission to the agent.
@@ -43,27 +47,64 @@ func NewTransport(hostname, port string) Transport {
 	return newHTTPTransport(hostname, port)
 }
 
+// NewTransportWithHTTPClient returns a Transport sending traces to a trace
+// agent running on the given hostname and port, as NewTransport does, with
+// the given HTTP client rather than the default one, so that the payloads can
+// go through a proxy, a custom TLS setup or a service mesh. The client should
+// have a timeout, and mustn't be traced itself.
+func NewTransportWithHTTPClient(hostname, port string, client *http.Client) Transport {
+	t := NewTransport(hostname, port).(*httpTransport)
+	if client != nil {
+		t.client = client
+	}
+	return t
+}
+
 // newDefaultTransport return a default transport for this tracing client
 func newDefaultTransport() Transport {
 	return newHTTPTransport(defaultHostname, defaultPort)
 }
 
 type httpTransport struct {
+	baseURL           string            // the URL of the agent, the endpoints are relative to
 	traceURL          string            // the delivery URL for traces
 	legacyTraceURL    string            // the legacy delivery URL for traces
+	v03TraceURL       string            // the delivery URL for traces in the default msgpack format
+	v05TraceURL       string            // the delivery URL for traces in the v0.5 format
 	serviceURL        string            // the delivery URL for services
 	legacyServiceURL  string            // the legacy delivery URL for services
+	infoURL           string            // the URL describing the features of the agent
+	statsURL          string            // the delivery URL for the statistics computed by the tracer
 	client            *http.Client      // the HTTP client used in the POST
 	headers           map[string]string // the Transport headers
 	compatibilityMode bool              // the Agent targets a legacy API for compatibility reasons
+	maxPayloadSize    int               // payloads above this size are split in several requests
+	compression       bool              // trace payloads are gzipped when the agent supports it, until it rejects them
+	features          AgentFeatures     // the features of the agent, once discovered
 
-	// [WARNING] We tried to reuse encoders thanks to a pool, but that led us to having race conditions.
-	// Indeed, when we send the encoder as the request body, the persistConn.writeLoop() goroutine
-	// can theoretically read the underlying buffer whereas the encoder has been returned to the pool.
-	// Since the underlying bytes.Buffer is not thread safe, this can make the app panicking.
-	// since this method will later on spawn a goroutine referencing this buffer.
-	// That's why we prefer the less performant yet SAFE implementation of allocating a new encoder every time we flush.
+	// onRates is called with the sample rates by service returned by the
+	// agent in its responses to the trace payloads, if set.
+	onRates func(rates map[string]float64)
+
+	// getEncoder returns the encoder used for the next payload. Encoders are
+	// pooled and given as the request body, which the HTTP transport closes
+	// once it is done with it, even on errors: this is when the encoder goes
+	// back to the pool. Reading and closing it are synchronized, since the
+	// persistConn.writeLoop() goroutine may still be reading the body when
+	// the request is over.
 	getEncoder encoderFactory
+
+	// encoding is the encoding set with Tracer.SetPayloadEncoding, nil when
+	// the encoder depends on the features of the agent.
+	encoding *PayloadEncoding
+	// v05 is true when the agent advertises the v0.5 API and hasn't
+	// rejected it: the traces are sent in that format, unless an encoding
+	// is set.
+	v05 bool
+
+	// mu guards the URLs, headers, encoder, encoding, compression, features
+	// and compatibility mode, as payloads may be sent concurrently.
+	mu sync.RWMutex
 }
 
 // newHTTPTransport returns an httpTransport for the given endpoint
@@ -77,10 +118,15 @@ func newHTTPTransport(hostname, port string) *httpTransport {
 	}
 
 	return &httpTransport{
+		baseURL:          fmt.Sprintf("http://%s:%s", hostname, port),
 		traceURL:         fmt.Sprintf("http://%s:%s/v0.3/traces", hostname, port),
 		legacyTraceURL:   fmt.Sprintf("http://%s:%s/v0.2/traces", hostname, port),
+		v03TraceURL:      fmt.Sprintf("http://%s:%s/v0.3/traces", hostname, port),
+		v05TraceURL:      fmt.Sprintf("http://%s:%s%s", hostname, port, v05TracesPath),
 		serviceURL:       fmt.Sprintf("http://%s:%s/v0.3/services", hostname, port),
 		legacyServiceURL: fmt.Sprintf("http://%s:%s/v0.2/services", hostname, port),
+		infoURL:          fmt.Sprintf("http://%s:%s%s", hostname, port, infoPath),
+		statsURL:         fmt.Sprintf("http://%s:%s%s", hostname, port, statsPath),
 		getEncoder:       msgpackEncoderFactory,
 		client: &http.Client{
 			// We copy the transport to avoid using the default one, as it might be
@@ -102,29 +148,69 @@ func newHTTPTransport(hostname, port string) *httpTransport {
 		},
 		headers:           defaultHeaders,
 		compatibilityMode: false,
+		maxPayloadSize:    maxPayloadSize,
 	}
 }
 
 func (t *httpTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
-	if t.traceURL == "" {
+	return t.sendTraces(traces, true)
+}
+
+// probe sends an empty trace payload to check the connection to the agent.
+// Unlike SendTraces, it never changes the API, the encoding or the
+// compression when the agent rejects the payload, as a probe failing must not
+// alter how the traces are sent.
+func (t *httpTransport) probe() (*http.Response, error) {
+	return t.sendTraces([][]*Span{}, false)
+}
+
+// sendTraces sends the traces to the agent. When adapt is true, it falls back
+// to the API, encoding and compression the agent supports if it rejects the
+// payload.
+func (t *httpTransport) sendTraces(traces [][]*Span, adapt bool) (*http.Response, error) {
+	t.mu.RLock()
+	traceURL, getEncoder, compatibilityMode := t.traceURL, t.getEncoder, t.compatibilityMode
+	// the payloads are only compressed for the agents advertising it
+	compression := t.compression && t.features.PayloadCompression
+	t.mu.RUnlock()
+
+	if traceURL == "" {
 		return nil, errors.New("provided an empty URL, giving up")
 	}
 
-	encoder := t.getEncoder()
+	encoder := getEncoder()
 
 	// encode the spans and return the error if any
 	err := encoder.EncodeTraces(traces)
 	if err != nil {
-		return nil, err
+		closeEncoder(encoder)
+		return nil, &errorEncoding{Err: err}
+	}
+	if encoderLen(encoder) > t.maxPayloadSize && len(traces) > 1 {
+		// the agent would reject the whole payload, split it instead
+		closeEncoder(encoder)
+		return t.sendTracesChunks(traces)
 	}
 
-	// prepare the client and send the payload
-	req, _ := http.NewRequest("POST", t.traceURL, encoder)
-	for header, value := range t.headers {
-		req.Header.Set(header, value)
+	// prepare the client and stream the payload straight from the encoder,
+	// unless it has to be compressed first
+	var body io.Reader = encoder
+	length, contentType := encoderLen(encoder), encoder.ContentType()
+	if compression {
+		buf, err := gzipPayload(encoder)
+		if err != nil {
+			return nil, &errorEncoding{Err: err}
+		}
+		body, length = buf, buf.Len()
 	}
+	req, _ := http.NewRequest("POST", traceURL, body)
+	req.ContentLength = int64(length)
+	t.setHeaders(req)
 	req.Header.Set(traceCountHeader, strconv.Itoa(len(traces)))
-	req.Header.Set("Content-Type", encoder.ContentType())
+	req.Header.Set("Content-Type", contentType)
+	if compression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 	response, err := t.client.Do(req)
 
 	// if we have an error, return an empty Response to protect against nil pointer dereference
@@ -133,9 +219,24 @@ func (t *httpTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
 	}
 	defer response.Body.Close()
 
+	// agents which can't decompress payloads reject them: stop compressing
+	if adapt && compression && response.StatusCode == 415 {
+		logf(logWarn, "transport", "the agent at '%s' doesn't accept compressed payloads; disabling compression\n", traceURL)
+		t.setCompression(false)
+		return t.SendTraces(traces)
+	}
+
+	// the v0.5 API is only used when the agent advertises it or when it is
+	// set explicitly, fall back to the default one if it is rejected
+	if adapt && (response.StatusCode == 404 || response.StatusCode == 415) && traceURL == t.v05TraceURL && !compatibilityMode {
+		logf(logWarn, "transport", "calling the endpoint '%s' but received %d; falling back to the default API\n", traceURL, response.StatusCode)
+		t.rejectV05()
+		return t.SendTraces(traces)
+	}
+
 	// if we got a 404 we should downgrade the API to a stable version (at most once)
-	if (response.StatusCode == 404 || response.StatusCode == 415) && !t.compatibilityMode {
-		log.Printf("calling the endpoint '%s' but received %d; downgrading the API\n", t.traceURL, response.StatusCode)
+	if adapt && (response.StatusCode == 404 || response.StatusCode == 415) && !compatibilityMode {
+		logf(logWarn, "transport", "calling the endpoint '%s' but received %d; downgrading the API\n", traceURL, response.StatusCode)
 		t.apiDowngrade()
 		return t.SendTraces(traces)
 	}
@@ -144,28 +245,73 @@ func (t *httpTransport) SendTraces(traces [][]*Span) (*http.Response, error) {
 		return response, fmt.Errorf("SendTraces expected response code 200, received %v", sc)
 	}
 
+	t.mu.RLock()
+	onRates := t.onRates
+	t.mu.RUnlock()
+	if onRates != nil {
+		if rates := readRatesResponse(response.Body); rates != nil {
+			onRates(rates)
+		}
+	}
+
 	return response, err
 }
 
+// sendTracesChunks sends the given traces in two halves, each of them being
+// split further if it's still above the maximum payload size. It returns the
+// first error encountered, if any.
+func (t *httpTransport) sendTracesChunks(traces [][]*Span) (*http.Response, error) {
+	half := len(traces) / 2
+	var (
+		response *http.Response
+		failed   [][]*Span
+		err      error
+	)
+	for _, chunk := range [][][]*Span{traces[:half], traces[half:]} {
+		r, e := t.SendTraces(chunk)
+		if e == nil {
+			if response == nil {
+				response = r
+			}
+			continue
+		}
+		response, err = r, e
+		if ce, ok := e.(*errorChunks); ok {
+			failed, err = append(failed, ce.Traces...), ce.Err
+		} else {
+			failed = append(failed, chunk...)
+		}
+	}
+	if err == nil || len(failed) == len(traces) {
+		return response, err
+	}
+	return response, &errorChunks{Traces: failed, Err: err}
+}
+
 func (t *httpTransport) SendServices(services map[string]Service) (*http.Response, error) {
-	if t.serviceURL == "" {
+	t.mu.RLock()
+	serviceURL, getEncoder, compatibilityMode := t.serviceURL, t.getEncoder, t.compatibilityMode
+	t.mu.RUnlock()
+
+	if serviceURL == "" {
 		return nil, errors.New("provided an empty URL, giving up")
 	}
 
-	encoder := t.getEncoder()
+	encoder := getEncoder()
 
 	if err := encoder.EncodeServices(services); err != nil {
-		return nil, err
+		closeEncoder(encoder)
+		return nil, &errorEncoding{Err: err}
 	}
 
 	// Send it
-	req, err := http.NewRequest("POST", t.serviceURL, encoder)
+	req, err := http.NewRequest("POST", serviceURL, encoder)
 	if err != nil {
+		closeEncoder(encoder)
 		return nil, fmt.Errorf("cannot create http request: %v", err)
 	}
-	for header, value := range t.headers {
-		req.Header.Set(header, value)
-	}
+	req.ContentLength = int64(encoderLen(encoder))
+	t.setHeaders(req)
 	req.Header.Set("Content-Type", encoder.ContentType())
 
 	response, err := t.client.Do(req)
@@ -175,8 +321,8 @@ func (t *httpTransport) SendServices(services map[string]Service) (*http.Respons
 	defer response.Body.Close()
 
 	// Downgrade if necessary
-	if (response.StatusCode == 404 || response.StatusCode == 415) && !t.compatibilityMode {
-		log.Printf("calling the endpoint '%s' but received %d; downgrading the API\n", t.traceURL, response.StatusCode)
+	if (response.StatusCode == 404 || response.StatusCode == 415) && !compatibilityMode {
+		logf(logWarn, "transport", "calling the endpoint '%s' but received %d; downgrading the API\n", serviceURL, response.StatusCode)
 		t.apiDowngrade()
 		return t.SendServices(services)
 	}
@@ -188,15 +334,144 @@ func (t *httpTransport) SendServices(services map[string]Service) (*http.Respons
 	return response, err
 }
 
+// gzipWriterPool holds the gzip writers used to compress payloads, which
+// are expensive to allocate.
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// gzipPayload compresses the payload held by the given encoder, which is
+// closed, and returns the compressed payload.
+func gzipPayload(encoder Encoder) (*bytes.Buffer, error) {
+	defer closeEncoder(encoder)
+	buf := bytes.NewBuffer(make([]byte, 0, encoderLen(encoder)/4))
+	zw := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(zw)
+	zw.Reset(buf)
+	if _, err := io.Copy(zw, encoder); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// setCompression enables or disables the gzip compression of the trace
+// payloads, which applies once the agent has advertised it supports them. It
+// is turned off on its own if the agent rejects compressed payloads anyway.
+func (t *httpTransport) setCompression(enabled bool) {
+	t.mu.Lock()
+	t.compression = enabled
+	t.mu.Unlock()
+}
+
+// compressionEnabled reports whether the trace payloads are compressed.
+func (t *httpTransport) compressionEnabled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.compression
+}
+
+// setRatesHandler sets the function called with the sample rates by service
+// returned by the agent.
+func (t *httpTransport) setRatesHandler(onRates func(rates map[string]float64)) {
+	t.mu.Lock()
+	t.onRates = onRates
+	t.mu.Unlock()
+}
+
+// endpoint returns the URL traces are currently sent to.
+func (t *httpTransport) endpoint() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.traceURL
+}
+
 // SetHeader sets the internal header for the httpTransport
 func (t *httpTransport) SetHeader(key, value string) {
+	t.mu.Lock()
 	t.headers[key] = value
+	t.mu.Unlock()
+}
+
+// setHeaders sets the internal headers on the given request.
+func (t *httpTransport) setHeaders(req *http.Request) {
+	t.mu.RLock()
+	for header, value := range t.headers {
+		req.Header.Set(header, value)
+	}
+	t.mu.RUnlock()
 }
 
 // changeEncoder switches the encoder so that a different API with different
 // format can be targeted, preventing failures because of outdated agents
 func (t *httpTransport) changeEncoder(encoderFactory encoderFactory) {
+	t.mu.Lock()
 	t.getEncoder = encoderFactory
+	t.mu.Unlock()
+}
+
+// useV05 switches the traces to the v0.5 API and format, or back to the
+// default ones. The switch is deferred while in compatibility mode or when an
+// encoding is set.
+func (t *httpTransport) useV05(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.v05 = enabled
+	if t.compatibilityMode || t.encoding != nil {
+		return
+	}
+	t.useDefaultEncoding()
+}
+
+// rejectV05 switches the traces back to the default API and format once the
+// agent rejected the v0.5 ones, even if the encoding was set explicitly.
+func (t *httpTransport) rejectV05() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.v05 = false
+	if e := t.encoding; e != nil && t.baseURL+e.Path == t.v05TraceURL {
+		t.encoding = nil
+	}
+	if t.compatibilityMode || t.encoding != nil {
+		return
+	}
+	t.useDefaultEncoding()
+}
+
+// useDefaultEncoding sends the traces in the v0.5 format if the agent
+// supports it and in the v0.3 one otherwise: the transport must be locked.
+func (t *httpTransport) useDefaultEncoding() {
+	if t.v05 {
+		t.traceURL, t.getEncoder = t.v05TraceURL, msgpackV05EncoderFactory
+	} else {
+		t.traceURL, t.getEncoder = t.v03TraceURL, msgpackEncoderFactory
+	}
+}
+
+// setEncoding sends the traces to the endpoint of the given encoding with its
+// encoder, or with the default ones when it is nil.
+func (t *httpTransport) setEncoding(e *PayloadEncoding) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.encoding = e
+	switch {
+	case e != nil:
+		t.traceURL, t.getEncoder = t.baseURL+e.Path, e.NewEncoder
+	case !t.compatibilityMode:
+		t.useDefaultEncoding()
+	}
+}
+
+// encodingName returns the name of the encoding set with setEncoding, if any.
+func (t *httpTransport) encodingName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.encoding == nil {
+		return ""
+	}
+	return t.encoding.Name
 }
 
 // apiDowngrade downgrades the used encoder and API level. This method must fallback to a safe
@@ -204,8 +479,13 @@ func (t *httpTransport) changeEncoder(encoderFactory encoderFactory) {
 // ensures that the compatibility mode is activated so that the downgrade will be
 // executed only once.
 func (t *httpTransport) apiDowngrade() {
+	t.mu.Lock()
 	t.compatibilityMode = true
+	// the encoding set, if any, isn't used anymore
+	t.encoding = nil
+	t.v05 = false
 	t.traceURL = t.legacyTraceURL
 	t.serviceURL = t.legacyServiceURL
+	t.mu.Unlock()
 	t.changeEncoder(jsonEncoderFactory)
 }
@@ -1,14 +1,19 @@
 package tracer
 
 import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
 )
 
 // getTestSpan returns a Span with different fields set
@@ -200,3 +205,251 @@ func TestTraceCountHeader(t *testing.T) {
 
 	receiver.Close()
 }
+
+// countingRoundTripper counts the requests it passes to the default transport.
+type countingRoundTripper struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.hosts = append(rt.hosts, req.URL.Host)
+	rt.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTransportWithHTTPClient(t *testing.T) {
+	assert := assert.New(t)
+
+	receiver := mockDatadogAPINewServer(t)
+	defer receiver.Close()
+	u, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+
+	rt := new(countingRoundTripper)
+	transport := NewTransportWithHTTPClient(u.Hostname(), u.Port(), &http.Client{Transport: rt})
+	response, err := transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{u.Host}, rt.hosts)
+
+	// a nil client keeps the default one
+	transport = NewTransportWithHTTPClient(u.Hostname(), u.Port(), nil)
+	assert.NotNil(transport.(*httpTransport).client)
+}
+
+func TestTransportChunkedPayload(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu     sync.Mutex
+		counts []int
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var traces [][]*Span
+		err := codec.NewDecoder(r.Body, &mh).Decode(&traces)
+		assert.NoError(err)
+		count, err := strconv.Atoi(r.Header.Get(traceCountHeader))
+		assert.NoError(err)
+		assert.Equal(len(traces), count)
+
+		mu.Lock()
+		counts = append(counts, count)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+
+	// fits in a single request
+	response, err := transport.SendTraces(getTestTrace(10, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]int{10}, counts)
+
+	// a payload above the limit is split in several well-formed requests
+	encoder := newMsgpackEncoder()
+	assert.NoError(encoder.EncodeTraces(getTestTrace(1, 1)))
+	transport.maxPayloadSize = 3 * encoder.Len()
+	counts = nil
+
+	response, err = transport.SendTraces(getTestTrace(10, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.True(len(counts) > 1, "payload should have been split")
+	total := 0
+	for _, count := range counts {
+		assert.True(count <= 3)
+		total += count
+	}
+	assert.Equal(10, total)
+}
+
+func TestTransportChunksFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu     sync.Mutex
+		counts []int
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, err := strconv.Atoi(r.Header.Get(traceCountHeader))
+		assert.NoError(err)
+		mu.Lock()
+		defer mu.Unlock()
+		counts = append(counts, count)
+		if len(counts) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+	encoder := newMsgpackEncoder()
+	assert.NoError(encoder.EncodeTraces(getTestTrace(1, 1)))
+	transport.maxPayloadSize = 3 * encoder.Len()
+
+	// only the traces of the first chunk are reported as not sent, along
+	// with its response
+	response, err := transport.SendTraces(getTestTrace(10, 1))
+	assert.Equal(500, response.StatusCode)
+	if assert.IsType(&errorChunks{}, err) {
+		assert.Len(err.(*errorChunks).Traces, counts[0])
+	}
+	assert.True(len(counts) > 2)
+}
+
+// streamingEncoder is an Encoder which isn't a BufferedEncoder.
+type streamingEncoder struct {
+	Encoder
+}
+
+func TestTransportStreamingEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	var transferEncodings []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		transferEncodings = append(transferEncodings, r.TransferEncoding...)
+		var traces [][]*Span
+		assert.NoError(codec.NewDecoder(r.Body, &mh).Decode(&traces))
+		assert.Len(traces, 10)
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+	transport.getEncoder = func() Encoder { return streamingEncoder{newMsgpackEncoder()} }
+	transport.maxPayloadSize = 1
+
+	// the payload is streamed as is, without being split
+	response, err := transport.SendTraces(getTestTrace(10, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{"chunked"}, transferEncodings)
+}
+
+func TestTransportContentLength(t *testing.T) {
+	assert := assert.New(t)
+
+	var lengths []int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(err)
+		// the payload is sent with a known length rather than chunked
+		assert.Len(r.TransferEncoding, 0)
+		assert.Equal(int64(len(body)), r.ContentLength)
+		lengths = append(lengths, r.ContentLength)
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+
+	_, err = transport.SendTraces(getTestTrace(2, 2))
+	assert.NoError(err)
+	_, err = transport.SendServices(getTestServices())
+	assert.NoError(err)
+	assert.Len(lengths, 2)
+	for _, l := range lengths {
+		assert.True(l > 0)
+	}
+}
+
+func TestTransportCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu        sync.Mutex
+		encodings []string
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		mu.Lock()
+		encodings = append(encodings, encoding)
+		mu.Unlock()
+		var body io.Reader = r.Body
+		if encoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			assert.NoError(err)
+			body = zr
+		}
+		var traces [][]*Span
+		assert.NoError(codec.NewDecoder(body, &mh).Decode(&traces))
+		assert.Len(traces, 3)
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+	transport.setCompression(true)
+
+	// not until the agent advertises it
+	response, err := transport.SendTraces(getTestTrace(3, 2))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{""}, encodings)
+	assert.True(transport.compressionEnabled())
+
+	transport.features = AgentFeatures{Discovered: true, PayloadCompression: true}
+	response, err = transport.SendTraces(getTestTrace(3, 2))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{"", "gzip"}, encodings)
+	assert.True(transport.compressionEnabled())
+}
+
+func TestTransportCompressionUnsupported(t *testing.T) {
+	assert := assert.New(t)
+
+	var encodings []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		if encoding != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer receiver.Close()
+
+	parsedURL, err := url.Parse(receiver.URL)
+	assert.NoError(err)
+	transport := newHTTPTransport(parsedURL.Hostname(), parsedURL.Port())
+	transport.setCompression(true)
+	transport.features = AgentFeatures{Discovered: true, PayloadCompression: true}
+
+	// the payload is sent again uncompressed, without downgrading the API
+	response, err := transport.SendTraces(getTestTrace(1, 1))
+	assert.NoError(err)
+	assert.Equal(200, response.StatusCode)
+	assert.Equal([]string{"gzip", ""}, encodings)
+	assert.False(transport.compressionEnabled())
+	assert.False(transport.compatibilityMode)
+}
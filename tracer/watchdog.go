@@ -0,0 +1,83 @@
+package tracer
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// watchdogStallIntervals is the number of flush intervals the worker can
+	// go without servicing its ticker before it is reported as stalled.
+	watchdogStallIntervals = 5
+	// watchdogStackSize caps the size of the goroutine dump logged when the
+	// worker is stalled.
+	watchdogStackSize = 64 * 1024
+)
+
+// workerWatchdog detects a stalled worker goroutine. The worker records a
+// heartbeat each time it wakes up, and the watchdog periodically checks that
+// the last one is recent enough.
+type workerWatchdog struct {
+	// heartbeat is the time of the last worker wake up, in nanoseconds since
+	// epoch. It should only be accessed atomically.
+	heartbeat int64
+	// stalls is the number of stalls detected. It should only be accessed atomically.
+	stalls uint64
+
+	timeout time.Duration
+	stalled bool // only accessed by the watchdog goroutine
+}
+
+func newWorkerWatchdog(interval time.Duration) *workerWatchdog {
+	w := &workerWatchdog{timeout: watchdogStallIntervals * interval}
+	w.beat(time.Now())
+	return w
+}
+
+// beat records that the worker is alive at the given time.
+func (w *workerWatchdog) beat(now time.Time) {
+	atomic.StoreInt64(&w.heartbeat, now.UnixNano())
+}
+
+// check reports whether the worker is stalled at the given time. A stall is
+// logged, along with a dump of all goroutines, only once until the worker
+// recovers.
+func (w *workerWatchdog) check(now time.Time) bool {
+	last := time.Unix(0, atomic.LoadInt64(&w.heartbeat))
+	if now.Sub(last) <= w.timeout {
+		w.stalled = false
+		return false
+	}
+	if !w.stalled {
+		w.stalled = true
+		atomic.AddUint64(&w.stalls, 1)
+		buf := make([]byte, watchdogStackSize)
+		buf = buf[:runtime.Stack(buf, true)]
+		logf(logError, "watchdog", "worker stalled, last flush %s ago, traces are being dropped; goroutines:\n%s",
+			now.Sub(last), buf)
+	}
+	return true
+}
+
+// stallCount returns the number of stalls detected so far.
+func (w *workerWatchdog) stallCount() uint64 {
+	return atomic.LoadUint64(&w.stalls)
+}
+
+// watch checks the worker at each interval, until the tracer exits.
+func (t *Tracer) watch(interval time.Duration) {
+	defer t.exitWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.watchdog.check(now)
+		case <-t.exit:
+			return
+		}
+	}
+}
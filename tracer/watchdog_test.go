@@ -0,0 +1,52 @@
+package tracer
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdogStall(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	start := time.Now()
+	w := newWorkerWatchdog(time.Second)
+	w.beat(start)
+
+	assert.False(w.check(start.Add(watchdogStallIntervals * time.Second)))
+	assert.Equal(0, buf.Len())
+
+	// the stall is reported once, with a goroutine dump
+	assert.True(w.check(start.Add(10 * time.Second)))
+	assert.Contains(buf.String(), errorPrefix+"worker stalled")
+	assert.Contains(buf.String(), "goroutine ")
+	buf.Reset()
+	assert.True(w.check(start.Add(11 * time.Second)))
+	assert.Equal(0, buf.Len())
+	assert.Equal(uint64(1), w.stallCount())
+
+	// once the worker recovers, a new stall is reported again
+	w.beat(start.Add(12 * time.Second))
+	assert.False(w.check(start.Add(13 * time.Second)))
+	assert.True(w.check(start.Add(30 * time.Second)))
+	assert.Contains(buf.String(), "worker stalled")
+	assert.Equal(uint64(2), w.stallCount())
+}
+
+func TestTracerWatchdog(t *testing.T) {
+	assert := assert.New(t)
+
+	tracer, _ := getTestTracer()
+	defer tracer.Stop()
+
+	assert.NotNil(tracer.watchdog)
+	assert.Equal(uint64(0), tracer.Stats().WorkerStalls)
+}